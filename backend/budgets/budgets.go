@@ -0,0 +1,205 @@
+// Package budgets compara as despesas com o orçamento mensal de cada categoria, com o mesmo período do ano
+// anterior, e avisa os administradores quando uma categoria atinge os limites de alerta do orçamento.
+package budgets
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"cmdimport/backend/models"
+	"cmdimport/backend/money"
+	"cmdimport/backend/notifications"
+	"cmdimport/backend/timezone"
+	"cmdimport/backend/utils"
+
+	"gorm.io/gorm"
+)
+
+// AlertasPadrao são os limites de alerta de um orçamento sem limites próprios, em % do orçamento
+var AlertasPadrao = models.Percentuais{80, 100}
+
+func arredondar(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// Mes retorna o mês (YYYY-MM) de uma data do calendário. Para o mês de um instante, use timezone.Agora() ou
+// t.In(timezone.Local()).
+func Mes(t time.Time) string {
+	return t.Format("2006-01")
+}
+
+// Intervalo retorna o primeiro dia do mês e o primeiro dia do mês seguinte, como datas do calendário (as
+// despesas têm só o dia)
+func Intervalo(mes string) (time.Time, time.Time, error) {
+	inicio, err := time.Parse("2006-01", mes)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	return inicio, inicio.AddDate(0, 1, 0), nil
+}
+
+// Linha é o orçado contra o realizado de uma categoria (ou o total) no mês e no acumulado do ano
+type Linha struct {
+	CategoriaID          int             `gorm:"column:categoriaId" json:"categoriaId,omitempty"`
+	Categoria            string          `gorm:"column:categoria" json:"categoria,omitempty"`
+	Orcado               *money.Dinheiro `gorm:"column:orcado" json:"orcado"` // nil: categoria sem orçamento no mês
+	Realizado            money.Dinheiro  `gorm:"column:realizado" json:"realizado"`
+	Percentual           *float64        `gorm:"-" json:"percentual"` // Realizado sobre o orçado
+	Saldo                *money.Dinheiro `gorm:"-" json:"saldo"`      // Orçado menos realizado (negativo: estourado)
+	MesAnoAnterior       money.Dinheiro  `gorm:"column:mesAnoAnterior" json:"mesAnoAnterior"`
+	VariacaoAnual        *float64        `gorm:"-" json:"variacaoAnual"` // Realizado contra o mesmo mês do ano anterior, em %
+	OrcadoAno            *money.Dinheiro `gorm:"column:orcadoAno" json:"orcadoAno"`
+	AcumuladoAno         money.Dinheiro  `gorm:"column:acumuladoAno" json:"acumuladoAno"`                 // De janeiro até o mês
+	AcumuladoAnoAnterior money.Dinheiro  `gorm:"column:acumuladoAnoAnterior" json:"acumuladoAnoAnterior"` // Mesmo período do ano anterior
+	VariacaoAcumulada    *float64        `gorm:"-" json:"variacaoAcumulada"`
+}
+
+func variacao(atual, anterior money.Dinheiro) *float64 {
+	if anterior == 0 {
+		return nil
+	}
+	v := arredondar(float64(atual-anterior) / float64(anterior) * 100)
+	return &v
+}
+
+// calcular preenche os indicadores da linha
+func calcular(l *Linha) {
+	if l.Orcado != nil {
+		saldo := *l.Orcado - l.Realizado
+		l.Saldo = &saldo
+		if *l.Orcado > 0 {
+			p := arredondar(float64(l.Realizado) / float64(*l.Orcado) * 100)
+			l.Percentual = &p
+		}
+	}
+	l.VariacaoAnual = variacao(l.Realizado, l.MesAnoAnterior)
+	l.VariacaoAcumulada = variacao(l.AcumuladoAno, l.AcumuladoAnoAnterior)
+}
+
+// Relatorio compara, por categoria, o orçado com o realizado no mês e no acumulado do ano, e ambos com o
+// mesmo período do ano anterior. Categorias sem orçamento e sem despesas nos períodos ficam de fora.
+func Relatorio(db *gorm.DB, mes string) ([]Linha, Linha, error) {
+	inicio, fim, err := Intervalo(mes)
+	if err != nil {
+		return nil, Linha{}, err
+	}
+	inicioAA, fimAA := inicio.AddDate(-1, 0, 0), fim.AddDate(-1, 0, 0)
+	anoInicio := time.Date(inicio.Year(), 1, 1, 0, 0, 0, 0, time.UTC)
+	anoInicioAA := anoInicio.AddDate(-1, 0, 0)
+	data := func(t time.Time) string { return t.Format("2006-01-02") }
+
+	var linhas []Linha
+	err = db.Raw("SELECT cd.id as categoriaId, cd.nome as categoria, "+
+		"COALESCE(SUM(CASE WHEN d.data >= ? AND d.data < ? THEN d.valor END), 0) as realizado, "+
+		"COALESCE(SUM(CASE WHEN d.data >= ? AND d.data < ? THEN d.valor END), 0) as mesAnoAnterior, "+
+		"COALESCE(SUM(CASE WHEN d.data >= ? AND d.data < ? THEN d.valor END), 0) as acumuladoAno, "+
+		"COALESCE(SUM(CASE WHEN d.data >= ? AND d.data < ? THEN d.valor END), 0) as acumuladoAnoAnterior, "+
+		"(SELECT o.valor FROM OrcamentoDespesa o WHERE o.categoriaId = cd.id AND o.mes = ?) as orcado, "+
+		"(SELECT SUM(o.valor) FROM OrcamentoDespesa o WHERE o.categoriaId = cd.id AND o.mes >= ? AND o.mes <= ?) as orcadoAno "+
+		"FROM CategoriaDespesa cd "+
+		"LEFT JOIN Despesa d ON d.categoriaId = cd.id AND d.data >= ? AND d.data < ? AND d.deletedAt IS NULL "+
+		"WHERE cd.deletedAt IS NULL "+
+		"GROUP BY cd.id, cd.nome ORDER BY cd.nome",
+		data(inicio), data(fim),
+		data(inicioAA), data(fimAA),
+		data(anoInicio), data(fim),
+		data(anoInicioAA), data(fimAA),
+		mes,
+		Mes(anoInicio), mes,
+		data(anoInicioAA), data(fim),
+	).Scan(&linhas).Error
+	if err != nil {
+		return nil, Linha{}, err
+	}
+
+	resultado := make([]Linha, 0, len(linhas))
+	var total Linha
+	var orcado, orcadoAno money.Dinheiro
+	temOrcado, temOrcadoAno := false, false
+	for _, l := range linhas {
+		if l.Orcado == nil && l.OrcadoAno == nil && l.Realizado == 0 && l.MesAnoAnterior == 0 &&
+			l.AcumuladoAno == 0 && l.AcumuladoAnoAnterior == 0 {
+			continue
+		}
+		calcular(&l)
+		resultado = append(resultado, l)
+
+		total.Realizado += l.Realizado
+		total.MesAnoAnterior += l.MesAnoAnterior
+		total.AcumuladoAno += l.AcumuladoAno
+		total.AcumuladoAnoAnterior += l.AcumuladoAnoAnterior
+		if l.Orcado != nil {
+			orcado += *l.Orcado
+			temOrcado = true
+		}
+		if l.OrcadoAno != nil {
+			orcadoAno += *l.OrcadoAno
+			temOrcadoAno = true
+		}
+	}
+	if temOrcado {
+		total.Orcado = &orcado
+	}
+	if temOrcadoAno {
+		total.OrcadoAno = &orcadoAno
+	}
+	calcular(&total)
+	return resultado, total, nil
+}
+
+// Verificar confere os orçamentos do mês atual e do anterior (que ainda recebe lançamentos atrasados) e avisa os
+// administradores de cada limite de alerta atingido. Cada limite é avisado uma vez por valor de orçamento.
+// Retorna quantas notificações foram entregues.
+func Verificar(db *gorm.DB, agora time.Time) (int, error) {
+	var orcamentos []struct {
+		ID          int                `gorm:"column:id"`
+		Mes         string             `gorm:"column:mes"`
+		Valor       money.Dinheiro     `gorm:"column:valor"`
+		Alertas     models.Percentuais `gorm:"column:alertas"`
+		CategoriaID int                `gorm:"column:categoriaId"`
+		Categoria   string             `gorm:"column:categoria"`
+		Realizado   money.Dinheiro     `gorm:"column:realizado"`
+	}
+	hoje := timezone.Data(agora)
+	atual := time.Date(hoje.Year(), hoje.Month(), 1, 0, 0, 0, 0, time.UTC)
+	if err := db.Raw("SELECT o.id, o.mes, o.valor, o.alertas, o.categoriaId, cd.nome as categoria, "+
+		"(SELECT COALESCE(SUM(d.valor), 0) FROM Despesa d WHERE d.categoriaId = o.categoriaId AND d.deletedAt IS NULL "+
+		"AND d.data >= STR_TO_DATE(CONCAT(o.mes, '-01'), '%Y-%m-%d') "+
+		"AND d.data < DATE_ADD(STR_TO_DATE(CONCAT(o.mes, '-01'), '%Y-%m-%d'), INTERVAL 1 MONTH)) as realizado "+
+		"FROM OrcamentoDespesa o JOIN CategoriaDespesa cd ON cd.id = o.categoriaId AND cd.deletedAt IS NULL "+
+		"WHERE o.mes IN (?, ?) AND o.valor > 0",
+		Mes(atual.AddDate(0, -1, 0)), Mes(atual)).Scan(&orcamentos).Error; err != nil {
+		return 0, err
+	}
+
+	total := 0
+	for _, o := range orcamentos {
+		alertas := o.Alertas
+		if len(alertas) == 0 {
+			alertas = AlertasPadrao
+		}
+		percentual := float64(o.Realizado) / float64(o.Valor) * 100
+		for _, limite := range alertas {
+			if percentual < limite {
+				continue
+			}
+			titulo := fmt.Sprintf("Orçamento de %s atingiu %s%%", o.Categoria, utils.FormatFloatBR(limite, 0))
+			if limite >= 100 {
+				titulo = fmt.Sprintf("Orçamento de %s estourado", o.Categoria)
+			}
+			n, err := notifications.NotificarAdmins(db, notifications.Aviso{
+				Tipo:   notifications.TipoOrcamentoDespesa,
+				Titulo: titulo,
+				Mensagem: fmt.Sprintf("Despesas de %s em %s: R$ %s de R$ %s orçados (%s%%)", o.Categoria, o.Mes,
+					o.Realizado.BR(), o.Valor.BR(), utils.FormatFloatBR(percentual, 1)),
+				Chave: fmt.Sprintf("orcamento-despesa:%d:%g:%d", o.ID, limite, o.Valor.Centavos()),
+			})
+			if err != nil {
+				return total, err
+			}
+			total += n
+		}
+	}
+	return total, nil
+}
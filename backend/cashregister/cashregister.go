@@ -0,0 +1,309 @@
+// Package cashregister controla as sessões de caixa: a gaveta de dinheiro de um vendedor ou de uma loja,
+// da abertura (fundo de troco) ao fechamento (valor contado contra o esperado).
+package cashregister
+
+import (
+	"errors"
+	"fmt"
+
+	"cmdimport/backend/models"
+	"cmdimport/backend/money"
+	"cmdimport/backend/payments"
+	"cmdimport/backend/reservations"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Situações de uma sessão de caixa
+const (
+	StatusAberta   = "aberta"
+	StatusFechada  = "fechada"
+	StatusAprovada = "aprovada"
+)
+
+// Tipos de movimento
+const (
+	TipoVenda      = "venda"
+	TipoEstorno    = "estorno" // Venda deletada ou com valor em dinheiro reduzido
+	TipoSangria    = "sangria"
+	TipoSuprimento = "suprimento"
+	TipoSinal      = "sinal" // Sinal de reserva recebido em dinheiro; a devolução entra negativa
+)
+
+// Erros de movimentação com mensagem para o cliente
+var (
+	ErrSemCaixa          = errors.New("o caixa não está aberto")
+	ErrSaldoInsuficiente = errors.New("a sangria é maior que o dinheiro esperado na gaveta")
+)
+
+// travar busca a sessão aberta com a consulta informada, travando a linha até o fim da transação
+func travar(tx *gorm.DB, query string, args ...interface{}) (*models.SessaoCaixa, error) {
+	var sessao models.SessaoCaixa
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("status = ?", StatusAberta).Where(query, args...).
+		Order("id DESC").First(&sessao).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &sessao, nil
+}
+
+// SessaoAberta retorna o caixa aberto do vendedor ou, sem ele, o da loja do vendedor (nil se não há)
+func SessaoAberta(tx *gorm.DB, vendedor models.Usuario) (*models.SessaoCaixa, error) {
+	sessao, err := travar(tx, "usuarioId = ?", vendedor.ID)
+	if err != nil || sessao != nil || vendedor.LojaID == nil {
+		return sessao, err
+	}
+	return travar(tx, "lojaId = ?", *vendedor.LojaID)
+}
+
+// SessaoAbertaDoDono retorna o caixa aberto do mesmo dono (vendedor ou loja) da sessão informada
+func SessaoAbertaDoDono(tx *gorm.DB, sessao models.SessaoCaixa) (*models.SessaoCaixa, error) {
+	if sessao.UsuarioID != nil {
+		return travar(tx, "usuarioId = ?", *sessao.UsuarioID)
+	}
+	if sessao.LojaID != nil {
+		return travar(tx, "lojaId = ?", *sessao.LojaID)
+	}
+	return nil, nil
+}
+
+// SincronizarVenda lança no caixa a diferença entre o dinheiro recebido na venda (linhas de pagamento em
+// dinheiro) e o que já foi lançado para ela. Uma venda nova entra no caixa aberto do vendedor; alterações e
+// exclusões entram no caixa da venda, se ainda aberto, ou no caixa aberto do mesmo dono. Sem caixa aberto,
+// nada é lançado.
+func SincronizarVenda(tx *gorm.DB, vendaID string) error {
+	var dinheiro, lancado money.Dinheiro
+	if err := tx.Model(&models.PagamentoVenda{}).
+		Where("vendaId = ? AND metodo = ?", vendaID, payments.MetodoDinheiro).
+		Select("COALESCE(SUM(valor), 0)").Scan(&dinheiro).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(&models.MovimentoCaixa{}).
+		Where("vendaId = ?", vendaID).
+		Select("COALESCE(SUM(valor), 0)").Scan(&lancado).Error; err != nil {
+		return err
+	}
+	diferenca := dinheiro - lancado
+	if diferenca == 0 {
+		return nil
+	}
+
+	var vendedor models.Usuario
+	var sessao *models.SessaoCaixa
+	var ultimo models.MovimentoCaixa
+	err := tx.Where("vendaId = ?", vendaID).Order("id DESC").First(&ultimo).Error
+	switch {
+	case err == nil:
+		var original models.SessaoCaixa
+		if err := tx.First(&original, ultimo.SessaoCaixaID).Error; err != nil {
+			return err
+		}
+		sessao = &original
+		if original.Status != StatusAberta {
+			if sessao, err = SessaoAbertaDoDono(tx, original); err != nil {
+				return err
+			}
+		}
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		// Venda nova: o caixa é o do vendedor que registrou a venda
+		var historico models.HistoricoVenda
+		if err := tx.Where("vendaId = ?", vendaID).First(&historico).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil
+			}
+			return err
+		}
+		if err := tx.First(&vendedor, historico.UsuarioID).Error; err != nil {
+			return err
+		}
+		if sessao, err = SessaoAberta(tx, vendedor); err != nil {
+			return err
+		}
+		// Vendas anteriores à abertura (feitas sem caixa) não entram depois
+		if sessao != nil && historico.CreatedAt.Before(sessao.AbertaEm) {
+			return nil
+		}
+	default:
+		return err
+	}
+	if sessao == nil {
+		return nil
+	}
+
+	movimento := models.MovimentoCaixa{
+		SessaoCaixaID: sessao.ID,
+		Tipo:          TipoVenda,
+		Valor:         diferenca,
+		VendaID:       &vendaID,
+		UsuarioID:     vendedor.ID,
+		UsuarioNome:   vendedor.Nome,
+	}
+	descricao := "Venda " + vendaID
+	if ultimo.ID > 0 {
+		movimento.UsuarioID, movimento.UsuarioNome = ultimo.UsuarioID, ultimo.UsuarioNome
+		descricao = "Alteração da venda " + vendaID
+		if diferenca < 0 {
+			movimento.Tipo = TipoEstorno
+			descricao = "Estorno da venda " + vendaID
+		}
+	}
+	movimento.Descricao = &descricao
+	return tx.Create(&movimento).Error
+}
+
+// SincronizarReserva lança no caixa a diferença entre o sinal em dinheiro que fica com a loja (recebido e não
+// devolvido) e o que já foi lançado para a reserva. O sinal recebido entra no caixa aberto de quem reservou; a
+// devolução sai do caixa em que o sinal entrou, se ainda aberto, ou do caixa aberto do mesmo dono. Sem caixa
+// aberto, nada é lançado.
+func SincronizarReserva(tx *gorm.DB, reserva models.ReservaEstoque, operador models.Usuario) error {
+	var devido, lancado money.Dinheiro
+	if reserva.FormaSinal != nil && *reserva.FormaSinal == payments.MetodoDinheiro &&
+		(reserva.DestinoSinal == nil || *reserva.DestinoSinal != reservations.SinalDevolvido) {
+		devido = reserva.ValorSinal
+	}
+	if err := tx.Model(&models.MovimentoCaixa{}).
+		Where("reservaId = ?", reserva.ID).
+		Select("COALESCE(SUM(valor), 0)").Scan(&lancado).Error; err != nil {
+		return err
+	}
+	diferenca := devido - lancado
+	if diferenca == 0 {
+		return nil
+	}
+
+	var sessao *models.SessaoCaixa
+	var ultimo models.MovimentoCaixa
+	err := tx.Where("reservaId = ?", reserva.ID).Order("id DESC").First(&ultimo).Error
+	switch {
+	case err == nil:
+		var original models.SessaoCaixa
+		if err := tx.First(&original, ultimo.SessaoCaixaID).Error; err != nil {
+			return err
+		}
+		sessao = &original
+		if original.Status != StatusAberta {
+			if sessao, err = SessaoAbertaDoDono(tx, original); err != nil {
+				return err
+			}
+		}
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		if sessao, err = SessaoAberta(tx, operador); err != nil {
+			return err
+		}
+	default:
+		return err
+	}
+	if sessao == nil {
+		return nil
+	}
+
+	reservaID := reserva.ID
+	descricao := fmt.Sprintf("Sinal da reserva %d", reserva.ID)
+	if diferenca < 0 {
+		descricao = fmt.Sprintf("Devolução do sinal da reserva %d", reserva.ID)
+	}
+	if reserva.ClienteNome != nil {
+		descricao += " (" + *reserva.ClienteNome + ")"
+	}
+	return tx.Create(&models.MovimentoCaixa{
+		SessaoCaixaID: sessao.ID,
+		Tipo:          TipoSinal,
+		Valor:         diferenca,
+		ReservaID:     &reservaID,
+		Descricao:     &descricao,
+		UsuarioID:     operador.ID,
+		UsuarioNome:   operador.Nome,
+	}).Error
+}
+
+// Resumo são os totais de uma sessão de caixa
+type Resumo struct {
+	Abertura    money.Dinheiro `json:"abertura"`
+	Vendas      money.Dinheiro `json:"vendas"`      // Vendas em dinheiro (já descontado o troco)
+	QtdVendas   int            `json:"qtdVendas"`   // Vendas distintas com dinheiro
+	Estornos    money.Dinheiro `json:"estornos"`    // Negativo
+	Suprimentos money.Dinheiro `json:"suprimentos"` // Positivo
+	Sangrias    money.Dinheiro `json:"sangrias"`    // Negativo
+	Sinais      money.Dinheiro `json:"sinais"`      // Sinais de reserva em dinheiro, menos os devolvidos
+	Esperado    money.Dinheiro `json:"esperado"`
+}
+
+// Resumir soma os movimentos da sessão e calcula o valor esperado na gaveta
+func Resumir(db *gorm.DB, sessao models.SessaoCaixa) (Resumo, error) {
+	var linhas []struct {
+		Tipo   string         `gorm:"column:tipo"`
+		Total  money.Dinheiro `gorm:"column:total"`
+		Vendas int            `gorm:"column:vendas"`
+	}
+	if err := db.Model(&models.MovimentoCaixa{}).
+		Select("tipo, SUM(valor) as total, COUNT(DISTINCT vendaId) as vendas").
+		Where("sessaoCaixaId = ?", sessao.ID).
+		Group("tipo").Scan(&linhas).Error; err != nil {
+		return Resumo{}, err
+	}
+
+	r := Resumo{Abertura: sessao.ValorAbertura}
+	esperado := sessao.ValorAbertura
+	for _, l := range linhas {
+		switch l.Tipo {
+		case TipoVenda:
+			r.Vendas = l.Total
+			r.QtdVendas = l.Vendas
+		case TipoEstorno:
+			r.Estornos = l.Total
+		case TipoSuprimento:
+			r.Suprimentos = l.Total
+		case TipoSangria:
+			r.Sangrias = l.Total
+		case TipoSinal:
+			r.Sinais = l.Total
+		}
+		esperado += l.Total
+	}
+	r.Esperado = esperado
+	return r, nil
+}
+
+// Movimentar lança uma sangria (valor sai da gaveta) ou um suprimento (valor entra) na sessão aberta
+func Movimentar(tx *gorm.DB, sessaoID int, tipo string, valor money.Dinheiro, descricao *string, operador models.Usuario) (*models.MovimentoCaixa, error) {
+	if tipo != TipoSangria && tipo != TipoSuprimento {
+		return nil, fmt.Errorf("tipo de movimento inválido: %s", tipo)
+	}
+	sessao, err := travar(tx, "id = ?", sessaoID)
+	if err != nil {
+		return nil, err
+	}
+	if sessao == nil {
+		return nil, ErrSemCaixa
+	}
+
+	valor = valor.Abs()
+	if tipo == TipoSangria {
+		resumo, err := Resumir(tx, *sessao)
+		if err != nil {
+			return nil, err
+		}
+		if valor > resumo.Esperado {
+			return nil, fmt.Errorf("%w (R$ %s)", ErrSaldoInsuficiente, resumo.Esperado.BR())
+		}
+		valor = -valor
+	}
+
+	movimento := models.MovimentoCaixa{
+		SessaoCaixaID: sessao.ID,
+		Tipo:          tipo,
+		Valor:         valor,
+		Descricao:     descricao,
+		UsuarioID:     operador.ID,
+		UsuarioNome:   operador.Nome,
+	}
+	if err := tx.Create(&movimento).Error; err != nil {
+		return nil, err
+	}
+	return &movimento, nil
+}
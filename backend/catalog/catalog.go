@@ -0,0 +1,244 @@
+// Package catalog extrai marca, modelo, armazenamento e cor de nomes de produtos
+// e agrupa nomes parecidos em SKUs do catálogo (models.ProdutoSKU).
+package catalog
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Atributos identificam um SKU
+type Atributos struct {
+	Marca         string  `json:"marca"`
+	Modelo        string  `json:"modelo"`
+	Armazenamento *string `json:"armazenamento"`
+	Cor           *string `json:"cor"`
+}
+
+var (
+	reArmazenamento = regexp.MustCompile(`\b(\d+)\s*(gb|tb)\b`)
+	// memória + armazenamento, ex.: "8/256GB" ou "8GB+256GB"
+	reMemoria = regexp.MustCompile(`\b(\d+)\s*(?:gb)?\s*[/+]\s*(\d+)\s*(gb|tb)\b`)
+)
+
+// marcas conhecidas (primeira palavra do nome)
+var marcas = map[string]string{
+	"apple":    "Apple",
+	"samsung":  "Samsung",
+	"xiaomi":   "Xiaomi",
+	"motorola": "Motorola",
+	"realme":   "Realme",
+	"huawei":   "Huawei",
+	"jbl":      "JBL",
+	"google":   "Google",
+	"oneplus":  "OnePlus",
+	"asus":     "Asus",
+	"lg":       "LG",
+	"sony":     "Sony",
+}
+
+// linhas de produto que identificam a marca quando ela não aparece no nome
+var linhas = map[string]string{
+	"iphone":  "Apple",
+	"ipad":    "Apple",
+	"airpods": "Apple",
+	"macbook": "Apple",
+	"imac":    "Apple",
+	"galaxy":  "Samsung",
+	"redmi":   "Xiaomi",
+	"poco":    "Xiaomi",
+	"moto":    "Motorola",
+	"pixel":   "Google",
+}
+
+// cores conhecidas (português e inglês), já sem acentos
+var cores = map[string]string{
+	"cinza espacial":  "Cinza Espacial",
+	"space gray":      "Cinza Espacial",
+	"meia noite":      "Meia-noite",
+	"titanio natural": "Titânio Natural",
+	"titanio preto":   "Titânio Preto",
+	"titanio branco":  "Titânio Branco",
+	"titanio azul":    "Titânio Azul",
+	"preto":           "Preto",
+	"black":           "Preto",
+	"branco":          "Branco",
+	"white":           "Branco",
+	"azul":            "Azul",
+	"blue":            "Azul",
+	"vermelho":        "Vermelho",
+	"red":             "Vermelho",
+	"verde":           "Verde",
+	"green":           "Verde",
+	"rosa":            "Rosa",
+	"pink":            "Rosa",
+	"roxo":            "Roxo",
+	"purple":          "Roxo",
+	"lilas":           "Lilás",
+	"amarelo":         "Amarelo",
+	"yellow":          "Amarelo",
+	"dourado":         "Dourado",
+	"gold":            "Dourado",
+	"prata":           "Prata",
+	"silver":          "Prata",
+	"grafite":         "Grafite",
+	"graphite":        "Grafite",
+	"cinza":           "Cinza",
+	"gray":            "Cinza",
+	"grey":            "Cinza",
+	"midnight":        "Meia-noite",
+	"estelar":         "Estelar",
+	"starlight":       "Estelar",
+}
+
+// chavesCores ordena as cores compostas primeiro ("cinza espacial" antes de "cinza")
+var chavesCores = func() []string {
+	chaves := make([]string, 0, len(cores))
+	for chave := range cores {
+		chaves = append(chaves, chave)
+	}
+	sort.Slice(chaves, func(i, j int) bool {
+		pi, pj := len(strings.Fields(chaves[i])), len(strings.Fields(chaves[j]))
+		if pi != pj {
+			return pi > pj
+		}
+		return chaves[i] < chaves[j]
+	})
+	return chaves
+}()
+
+// grafias próprias usadas ao montar o nome do modelo
+var grafias = map[string]string{
+	"iphone":  "iPhone",
+	"ipad":    "iPad",
+	"airpods": "AirPods",
+	"macbook": "MacBook",
+	"imac":    "iMac",
+	"se":      "SE",
+	"5g":      "5G",
+	"4g":      "4G",
+}
+
+// Normalizar deixa o texto em minúsculas, sem acentos e com palavras separadas por um espaço
+func Normalizar(s string) string {
+	s = semAcentos(strings.ToLower(s))
+	var b strings.Builder
+	for _, r := range s {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune(' ')
+		}
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+func semAcentos(s string) string {
+	return strings.NewReplacer(
+		"á", "a", "à", "a", "â", "a", "ã", "a",
+		"é", "e", "ê", "e",
+		"í", "i",
+		"ó", "o", "ô", "o", "õ", "o",
+		"ú", "u", "ü", "u",
+		"ç", "c",
+	).Replace(s)
+}
+
+// Extrair separa os atributos de um nome livre, ex.: "Iphone 13 Pro 128gb azul".
+// cor é a cor cadastrada no lote e só é usada quando o nome não traz uma cor.
+func Extrair(nome string, cor *string) Atributos {
+	var attrs Atributos
+
+	minusculo := strings.ToLower(nome)
+	if m := reMemoria.FindStringSubmatch(minusculo); m != nil {
+		armazenamento := m[1] + "GB/" + m[2] + strings.ToUpper(m[3])
+		attrs.Armazenamento = &armazenamento
+		minusculo = strings.Replace(minusculo, m[0], " ", 1)
+	}
+	texto := " " + Normalizar(minusculo) + " "
+
+	if m := reArmazenamento.FindStringSubmatch(texto); m != nil && attrs.Armazenamento == nil {
+		armazenamento := m[1] + strings.ToUpper(m[2])
+		attrs.Armazenamento = &armazenamento
+		texto = strings.Replace(texto, m[0], " ", 1)
+	}
+
+	for _, chave := range chavesCores {
+		if strings.Contains(texto, " "+chave+" ") {
+			valor := cores[chave]
+			attrs.Cor = &valor
+			texto = strings.Replace(texto, " "+chave+" ", " ", 1)
+			break
+		}
+	}
+	if attrs.Cor == nil && cor != nil && strings.TrimSpace(*cor) != "" {
+		valor := NormalizarCor(*cor)
+		attrs.Cor = &valor
+	}
+
+	palavras := strings.Fields(texto)
+	if len(palavras) > 0 {
+		if marca, ok := marcas[palavras[0]]; ok {
+			attrs.Marca = marca
+			palavras = palavras[1:]
+		}
+	}
+	if attrs.Marca == "" && len(palavras) > 0 {
+		if marca, ok := linhas[palavras[0]]; ok {
+			attrs.Marca = marca
+		}
+	}
+
+	for i, p := range palavras {
+		palavras[i] = grafar(p)
+	}
+	attrs.Modelo = strings.Join(palavras, " ")
+
+	return attrs
+}
+
+// NormalizarCor converte a cor para a grafia do catálogo ("black" -> "Preto")
+func NormalizarCor(cor string) string {
+	if valor, ok := cores[Normalizar(cor)]; ok {
+		return valor
+	}
+	palavras := strings.Fields(strings.TrimSpace(cor))
+	for i, p := range palavras {
+		palavras[i] = grafar(strings.ToLower(p))
+	}
+	return strings.Join(palavras, " ")
+}
+
+func grafar(palavra string) string {
+	if g, ok := grafias[palavra]; ok {
+		return g
+	}
+	runes := []rune(palavra)
+	runes[0] = unicode.ToUpper(runes[0])
+	return string(runes)
+}
+
+// NomeSKU monta o nome de exibição, ex.: "Apple iPhone 13 Pro 128GB Azul"
+func NomeSKU(attrs Atributos) string {
+	partes := []string{}
+	if attrs.Marca != "" {
+		partes = append(partes, attrs.Marca)
+	}
+	if attrs.Modelo != "" {
+		partes = append(partes, attrs.Modelo)
+	}
+	if attrs.Armazenamento != nil && *attrs.Armazenamento != "" {
+		partes = append(partes, *attrs.Armazenamento)
+	}
+	if attrs.Cor != nil && *attrs.Cor != "" {
+		partes = append(partes, *attrs.Cor)
+	}
+	return strings.Join(partes, " ")
+}
+
+// CodigoSKU monta o código do SKU a partir dos atributos, ex.: "APPLE-IPHONE-13-PRO-128GB-AZUL"
+func CodigoSKU(attrs Atributos) string {
+	return strings.ToUpper(strings.ReplaceAll(Normalizar(NomeSKU(attrs)), " ", "-"))
+}
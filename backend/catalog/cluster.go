@@ -0,0 +1,292 @@
+package catalog
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"cmdimport/backend/models"
+
+	"gorm.io/gorm"
+)
+
+// Origem é um par nome/cor de ProdutoComprado que ainda não aponta para um SKU
+type Origem struct {
+	Nome  string  `json:"nome"`
+	Cor   *string `json:"cor"`
+	Lotes int     `json:"lotes"`
+}
+
+// Sugestao agrupa nomes livres que parecem ser o mesmo SKU, para revisão do admin
+type Sugestao struct {
+	Atributos
+	Codigo      string   `json:"codigo"`
+	Nome        string   `json:"nome"`
+	SKUID       *int     `json:"skuId"` // SKU já cadastrado com o mesmo código
+	CategoriaID *int     `json:"categoriaId"`
+	Origens     []Origem `json:"origens"`
+	Lotes       int      `json:"lotes"`
+}
+
+// ErrSKUNaoEncontrado indica que o SKU informado não existe
+var ErrSKUNaoEncontrado = errors.New("SKU não encontrado")
+
+// Sugerir agrupa os nomes de ProdutoComprado sem SKU pelos atributos extraídos do nome.
+// Modelos com erro de digitação (ex.: "iphne 13" e "iphone 13") caem no mesmo grupo
+// quando diferem em uma letra e têm os mesmos números, armazenamento e cor.
+func Sugerir(db *gorm.DB) ([]Sugestao, error) {
+	type linha struct {
+		Nome        string
+		Cor         *string
+		CategoriaID *int `gorm:"column:categoria_id"`
+		Lotes       int
+	}
+
+	var linhas []linha
+	if err := db.Model(&models.ProdutoComprado{}).
+		Select("nome, cor, MAX(categoriaId) as categoria_id, COUNT(*) as lotes").
+		Where("skuId IS NULL").
+		Group("nome, cor").
+		Order("nome ASC").
+		Scan(&linhas).Error; err != nil {
+		return nil, err
+	}
+
+	grupos := make(map[string]*Sugestao)
+	ordem := make([]string, 0)
+	for _, l := range linhas {
+		attrs := Extrair(l.Nome, l.Cor)
+		chave := chaveGrupo(attrs)
+
+		// Procurar um grupo com o mesmo modelo escrito de forma parecida
+		if _, ok := grupos[chave]; !ok {
+			for _, outra := range ordem {
+				if parecidos(grupos[outra].Atributos, attrs) {
+					chave = outra
+					break
+				}
+			}
+		}
+
+		grupo, ok := grupos[chave]
+		if !ok {
+			grupo = &Sugestao{
+				Atributos:   attrs,
+				Codigo:      CodigoSKU(attrs),
+				Nome:        NomeSKU(attrs),
+				CategoriaID: l.CategoriaID,
+			}
+			grupos[chave] = grupo
+			ordem = append(ordem, chave)
+		}
+		// Preferir os atributos do nome que trouxe a marca
+		if grupo.Marca == "" && attrs.Marca != "" {
+			grupo.Atributos = attrs
+			grupo.Codigo = CodigoSKU(attrs)
+			grupo.Nome = NomeSKU(attrs)
+		}
+		grupo.Origens = append(grupo.Origens, Origem{Nome: l.Nome, Cor: l.Cor, Lotes: l.Lotes})
+		grupo.Lotes += l.Lotes
+		if grupo.CategoriaID == nil {
+			grupo.CategoriaID = l.CategoriaID
+		}
+	}
+
+	sugestoes := make([]Sugestao, 0, len(ordem))
+	codigos := make([]string, 0, len(ordem))
+	for _, chave := range ordem {
+		sugestoes = append(sugestoes, *grupos[chave])
+		codigos = append(codigos, grupos[chave].Codigo)
+	}
+
+	// Apontar para SKUs já cadastrados com o mesmo código
+	if len(codigos) > 0 {
+		var existentes []models.ProdutoSKU
+		if err := db.Where("codigo IN ?", codigos).Find(&existentes).Error; err != nil {
+			return nil, err
+		}
+		porCodigo := make(map[string]int)
+		for _, sku := range existentes {
+			porCodigo[sku.Codigo] = sku.ID
+		}
+		for i := range sugestoes {
+			if id, ok := porCodigo[sugestoes[i].Codigo]; ok {
+				id := id
+				sugestoes[i].SKUID = &id
+			}
+		}
+	}
+
+	sort.SliceStable(sugestoes, func(i, j int) bool { return sugestoes[i].Nome < sugestoes[j].Nome })
+	return sugestoes, nil
+}
+
+// Aplicar cria (ou reutiliza) o SKU da sugestão revisada, aponta os lotes das origens para ele
+// e leva a precificação antiga do nome para o SKU. Retorna o SKU e quantos lotes foram associados.
+func Aplicar(db *gorm.DB, s Sugestao) (*models.ProdutoSKU, int64, error) {
+	if len(s.Origens) == 0 {
+		return nil, 0, errors.New("nenhum nome de produto informado")
+	}
+
+	var sku models.ProdutoSKU
+	var associados int64
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if s.SKUID != nil {
+			if err := tx.First(&sku, *s.SKUID).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return ErrSKUNaoEncontrado
+				}
+				return err
+			}
+		} else {
+			if strings.TrimSpace(s.Modelo) == "" {
+				return errors.New("modelo é obrigatório")
+			}
+			codigo := strings.TrimSpace(s.Codigo)
+			if codigo == "" {
+				codigo = CodigoSKU(s.Atributos)
+			}
+			err := tx.Where("codigo = ?", codigo).First(&sku).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				sku = models.ProdutoSKU{
+					Codigo:        codigo,
+					Nome:          NomeSKU(s.Atributos),
+					Marca:         s.Marca,
+					Modelo:        s.Modelo,
+					Armazenamento: s.Armazenamento,
+					Cor:           s.Cor,
+					CategoriaID:   s.CategoriaID,
+					Ativo:         true,
+				}
+				if err := tx.Create(&sku).Error; err != nil {
+					return err
+				}
+			} else if err != nil {
+				return err
+			}
+		}
+
+		nomes := make([]string, 0, len(s.Origens))
+		for _, o := range s.Origens {
+			cor := ""
+			if o.Cor != nil {
+				cor = *o.Cor
+			}
+			result := tx.Model(&models.ProdutoComprado{}).
+				Where("nome = ? AND COALESCE(cor, '') = ? AND skuId IS NULL", o.Nome, cor).
+				Update("skuId", sku.ID)
+			if result.Error != nil {
+				return result.Error
+			}
+			associados += result.RowsAffected
+			nomes = append(nomes, o.Nome)
+		}
+
+		return migrarPrecificacao(tx, &sku, nomes)
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return &sku, associados, nil
+}
+
+// migrarPrecificacao copia para o SKU os preços mais recentes cadastrados por nome.
+// Os registros por nome continuam valendo para lotes que ainda não têm SKU.
+func migrarPrecificacao(tx *gorm.DB, sku *models.ProdutoSKU, nomes []string) error {
+	var count int64
+	if err := tx.Model(&models.Precificacao{}).Where("skuId = ?", sku.ID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	var antiga models.Precificacao
+	err := tx.Where("nomeProduto IN ? AND skuId IS NULL", nomes).Order("updatedAt DESC").First(&antiga).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	nova := antiga
+	nova.ID = 0
+	nova.NomeProduto = ChavePrecificacao(sku)
+	nova.SKUID = &sku.ID
+	if err := tx.Create(&nova).Error; err != nil {
+		return fmt.Errorf("erro ao migrar precificação: %w", err)
+	}
+	return nil
+}
+
+// ChavePrecificacao é o valor gravado em Precificacao.nomeProduto (único) para preços de SKU
+func ChavePrecificacao(sku *models.ProdutoSKU) string {
+	return "SKU:" + sku.Codigo
+}
+
+func chaveGrupo(attrs Atributos) string {
+	armazenamento, cor := "", ""
+	if attrs.Armazenamento != nil {
+		armazenamento = *attrs.Armazenamento
+	}
+	if attrs.Cor != nil {
+		cor = *attrs.Cor
+	}
+	return strings.Join([]string{attrs.Marca, Normalizar(attrs.Modelo), armazenamento, cor}, "|")
+}
+
+// parecidos indica se dois modelos são o mesmo com erro de digitação
+func parecidos(a, b Atributos) bool {
+	if (a.Marca != "" && b.Marca != "" && a.Marca != b.Marca) || valor(a.Armazenamento) != valor(b.Armazenamento) || valor(a.Cor) != valor(b.Cor) {
+		return false
+	}
+	ma := strings.ReplaceAll(Normalizar(a.Modelo), " ", "")
+	mb := strings.ReplaceAll(Normalizar(b.Modelo), " ", "")
+	if len(ma) < 5 || len(mb) < 5 || digitos(ma) != digitos(mb) {
+		return false
+	}
+	return distancia(ma, mb) <= 1
+}
+
+func valor(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func digitos(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// distancia é a distância de edição (Levenshtein) entre a e b
+func distancia(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	anterior := make([]int, len(rb)+1)
+	atual := make([]int, len(rb)+1)
+	for j := range anterior {
+		anterior[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		atual[0] = i
+		for j := 1; j <= len(rb); j++ {
+			custo := 1
+			if ra[i-1] == rb[j-1] {
+				custo = 0
+			}
+			atual[j] = min(anterior[j]+1, atual[j-1]+1, anterior[j-1]+custo)
+		}
+		anterior, atual = atual, anterior
+	}
+	return anterior[len(rb)]
+}
@@ -0,0 +1,96 @@
+// Comando para migrar os nomes livres de ProdutoComprado para SKUs do catálogo.
+//
+// Primeiro gere as sugestões, revise o arquivo (remova grupos errados, corrija marca/modelo/cor,
+// mova nomes entre grupos) e depois aplique:
+//
+//	go run ./cmd/agrupar-sku -saida sugestoes.json
+//	go run ./cmd/agrupar-sku -aplicar sugestoes.json
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"cmdimport/backend/catalog"
+	"cmdimport/backend/config"
+	"cmdimport/backend/database"
+
+	"gorm.io/gorm"
+)
+
+func main() {
+	saida := flag.String("saida", "", "grava as sugestões de agrupamento neste arquivo JSON")
+	aplicar := flag.String("aplicar", "", "aplica as sugestões revisadas deste arquivo JSON")
+	flag.Parse()
+
+	if (*saida == "") == (*aplicar == "") {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	cfg := config.Load()
+	db, err := database.Connect(cfg.DatabaseURL)
+	if err != nil {
+		log.Fatalf("Erro ao conectar ao banco de dados: %v", err)
+	}
+
+	if *saida != "" {
+		sugestoes, err := catalog.Sugerir(db)
+		if err != nil {
+			log.Fatalf("Erro ao agrupar produtos: %v", err)
+		}
+
+		f, err := os.Create(*saida)
+		if err != nil {
+			log.Fatalf("Erro ao criar arquivo: %v", err)
+		}
+		defer f.Close()
+
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(sugestoes); err != nil {
+			log.Fatalf("Erro ao gravar sugestões: %v", err)
+		}
+
+		nomes := 0
+		for _, s := range sugestoes {
+			nomes += len(s.Origens)
+		}
+		fmt.Printf("%d nomes agrupados em %d SKUs sugeridos. Revise %s e aplique com -aplicar.\n",
+			nomes, len(sugestoes), *saida)
+		return
+	}
+
+	f, err := os.Open(*aplicar)
+	if err != nil {
+		log.Fatalf("Erro ao abrir arquivo: %v", err)
+	}
+	defer f.Close()
+
+	var sugestoes []catalog.Sugestao
+	if err := json.NewDecoder(f).Decode(&sugestoes); err != nil {
+		log.Fatalf("Arquivo de sugestões inválido: %v", err)
+	}
+
+	// Tudo ou nada: com erro em uma sugestão, nenhuma é aplicada e o arquivo pode ser corrigido e aplicado de novo
+	var linhas []string
+	err = db.Transaction(func(tx *gorm.DB) error {
+		for _, s := range sugestoes {
+			sku, associados, err := catalog.Aplicar(tx, s)
+			if err != nil {
+				return fmt.Errorf("%s: %w", s.Nome, err)
+			}
+			linhas = append(linhas, fmt.Sprintf("%s (%s): %d lotes associados", sku.Nome, sku.Codigo, associados))
+		}
+		return nil
+	})
+	if err != nil {
+		log.Fatalf("Erro ao aplicar %v. Nenhuma sugestão foi aplicada", err)
+	}
+	for _, l := range linhas {
+		fmt.Println(l)
+	}
+}
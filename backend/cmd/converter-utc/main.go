@@ -0,0 +1,70 @@
+// Comando para converter para UTC os horários gravados no horário local, antes de a conexão passar a gravar em UTC.
+//
+// Pare o servidor antes de converter: horários gravados pela versão nova já estão em UTC e seriam convertidos de
+// novo. Sem -executar, só lista as colunas datetime e quais já foram convertidas. Cada coluna convertida fica
+// registrada na tabela ConversaoUTC, então rodar o comando de novo (ou depois de uma falha) só converte as que
+// faltam:
+//
+//	go run ./cmd/converter-utc
+//	go run ./cmd/converter-utc -fuso America/Sao_Paulo -executar
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+
+	"cmdimport/backend/config"
+	"cmdimport/backend/database"
+)
+
+func main() {
+	cfg := config.Load()
+	fuso := flag.String("fuso", cfg.Timezone, "fuso em que os horários foram gravados (o do servidor que rodava a API), nome IANA ou deslocamento como -03:00")
+	executar := flag.Bool("executar", false, "converte as colunas que faltam; sem ele, só lista")
+	flag.Parse()
+
+	db, err := database.Connect(cfg.DatabaseURL)
+	if err != nil {
+		log.Fatalf("Erro ao conectar ao banco de dados: %v", err)
+	}
+
+	colunas, err := database.ColunasDatetime(db)
+	if err != nil {
+		log.Fatalf("Erro ao listar as colunas datetime: %v", err)
+	}
+	convertidas, err := database.Convertidas(db)
+	if err != nil {
+		log.Fatalf("Erro ao ler as conversões já feitas: %v", err)
+	}
+
+	if !*executar {
+		faltam := 0
+		for _, c := range colunas {
+			if conversao, ok := convertidas[c.String()]; ok {
+				fmt.Printf("%s: convertida de %s em %s\n", c, conversao.Fuso, conversao.ConvertidaEm.Format("02/01/2006 15:04"))
+				continue
+			}
+			fmt.Printf("%s: a converter\n", c)
+			faltam++
+		}
+		fmt.Printf("%d de %d colunas a converter. Pare o servidor e rode com -executar para converter de %s para UTC.\n",
+			faltam, len(colunas), *fuso)
+		return
+	}
+
+	if err := database.ConferirFuso(db, *fuso); err != nil {
+		log.Fatalf("Erro no fuso: %v", err)
+	}
+	for _, c := range colunas {
+		convertida, linhas, err := database.ConverterParaUTC(db, c, *fuso)
+		if err != nil {
+			log.Fatalf("Erro ao converter: %v", err)
+		}
+		if !convertida {
+			fmt.Printf("%s: já convertida\n", c)
+			continue
+		}
+		fmt.Printf("%s: %d linhas convertidas\n", c, linhas)
+	}
+}
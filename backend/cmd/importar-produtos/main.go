@@ -0,0 +1,74 @@
+// Comando para importar produtos comprados em lote a partir de uma planilha CSV ou XLSX.
+//
+// Uso:
+//
+//	go run ./cmd/importar-produtos -arquivo remessa.xlsx -dry-run
+//	go run ./cmd/importar-produtos -arquivo remessa.xlsx
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"cmdimport/backend/config"
+	"cmdimport/backend/database"
+	"cmdimport/backend/importer"
+)
+
+func main() {
+	arquivo := flag.String("arquivo", "", "caminho da planilha (.csv ou .xlsx)")
+	dryRun := flag.Bool("dry-run", false, "apenas valida as linhas, sem cadastrar")
+	saidaJSON := flag.Bool("json", false, "imprime o relatório em JSON")
+	flag.Parse()
+
+	if *arquivo == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	f, err := os.Open(*arquivo)
+	if err != nil {
+		log.Fatalf("Erro ao abrir arquivo: %v", err)
+	}
+	defer f.Close()
+
+	cfg := config.Load()
+	db, err := database.Connect(cfg.DatabaseURL)
+	if err != nil {
+		log.Fatalf("Erro ao conectar ao banco de dados: %v", err)
+	}
+
+	resultado, err := importer.ImportarArquivo(db, filepath.Base(*arquivo), f, *dryRun)
+	if err != nil {
+		log.Fatalf("Erro ao importar produtos: %v", err)
+	}
+
+	if *saidaJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(resultado)
+	} else {
+		fmt.Printf("Linhas: %d | Válidas: %d | Importadas: %d\n",
+			resultado.TotalLinhas, resultado.LinhasValidas, resultado.Importados)
+		for _, e := range resultado.Erros {
+			if e.Campo != "" {
+				fmt.Printf("Linha %d [%s]: %s\n", e.Linha, e.Campo, e.Mensagem)
+			} else {
+				fmt.Printf("Linha %d: %s\n", e.Linha, e.Mensagem)
+			}
+		}
+		if len(resultado.Erros) > 0 {
+			fmt.Println("Nenhum produto foi cadastrado.")
+		} else if *dryRun {
+			fmt.Println("Planilha válida (dry-run, nada foi gravado).")
+		}
+	}
+
+	if len(resultado.Erros) > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,488 @@
+// Package commissions calcula a comissão dos vendedores a partir das vendas: escolhe a regra de cada
+// item (vendedor, papel e categoria), aplica as faixas mensais e mantém o extrato de cada mês,
+// lançando estornos quando um item de um mês já fechado é devolvido, alterado ou transferido.
+package commissions
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"cmdimport/backend/models"
+	"cmdimport/backend/money"
+	"cmdimport/backend/timezone"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Bases de cálculo da comissão
+const (
+	BaseValor  = "valor"  // Valor vendido, já descontados os descontos da venda e do cupom
+	BaseMargem = "margem" // Valor vendido menos o custo do produto
+)
+
+// Papéis de usuário aos quais uma regra pode se aplicar
+const (
+	PapelVendedor = "vendedor"
+	PapelAdmin    = "admin"
+)
+
+// Situações do extrato mensal
+const (
+	StatusAberto  = "aberto"
+	StatusFechado = "fechado"
+	StatusPago    = "pago"
+)
+
+// Tipos de lançamento do extrato
+const (
+	TipoVenda   = "venda"
+	TipoEstorno = "estorno"
+	TipoAjuste  = "ajuste" // Lançamento manual; não é recalculado
+)
+
+// ErrExtratoEncerrado indica um extrato já fechado ou pago, que não pode mais ser recalculado
+var ErrExtratoEncerrado = errors.New("extrato de comissão já fechado")
+
+// Papel retorna o papel do usuário usado na escolha da regra
+func Papel(u models.Usuario) string {
+	if u.IsAdmin {
+		return PapelAdmin
+	}
+	return PapelVendedor
+}
+
+// Mes retorna o mês de referência (YYYY-MM) de um instante, no fuso do negócio
+func Mes(t time.Time) string {
+	return t.In(timezone.Local()).Format("2006-01")
+}
+
+// Intervalo retorna o início do mês e o início do mês seguinte, no fuso do negócio
+func Intervalo(mes string) (time.Time, time.Time, error) {
+	inicio, err := timezone.ParseMes(mes)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("mês inválido: use o formato AAAA-MM")
+	}
+	return inicio, timezone.InicioDoMes(inicio.AddDate(0, 1, 0)), nil
+}
+
+// Regras são as regras de comissão ativas
+type Regras []models.RegraComissao
+
+// CarregarRegras lê as regras de comissão ativas
+func CarregarRegras(db *gorm.DB) (Regras, error) {
+	var regras []models.RegraComissao
+	if err := db.Where("ativo = ?", true).Order("id ASC").Find(&regras).Error; err != nil {
+		return nil, err
+	}
+	return regras, nil
+}
+
+// Regra escolhe a regra mais específica para o vendedor e a categoria: a do vendedor vence a do papel,
+// que vence a geral; entre elas, a da categoria vence a sem categoria. Retorna nil se nenhuma se aplica.
+func (r Regras) Regra(u models.Usuario, categoriaID *int) *models.RegraComissao {
+	papel := Papel(u)
+	var escolhida *models.RegraComissao
+	melhor := -1
+	for i := range r {
+		regra := &r[i]
+		pontos := 0
+		if regra.UsuarioID != nil {
+			if *regra.UsuarioID != u.ID {
+				continue
+			}
+			pontos += 4
+		}
+		if regra.Papel != nil {
+			if *regra.Papel != papel {
+				continue
+			}
+			pontos += 2
+		}
+		if regra.CategoriaID != nil {
+			if categoriaID == nil || *regra.CategoriaID != *categoriaID {
+				continue
+			}
+			pontos++
+		}
+		if pontos > melhor {
+			escolhida, melhor = regra, pontos
+		}
+	}
+	return escolhida
+}
+
+// Percentual retorna o percentual da regra para o total vendido no mês: o da maior faixa
+// atingida ou, abaixo de todas as faixas, o percentual base da regra
+func Percentual(regra models.RegraComissao, totalMes money.Dinheiro) float64 {
+	faixas := append(models.FaixasComissao(nil), regra.Faixas...)
+	sort.Slice(faixas, func(i, j int) bool { return faixas[i].ValorMinimo < faixas[j].ValorMinimo })
+	percentual := regra.Percentual
+	for _, f := range faixas {
+		if totalMes >= f.ValorMinimo {
+			percentual = f.Percentual
+		}
+	}
+	return percentual
+}
+
+// Item é um item vendido com os valores usados na comissão
+type Item struct {
+	HistoricoVendaID int            `gorm:"column:id"`
+	VendaID          *string        `gorm:"column:vendaId"`
+	UsuarioID        int            `gorm:"column:usuarioId"`
+	Transferida      bool           `gorm:"column:transferida"`
+	ProdutoNome      string         `gorm:"column:produtoNome"`
+	Quantidade       int            `gorm:"column:quantidade"`
+	Receita          money.Dinheiro `gorm:"column:receita"` // Valor vendido menos os descontos da venda e do cupom
+	Custo            money.Dinheiro `gorm:"column:custo"`
+	CategoriaID      *int           `gorm:"column:categoriaId"`
+	CreatedAt        time.Time      `gorm:"column:createdAt"`
+}
+
+// ValorBase retorna o valor sobre o qual incide a comissão
+func (i Item) ValorBase(base string) money.Dinheiro {
+	if base == BaseMargem {
+		return i.Receita - i.Custo
+	}
+	return i.Receita
+}
+
+func (i Item) descricao() string {
+	if i.VendaID != nil {
+		return fmt.Sprintf("%dx %s (%s)", i.Quantidade, i.ProdutoNome, *i.VendaID)
+	}
+	return fmt.Sprintf("%dx %s", i.Quantidade, i.ProdutoNome)
+}
+
+func consultaItens(db *gorm.DB) *gorm.DB {
+	return db.Table("HistoricoVenda hv").
+		Select("hv.id, hv.vendaId, hv.usuarioId, hv.transferida, hv.produtoNome, hv.quantidade, hv.createdAt, " +
+			"hv.precoUnitario * hv.quantidade - hv.descontoVenda - hv.descontoCupom as receita, " +
+			"pc.preco * hv.quantidade as custo, pc.categoriaId").
+		Joins("JOIN Estoque e ON e.id = hv.estoqueId").
+		Joins("JOIN ProdutoComprado pc ON pc.id = e.produtoCompradoId")
+}
+
+// Extrato busca o extrato do vendedor no mês, criando-o (aberto) se ainda não existe.
+// A linha fica travada até o fim da transação.
+func Extrato(tx *gorm.DB, usuarioID int, mes string) (*models.FechamentoComissao, error) {
+	var extrato models.FechamentoComissao
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("usuarioId = ? AND mes = ?", usuarioID, mes).First(&extrato).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		extrato = models.FechamentoComissao{UsuarioID: usuarioID, Mes: mes, Status: StatusAberto}
+		err = tx.Create(&extrato).Error
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &extrato, nil
+}
+
+// Apuracao é o extrato do vendedor no mês com os seus lançamentos
+type Apuracao struct {
+	Extrato     models.FechamentoComissao
+	Lancamentos []models.LancamentoComissao
+}
+
+// Calcular monta o extrato do vendedor no mês sem gravar nada. O extrato aberto (ou ainda não criado) é
+// calculado com as vendas e os estornos atuais mais os ajustes já lançados; o fechado ou pago é o gravado.
+func Calcular(db *gorm.DB, usuario models.Usuario, mes string) (*Apuracao, error) {
+	if _, _, err := Intervalo(mes); err != nil {
+		return nil, err
+	}
+	apuracao := &Apuracao{}
+	err := db.Where("usuarioId = ? AND mes = ?", usuario.ID, mes).First(&apuracao.Extrato).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		apuracao.Extrato = models.FechamentoComissao{UsuarioID: usuario.ID, Mes: mes, Status: StatusAberto}
+	} else if err != nil {
+		return nil, err
+	}
+	if apuracao.Extrato.Status != StatusAberto {
+		if err := db.Where("fechamentoId = ?", apuracao.Extrato.ID).Order("id ASC").
+			Find(&apuracao.Lancamentos).Error; err != nil {
+			return nil, err
+		}
+		return apuracao, nil
+	}
+
+	lancamentos, totalMes, err := calcular(db, usuario, apuracao.Extrato.ID, mes)
+	if err != nil {
+		return nil, err
+	}
+	if apuracao.Extrato.ID != 0 {
+		var ajustes []models.LancamentoComissao
+		if err := db.Where("fechamentoId = ? AND tipo = ?", apuracao.Extrato.ID, TipoAjuste).Order("id ASC").
+			Find(&ajustes).Error; err != nil {
+			return nil, err
+		}
+		lancamentos = append(lancamentos, ajustes...)
+	}
+	apuracao.Lancamentos = lancamentos
+	apuracao.Extrato.TotalVendas = totalMes
+	apuracao.Extrato.TotalComissao = 0
+	for _, l := range lancamentos {
+		apuracao.Extrato.TotalComissao += l.Valor
+	}
+	return apuracao, nil
+}
+
+// Apurar recalcula e grava o extrato aberto do vendedor no mês: refaz os lançamentos das vendas do mês e os
+// estornos dos itens de meses fechados que mudaram desde o fechamento. Os ajustes manuais são mantidos.
+// Usado pelas ações que alteram o extrato (fechamento e ajustes); as consultas usam Calcular.
+func Apurar(tx *gorm.DB, usuario models.Usuario, mes string) (*models.FechamentoComissao, error) {
+	if _, _, err := Intervalo(mes); err != nil {
+		return nil, err
+	}
+	extrato, err := Extrato(tx, usuario.ID, mes)
+	if err != nil {
+		return nil, err
+	}
+	if extrato.Status != StatusAberto {
+		return extrato, ErrExtratoEncerrado
+	}
+
+	lancamentos, totalMes, err := calcular(tx, usuario, extrato.ID, mes)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Where("fechamentoId = ? AND tipo != ?", extrato.ID, TipoAjuste).
+		Delete(&models.LancamentoComissao{}).Error; err != nil {
+		return nil, err
+	}
+	if len(lancamentos) > 0 {
+		if err := tx.Create(&lancamentos).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	var totalComissao money.Dinheiro
+	if err := tx.Model(&models.LancamentoComissao{}).
+		Where("fechamentoId = ?", extrato.ID).
+		Select("COALESCE(SUM(valor), 0)").Scan(&totalComissao).Error; err != nil {
+		return nil, err
+	}
+	extrato.TotalVendas = totalMes
+	extrato.TotalComissao = totalComissao
+	if err := tx.Model(extrato).Updates(map[string]interface{}{
+		"totalVendas":   extrato.TotalVendas,
+		"totalComissao": extrato.TotalComissao,
+	}).Error; err != nil {
+		return nil, err
+	}
+	return extrato, nil
+}
+
+// calcular lê as vendas do mês e os itens dos meses fechados e monta os lançamentos do extrato aberto
+// (sem os ajustes), com o total vendido no mês
+func calcular(db *gorm.DB, usuario models.Usuario, extratoID int, mes string) ([]models.LancamentoComissao, money.Dinheiro, error) {
+	inicio, fim, err := Intervalo(mes)
+	if err != nil {
+		return nil, 0, err
+	}
+	regras, err := CarregarRegras(db)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var itens []Item
+	if err := consultaItens(db).
+		Where("hv.usuarioId = ? AND hv.createdAt >= ? AND hv.createdAt < ?", usuario.ID, inicio, fim).
+		Order("hv.createdAt ASC, hv.id ASC").
+		Scan(&itens).Error; err != nil {
+		return nil, 0, err
+	}
+	lancamentos, totalMes := lancamentosDoMes(usuario, extratoID, itens, regras)
+
+	estornos, err := estornos(db, usuario, extratoID, mes, regras)
+	if err != nil {
+		return nil, 0, err
+	}
+	return append(lancamentos, estornos...), totalMes, nil
+}
+
+// lancamentosDoMes lança a comissão de cada item vendido no mês; a faixa é escolhida pelo total vendido no mês
+func lancamentosDoMes(usuario models.Usuario, extratoID int, itens []Item, regras Regras) ([]models.LancamentoComissao, money.Dinheiro) {
+	var totalMes money.Dinheiro
+	for _, item := range itens {
+		totalMes += item.Receita
+	}
+
+	lancamentos := make([]models.LancamentoComissao, 0, len(itens))
+	for _, item := range itens {
+		regra := regras.Regra(usuario, item.CategoriaID)
+		if regra == nil {
+			continue
+		}
+		historicoID := item.HistoricoVendaID
+		percentual := Percentual(*regra, totalMes)
+		valorBase := item.ValorBase(regra.Base)
+		lancamentos = append(lancamentos, models.LancamentoComissao{
+			FechamentoID:     extratoID,
+			UsuarioID:        usuario.ID,
+			HistoricoVendaID: &historicoID,
+			VendaID:          item.VendaID,
+			Tipo:             TipoVenda,
+			Base:             regra.Base,
+			ValorBase:        valorBase,
+			Percentual:       percentual,
+			Valor:            valorBase.Percentual(percentual),
+			Descricao:        item.descricao(),
+		})
+	}
+	return lancamentos, totalMes
+}
+
+// lancado é o que já foi lançado para um item nos extratos fechados do vendedor
+type lancado struct {
+	base       string
+	percentual float64
+	valorBase  money.Dinheiro
+	descricao  string
+	vendaID    *string
+}
+
+// lancadosEmFechados soma, por item, o que foi lançado nos outros extratos do vendedor (no fechamento e em
+// estornos). Só interessam os itens lançados em um extrato fechado; os de extratos abertos são recalculados neles.
+func lancadosEmFechados(fechados []models.FechamentoComissao, anteriores []models.LancamentoComissao) map[int]*lancado {
+	fechadoIDs := make([]int, len(fechados))
+	for i, f := range fechados {
+		fechadoIDs[i] = f.ID
+	}
+	porItem := make(map[int]*lancado)
+	for _, l := range anteriores {
+		if _, ok := porItem[*l.HistoricoVendaID]; ok || !contem(fechadoIDs, l.FechamentoID) {
+			continue
+		}
+		porItem[*l.HistoricoVendaID] = &lancado{base: l.Base, percentual: l.Percentual, descricao: l.Descricao, vendaID: l.VendaID}
+	}
+	for _, l := range anteriores {
+		if p, ok := porItem[*l.HistoricoVendaID]; ok && l.Tipo != TipoAjuste {
+			p.valorBase += l.ValorBase
+		}
+	}
+	return porItem
+}
+
+// estornos lê os extratos fechados do vendedor, o que foi lançado neles e a situação atual dos itens, e
+// calcula os estornos com calcularEstornos
+func estornos(db *gorm.DB, usuario models.Usuario, extratoID int, mes string, regras Regras) ([]models.LancamentoComissao, error) {
+	var fechados []models.FechamentoComissao
+	if err := db.Where("usuarioId = ? AND status != ? AND mes < ?", usuario.ID, StatusAberto, mes).
+		Find(&fechados).Error; err != nil {
+		return nil, err
+	}
+	if len(fechados) == 0 {
+		return nil, nil
+	}
+	meses := make([]string, len(fechados))
+	for i, f := range fechados {
+		meses[i] = f.Mes
+	}
+
+	var anteriores []models.LancamentoComissao
+	if err := db.Where("usuarioId = ? AND fechamentoId != ? AND historicoVendaId IS NOT NULL", usuario.ID, extratoID).
+		Order("id ASC").Find(&anteriores).Error; err != nil {
+		return nil, err
+	}
+	porItem := lancadosEmFechados(fechados, anteriores)
+
+	// Situação atual dos itens lançados e dos itens que hoje pertencem ao vendedor nos meses fechados
+	var atuais []Item
+	mesVenda := "DATE_FORMAT(" + timezone.SQL("hv.createdAt") + ", '%Y-%m')"
+	query := consultaItens(db).Where(mesVenda+" IN ? AND hv.usuarioId = ?", meses, usuario.ID)
+	if len(porItem) > 0 {
+		ids := make([]int, 0, len(porItem))
+		for id := range porItem {
+			ids = append(ids, id)
+		}
+		query = consultaItens(db).Where("("+mesVenda+" IN ? AND hv.usuarioId = ?) OR hv.id IN ?",
+			meses, usuario.ID, ids)
+	}
+	if err := query.Order("hv.id ASC").Scan(&atuais).Error; err != nil {
+		return nil, err
+	}
+	return calcularEstornos(usuario, extratoID, fechados, anteriores, atuais, regras), nil
+}
+
+// calcularEstornos compara os itens de meses já fechados com o que foi lançado para eles (no fechamento e em
+// estornos de outros extratos) e lança a diferença: negativa se o item foi devolvido, reduzido ou
+// transferido para outro vendedor; positiva se aumentou ou se foi transferido para este vendedor.
+func calcularEstornos(usuario models.Usuario, extratoID int, fechados []models.FechamentoComissao,
+	anteriores []models.LancamentoComissao, atuais []Item, regras Regras) []models.LancamentoComissao {
+	totalPorMes := make(map[string]money.Dinheiro, len(fechados))
+	for _, f := range fechados {
+		totalPorMes[f.Mes] = f.TotalVendas
+	}
+	porItem := lancadosEmFechados(fechados, anteriores)
+	atualPorID := make(map[int]Item, len(atuais))
+	for _, item := range atuais {
+		if item.UsuarioID == usuario.ID {
+			atualPorID[item.HistoricoVendaID] = item
+		}
+	}
+
+	var lancamentos []models.LancamentoComissao
+	adicionar := func(historicoID int, vendaID *string, base string, percentual float64, diferenca money.Dinheiro, descricao string) {
+		if diferenca == 0 {
+			return
+		}
+		id := historicoID
+		lancamentos = append(lancamentos, models.LancamentoComissao{
+			FechamentoID:     extratoID,
+			UsuarioID:        usuario.ID,
+			HistoricoVendaID: &id,
+			VendaID:          vendaID,
+			Tipo:             TipoEstorno,
+			Base:             base,
+			ValorBase:        diferenca,
+			Percentual:       percentual,
+			Valor:            diferenca.Percentual(percentual),
+			Descricao:        descricao,
+		})
+	}
+
+	// Itens já comissionados: a diferença usa o percentual do fechamento
+	ids := make([]int, 0, len(porItem))
+	for id := range porItem {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	for _, id := range ids {
+		p := porItem[id]
+		var atual money.Dinheiro
+		descricao := "Estorno: " + p.descricao
+		if item, ok := atualPorID[id]; ok {
+			atual = item.ValorBase(p.base)
+			descricao = "Alteração: " + item.descricao()
+		}
+		adicionar(id, p.vendaID, p.base, p.percentual, atual-p.valorBase, descricao)
+	}
+
+	// Itens que chegaram ao vendedor depois do fechamento (transferência): a faixa é a do mês da venda
+	for _, item := range atuais {
+		if _, ok := porItem[item.HistoricoVendaID]; ok || item.UsuarioID != usuario.ID || !item.Transferida {
+			continue
+		}
+		regra := regras.Regra(usuario, item.CategoriaID)
+		if regra == nil {
+			continue
+		}
+		percentual := Percentual(*regra, totalPorMes[Mes(item.CreatedAt)])
+		adicionar(item.HistoricoVendaID, item.VendaID, regra.Base, percentual, item.ValorBase(regra.Base),
+			"Transferência: "+item.descricao())
+	}
+	return lancamentos
+}
+
+func contem(ids []int, id int) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,186 @@
+package commissions
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"cmdimport/backend/models"
+	"cmdimport/backend/money"
+)
+
+func inteiro(v int) *int {
+	return &v
+}
+
+func texto(s string) *string {
+	return &s
+}
+
+// Regra geral de 1%; vendedores com 3% e faixas de 4% e 5%; categoria 9 pela margem; o vendedor 8 com regra própria
+var regras = Regras{
+	{ID: 1, Base: BaseValor, Percentual: 1},
+	{ID: 2, Papel: texto(PapelVendedor), Base: BaseValor, Percentual: 3, Faixas: models.FaixasComissao{
+		{ValorMinimo: money.Reais(20000), Percentual: 5},
+		{ValorMinimo: money.Reais(10000), Percentual: 4},
+	}},
+	{ID: 3, Papel: texto(PapelVendedor), CategoriaID: inteiro(9), Base: BaseMargem, Percentual: 10},
+	{ID: 4, UsuarioID: inteiro(8), Base: BaseValor, Percentual: 6},
+}
+
+var (
+	vendedor = models.Usuario{ID: 7}
+	admin    = models.Usuario{ID: 1, IsAdmin: true}
+	outro    = models.Usuario{ID: 8}
+)
+
+func TestRegra(t *testing.T) {
+	casos := []struct {
+		nome      string
+		usuario   models.Usuario
+		categoria *int
+		regra     int
+	}{
+		{"papel vence a geral", vendedor, nil, 2},
+		{"categoria vence a sem categoria", vendedor, inteiro(9), 3},
+		{"categoria sem regra própria", vendedor, inteiro(5), 2},
+		{"vendedor vence papel e categoria", outro, inteiro(9), 4},
+		{"admin fica com a geral", admin, inteiro(9), 1},
+	}
+	for _, c := range casos {
+		regra := regras.Regra(c.usuario, c.categoria)
+		if regra == nil || regra.ID != c.regra {
+			t.Errorf("%s: regra %v, esperado %d", c.nome, regra, c.regra)
+		}
+	}
+	if regra := (Regras{regras[1]}).Regra(admin, nil); regra != nil {
+		t.Errorf("regra de vendedor aplicada ao admin: %d", regra.ID)
+	}
+}
+
+func TestPercentualFaixas(t *testing.T) {
+	casos := []struct {
+		totalMes money.Dinheiro
+		esperado float64
+	}{
+		{0, 3},
+		{money.Reais(9999.99), 3},
+		{money.Reais(10000), 4},
+		{money.Reais(19999.99), 4},
+		{money.Reais(20000), 5},
+		{money.Reais(50000), 5},
+	}
+	for _, c := range casos {
+		if got := Percentual(regras[1], c.totalMes); got != c.esperado {
+			t.Errorf("Percentual com R$ %s no mês = %v, esperado %v", c.totalMes.BR(), got, c.esperado)
+		}
+	}
+	if got := Percentual(regras[0], money.Reais(50000)); got != 1 {
+		t.Errorf("Percentual sem faixas = %v, esperado 1", got)
+	}
+}
+
+func TestLancamentosDoMes(t *testing.T) {
+	itens := []Item{
+		{HistoricoVendaID: 10, VendaID: texto("V1"), ProdutoNome: "iPhone 13", Quantidade: 2, Receita: money.Reais(8000), Custo: money.Reais(6000)},
+		{HistoricoVendaID: 11, VendaID: texto("V2"), ProdutoNome: "Capinha", Quantidade: 1, Receita: money.Reais(4000), Custo: money.Reais(3000), CategoriaID: inteiro(9)},
+	}
+	lancamentos, totalMes := lancamentosDoMes(vendedor, 3, itens, regras)
+	if totalMes != money.Reais(12000) {
+		t.Fatalf("totalMes = %v, esperado 12000.00", totalMes)
+	}
+	esperados := []struct {
+		base       string
+		valorBase  money.Dinheiro
+		percentual float64
+		valor      money.Dinheiro
+		descricao  string
+	}{
+		// O total do mês (R$ 12.000) atinge a faixa de 4%
+		{BaseValor, money.Reais(8000), 4, money.Reais(320), "2x iPhone 13 (V1)"},
+		{BaseMargem, money.Reais(1000), 10, money.Reais(100), "1x Capinha (V2)"},
+	}
+	if len(lancamentos) != len(esperados) {
+		t.Fatalf("%d lançamentos, esperado %d", len(lancamentos), len(esperados))
+	}
+	for i, e := range esperados {
+		l := lancamentos[i]
+		if l.Tipo != TipoVenda || l.FechamentoID != 3 || l.UsuarioID != vendedor.ID || *l.HistoricoVendaID != itens[i].HistoricoVendaID {
+			t.Errorf("lançamento %d: %+v", i+1, l)
+		}
+		if l.Base != e.base || l.ValorBase != e.valorBase || l.Percentual != e.percentual || l.Valor != e.valor || l.Descricao != e.descricao {
+			t.Errorf("lançamento %d = %s %v a %v%% = %v %q; esperado %s %v a %v%% = %v %q", i+1,
+				l.Base, l.ValorBase, l.Percentual, l.Valor, l.Descricao, e.base, e.valorBase, e.percentual, e.valor, e.descricao)
+		}
+	}
+
+	// Sem regra que se aplique, o item não é comissionado, mas conta no total do mês
+	lancamentos, totalMes = lancamentosDoMes(admin, 3, itens, Regras{regras[1]})
+	if len(lancamentos) != 0 || totalMes != money.Reais(12000) {
+		t.Errorf("sem regra: %d lançamentos, total %v", len(lancamentos), totalMes)
+	}
+}
+
+func TestCalcularEstornos(t *testing.T) {
+	fechados := []models.FechamentoComissao{
+		{ID: 1, UsuarioID: 7, Mes: "2026-08", Status: StatusFechado, TotalVendas: money.Reais(12000)},
+		{ID: 2, UsuarioID: 7, Mes: "2026-09", Status: StatusPago, TotalVendas: money.Reais(3000)},
+	}
+	lancamento := func(fechamento, item int, tipo, base string, percentual float64, valorBase money.Dinheiro) models.LancamentoComissao {
+		return models.LancamentoComissao{FechamentoID: fechamento, UsuarioID: 7, HistoricoVendaID: inteiro(item), VendaID: texto("V1"),
+			Tipo: tipo, Base: base, Percentual: percentual, ValorBase: valorBase, Descricao: "1x Produto (V1)"}
+	}
+	anteriores := []models.LancamentoComissao{
+		lancamento(1, 100, TipoVenda, BaseValor, 4, money.Reais(8000)),
+		lancamento(1, 101, TipoVenda, BaseMargem, 10, money.Reais(1000)),
+		lancamento(1, 102, TipoVenda, BaseValor, 4, money.Reais(500)),
+		lancamento(1, 103, TipoVenda, BaseValor, 4, money.Reais(300)),
+		lancamento(1, 100, TipoAjuste, BaseValor, 0, money.Reais(999)), // Ajuste manual não entra na comparação
+		lancamento(2, 102, TipoEstorno, BaseValor, 4, -money.Reais(500)),
+	}
+	agosto := time.Date(2026, 8, 15, 15, 0, 0, 0, time.UTC)
+	atuais := []Item{
+		{HistoricoVendaID: 100, VendaID: texto("V1"), UsuarioID: 7, ProdutoNome: "Produto", Quantidade: 1, Receita: money.Reais(6000), CreatedAt: agosto},
+		{HistoricoVendaID: 103, VendaID: texto("V3"), UsuarioID: 8, Transferida: true, ProdutoNome: "Produto", Quantidade: 1, Receita: money.Reais(300), CreatedAt: agosto},
+		{HistoricoVendaID: 104, VendaID: texto("V4"), UsuarioID: 7, Transferida: true, ProdutoNome: "Fone", Quantidade: 1, Receita: money.Reais(1000), CreatedAt: agosto},
+		{HistoricoVendaID: 105, VendaID: texto("V5"), UsuarioID: 7, ProdutoNome: "Fone", Quantidade: 1, Receita: money.Reais(700), CreatedAt: agosto},
+	}
+
+	lancamentos := calcularEstornos(vendedor, 3, fechados, anteriores, atuais, regras)
+	esperados := []struct {
+		item      int
+		valorBase money.Dinheiro
+		valor     money.Dinheiro
+		descricao string
+	}{
+		{100, -money.Reais(2000), -money.Reais(80), "Alteração: 1x Produto (V1)"}, // Devolução parcial
+		{101, -money.Reais(1000), -money.Reais(100), "Estorno: 1x Produto (V1)"},  // Item devolvido
+		{103, -money.Reais(300), -money.Reais(12), "Estorno: 1x Produto (V1)"},    // Transferido para outro vendedor
+		{104, money.Reais(1000), money.Reais(40), "Transferência: 1x Fone (V4)"},  // Recebido com a faixa de agosto
+	}
+	if len(lancamentos) != len(esperados) {
+		t.Fatalf("%d estornos, esperado %d: %+v", len(lancamentos), len(esperados), lancamentos)
+	}
+	for i, e := range esperados {
+		l := lancamentos[i]
+		if l.Tipo != TipoEstorno || l.FechamentoID != 3 || *l.HistoricoVendaID != e.item {
+			t.Errorf("estorno %d: tipo %s, extrato %d, item %d; esperado item %d", i+1, l.Tipo, l.FechamentoID, *l.HistoricoVendaID, e.item)
+		}
+		if l.ValorBase != e.valorBase || l.Valor != e.valor || l.Descricao != e.descricao {
+			t.Errorf("estorno %d = %v (%v) %q; esperado %v (%v) %q", i+1, l.ValorBase, l.Valor, l.Descricao, e.valorBase, e.valor, e.descricao)
+		}
+	}
+
+	// Com os estornos lançados no extrato de setembro, já fechado, nada muda na próxima apuração
+	for _, l := range lancamentos {
+		l.FechamentoID = 2
+		anteriores = append(anteriores, l)
+	}
+	if repetidos := calcularEstornos(vendedor, 3, fechados, anteriores, atuais, regras); len(repetidos) != 0 {
+		var descricoes []string
+		for _, l := range repetidos {
+			descricoes = append(descricoes, l.Descricao)
+		}
+		t.Errorf("estornos repetidos: %s", strings.Join(descricoes, "; "))
+	}
+}
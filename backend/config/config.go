@@ -5,6 +5,7 @@ import (
 	"log"
 	"net/url"
 	"os"
+	"strconv"
 	"strings"
 
 	"github.com/joho/godotenv"
@@ -15,6 +16,10 @@ type Config struct {
 	JWTSecret   string
 	Port        string
 	AllowOrigins []string
+	ExchangeRateURL string
+	LixeiraRetencaoDias int // Dias na lixeira antes da exclusão definitiva
+	Timezone string // Fuso horário do negócio (nome IANA), usado nas datas dos filtros e relatórios
+	TrustedProxies []string // IPs/CIDRs dos proxies cujo X-Forwarded-For vale como IP do cliente; vazio: nenhum
 }
 
 func Load() *Config {
@@ -29,13 +34,17 @@ func Load() *Config {
 
 	// Carregar AllowOrigins do .env (separado por vírgulas)
 	allowOriginsStr := getEnv("ALLOW_ORIGINS", "")
-	allowOrigins := parseAllowOrigins(allowOriginsStr)
+	allowOrigins := parseLista(allowOriginsStr)
 
 	return &Config{
 		DatabaseURL: databaseURL,
 		JWTSecret:   getEnv("JWT_SECRET", "your-secret-key-change-in-production"),
 		Port:        getEnv("PORT", "8080"),
 		AllowOrigins: allowOrigins,
+		ExchangeRateURL: getEnv("EXCHANGE_RATE_URL", ""),
+		LixeiraRetencaoDias: getEnvInt("LIXEIRA_RETENCAO_DIAS", 30),
+		Timezone: getEnv("TIMEZONE", "America/Sao_Paulo"),
+		TrustedProxies: parseLista(getEnv("TRUSTED_PROXIES", "")),
 	}
 }
 
@@ -88,7 +97,7 @@ func normalizeDatabaseURL(dbURL string) string {
 
 		// Se não tem senha, construir sem senha
 		if !hasPassword {
-			dsn := fmt.Sprintf("%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local",
+			dsn := fmt.Sprintf("%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=UTC",
 				username, host, port, database)
 			return dsn
 		}
@@ -99,8 +108,8 @@ func normalizeDatabaseURL(dbURL string) string {
 			password = decodedPassword
 		}
 
-		// Construir DSN no formato: usuario:senha@tcp(host:porta)/database?charset=utf8mb4&parseTime=True&loc=Local
-		dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local",
+		// Construir DSN no formato: usuario:senha@tcp(host:porta)/database?charset=utf8mb4&parseTime=True&loc=UTC
+		dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=UTC",
 			username, password, host, port, database)
 
 		return dsn
@@ -118,19 +127,33 @@ func getEnv(key, defaultValue string) string {
 	return value
 }
 
-// parseAllowOrigins converte string separada por vírgulas em slice de strings
-func parseAllowOrigins(originsStr string) []string {
-	if originsStr == "" {
+// getEnvInt lê uma variável inteira e positiva; vazia ou inválida, usa o padrão
+func getEnvInt(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		log.Printf("Valor inválido em %s: %q, usando %d", key, value, defaultValue)
+		return defaultValue
+	}
+	return n
+}
+
+// parseLista converte uma string separada por vírgulas em slice de strings, sem espaços e itens vazios
+func parseLista(valor string) []string {
+	if valor == "" {
 		return []string{}
 	}
 	
-	origins := strings.Split(originsStr, ",")
-	result := make([]string, 0, len(origins))
+	itens := strings.Split(valor, ",")
+	result := make([]string, 0, len(itens))
 	
-	for _, origin := range origins {
-		origin = strings.TrimSpace(origin)
-		if origin != "" {
-			result = append(result, origin)
+	for _, item := range itens {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			result = append(result, item)
 		}
 	}
 	
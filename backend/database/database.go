@@ -6,11 +6,28 @@ import (
 	"os"
 	"time"
 
+	mysqldriver "github.com/go-sql-driver/mysql"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 )
 
+// dsnUTC força a conexão a gravar e ler datas em UTC, qualquer que seja o fuso do servidor ou o loc da URL
+func dsnUTC(databaseURL string) (string, error) {
+	cfg, err := mysqldriver.ParseDSN(databaseURL)
+	if err != nil {
+		return "", err
+	}
+	cfg.ParseTime = true
+	cfg.Loc = time.UTC
+	if cfg.Params == nil {
+		cfg.Params = map[string]string{}
+	}
+	// NOW() e CURRENT_TIMESTAMP do banco também em UTC
+	cfg.Params["time_zone"] = "'+00:00'"
+	return cfg.FormatDSN(), nil
+}
+
 func Connect(databaseURL string) (*gorm.DB, error) {
 	// Configurar logger baseado no modo (silencioso em produção)
 	logLevel := logger.Info
@@ -18,8 +35,16 @@ func Connect(databaseURL string) (*gorm.DB, error) {
 		logLevel = logger.Error // Apenas erros em produção
 	}
 	
-	db, err := gorm.Open(mysql.Open(databaseURL), &gorm.Config{
+	dsn, err := dsnUTC(databaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("DATABASE_URL inválida: %w", err)
+	}
+
+	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{
 		Logger: logger.Default.LogMode(logLevel),
+		NowFunc: func() time.Time {
+			return time.Now().UTC()
+		},
 	})
 	if err != nil {
 		return nil, fmt.Errorf("erro ao conectar ao banco: %w", err)
@@ -0,0 +1,107 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"cmdimport/backend/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ColunaDatetime é uma coluna datetime de uma tabela do banco
+type ColunaDatetime struct {
+	Tabela string `gorm:"column:tabela"`
+	Coluna string `gorm:"column:coluna"`
+}
+
+func (c ColunaDatetime) String() string {
+	return c.Tabela + "." + c.Coluna
+}
+
+// ColunasDatetime lista as colunas datetime do banco da conexão. Ficam de fora as colunas date (só o dia do
+// calendário), as timestamp (o MySQL já as guarda em UTC), a ConversaoUTC e as tabelas internas (_prisma_migrations).
+func ColunasDatetime(db *gorm.DB) ([]ColunaDatetime, error) {
+	var colunas []ColunaDatetime
+	err := db.Raw("SELECT TABLE_NAME as tabela, COLUMN_NAME as coluna FROM information_schema.COLUMNS "+
+		"WHERE TABLE_SCHEMA = DATABASE() AND DATA_TYPE = 'datetime' AND TABLE_NAME != ? AND TABLE_NAME NOT LIKE '\\_%' "+
+		"ORDER BY TABLE_NAME, ORDINAL_POSITION", models.ConversaoUTC{}.TableName()).
+		Scan(&colunas).Error
+	return colunas, err
+}
+
+// Convertidas retorna as colunas já convertidas para UTC, por "Tabela.coluna"
+func Convertidas(db *gorm.DB) (map[string]models.ConversaoUTC, error) {
+	if !db.Migrator().HasTable(&models.ConversaoUTC{}) {
+		return map[string]models.ConversaoUTC{}, nil
+	}
+	var conversoes []models.ConversaoUTC
+	if err := db.Find(&conversoes).Error; err != nil {
+		return nil, err
+	}
+	porColuna := make(map[string]models.ConversaoUTC, len(conversoes))
+	for _, c := range conversoes {
+		porColuna[ColunaDatetime{c.Tabela, c.Coluna}.String()] = c
+	}
+	return porColuna, nil
+}
+
+// ConferirFuso confere se o MySQL converte o fuso (nome IANA ou deslocamento como -03:00). Pelo nome, CONVERT_TZ
+// precisa das tabelas de fuso do MySQL; sem elas, retorna NULL e a conversão apagaria os horários.
+func ConferirFuso(db *gorm.DB, fuso string) error {
+	var convertido sql.NullString
+	if err := db.Raw("SELECT CONVERT_TZ('2024-01-01 12:00:00', ?, '+00:00')", fuso).Row().Scan(&convertido); err != nil {
+		return err
+	}
+	if !convertido.Valid {
+		return fmt.Errorf("o MySQL não reconhece o fuso %q: carregue as tabelas de fuso (mysql_tzinfo_to_sql) "+
+			"ou informe o deslocamento, ex.: -03:00", fuso)
+	}
+	return nil
+}
+
+// ConverterParaUTC converte os horários da coluna do fuso informado para UTC e registra a conversão na mesma
+// transação. Se a coluna já foi convertida, não faz nada e retorna false.
+func ConverterParaUTC(db *gorm.DB, coluna ColunaDatetime, fuso string) (bool, int64, error) {
+	if !db.Migrator().HasTable(&models.ConversaoUTC{}) {
+		if err := db.Migrator().CreateTable(&models.ConversaoUTC{}); err != nil {
+			return false, 0, err
+		}
+	}
+
+	convertida := false
+	var linhas int64
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// O registro vem primeiro: em duas execuções ao mesmo tempo, a segunda espera a primeira e não converte
+		registro := models.ConversaoUTC{Tabela: coluna.Tabela, Coluna: coluna.Coluna, Fuso: fuso, ConvertidaEm: time.Now().UTC()}
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&registro)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		tabela, nome := identificador(coluna.Tabela), identificador(coluna.Coluna)
+		result = tx.Exec(fmt.Sprintf("UPDATE %s SET %s = CONVERT_TZ(%s, ?, '+00:00') WHERE %s IS NOT NULL",
+			tabela, nome, nome, nome), fuso)
+		if result.Error != nil {
+			return result.Error
+		}
+		linhas = result.RowsAffected
+		convertida = true
+		return tx.Model(&registro).Update("linhas", linhas).Error
+	})
+	if err != nil {
+		return false, 0, fmt.Errorf("%s: %w", coluna, err)
+	}
+	return convertida, linhas, nil
+}
+
+// identificador coloca o nome de tabela ou coluna entre crases para o SQL
+func identificador(nome string) string {
+	return "`" + strings.ReplaceAll(nome, "`", "``") + "`"
+}
@@ -0,0 +1,214 @@
+// Package discounts calcula descontos de item, de venda e de cupom, rateia o desconto da venda
+// entre os itens e confere o limite de desconto de cada papel.
+package discounts
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"cmdimport/backend/models"
+	"cmdimport/backend/money"
+	"cmdimport/backend/timezone"
+
+	"gorm.io/gorm"
+)
+
+// Tipos de desconto
+const (
+	TipoPercentual = "percentual"
+	TipoValor      = "valor"
+)
+
+// Papéis com limite de desconto
+const (
+	PapelVendedor = "vendedor"
+	PapelAdmin    = "admin"
+)
+
+// Situações de uma aprovação de desconto
+const (
+	AprovacaoPendente = "pendente"
+	AprovacaoAprovada = "aprovada"
+	AprovacaoRecusada = "recusada"
+	AprovacaoUsada    = "usada"
+)
+
+// ErrCupomInvalido indica um cupom inexistente, inativo, fora da validade ou esgotado
+var ErrCupomInvalido = errors.New("cupom inválido")
+
+// Desconto é um desconto percentual ou em reais
+type Desconto struct {
+	Tipo  string  `json:"tipo"` // "percentual" ou "valor"
+	Valor float64 `json:"valor"`
+}
+
+// Validar confere o tipo e o valor do desconto
+func (d Desconto) Validar() error {
+	switch d.Tipo {
+	case TipoPercentual:
+		if d.Valor < 0 || d.Valor > 100 {
+			return fmt.Errorf("desconto percentual deve estar entre 0 e 100")
+		}
+	case TipoValor:
+		if d.Valor < 0 {
+			return fmt.Errorf("desconto em valor não pode ser negativo")
+		}
+	default:
+		return fmt.Errorf("tipo de desconto inválido %q (use percentual ou valor)", d.Tipo)
+	}
+	return nil
+}
+
+// Sobre retorna o valor do desconto sobre base, em reais, sem passar da base
+func (d Desconto) Sobre(base money.Dinheiro) money.Dinheiro {
+	var valor money.Dinheiro
+	if d.Tipo == TipoPercentual {
+		valor = base.Percentual(d.Valor)
+	} else {
+		valor = money.Reais(d.Valor)
+	}
+	if valor < 0 {
+		return 0
+	}
+	if valor > base {
+		return base
+	}
+	return valor
+}
+
+// Ratear divide o desconto entre os itens proporcionalmente ao valor de cada um.
+// A diferença de arredondamento fica no item de maior valor, para que a soma feche.
+func Ratear(desconto money.Dinheiro, valores []money.Dinheiro) []money.Dinheiro {
+	partes := make([]money.Dinheiro, len(valores))
+	var total money.Dinheiro
+	maior := -1
+	for i, v := range valores {
+		total += v
+		if maior < 0 || v > valores[maior] {
+			maior = i
+		}
+	}
+	if desconto <= 0 || total <= 0 {
+		return partes
+	}
+
+	var distribuido money.Dinheiro
+	for i, v := range valores {
+		partes[i] = desconto.Proporcao(v, total)
+		distribuido += partes[i]
+	}
+	partes[maior] += desconto - distribuido
+	return partes
+}
+
+// Percentual é o desconto em % sobre o valor de tabela
+func Percentual(desconto, tabela money.Dinheiro) float64 {
+	if tabela <= 0 {
+		return 0
+	}
+	return math.Round(float64(desconto)/float64(tabela)*10000) / 100
+}
+
+// DoItem é o desconto dado no preço do item em relação ao de tabela; preço acima da tabela não conta
+func DoItem(tabela, praticado money.Dinheiro, quantidade int) money.Dinheiro {
+	if praticado >= tabela {
+		return 0
+	}
+	return (tabela - praticado).Vezes(quantidade)
+}
+
+// AcimaDoLimite indica se o desconto (%) passa do limite do papel; sem limite cadastrado, nunca passa
+func AcimaDoLimite(percentual, limite float64, temLimite bool) bool {
+	return temLimite && percentual > limite
+}
+
+// Papel retorna o papel do usuário para o limite de desconto
+func Papel(u models.Usuario) string {
+	if u.IsAdmin {
+		return PapelAdmin
+	}
+	return PapelVendedor
+}
+
+// LimiteDoPapel retorna o desconto máximo (%) do papel sem aprovação.
+// Sem limite cadastrado, retorna false (desconto livre).
+func LimiteDoPapel(db *gorm.DB, papel string) (float64, bool, error) {
+	var limite models.LimiteDesconto
+	err := db.Where("papel = ?", papel).First(&limite).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return limite.PercentualMaximo, true, nil
+}
+
+// BuscarCupom encontra o cupom ativo pelo código (sem diferenciar maiúsculas)
+func BuscarCupom(db *gorm.DB, codigo string) (*models.Cupom, error) {
+	var cupom models.Cupom
+	err := db.Where("codigo = ? AND ativo = ?", NormalizarCodigo(codigo), true).First(&cupom).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrCupomInvalido
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &cupom, nil
+}
+
+// NormalizarCodigo deixa o código do cupom em maiúsculas e sem espaços nas pontas
+func NormalizarCodigo(codigo string) string {
+	return strings.ToUpper(strings.TrimSpace(codigo))
+}
+
+// ValidarCupom confere validade, limite de usos e valor mínimo e retorna o desconto sobre subtotal
+func ValidarCupom(cupom *models.Cupom, subtotal money.Dinheiro, agora time.Time) (money.Dinheiro, error) {
+	if !cupom.Ativo {
+		return 0, ErrCupomInvalido
+	}
+	if cupom.ValidoDe != nil && agora.Before(*cupom.ValidoDe) {
+		return 0, fmt.Errorf("%w: válido a partir de %s", ErrCupomInvalido, cupom.ValidoDe.In(timezone.Local()).Format("02/01/2006"))
+	}
+	if cupom.ValidoAte != nil && agora.After(*cupom.ValidoAte) {
+		return 0, fmt.Errorf("%w: expirou em %s", ErrCupomInvalido, cupom.ValidoAte.In(timezone.Local()).Format("02/01/2006"))
+	}
+	if cupom.LimiteUsos != nil && cupom.Usos >= *cupom.LimiteUsos {
+		return 0, fmt.Errorf("%w: limite de usos atingido", ErrCupomInvalido)
+	}
+	if subtotal < cupom.ValorMinimo {
+		return 0, fmt.Errorf("%w: valor mínimo da compra é R$ %s", ErrCupomInvalido, cupom.ValorMinimo.BR())
+	}
+	return Desconto{Tipo: cupom.Tipo, Valor: cupom.Valor}.Sobre(subtotal), nil
+}
+
+// UsarCupom conta um uso do cupom, sem passar do limite mesmo com vendas simultâneas
+func UsarCupom(tx *gorm.DB, cupom *models.Cupom) error {
+	result := tx.Model(&models.Cupom{}).
+		Where("id = ? AND (limiteUsos IS NULL OR usos < limiteUsos)", cupom.ID).
+		Update("usos", gorm.Expr("usos + 1"))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w: limite de usos atingido", ErrCupomInvalido)
+	}
+	return nil
+}
+
+// DevolverCupom desconta o uso do cupom de uma venda deletada
+func DevolverCupom(tx *gorm.DB, codigo string) error {
+	return tx.Model(&models.Cupom{}).
+		Where("codigo = ? AND usos > 0", codigo).
+		Update("usos", gorm.Expr("usos - 1")).Error
+}
+
+// LiberarAprovacao volta para aprovada a aprovação de desconto usada na venda deletada, para que seja usada de novo
+func LiberarAprovacao(tx *gorm.DB, vendaID string) error {
+	return tx.Model(&models.AprovacaoDesconto{}).
+		Where("vendaId = ? AND status = ?", vendaID, AprovacaoUsada).
+		Updates(map[string]interface{}{"status": AprovacaoAprovada, "vendaId": nil}).Error
+}
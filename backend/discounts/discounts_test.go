@@ -0,0 +1,206 @@
+package discounts
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"cmdimport/backend/models"
+	"cmdimport/backend/money"
+)
+
+func TestValidar(t *testing.T) {
+	validos := []Desconto{
+		{TipoPercentual, 0},
+		{TipoPercentual, 100},
+		{TipoValor, 0},
+		{TipoValor, 1500.5},
+	}
+	for _, d := range validos {
+		if err := d.Validar(); err != nil {
+			t.Errorf("%+v: %v", d, err)
+		}
+	}
+	invalidos := []Desconto{
+		{TipoPercentual, -1},
+		{TipoPercentual, 100.01},
+		{TipoValor, -0.01},
+		{"cortesia", 10},
+		{"", 10},
+	}
+	for _, d := range invalidos {
+		if err := d.Validar(); err == nil {
+			t.Errorf("%+v aceito como válido", d)
+		}
+	}
+}
+
+func TestSobre(t *testing.T) {
+	casos := []struct {
+		desconto Desconto
+		base     money.Dinheiro
+		esperado money.Dinheiro
+	}{
+		{Desconto{TipoPercentual, 10}, money.Reais(150), money.Reais(15)},
+		{Desconto{TipoPercentual, 12.5}, money.Reais(0.99), 12},
+		{Desconto{TipoPercentual, 100}, money.Reais(80), money.Reais(80)},
+		{Desconto{TipoValor, 25}, money.Reais(80), money.Reais(25)},
+		{Desconto{TipoValor, 100}, money.Reais(80), money.Reais(80)},
+		{Desconto{TipoValor, -5}, money.Reais(80), 0},
+		{Desconto{TipoPercentual, 10}, 0, 0},
+	}
+	for _, c := range casos {
+		if got := c.desconto.Sobre(c.base); got != c.esperado {
+			t.Errorf("%+v sobre %v = %v, esperado %v", c.desconto, c.base, got, c.esperado)
+		}
+	}
+}
+
+func TestRatear(t *testing.T) {
+	casos := []struct {
+		desconto money.Dinheiro
+		valores  []money.Dinheiro
+		esperado []money.Dinheiro
+	}{
+		{1000, []money.Dinheiro{10000, 20000, 30000}, []money.Dinheiro{167, 333, 500}},
+		// A sobra do arredondamento fica no item de maior valor (o primeiro, no empate)
+		{1000, []money.Dinheiro{100, 100, 100}, []money.Dinheiro{334, 333, 333}},
+		{10, []money.Dinheiro{300, 900, 300}, []money.Dinheiro{2, 6, 2}},
+		{1, []money.Dinheiro{500, 500}, []money.Dinheiro{1, 0}},
+		{700, []money.Dinheiro{0, 5000}, []money.Dinheiro{0, 700}},
+		{0, []money.Dinheiro{100, 200}, []money.Dinheiro{0, 0}},
+		{500, []money.Dinheiro{0, 0}, []money.Dinheiro{0, 0}},
+		{500, nil, []money.Dinheiro{}},
+	}
+	for _, c := range casos {
+		partes := Ratear(c.desconto, c.valores)
+		if len(partes) != len(c.esperado) {
+			t.Errorf("Ratear(%v, %v) = %v, esperado %v", c.desconto, c.valores, partes, c.esperado)
+			continue
+		}
+		var soma money.Dinheiro
+		for i := range partes {
+			if partes[i] != c.esperado[i] {
+				t.Errorf("Ratear(%v, %v) = %v, esperado %v", c.desconto, c.valores, partes, c.esperado)
+				break
+			}
+			soma += partes[i]
+		}
+		var total money.Dinheiro
+		for _, v := range c.valores {
+			total += v
+		}
+		if total > 0 && soma != c.desconto {
+			t.Errorf("Ratear(%v, %v) soma %v", c.desconto, c.valores, soma)
+		}
+	}
+}
+
+func TestPercentual(t *testing.T) {
+	casos := []struct {
+		desconto, tabela money.Dinheiro
+		esperado         float64
+	}{
+		{money.Reais(10), money.Reais(100), 10},
+		{money.Reais(10), money.Reais(30), 33.33},
+		{money.Reais(20), money.Reais(30), 66.67},
+		{0, money.Reais(30), 0},
+		{money.Reais(10), 0, 0},
+	}
+	for _, c := range casos {
+		if got := Percentual(c.desconto, c.tabela); got != c.esperado {
+			t.Errorf("Percentual(%v, %v) = %v, esperado %v", c.desconto, c.tabela, got, c.esperado)
+		}
+	}
+}
+
+func TestDoItem(t *testing.T) {
+	casos := []struct {
+		tabela, praticado money.Dinheiro
+		quantidade        int
+		esperado          money.Dinheiro
+	}{
+		{money.Reais(100), money.Reais(90), 3, money.Reais(30)},
+		{money.Reais(100), money.Reais(100), 2, 0},
+		{money.Reais(100), money.Reais(120), 2, 0}, // Acréscimo não compensa desconto de outro item
+		{money.Reais(99.99), 0, 1, money.Reais(99.99)},
+	}
+	for _, c := range casos {
+		if got := DoItem(c.tabela, c.praticado, c.quantidade); got != c.esperado {
+			t.Errorf("DoItem(%v, %v, %d) = %v, esperado %v", c.tabela, c.praticado, c.quantidade, got, c.esperado)
+		}
+	}
+}
+
+func TestAcimaDoLimite(t *testing.T) {
+	tabela := money.Reais(10000)
+	casos := []struct {
+		nome      string
+		desconto  money.Dinheiro
+		limite    float64
+		temLimite bool
+		acima     bool
+	}{
+		{"no limite", money.Reais(1000), 10, true, false},
+		{"R$ 1 acima", money.Reais(1001), 10, true, true},
+		{"abaixo do centésimo de ponto", money.Reais(1000.49), 10, true, false},
+		{"sem limite cadastrado", money.Reais(9000), 10, false, false},
+		{"limite zero", money.Reais(0.01), 0, true, false},
+		{"limite zero com desconto", money.Reais(1), 0, true, true},
+	}
+	for _, c := range casos {
+		percentual := Percentual(c.desconto, tabela)
+		if got := AcimaDoLimite(percentual, c.limite, c.temLimite); got != c.acima {
+			t.Errorf("%s: %v%% contra %v%% = %v, esperado %v", c.nome, percentual, c.limite, got, c.acima)
+		}
+	}
+}
+
+func TestPapel(t *testing.T) {
+	if Papel(models.Usuario{IsAdmin: true}) != PapelAdmin || Papel(models.Usuario{}) != PapelVendedor {
+		t.Errorf("Papel não separa admin de vendedor")
+	}
+}
+
+func TestValidarCupom(t *testing.T) {
+	agora := time.Date(2026, 10, 19, 15, 0, 0, 0, time.UTC)
+	antes, depois := agora.Add(-time.Hour), agora.Add(time.Hour)
+	limite := 5
+
+	casos := []struct {
+		nome     string
+		cupom    models.Cupom
+		subtotal money.Dinheiro
+		desconto money.Dinheiro
+		erro     string
+	}{
+		{"percentual", models.Cupom{Tipo: TipoPercentual, Valor: 10, Ativo: true}, money.Reais(250), money.Reais(25), ""},
+		{"valor limitado ao subtotal", models.Cupom{Tipo: TipoValor, Valor: 50, Ativo: true}, money.Reais(30), money.Reais(30), ""},
+		{"dentro da validade", models.Cupom{Tipo: TipoValor, Valor: 5, Ativo: true, ValidoDe: &antes, ValidoAte: &depois, LimiteUsos: &limite, Usos: 4}, money.Reais(30), money.Reais(5), ""},
+		{"no valor mínimo", models.Cupom{Tipo: TipoValor, Valor: 5, Ativo: true, ValorMinimo: money.Reais(30)}, money.Reais(30), money.Reais(5), ""},
+		{"inativo", models.Cupom{Tipo: TipoValor, Valor: 5}, money.Reais(30), 0, "cupom inválido"},
+		{"antes da validade", models.Cupom{Tipo: TipoValor, Valor: 5, Ativo: true, ValidoDe: &depois}, money.Reais(30), 0, "válido a partir de"},
+		{"expirado", models.Cupom{Tipo: TipoValor, Valor: 5, Ativo: true, ValidoAte: &antes}, money.Reais(30), 0, "expirou em"},
+		{"esgotado", models.Cupom{Tipo: TipoValor, Valor: 5, Ativo: true, LimiteUsos: &limite, Usos: 5}, money.Reais(30), 0, "limite de usos"},
+		{"abaixo do mínimo", models.Cupom{Tipo: TipoValor, Valor: 5, Ativo: true, ValorMinimo: money.Reais(30)}, money.Reais(29.99), 0, "valor mínimo da compra é R$ 30,00"},
+	}
+	for _, c := range casos {
+		desconto, err := ValidarCupom(&c.cupom, c.subtotal, agora)
+		if c.erro == "" {
+			if err != nil || desconto != c.desconto {
+				t.Errorf("%s: %v, %v; esperado %v", c.nome, desconto, err, c.desconto)
+			}
+			continue
+		}
+		if !errors.Is(err, ErrCupomInvalido) || !strings.Contains(err.Error(), c.erro) {
+			t.Errorf("%s: erro %v, esperado contendo %q", c.nome, err, c.erro)
+		}
+	}
+}
+
+func TestNormalizarCodigo(t *testing.T) {
+	if got := NormalizarCodigo("  natal10 "); got != "NATAL10" {
+		t.Errorf("NormalizarCodigo = %q", got)
+	}
+}
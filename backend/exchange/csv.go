@@ -0,0 +1,62 @@
+package exchange
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"cmdimport/backend/spreadsheet"
+	"cmdimport/backend/utils"
+)
+
+// CSVProvider lê cotações de um arquivo CSV com as colunas data e taxa.
+// Aceita separador ";" ou ",", cabeçalho opcional, datas em YYYY-MM-DD ou DD/MM/YYYY
+// e taxas no formato brasileiro (5,1234) ou internacional (5.1234).
+type CSVProvider struct {
+	Reader io.Reader
+}
+
+func (p *CSVProvider) Fonte() string {
+	return "csv"
+}
+
+func (p *CSVProvider) Buscar(ctx context.Context, inicio, fim time.Time) ([]Cotacao, error) {
+	linhas, err := spreadsheet.LerCSV(p.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	cotacoes := make([]Cotacao, 0, len(linhas))
+	for i, linha := range linhas {
+		if len(linha) == 0 || (len(linha) == 1 && strings.TrimSpace(linha[0]) == "") {
+			continue
+		}
+		if len(linha) < 2 {
+			return nil, fmt.Errorf("linha %d: esperado data e taxa", i+1)
+		}
+
+		data, err := ParseData(linha[0])
+		if err != nil {
+			// Primeira linha pode ser cabeçalho
+			if i == 0 {
+				continue
+			}
+			return nil, fmt.Errorf("linha %d: %v", i+1, err)
+		}
+
+		taxa, err := utils.ParseFloatBR(strings.TrimSpace(linha[1]))
+		if err != nil || taxa <= 0 {
+			return nil, fmt.Errorf("linha %d: taxa inválida %q", i+1, linha[1])
+		}
+
+		if (!inicio.IsZero() && data.Before(SomenteData(inicio))) || (!fim.IsZero() && data.After(SomenteData(fim))) {
+			continue
+		}
+
+		cotacoes = append(cotacoes, Cotacao{Data: data, Taxa: taxa})
+	}
+
+	return cotacoes, nil
+}
@@ -0,0 +1,111 @@
+// Package exchange importa e consulta as cotações do dólar usadas no custo dos produtos.
+package exchange
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"cmdimport/backend/models"
+	"cmdimport/backend/timezone"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Cotacao é uma taxa do dólar válida a partir de uma data
+type Cotacao struct {
+	Data time.Time
+	Taxa float64
+}
+
+// Provider é uma fonte de cotações (arquivo CSV, serviço HTTP, ...)
+type Provider interface {
+	// Fonte identifica a origem gravada em CotacaoDolar.Fonte
+	Fonte() string
+	// Buscar retorna as cotações entre inicio e fim (inclusive).
+	// Provedores que não filtram por data podem retornar tudo o que possuem.
+	Buscar(ctx context.Context, inicio, fim time.Time) ([]Cotacao, error)
+}
+
+// ErrSemCotacao indica que não há cotação cadastrada até a data consultada
+var ErrSemCotacao = errors.New("nenhuma cotação cadastrada até a data informada")
+
+// Importar busca as cotações no provider e grava na tabela CotacaoDolar,
+// substituindo a taxa de datas que já existirem. Retorna quantas datas foram gravadas.
+func Importar(ctx context.Context, db *gorm.DB, p Provider, inicio, fim time.Time) (int, error) {
+	cotacoes, err := p.Buscar(ctx, inicio, fim)
+	if err != nil {
+		return 0, err
+	}
+
+	registros := make([]models.CotacaoDolar, 0, len(cotacoes))
+	vistas := make(map[string]bool)
+	for _, cot := range cotacoes {
+		if cot.Taxa <= 0 {
+			return 0, fmt.Errorf("taxa inválida para %s", cot.Data.Format("2006-01-02"))
+		}
+		chave := cot.Data.Format("2006-01-02")
+		if vistas[chave] {
+			return 0, fmt.Errorf("data duplicada: %s", chave)
+		}
+		vistas[chave] = true
+		registros = append(registros, models.CotacaoDolar{
+			Data:  SomenteData(cot.Data),
+			Taxa:  cot.Taxa,
+			Fonte: p.Fonte(),
+		})
+	}
+
+	if len(registros) == 0 {
+		return 0, nil
+	}
+
+	sort.Slice(registros, func(i, j int) bool { return registros[i].Data.Before(registros[j].Data) })
+
+	err = db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "data"}},
+		DoUpdates: clause.AssignmentColumns([]string{"taxa", "fonte", "updatedAt"}),
+	}).CreateInBatches(&registros, 200).Error
+	if err != nil {
+		return 0, err
+	}
+
+	return len(registros), nil
+}
+
+// TaxaVigente retorna a cotação válida no instante informado, ou seja,
+// a última cotação cadastrada com data menor ou igual ao dia, no fuso do negócio.
+func TaxaVigente(db *gorm.DB, data time.Time) (*models.CotacaoDolar, error) {
+	var cotacao models.CotacaoDolar
+	err := db.Where("data <= ?", data.In(timezone.Local()).Format("2006-01-02")).
+		Order("data DESC").
+		First(&cotacao).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrSemCotacao
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &cotacao, nil
+}
+
+// SomenteData descarta o horário, mantendo dia/mês/ano em UTC
+// para que a coluna DATE receba exatamente o dia informado
+func SomenteData(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// ParseData aceita datas nos formatos YYYY-MM-DD e DD/MM/YYYY
+func ParseData(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range []string{"2006-01-02", "02/01/2006"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("data inválida: %q", s)
+}
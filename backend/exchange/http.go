@@ -0,0 +1,103 @@
+package exchange
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"cmdimport/backend/utils"
+)
+
+// HTTPProvider busca cotações em um serviço HTTP configurado em EXCHANGE_RATE_URL.
+//
+// A requisição é um GET com os parâmetros dataInicio e dataFim (YYYY-MM-DD) e a
+// resposta deve ser uma lista JSON no formato [{"data": "2024-01-02", "taxa": 4.8912}],
+// opcionalmente dentro de {"data": [...]}. Para testes locais basta apontar a URL
+// para um servidor que devolva um arquivo JSON fixo.
+type HTTPProvider struct {
+	URL    string
+	Client *http.Client
+}
+
+type cotacaoHTTP struct {
+	Data string      `json:"data"`
+	Taxa interface{} `json:"taxa"`
+}
+
+func (p *HTTPProvider) Fonte() string {
+	return "http"
+}
+
+func (p *HTTPProvider) Buscar(ctx context.Context, inicio, fim time.Time) ([]Cotacao, error) {
+	if p.URL == "" {
+		return nil, fmt.Errorf("URL de cotações não configurada")
+	}
+
+	endereco, err := url.Parse(p.URL)
+	if err != nil {
+		return nil, fmt.Errorf("URL de cotações inválida: %w", err)
+	}
+	params := endereco.Query()
+	if !inicio.IsZero() {
+		params.Set("dataInicio", inicio.Format("2006-01-02"))
+	}
+	if !fim.IsZero() {
+		params.Set("dataFim", fim.Format("2006-01-02"))
+	}
+	endereco.RawQuery = params.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endereco.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	client := p.Client
+	if client == nil {
+		client = &http.Client{Timeout: 15 * time.Second}
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao consultar cotações: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("serviço de cotações respondeu %d", resp.StatusCode)
+	}
+
+	var corpo json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&corpo); err != nil {
+		return nil, fmt.Errorf("resposta de cotações inválida: %w", err)
+	}
+
+	var itens []cotacaoHTTP
+	if err := json.Unmarshal(corpo, &itens); err != nil {
+		var envelope struct {
+			Data []cotacaoHTTP `json:"data"`
+		}
+		if err := json.Unmarshal(corpo, &envelope); err != nil {
+			return nil, fmt.Errorf("resposta de cotações inválida: %w", err)
+		}
+		itens = envelope.Data
+	}
+
+	cotacoes := make([]Cotacao, 0, len(itens))
+	for _, item := range itens {
+		data, err := ParseData(item.Data)
+		if err != nil {
+			return nil, err
+		}
+		taxa, err := utils.ParseFloatFlexible(item.Taxa)
+		if err != nil || taxa == nil {
+			return nil, fmt.Errorf("taxa inválida para %s", item.Data)
+		}
+		cotacoes = append(cotacoes, Cotacao{Data: data, Taxa: *taxa})
+	}
+
+	return cotacoes, nil
+}
@@ -0,0 +1,137 @@
+// Package expenses gera as despesas das recorrências (aluguel, salários, assinaturas) e projeta as próximas.
+package expenses
+
+import (
+	"time"
+
+	"cmdimport/backend/models"
+	"cmdimport/backend/timezone"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Frequências de uma despesa recorrente
+const (
+	FrequenciaSemanal = "semanal"
+	FrequenciaMensal  = "mensal"
+	FrequenciaAnual   = "anual"
+)
+
+// Situações de uma despesa
+const (
+	StatusPendente = "pendente"
+	StatusPaga     = "paga"
+)
+
+// maxPorExecucao limita as ocorrências geradas por recorrência em uma execução (recorrências antigas
+// alcançam a data atual nas execuções seguintes)
+const maxPorExecucao = 100
+
+// FrequenciaValida indica se a frequência é uma das aceitas
+func FrequenciaValida(f string) bool {
+	return f == FrequenciaSemanal || f == FrequenciaMensal || f == FrequenciaAnual
+}
+
+// Dia retorna o dia do calendário de uma data de coluna date (ou lida de YYYY-MM-DD) à 00:00 UTC, como a
+// coluna guarda. Para o dia de um instante, use timezone.Data.
+func Dia(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// somarMeses avança meses mantendo o dia; em meses mais curtos, usa o último dia (31/01 → 28/02 → 31/03)
+func somarMeses(d time.Time, meses int) time.Time {
+	primeiro := time.Date(d.Year(), d.Month()+time.Month(meses), 1, 0, 0, 0, 0, time.UTC)
+	dia := d.Day()
+	if ultimo := primeiro.AddDate(0, 1, -1).Day(); dia > ultimo {
+		dia = ultimo
+	}
+	return time.Date(primeiro.Year(), primeiro.Month(), dia, 0, 0, 0, 0, time.UTC)
+}
+
+// Ocorrencia retorna a data da n-ésima ocorrência (a primeira é n = 0)
+func Ocorrencia(r models.DespesaRecorrente, n int) time.Time {
+	inicio := Dia(r.DataInicio)
+	switch r.Frequencia {
+	case FrequenciaSemanal:
+		return inicio.AddDate(0, 0, 7*n)
+	case FrequenciaAnual:
+		return somarMeses(inicio, 12*n)
+	}
+	return somarMeses(inicio, n)
+}
+
+// encerrada indica se a data passou do fim da recorrência
+func encerrada(r models.DespesaRecorrente, data time.Time) bool {
+	return r.DataFim != nil && data.After(Dia(*r.DataFim))
+}
+
+// Previstas retorna as datas das ocorrências ainda não geradas até a data informada, inclusive
+func Previstas(r models.DespesaRecorrente, ate time.Time) []time.Time {
+	ate = Dia(ate)
+	datas := make([]time.Time, 0)
+	for n := r.Geradas; ; n++ {
+		data := Ocorrencia(r, n)
+		if data.After(ate) || encerrada(r, data) {
+			return datas
+		}
+		datas = append(datas, data)
+	}
+}
+
+// Gerar cria, como pendentes, as despesas das recorrências ativas com ocorrências até hoje (no fuso do negócio)
+// e retorna quantas foram criadas. A recorrência fica travada durante a geração e o índice único (recorrência, data) impede
+// duplicatas entre servidores.
+func Gerar(db *gorm.DB, agora time.Time) (int, error) {
+	hoje := timezone.Data(agora)
+	var ids []int
+	if err := db.Model(&models.DespesaRecorrente{}).
+		Where("ativo = ? AND proximaData <= ? AND (dataFim IS NULL OR proximaData <= dataFim)", true, hoje).
+		// Categoria na lixeira: a geração espera a categoria ser restaurada
+		Where("categoriaId IN (SELECT id FROM CategoriaDespesa WHERE deletedAt IS NULL)").
+		Pluck("id", &ids).Error; err != nil {
+		return 0, err
+	}
+
+	total := 0
+	for _, id := range ids {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			var r models.DespesaRecorrente
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&r, id).Error; err != nil {
+				return err
+			}
+			if !r.Ativo {
+				return nil
+			}
+			for i := 0; i < maxPorExecucao; i++ {
+				data := Ocorrencia(r, r.Geradas)
+				if data.After(hoje) || encerrada(r, data) {
+					break
+				}
+				despesa := models.Despesa{
+					Nome:                r.Nome,
+					Valor:               r.Valor,
+					CategoriaID:         r.CategoriaID,
+					Descricao:           r.Descricao,
+					Data:                data,
+					Status:              StatusPendente,
+					DespesaRecorrenteID: &r.ID,
+				}
+				result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&despesa)
+				if result.Error != nil {
+					return result.Error
+				}
+				total += int(result.RowsAffected)
+				r.Geradas++
+			}
+			return tx.Model(&r).Updates(map[string]interface{}{
+				"geradas":     r.Geradas,
+				"proximaData": Ocorrencia(r, r.Geradas),
+			}).Error
+		})
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
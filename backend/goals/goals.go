@@ -0,0 +1,136 @@
+// Package goals acompanha as metas de vendas: soma o realizado no período (faturamento, unidades e margem)
+// e projeta o resultado até o fim do período pelo ritmo atual.
+package goals
+
+import (
+	"math"
+	"time"
+
+	"cmdimport/backend/models"
+	"cmdimport/backend/money"
+	"cmdimport/backend/timezone"
+
+	"gorm.io/gorm"
+)
+
+// Realizado é o que foi vendido no período da meta
+type Realizado struct {
+	Faturamento money.Dinheiro `json:"faturamento"`
+	Unidades    int            `json:"unidades"`
+	Margem      money.Dinheiro `json:"margem"`
+}
+
+// Indicador é o acompanhamento de um dos objetivos da meta, em reais ou em unidades
+type Indicador struct {
+	Meta                float64 `json:"meta"`
+	Realizado           float64 `json:"realizado"`
+	Percentual          float64 `json:"percentual"`          // Realizado sobre a meta
+	Projecao            float64 `json:"projecao"`            // Resultado no fim do período mantido o ritmo atual
+	PercentualProjetado float64 `json:"percentualProjetado"` // Projeção sobre a meta
+	FaltaPorDia         float64 `json:"faltaPorDia"`         // Quanto vender por dia, nos dias restantes, para bater a meta
+}
+
+// Progresso é o acompanhamento da meta em um instante
+type Progresso struct {
+	DiasTotais     int        `json:"diasTotais"`
+	DiasDecorridos int        `json:"diasDecorridos"`
+	DiasRestantes  int        `json:"diasRestantes"` // Contando o dia de hoje
+	Encerrada      bool       `json:"encerrada"`
+	Faturamento    *Indicador `json:"faturamento,omitempty"`
+	Unidades       *Indicador `json:"unidades,omitempty"`
+	Margem         *Indicador `json:"margem,omitempty"`
+}
+
+func arredondar(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// Periodo retorna o início do primeiro dia e o início do dia seguinte ao último dia da meta, no fuso do negócio
+func Periodo(meta models.Meta) (time.Time, time.Time) {
+	return timezone.InicioDaData(meta.DataInicio), timezone.InicioDaData(meta.DataFim.AddDate(0, 0, 1))
+}
+
+// Apurar soma as vendas do período da meta até o instante informado
+func Apurar(db *gorm.DB, meta models.Meta, ate time.Time) (Realizado, error) {
+	inicio, fim := Periodo(meta)
+	if ate.Before(fim) {
+		fim = ate
+	}
+
+	query := db.Table("HistoricoVenda hv").
+		Select("COALESCE(SUM(hv.precoUnitario * hv.quantidade - hv.descontoVenda - hv.descontoCupom), 0) as faturamento, "+
+			"COALESCE(SUM(hv.quantidade), 0) as unidades, "+
+			"COALESCE(SUM(hv.precoUnitario * hv.quantidade - hv.descontoVenda - hv.descontoCupom - pc.preco * hv.quantidade), 0) as margem").
+		Joins("JOIN Estoque e ON e.id = hv.estoqueId").
+		Joins("JOIN ProdutoComprado pc ON pc.id = e.produtoCompradoId").
+		Where("hv.createdAt >= ? AND hv.createdAt < ?", inicio, fim)
+	if meta.UsuarioID != nil {
+		query = query.Where("hv.usuarioId = ?", *meta.UsuarioID)
+	}
+	if meta.LojaID != nil {
+		query = query.Where("hv.lojaId = ?", *meta.LojaID)
+	}
+	if meta.CategoriaID != nil {
+		query = query.Where("pc.categoriaId = ?", *meta.CategoriaID)
+	}
+
+	var realizado Realizado
+	if err := query.Scan(&realizado).Error; err != nil {
+		return Realizado{}, err
+	}
+	return realizado, nil
+}
+
+// Calcular monta o progresso da meta. A projeção é linear: o realizado dividido pela fração
+// já decorrida do período.
+func Calcular(meta models.Meta, realizado Realizado, agora time.Time) Progresso {
+	inicio, fim := Periodo(meta)
+	total := fim.Sub(inicio)
+	decorrido := agora.Sub(inicio)
+	if decorrido < 0 {
+		decorrido = 0
+	}
+	if decorrido > total {
+		decorrido = total
+	}
+
+	p := Progresso{
+		DiasTotais: int(math.Round(total.Hours() / 24)),
+		Encerrada:  !agora.Before(fim),
+	}
+	switch {
+	case agora.Before(inicio):
+		p.DiasRestantes = p.DiasTotais
+	case p.Encerrada:
+		p.DiasDecorridos = p.DiasTotais
+	default:
+		p.DiasDecorridos = int(math.Round(timezone.InicioDoDia(agora).Sub(inicio).Hours() / 24))
+		p.DiasRestantes = p.DiasTotais - p.DiasDecorridos
+	}
+	fracao := float64(decorrido) / float64(total)
+
+	indicador := func(objetivo, feito float64) *Indicador {
+		ind := &Indicador{Meta: objetivo, Realizado: feito, Projecao: feito}
+		if fracao > 0 {
+			ind.Projecao = arredondar(feito / fracao)
+		}
+		if objetivo > 0 {
+			ind.Percentual = arredondar(feito / objetivo * 100)
+			ind.PercentualProjetado = arredondar(ind.Projecao / objetivo * 100)
+		}
+		if falta := objetivo - feito; falta > 0 && p.DiasRestantes > 0 {
+			ind.FaltaPorDia = arredondar(falta / float64(p.DiasRestantes))
+		}
+		return ind
+	}
+	if meta.Faturamento != nil {
+		p.Faturamento = indicador(meta.Faturamento.Float64(), realizado.Faturamento.Float64())
+	}
+	if meta.Unidades != nil {
+		p.Unidades = indicador(float64(*meta.Unidades), float64(realizado.Unidades))
+	}
+	if meta.Margem != nil {
+		p.Margem = indicador(meta.Margem.Float64(), realizado.Margem.Float64())
+	}
+	return p
+}
@@ -0,0 +1,552 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"cmdimport/backend/cashregister"
+	"cmdimport/backend/models"
+	"cmdimport/backend/money"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type CashRegisterHandler struct {
+	DB *gorm.DB
+}
+
+func NewCashRegisterHandler(db *gorm.DB) *CashRegisterHandler {
+	return &CashRegisterHandler{DB: db}
+}
+
+// podeOperarCaixa indica se o usuário pode movimentar ou fechar a sessão: o dono, quem é da loja ou o admin
+func podeOperarCaixa(usuario models.Usuario, sessao models.SessaoCaixa) bool {
+	if usuario.IsAdmin {
+		return true
+	}
+	if sessao.UsuarioID != nil {
+		return *sessao.UsuarioID == usuario.ID
+	}
+	return sessao.LojaID != nil && usuario.LojaID != nil && *sessao.LojaID == *usuario.LojaID
+}
+
+// relatorioCaixa monta a sessão com os totais e os movimentos
+func (h *CashRegisterHandler) relatorioCaixa(sessao models.SessaoCaixa) (gin.H, error) {
+	resumo, err := cashregister.Resumir(h.DB, sessao)
+	if err != nil {
+		return nil, err
+	}
+	var movimentos []models.MovimentoCaixa
+	if err := h.DB.Where("sessaoCaixaId = ?", sessao.ID).Order("id ASC").Find(&movimentos).Error; err != nil {
+		return nil, err
+	}
+	return gin.H{
+		"sessao":     sessao,
+		"resumo":     resumo,
+		"movimentos": movimentos,
+	}, nil
+}
+
+type AbrirCaixaRequest struct {
+	ValorAbertura money.Dinheiro `json:"valorAbertura" binding:"min=0"` // Fundo de troco
+	LojaID        *int           `json:"lojaId"`                        // Caixa da loja; sem ela, o caixa é do vendedor
+}
+
+// Abrir abre a sessão de caixa do vendedor logado ou da loja informada.
+// Cada vendedor e cada loja têm no máximo um caixa aberto.
+func (h *CashRegisterHandler) Abrir(c *gin.Context) {
+	operador, ok := usuarioLogado(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "Usuário não autenticado",
+		})
+		return
+	}
+
+	var req AbrirCaixaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Valor de abertura inválido",
+		})
+		return
+	}
+
+	sessao := models.SessaoCaixa{
+		Status:        cashregister.StatusAberta,
+		ValorAbertura: req.ValorAbertura,
+		AbertaEm:      time.Now(),
+		AbertaPor:     operador.Nome,
+	}
+	if req.LojaID != nil {
+		if !operador.IsAdmin && (operador.LojaID == nil || *operador.LojaID != *req.LojaID) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"success": false,
+				"message": "Você só pode abrir o caixa da sua loja",
+			})
+			return
+		}
+		sessao.LojaID = req.LojaID
+	} else {
+		sessao.UsuarioID = &operador.ID
+	}
+
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		query := tx.Model(&models.SessaoCaixa{}).Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("status = ?", cashregister.StatusAberta)
+		if sessao.LojaID != nil {
+			var loja models.Loja
+			if err := tx.First(&loja, *sessao.LojaID).Error; err != nil {
+				return &erroVenda{http.StatusNotFound, "Loja não encontrada"}
+			}
+			query = query.Where("lojaId = ?", *sessao.LojaID)
+		} else {
+			query = query.Where("usuarioId = ?", operador.ID)
+		}
+		var abertas int64
+		if err := query.Count(&abertas).Error; err != nil {
+			return err
+		}
+		if abertas > 0 {
+			return &erroVenda{http.StatusBadRequest, "Já existe um caixa aberto. Feche-o antes de abrir outro"}
+		}
+		return tx.Create(&sessao).Error
+	})
+	var ev *erroVenda
+	if errors.As(err, &ev) {
+		c.JSON(ev.status, gin.H{
+			"success": false,
+			"message": ev.mensagem,
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao abrir caixa",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    sessao,
+		"message": "Caixa aberto",
+	})
+}
+
+// Atual mostra o caixa aberto que recebe as vendas do vendedor logado (o dele ou o da sua loja)
+func (h *CashRegisterHandler) Atual(c *gin.Context) {
+	operador, ok := usuarioLogado(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "Usuário não autenticado",
+		})
+		return
+	}
+
+	sessao, err := cashregister.SessaoAberta(h.DB, operador)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao buscar caixa",
+		})
+		return
+	}
+	if sessao == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "Nenhum caixa aberto",
+		})
+		return
+	}
+
+	relatorio, err := h.relatorioCaixa(*sessao)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao buscar movimentos do caixa",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    relatorio,
+	})
+}
+
+type MovimentoCaixaRequest struct {
+	Valor     money.Dinheiro `json:"valor" binding:"required,gt=0"`
+	Descricao *string        `json:"descricao"`
+}
+
+// Sangria retira dinheiro da gaveta (depósito, pagamento em espécie)
+func (h *CashRegisterHandler) Sangria(c *gin.Context) {
+	h.movimentar(c, cashregister.TipoSangria, "Sangria registrada")
+}
+
+// Suprimento coloca dinheiro na gaveta (reforço de troco)
+func (h *CashRegisterHandler) Suprimento(c *gin.Context) {
+	h.movimentar(c, cashregister.TipoSuprimento, "Suprimento registrado")
+}
+
+func (h *CashRegisterHandler) movimentar(c *gin.Context, tipo, mensagem string) {
+	operador, ok := usuarioLogado(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "Usuário não autenticado",
+		})
+		return
+	}
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "ID inválido",
+		})
+		return
+	}
+
+	var req MovimentoCaixaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Informe um valor maior que zero",
+		})
+		return
+	}
+
+	var sessao models.SessaoCaixa
+	if err := h.DB.First(&sessao, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "Caixa não encontrado",
+		})
+		return
+	}
+	if !podeOperarCaixa(operador, sessao) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"success": false,
+			"message": "Você não pode movimentar este caixa",
+		})
+		return
+	}
+
+	var movimento *models.MovimentoCaixa
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		movimento, err = cashregister.Movimentar(tx, id, tipo, req.Valor, req.Descricao, operador)
+		return err
+	})
+	if errors.Is(err, cashregister.ErrSemCaixa) || errors.Is(err, cashregister.ErrSaldoInsuficiente) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Não foi possível registrar o movimento: " + err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao registrar movimento",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    movimento,
+		"message": mensagem,
+	})
+}
+
+type FecharCaixaRequest struct {
+	ValorContado *money.Dinheiro `json:"valorContado" binding:"required,min=0"`
+	Observacoes  *string         `json:"observacoes"`
+}
+
+// Fechar encerra a sessão com o dinheiro contado na gaveta e registra a diferença para o esperado.
+// A sessão fechada aguarda a aprovação do admin.
+func (h *CashRegisterHandler) Fechar(c *gin.Context) {
+	operador, ok := usuarioLogado(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "Usuário não autenticado",
+		})
+		return
+	}
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "ID inválido",
+		})
+		return
+	}
+
+	var req FecharCaixaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Informe o valor contado na gaveta",
+		})
+		return
+	}
+
+	var sessao models.SessaoCaixa
+	var resumo cashregister.Resumo
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&sessao, id).Error; err != nil {
+			return &erroVenda{http.StatusNotFound, "Caixa não encontrado"}
+		}
+		if !podeOperarCaixa(operador, sessao) {
+			return &erroVenda{http.StatusForbidden, "Você não pode fechar este caixa"}
+		}
+		if sessao.Status != cashregister.StatusAberta {
+			return &erroVenda{http.StatusBadRequest, "O caixa já está fechado"}
+		}
+
+		var err error
+		if resumo, err = cashregister.Resumir(tx, sessao); err != nil {
+			return err
+		}
+		agora := time.Now()
+		diferenca := *req.ValorContado - resumo.Esperado
+		sessao.Status = cashregister.StatusFechada
+		sessao.FechadaEm = &agora
+		sessao.FechadaPor = &operador.Nome
+		sessao.ValorEsperado = &resumo.Esperado
+		sessao.ValorContado = req.ValorContado
+		sessao.Diferenca = &diferenca
+		sessao.ObservacoesFechamento = req.Observacoes
+		return tx.Save(&sessao).Error
+	})
+	var ev *erroVenda
+	if errors.As(err, &ev) {
+		c.JSON(ev.status, gin.H{
+			"success": false,
+			"message": ev.mensagem,
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao fechar caixa",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"sessao": sessao,
+			"resumo": resumo,
+		},
+		"message": "Caixa fechado. Aguardando aprovação",
+	})
+}
+
+// Listar lista as sessões de caixa (filtros: status, usuarioId, lojaId, dataInicio, dataFim pela abertura)
+func (h *CashRegisterHandler) Listar(c *gin.Context) {
+	query := h.DB.Model(&models.SessaoCaixa{})
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if usuarioID := c.Query("usuarioId"); usuarioID != "" {
+		query = query.Where("usuarioId = ?", usuarioID)
+	}
+	if lojaID := c.Query("lojaId"); lojaID != "" {
+		query = query.Where("lojaId = ?", lojaID)
+	}
+	query = filtrarPeriodo(query, "abertaEm", c.Query("dataInicio"), c.Query("dataFim"))
+
+	var sessoes []models.SessaoCaixa
+	if err := query.Order("abertaEm DESC").Limit(200).Find(&sessoes).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao buscar caixas",
+		})
+		return
+	}
+
+	var totalDiferenca money.Dinheiro
+	for _, s := range sessoes {
+		if s.Diferenca != nil {
+			totalDiferenca += *s.Diferenca
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"sessoes":        sessoes,
+			"totalDiferenca": totalDiferenca,
+		},
+	})
+}
+
+// Relatorio mostra uma sessão de caixa com os totais (abertura, vendas, estornos, sangrias, suprimentos,
+// esperado) e todos os movimentos
+func (h *CashRegisterHandler) Relatorio(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "ID inválido",
+		})
+		return
+	}
+
+	var sessao models.SessaoCaixa
+	if err := h.DB.First(&sessao, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "Caixa não encontrado",
+		})
+		return
+	}
+
+	relatorio, err := h.relatorioCaixa(sessao)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao buscar movimentos do caixa",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    relatorio,
+	})
+}
+
+// Aprovar confere o fechamento de uma sessão ({"observacoes": "..."} opcional)
+func (h *CashRegisterHandler) Aprovar(c *gin.Context) {
+	admin, ok := usuarioLogado(c)
+	if !ok || !admin.IsAdmin {
+		c.JSON(http.StatusForbidden, gin.H{
+			"success": false,
+			"message": "Apenas administradores podem aprovar o fechamento de caixa",
+		})
+		return
+	}
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "ID inválido",
+		})
+		return
+	}
+
+	var req struct {
+		Observacoes *string `json:"observacoes"`
+	}
+	c.ShouldBindJSON(&req)
+
+	result := h.DB.Model(&models.SessaoCaixa{}).
+		Where("id = ? AND status = ?", id, cashregister.StatusFechada).
+		Updates(map[string]interface{}{
+			"status":               cashregister.StatusAprovada,
+			"aprovadaEm":           time.Now(),
+			"aprovadaPor":          admin.Nome,
+			"observacoesAprovacao": req.Observacoes,
+		})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao aprovar caixa",
+		})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Só caixas fechados podem ser aprovados",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Fechamento de caixa aprovado",
+	})
+}
+
+// Reabrir devolve uma sessão fechada (não aprovada) para aberta, para uma nova contagem.
+// Não é possível se o dono já abriu outro caixa.
+func (h *CashRegisterHandler) Reabrir(c *gin.Context) {
+	admin, ok := usuarioLogado(c)
+	if !ok || !admin.IsAdmin {
+		c.JSON(http.StatusForbidden, gin.H{
+			"success": false,
+			"message": "Apenas administradores podem reabrir caixas",
+		})
+		return
+	}
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "ID inválido",
+		})
+		return
+	}
+
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		var sessao models.SessaoCaixa
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&sessao, id).Error; err != nil {
+			return &erroVenda{http.StatusNotFound, "Caixa não encontrado"}
+		}
+		if sessao.Status != cashregister.StatusFechada {
+			return &erroVenda{http.StatusBadRequest, "Só caixas fechados e não aprovados podem ser reabertos"}
+		}
+		outra, err := cashregister.SessaoAbertaDoDono(tx, sessao)
+		if err != nil {
+			return err
+		}
+		if outra != nil {
+			return &erroVenda{http.StatusBadRequest, "Já existe outro caixa aberto para este vendedor ou loja"}
+		}
+		return tx.Model(&sessao).Updates(map[string]interface{}{
+			"status":                cashregister.StatusAberta,
+			"fechadaEm":             nil,
+			"fechadaPor":            nil,
+			"valorEsperado":         nil,
+			"valorContado":          nil,
+			"diferenca":             nil,
+			"observacoesFechamento": nil,
+		}).Error
+	})
+	var ev *erroVenda
+	if errors.As(err, &ev) {
+		c.JSON(ev.status, gin.H{
+			"success": false,
+			"message": ev.mensagem,
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao reabrir caixa",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Caixa reaberto",
+	})
+}
@@ -0,0 +1,313 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"cmdimport/backend/catalog"
+	"cmdimport/backend/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type CatalogHandler struct {
+	DB *gorm.DB
+}
+
+func NewCatalogHandler(db *gorm.DB) *CatalogHandler {
+	return &CatalogHandler{DB: db}
+}
+
+type SalvarSKURequest struct {
+	Codigo        string  `json:"codigo"` // Opcional: gerado a partir dos atributos
+	Marca         string  `json:"marca"`
+	Modelo        string  `json:"modelo" binding:"required"`
+	Armazenamento *string `json:"armazenamento"`
+	Cor           *string `json:"cor"`
+	CategoriaID   *int    `json:"categoriaId"`
+	Ativo         *bool   `json:"ativo"`
+}
+
+// Listar lista os SKUs do catálogo (filtros: busca, ativo)
+func (h *CatalogHandler) Listar(c *gin.Context) {
+	query := h.DB.Model(&models.ProdutoSKU{}).Preload("Categoria")
+
+	if busca := c.Query("busca"); busca != "" {
+		query = query.Where("nome LIKE ? OR codigo LIKE ?", "%"+busca+"%", "%"+busca+"%")
+	}
+	if ativo := c.Query("ativo"); ativo != "" {
+		query = query.Where("ativo = ?", ativo == "true")
+	}
+
+	var skus []models.ProdutoSKU
+	if err := query.Order("nome ASC").Find(&skus).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao buscar catálogo",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    skus,
+	})
+}
+
+// Criar cadastra um SKU no catálogo
+func (h *CatalogHandler) Criar(c *gin.Context) {
+	var req SalvarSKURequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "O modelo é obrigatório",
+		})
+		return
+	}
+
+	sku := models.ProdutoSKU{Ativo: true}
+	preencherSKU(&sku, req)
+
+	var count int64
+	h.DB.Model(&models.ProdutoSKU{}).Where("codigo = ?", sku.Codigo).Count(&count)
+	if count > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Já existe um SKU com este código",
+		})
+		return
+	}
+
+	if err := h.DB.Create(&sku).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao cadastrar SKU",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    sku,
+		"message": "SKU cadastrado com sucesso",
+	})
+}
+
+// Atualizar altera os atributos de um SKU
+func (h *CatalogHandler) Atualizar(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "ID inválido",
+		})
+		return
+	}
+
+	var req SalvarSKURequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "O modelo é obrigatório",
+		})
+		return
+	}
+
+	var sku models.ProdutoSKU
+	if err := h.DB.First(&sku, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "SKU não encontrado",
+		})
+		return
+	}
+
+	codigoAnterior := sku.Codigo
+	preencherSKU(&sku, req)
+
+	var count int64
+	h.DB.Model(&models.ProdutoSKU{}).Where("codigo = ? AND id != ?", sku.Codigo, id).Count(&count)
+	if count > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Já existe um SKU com este código",
+		})
+		return
+	}
+
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&sku).Error; err != nil {
+			return err
+		}
+		// Manter a chave da precificação do SKU em dia com o código
+		if sku.Codigo != codigoAnterior {
+			return tx.Model(&models.Precificacao{}).Where("skuId = ?", sku.ID).
+				Update("nomeProduto", catalog.ChavePrecificacao(&sku)).Error
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao atualizar SKU",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    sku,
+		"message": "SKU atualizado com sucesso",
+	})
+}
+
+// Deletar remove um SKU que não tenha produtos associados
+func (h *CatalogHandler) Deletar(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "ID inválido",
+		})
+		return
+	}
+
+	var count int64
+	h.DB.Model(&models.ProdutoComprado{}).Where("skuId = ?", id).Count(&count)
+	if count > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Não é possível deletar SKU com produtos associados. Desative-o",
+		})
+		return
+	}
+
+	var deletado int64
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("skuId = ?", id).Delete(&models.Precificacao{}).Error; err != nil {
+			return err
+		}
+		result := tx.Delete(&models.ProdutoSKU{}, id)
+		deletado = result.RowsAffected
+		return result.Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao deletar SKU",
+		})
+		return
+	}
+	if deletado == 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "SKU não encontrado",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "SKU deletado com sucesso",
+	})
+}
+
+// Sugestoes agrupa os nomes de produtos sem SKU em SKUs sugeridos para revisão
+func (h *CatalogHandler) Sugestoes(c *gin.Context) {
+	sugestoes, err := catalog.Sugerir(h.DB)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao agrupar produtos",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    sugestoes,
+	})
+}
+
+// Aplicar grava as sugestões revisadas pelo admin: cria os SKUs e associa os lotes.
+// O lote é aplicado por inteiro ou, se alguma sugestão falhar, nenhuma é aplicada.
+func (h *CatalogHandler) Aplicar(c *gin.Context) {
+	var req struct {
+		Sugestoes []catalog.Sugestao `json:"sugestoes" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Informe as sugestões a aplicar",
+		})
+		return
+	}
+
+	resultado := make([]gin.H, 0, len(req.Sugestoes))
+	var falha *catalog.Sugestao
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		for i, s := range req.Sugestoes {
+			sku, associados, err := catalog.Aplicar(tx, s)
+			if err != nil {
+				falha = &req.Sugestoes[i]
+				return err
+			}
+			resultado = append(resultado, gin.H{
+				"sku":             sku,
+				"lotesAssociados": associados,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		if falha == nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "Erro ao aplicar as sugestões: " + err.Error(),
+			})
+			return
+		}
+		status := http.StatusBadRequest
+		if errors.Is(err, catalog.ErrSKUNaoEncontrado) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{
+			"success": false,
+			"message": "Erro ao aplicar " + falha.Nome + ": " + err.Error() + ". Nenhuma sugestão foi aplicada",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    resultado,
+		"message": "SKUs aplicados com sucesso",
+	})
+}
+
+func preencherSKU(sku *models.ProdutoSKU, req SalvarSKURequest) {
+	attrs := catalog.Atributos{
+		Marca:         strings.TrimSpace(req.Marca),
+		Modelo:        strings.TrimSpace(req.Modelo),
+		Armazenamento: req.Armazenamento,
+		Cor:           req.Cor,
+	}
+	sku.Marca = attrs.Marca
+	sku.Modelo = attrs.Modelo
+	sku.Armazenamento = attrs.Armazenamento
+	sku.Cor = attrs.Cor
+	sku.CategoriaID = req.CategoriaID
+	sku.Nome = catalog.NomeSKU(attrs)
+	// Sem código informado, gera no cadastro e mantém o atual na edição
+	if codigo := strings.ToUpper(strings.TrimSpace(req.Codigo)); codigo != "" {
+		sku.Codigo = codigo
+	} else if sku.Codigo == "" {
+		sku.Codigo = catalog.CodigoSKU(attrs)
+	}
+	if req.Ativo != nil {
+		sku.Ativo = *req.Ativo
+	}
+}
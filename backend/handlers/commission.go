@@ -0,0 +1,641 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"cmdimport/backend/commissions"
+	"cmdimport/backend/models"
+	"cmdimport/backend/money"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type CommissionHandler struct {
+	DB *gorm.DB
+}
+
+func NewCommissionHandler(db *gorm.DB) *CommissionHandler {
+	return &CommissionHandler{DB: db}
+}
+
+type SalvarRegraComissaoRequest struct {
+	UsuarioID   *int                  `json:"usuarioId"` // Informe o vendedor ou o papel; sem os dois, vale para todos
+	Papel       *string               `json:"papel"`
+	CategoriaID *int                  `json:"categoriaId"`
+	Base        string                `json:"base"` // "valor" (padrão) ou "margem"
+	Percentual  float64               `json:"percentual" binding:"min=0,max=100"`
+	Faixas      models.FaixasComissao `json:"faixas"`
+	Ativo       *bool                 `json:"ativo"`
+}
+
+func preencherRegraComissao(regra *models.RegraComissao, req SalvarRegraComissaoRequest) {
+	regra.UsuarioID = req.UsuarioID
+	regra.Papel = req.Papel
+	regra.CategoriaID = req.CategoriaID
+	regra.Base = req.Base
+	regra.Percentual = req.Percentual
+	regra.Faixas = req.Faixas
+	if req.Ativo != nil {
+		regra.Ativo = *req.Ativo
+	}
+}
+
+// ListarRegras lista as regras de comissão
+func (h *CommissionHandler) ListarRegras(c *gin.Context) {
+	if !exigirAdmin(c, "Apenas administradores podem gerenciar regras de comissão") {
+		return
+	}
+
+	var regras []models.RegraComissao
+	if err := h.DB.Order("usuarioId ASC, papel ASC, categoriaId ASC").Find(&regras).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao buscar regras de comissão",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    regras,
+	})
+}
+
+// CriarRegra cadastra uma regra de comissão de um vendedor, de um papel ou geral, opcionalmente por categoria
+func (h *CommissionHandler) CriarRegra(c *gin.Context) {
+	if !exigirAdmin(c, "Apenas administradores podem gerenciar regras de comissão") {
+		return
+	}
+
+	var req SalvarRegraComissaoRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Percentual de comissão inválido (0 a 100)",
+		})
+		return
+	}
+	if msg := h.validarRegra(&req, 0); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": msg,
+		})
+		return
+	}
+
+	regra := models.RegraComissao{Ativo: true}
+	preencherRegraComissao(&regra, req)
+	if err := h.DB.Create(&regra).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao cadastrar regra de comissão",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    regra,
+		"message": "Regra de comissão cadastrada com sucesso",
+	})
+}
+
+// AtualizarRegra altera uma regra de comissão. Vale para os extratos ainda abertos.
+func (h *CommissionHandler) AtualizarRegra(c *gin.Context) {
+	if !exigirAdmin(c, "Apenas administradores podem gerenciar regras de comissão") {
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "ID inválido",
+		})
+		return
+	}
+
+	var req SalvarRegraComissaoRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Percentual de comissão inválido (0 a 100)",
+		})
+		return
+	}
+
+	var regra models.RegraComissao
+	if err := h.DB.First(&regra, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "Regra de comissão não encontrada",
+		})
+		return
+	}
+
+	if msg := h.validarRegra(&req, id); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": msg,
+		})
+		return
+	}
+
+	preencherRegraComissao(&regra, req)
+	if err := h.DB.Save(&regra).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao atualizar regra de comissão",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    regra,
+		"message": "Regra de comissão atualizada com sucesso",
+	})
+}
+
+// DeletarRegra remove uma regra de comissão
+func (h *CommissionHandler) DeletarRegra(c *gin.Context) {
+	if !exigirAdmin(c, "Apenas administradores podem gerenciar regras de comissão") {
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "ID inválido",
+		})
+		return
+	}
+
+	result := h.DB.Delete(&models.RegraComissao{}, id)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao deletar regra de comissão",
+		})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "Regra de comissão não encontrada",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Regra de comissão deletada com sucesso",
+	})
+}
+
+// validarRegra normaliza a base e retorna a mensagem de erro para o cliente, ou "" se a regra é válida
+func (h *CommissionHandler) validarRegra(req *SalvarRegraComissaoRequest, ignorarID int) string {
+	if req.Base == "" {
+		req.Base = commissions.BaseValor
+	}
+	if req.Base != commissions.BaseValor && req.Base != commissions.BaseMargem {
+		return "Base inválida: use \"valor\" ou \"margem\""
+	}
+	if req.UsuarioID != nil && req.Papel != nil {
+		return "Informe o vendedor ou o papel, não os dois"
+	}
+	if req.Papel != nil && *req.Papel != commissions.PapelVendedor && *req.Papel != commissions.PapelAdmin {
+		return "Papel inválido: use \"vendedor\" ou \"admin\""
+	}
+	for _, f := range req.Faixas {
+		if f.ValorMinimo < 0 || f.Percentual < 0 || f.Percentual > 100 {
+			return "Faixa inválida: valor mínimo não pode ser negativo e o percentual vai de 0 a 100"
+		}
+	}
+
+	var count int64
+	if req.UsuarioID != nil {
+		h.DB.Model(&models.Usuario{}).Where("id = ?", *req.UsuarioID).Count(&count)
+		if count == 0 {
+			return "Vendedor não encontrado"
+		}
+	}
+	if req.CategoriaID != nil {
+		h.DB.Model(&models.CategoriaProduto{}).Where("id = ?", *req.CategoriaID).Count(&count)
+		if count == 0 {
+			return "Categoria não encontrada"
+		}
+	}
+
+	// Uma regra por combinação de vendedor/papel e categoria
+	query := h.DB.Model(&models.RegraComissao{}).Where("id != ?", ignorarID)
+	if req.UsuarioID != nil {
+		query = query.Where("usuarioId = ?", *req.UsuarioID)
+	} else {
+		query = query.Where("usuarioId IS NULL")
+	}
+	if req.Papel != nil {
+		query = query.Where("papel = ?", *req.Papel)
+	} else {
+		query = query.Where("papel IS NULL")
+	}
+	if req.CategoriaID != nil {
+		query = query.Where("categoriaId = ?", *req.CategoriaID)
+	} else {
+		query = query.Where("categoriaId IS NULL")
+	}
+	query.Count(&count)
+	if count > 0 {
+		return "Já existe uma regra de comissão para este vendedor/papel e categoria"
+	}
+	return ""
+}
+
+// mesConsulta lê o mês do parâmetro "mes" (padrão: mês atual)
+func mesConsulta(c *gin.Context) (string, bool) {
+	mes := c.DefaultQuery("mes", commissions.Mes(time.Now()))
+	if _, _, err := commissions.Intervalo(mes); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Mês inválido: use o formato AAAA-MM",
+		})
+		return "", false
+	}
+	return mes, true
+}
+
+// extrato responde o extrato do vendedor no mês; o aberto é calculado na hora, sem gravar
+func (h *CommissionHandler) extrato(c *gin.Context, vendedor models.Usuario, mes string) {
+	apuracao, err := commissions.Calcular(h.DB, vendedor, mes)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao calcular comissão",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"vendedorNome": vendedor.Nome,
+			"extrato":      apuracao.Extrato,
+			"lancamentos":  apuracao.Lancamentos,
+		},
+	})
+}
+
+// MeuExtrato mostra o extrato de comissão do vendedor logado (parâmetro: mes, AAAA-MM)
+func (h *CommissionHandler) MeuExtrato(c *gin.Context) {
+	vendedor, ok := usuarioLogado(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "Usuário não autenticado",
+		})
+		return
+	}
+	mes, ok := mesConsulta(c)
+	if !ok {
+		return
+	}
+	h.extrato(c, vendedor, mes)
+}
+
+// Extrato mostra o extrato de comissão de um vendedor (parâmetros: usuarioId, mes)
+func (h *CommissionHandler) Extrato(c *gin.Context) {
+	if !exigirAdmin(c, "Apenas administradores podem consultar comissões de outros vendedores") {
+		return
+	}
+
+	var vendedor models.Usuario
+	if err := h.DB.First(&vendedor, c.Query("usuarioId")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "Vendedor não encontrado",
+		})
+		return
+	}
+	mes, ok := mesConsulta(c)
+	if !ok {
+		return
+	}
+	h.extrato(c, vendedor, mes)
+}
+
+// Resumo lista o extrato de cada vendedor no mês (parâmetro: mes); os abertos são calculados na hora, sem gravar
+func (h *CommissionHandler) Resumo(c *gin.Context) {
+	if !exigirAdmin(c, "Apenas administradores podem consultar comissões de outros vendedores") {
+		return
+	}
+
+	mes, ok := mesConsulta(c)
+	if !ok {
+		return
+	}
+
+	var vendedores []models.Usuario
+	if err := h.DB.Order("nome ASC").Find(&vendedores).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao buscar vendedores",
+		})
+		return
+	}
+
+	resumo := make([]gin.H, 0, len(vendedores))
+	var totalVendas, totalComissao money.Dinheiro
+	for _, vendedor := range vendedores {
+		apuracao, err := commissions.Calcular(h.DB, vendedor, mes)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "Erro ao calcular comissões",
+			})
+			return
+		}
+		extrato := apuracao.Extrato
+		if extrato.TotalVendas == 0 && extrato.TotalComissao == 0 && extrato.Status == commissions.StatusAberto {
+			continue
+		}
+		totalVendas += extrato.TotalVendas
+		totalComissao += extrato.TotalComissao
+		resumo = append(resumo, gin.H{
+			"vendedorNome": vendedor.Nome,
+			"extrato":      extrato,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"mes":           mes,
+			"vendedores":    resumo,
+			"totalVendas":   totalVendas,
+			"totalComissao": totalComissao,
+		},
+	})
+}
+
+type FecharComissaoRequest struct {
+	UsuarioID int    `json:"usuarioId" binding:"required"`
+	Mes       string `json:"mes" binding:"required"`
+}
+
+// Fechar recalcula e fecha o extrato de um mês já encerrado. Depois de fechado, devoluções e
+// alterações dos itens viram estornos no extrato aberto seguinte.
+func (h *CommissionHandler) Fechar(c *gin.Context) {
+	admin, ok := usuarioLogado(c)
+	if !ok || !admin.IsAdmin {
+		c.JSON(http.StatusForbidden, gin.H{
+			"success": false,
+			"message": "Apenas administradores podem fechar comissões",
+		})
+		return
+	}
+
+	var req FecharComissaoRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Informe o vendedor e o mês",
+		})
+		return
+	}
+	if _, _, err := commissions.Intervalo(req.Mes); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Mês inválido: use o formato AAAA-MM",
+		})
+		return
+	}
+	if req.Mes >= commissions.Mes(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Só é possível fechar meses já encerrados",
+		})
+		return
+	}
+
+	var vendedor models.Usuario
+	if err := h.DB.First(&vendedor, req.UsuarioID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "Vendedor não encontrado",
+		})
+		return
+	}
+
+	var extrato *models.FechamentoComissao
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		extrato, err = commissions.Apurar(tx, vendedor, req.Mes)
+		if errors.Is(err, commissions.ErrExtratoEncerrado) {
+			return &erroVenda{http.StatusBadRequest, "O extrato deste mês já está fechado"}
+		}
+		if err != nil {
+			return err
+		}
+		agora := time.Now()
+		extrato.Status = commissions.StatusFechado
+		extrato.FechadoEm = &agora
+		extrato.FechadoPor = &admin.Nome
+		return tx.Model(extrato).Updates(map[string]interface{}{
+			"status":     extrato.Status,
+			"fechadoEm":  extrato.FechadoEm,
+			"fechadoPor": extrato.FechadoPor,
+		}).Error
+	})
+	var ev *erroVenda
+	if errors.As(err, &ev) {
+		c.JSON(ev.status, gin.H{
+			"success": false,
+			"message": ev.mensagem,
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao fechar comissão",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    extrato,
+		"message": "Comissão fechada com sucesso",
+	})
+}
+
+// Pagar registra o pagamento de um extrato fechado
+func (h *CommissionHandler) Pagar(c *gin.Context) {
+	admin, ok := usuarioLogado(c)
+	if !ok || !admin.IsAdmin {
+		c.JSON(http.StatusForbidden, gin.H{
+			"success": false,
+			"message": "Apenas administradores podem pagar comissões",
+		})
+		return
+	}
+	h.mudarStatus(c, commissions.StatusFechado, map[string]interface{}{
+		"status":  commissions.StatusPago,
+		"pagoEm":  time.Now(),
+		"pagoPor": admin.Nome,
+	}, "Só extratos fechados podem ser pagos", "Comissão paga")
+}
+
+// Reabrir devolve um extrato fechado (e ainda não pago) para aberto, para ser recalculado
+func (h *CommissionHandler) Reabrir(c *gin.Context) {
+	admin, ok := usuarioLogado(c)
+	if !ok || !admin.IsAdmin {
+		c.JSON(http.StatusForbidden, gin.H{
+			"success": false,
+			"message": "Apenas administradores podem reabrir comissões",
+		})
+		return
+	}
+	h.mudarStatus(c, commissions.StatusFechado, map[string]interface{}{
+		"status":     commissions.StatusAberto,
+		"fechadoEm":  nil,
+		"fechadoPor": nil,
+	}, "Só extratos fechados e não pagos podem ser reabertos", "Comissão reaberta")
+}
+
+func (h *CommissionHandler) mudarStatus(c *gin.Context, de string, updates map[string]interface{}, msgInvalido, msgSucesso string) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "ID inválido",
+		})
+		return
+	}
+
+	var extrato models.FechamentoComissao
+	if err := h.DB.First(&extrato, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "Extrato de comissão não encontrado",
+		})
+		return
+	}
+
+	result := h.DB.Model(&models.FechamentoComissao{}).Where("id = ? AND status = ?", id, de).Updates(updates)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao atualizar extrato de comissão",
+		})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": msgInvalido,
+		})
+		return
+	}
+
+	h.DB.First(&extrato, id)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    extrato,
+		"message": msgSucesso,
+	})
+}
+
+type AjusteComissaoRequest struct {
+	UsuarioID int            `json:"usuarioId" binding:"required"`
+	Mes       string         `json:"mes" binding:"required"`
+	Valor     money.Dinheiro `json:"valor" binding:"required"` // Positivo (bônus) ou negativo (desconto)
+	Descricao string         `json:"descricao" binding:"required"`
+}
+
+// LancarAjuste lança um valor manual (bônus ou desconto) no extrato aberto do vendedor
+func (h *CommissionHandler) LancarAjuste(c *gin.Context) {
+	admin, ok := usuarioLogado(c)
+	if !ok || !admin.IsAdmin {
+		c.JSON(http.StatusForbidden, gin.H{
+			"success": false,
+			"message": "Apenas administradores podem ajustar comissões",
+		})
+		return
+	}
+
+	var req AjusteComissaoRequest
+	if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Descricao) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Informe o vendedor, o mês, o valor e a descrição do ajuste",
+		})
+		return
+	}
+	if _, _, err := commissions.Intervalo(req.Mes); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Mês inválido: use o formato AAAA-MM",
+		})
+		return
+	}
+
+	var vendedor models.Usuario
+	if err := h.DB.First(&vendedor, req.UsuarioID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "Vendedor não encontrado",
+		})
+		return
+	}
+
+	var lancamento models.LancamentoComissao
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		extrato, err := commissions.Extrato(tx, vendedor.ID, req.Mes)
+		if err != nil {
+			return err
+		}
+		if extrato.Status != commissions.StatusAberto {
+			return &erroVenda{http.StatusBadRequest, "O extrato deste mês já está fechado: lance o ajuste em um mês aberto"}
+		}
+		lancamento = models.LancamentoComissao{
+			FechamentoID: extrato.ID,
+			UsuarioID:    vendedor.ID,
+			Tipo:         commissions.TipoAjuste,
+			Valor:        req.Valor,
+			Descricao:    strings.TrimSpace(req.Descricao) + " (" + admin.Nome + ")",
+		}
+		if err := tx.Create(&lancamento).Error; err != nil {
+			return err
+		}
+		_, err = commissions.Apurar(tx, vendedor, req.Mes)
+		return err
+	})
+	var ev *erroVenda
+	if errors.As(err, &ev) {
+		c.JSON(ev.status, gin.H{
+			"success": false,
+			"message": ev.mensagem,
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao lançar ajuste",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    lancamento,
+		"message": "Ajuste lançado com sucesso",
+	})
+}
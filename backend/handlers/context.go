@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"net/http"
+
+	"cmdimport/backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// usuarioLogado retorna o usuário autenticado pelo AuthMiddleware
+func usuarioLogado(c *gin.Context) (models.Usuario, bool) {
+	valor, ok := c.Get("user")
+	if !ok {
+		return models.Usuario{}, false
+	}
+	usuario, ok := valor.(models.Usuario)
+	return usuario, ok
+}
+
+// exigirAdmin responde 403 e retorna false quando o usuário logado não é administrador
+func exigirAdmin(c *gin.Context, mensagem string) bool {
+	usuario, ok := usuarioLogado(c)
+	if !ok || !usuario.IsAdmin {
+		c.JSON(http.StatusForbidden, gin.H{
+			"success": false,
+			"message": mensagem,
+		})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,624 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"cmdimport/backend/discounts"
+	"cmdimport/backend/models"
+	"cmdimport/backend/money"
+	"cmdimport/backend/timezone"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type DiscountHandler struct {
+	DB *gorm.DB
+}
+
+func NewDiscountHandler(db *gorm.DB) *DiscountHandler {
+	return &DiscountHandler{DB: db}
+}
+
+type SalvarCupomRequest struct {
+	Codigo      string         `json:"codigo" binding:"required"`
+	Descricao   *string        `json:"descricao"`
+	Tipo        string         `json:"tipo" binding:"required,oneof=percentual valor"`
+	Valor       float64        `json:"valor" binding:"required,gt=0"`
+	ValidoDe    *string        `json:"validoDe"`  // YYYY-MM-DD (início do dia)
+	ValidoAte   *string        `json:"validoAte"` // YYYY-MM-DD (fim do dia)
+	LimiteUsos  *int           `json:"limiteUsos"`
+	ValorMinimo money.Dinheiro `json:"valorMinimo"`
+	Ativo       *bool          `json:"ativo"`
+}
+
+// preencherCupom copia a requisição para o cupom e retorna a mensagem de erro para o cliente, se houver
+func preencherCupom(cupom *models.Cupom, req SalvarCupomRequest) string {
+	if err := (discounts.Desconto{Tipo: req.Tipo, Valor: req.Valor}).Validar(); err != nil {
+		return "Desconto inválido: " + err.Error()
+	}
+	if req.LimiteUsos != nil && *req.LimiteUsos < 1 {
+		return "O limite de usos deve ser maior que zero"
+	}
+	if req.ValorMinimo < 0 {
+		return "O valor mínimo não pode ser negativo"
+	}
+
+	cupom.ValidoDe, cupom.ValidoAte = nil, nil
+	if req.ValidoDe != nil && *req.ValidoDe != "" {
+		de, err := timezone.ParseData(*req.ValidoDe)
+		if err != nil {
+			return "Data de início inválida. Use YYYY-MM-DD"
+		}
+		cupom.ValidoDe = &de
+	}
+	if req.ValidoAte != nil && *req.ValidoAte != "" {
+		ate, err := timezone.ParseDiaSeguinte(*req.ValidoAte)
+		if err != nil {
+			return "Data de fim inválida. Use YYYY-MM-DD"
+		}
+		ate = ate.Add(-time.Second)
+		cupom.ValidoAte = &ate
+	}
+	if cupom.ValidoDe != nil && cupom.ValidoAte != nil && cupom.ValidoAte.Before(*cupom.ValidoDe) {
+		return "A data de fim deve ser posterior à de início"
+	}
+
+	cupom.Codigo = discounts.NormalizarCodigo(req.Codigo)
+	cupom.Descricao = req.Descricao
+	cupom.Tipo = req.Tipo
+	cupom.Valor = req.Valor
+	cupom.LimiteUsos = req.LimiteUsos
+	cupom.ValorMinimo = req.ValorMinimo
+	if req.Ativo != nil {
+		cupom.Ativo = *req.Ativo
+	}
+	return ""
+}
+
+// ListarCupons lista os cupons (filtro: ativo)
+func (h *DiscountHandler) ListarCupons(c *gin.Context) {
+	if !exigirAdmin(c, "Apenas administradores podem gerenciar cupons") {
+		return
+	}
+
+	query := h.DB.Model(&models.Cupom{})
+	if ativo := c.Query("ativo"); ativo != "" {
+		query = query.Where("ativo = ?", ativo == "true")
+	}
+
+	var cupons []models.Cupom
+	if err := query.Order("createdAt DESC").Find(&cupons).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao buscar cupons",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    cupons,
+	})
+}
+
+// CriarCupom cadastra um cupom de desconto
+func (h *DiscountHandler) CriarCupom(c *gin.Context) {
+	if !exigirAdmin(c, "Apenas administradores podem gerenciar cupons") {
+		return
+	}
+
+	var req SalvarCupomRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Informe código, tipo (percentual ou valor) e valor do cupom",
+		})
+		return
+	}
+
+	cupom := models.Cupom{Ativo: true}
+	if msg := preencherCupom(&cupom, req); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": msg,
+		})
+		return
+	}
+
+	var count int64
+	h.DB.Model(&models.Cupom{}).Where("codigo = ?", cupom.Codigo).Count(&count)
+	if count > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Já existe um cupom com este código",
+		})
+		return
+	}
+
+	if err := h.DB.Create(&cupom).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao cadastrar cupom",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    cupom,
+		"message": "Cupom cadastrado com sucesso",
+	})
+}
+
+// AtualizarCupom altera um cupom
+func (h *DiscountHandler) AtualizarCupom(c *gin.Context) {
+	if !exigirAdmin(c, "Apenas administradores podem gerenciar cupons") {
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "ID inválido",
+		})
+		return
+	}
+
+	var req SalvarCupomRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Informe código, tipo (percentual ou valor) e valor do cupom",
+		})
+		return
+	}
+
+	var cupom models.Cupom
+	if err := h.DB.First(&cupom, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "Cupom não encontrado",
+		})
+		return
+	}
+
+	if msg := preencherCupom(&cupom, req); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": msg,
+		})
+		return
+	}
+
+	var count int64
+	h.DB.Model(&models.Cupom{}).Where("codigo = ? AND id != ?", cupom.Codigo, id).Count(&count)
+	if count > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Já existe um cupom com este código",
+		})
+		return
+	}
+
+	if err := h.DB.Save(&cupom).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao atualizar cupom",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    cupom,
+		"message": "Cupom atualizado com sucesso",
+	})
+}
+
+// DeletarCupom remove um cupom que ainda não foi usado; cupons usados só podem ser desativados
+func (h *DiscountHandler) DeletarCupom(c *gin.Context) {
+	if !exigirAdmin(c, "Apenas administradores podem gerenciar cupons") {
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "ID inválido",
+		})
+		return
+	}
+
+	var cupom models.Cupom
+	if err := h.DB.First(&cupom, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "Cupom não encontrado",
+		})
+		return
+	}
+	if cupom.Usos > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Não é possível deletar cupom já usado. Desative-o",
+		})
+		return
+	}
+
+	if err := h.DB.Delete(&cupom).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao deletar cupom",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Cupom deletado com sucesso",
+	})
+}
+
+// ValidarCupom confere um cupom antes da venda e retorna o desconto sobre o valor informado
+func (h *DiscountHandler) ValidarCupom(c *gin.Context) {
+	valor, _ := money.Parse(c.DefaultQuery("valor", "0"))
+
+	cupom, err := discounts.BuscarCupom(h.DB, c.Param("codigo"))
+	var desconto money.Dinheiro
+	if err == nil {
+		desconto, err = discounts.ValidarCupom(cupom, valor, time.Now())
+	}
+	if errors.Is(err, discounts.ErrCupomInvalido) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Cupom " + err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao validar cupom",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"cupom":    cupom,
+			"desconto": desconto,
+		},
+	})
+}
+
+// ListarLimites retorna o desconto máximo sem aprovação de cada papel
+func (h *DiscountHandler) ListarLimites(c *gin.Context) {
+	if !exigirAdmin(c, "Apenas administradores podem gerenciar limites de desconto") {
+		return
+	}
+
+	var limites []models.LimiteDesconto
+	if err := h.DB.Order("papel ASC").Find(&limites).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao buscar limites de desconto",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    limites,
+	})
+}
+
+// SalvarLimites define o desconto máximo de cada papel. Papéis fora da lista ficam sem limite.
+func (h *DiscountHandler) SalvarLimites(c *gin.Context) {
+	if !exigirAdmin(c, "Apenas administradores podem gerenciar limites de desconto") {
+		return
+	}
+
+	var req struct {
+		Limites []struct {
+			Papel            string  `json:"papel" binding:"required,oneof=vendedor admin"`
+			PercentualMaximo float64 `json:"percentualMaximo" binding:"min=0,max=100"`
+		} `json:"limites" binding:"required,dive"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Limites inválidos. Informe papel (vendedor ou admin) e percentualMaximo entre 0 e 100",
+		})
+		return
+	}
+
+	limites := make([]models.LimiteDesconto, 0, len(req.Limites))
+	vistos := make(map[string]bool)
+	for _, l := range req.Limites {
+		if vistos[l.Papel] {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Papel repetido: " + l.Papel,
+			})
+			return
+		}
+		vistos[l.Papel] = true
+		limites = append(limites, models.LimiteDesconto{Papel: l.Papel, PercentualMaximo: l.PercentualMaximo})
+	}
+
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("1 = 1").Delete(&models.LimiteDesconto{}).Error; err != nil {
+			return err
+		}
+		if len(limites) == 0 {
+			return nil
+		}
+		return tx.Create(&limites).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao salvar limites de desconto",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    limites,
+		"message": "Limites de desconto salvos com sucesso",
+	})
+}
+
+// SolicitarAprovacao registra o pedido do vendedor para um desconto acima do seu limite
+func (h *DiscountHandler) SolicitarAprovacao(c *gin.Context) {
+	usuario, ok := usuarioLogado(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "Usuário não autenticado",
+		})
+		return
+	}
+
+	var req struct {
+		Percentual  float64 `json:"percentual" binding:"required,gt=0,max=100"`
+		Motivo      string  `json:"motivo" binding:"required"`
+		ClienteNome *string `json:"clienteNome"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Motivo) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Informe o percentual de desconto e o motivo",
+		})
+		return
+	}
+
+	aprovacao := models.AprovacaoDesconto{
+		SolicitanteID:   usuario.ID,
+		SolicitanteNome: usuario.Nome,
+		ClienteNome:     req.ClienteNome,
+		Percentual:      req.Percentual,
+		Motivo:          strings.TrimSpace(req.Motivo),
+		Status:          discounts.AprovacaoPendente,
+	}
+	if err := h.DB.Create(&aprovacao).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao solicitar aprovação",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    aprovacao,
+		"message": "Aprovação solicitada. Aguarde o admin",
+	})
+}
+
+// MinhasAprovacoes lista os pedidos de desconto do usuário logado
+func (h *DiscountHandler) MinhasAprovacoes(c *gin.Context) {
+	usuario, ok := usuarioLogado(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "Usuário não autenticado",
+		})
+		return
+	}
+
+	var aprovacoes []models.AprovacaoDesconto
+	if err := h.DB.Where("solicitanteId = ?", usuario.ID).Order("createdAt DESC").Limit(50).Find(&aprovacoes).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao buscar aprovações",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    aprovacoes,
+	})
+}
+
+// ListarAprovacoes lista os pedidos de desconto (filtro: status, padrão pendente)
+func (h *DiscountHandler) ListarAprovacoes(c *gin.Context) {
+	if !exigirAdmin(c, "Apenas administradores podem listar aprovações de desconto") {
+		return
+	}
+
+	status := c.DefaultQuery("status", discounts.AprovacaoPendente)
+
+	query := h.DB.Model(&models.AprovacaoDesconto{})
+	if status != "todas" {
+		query = query.Where("status = ?", status)
+	}
+
+	var aprovacoes []models.AprovacaoDesconto
+	if err := query.Order("createdAt DESC").Find(&aprovacoes).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao buscar aprovações",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    aprovacoes,
+	})
+}
+
+// AprovarDesconto autoriza o pedido; o percentual pode ser reduzido pelo admin
+func (h *DiscountHandler) AprovarDesconto(c *gin.Context) {
+	var req struct {
+		Percentual *float64 `json:"percentual"`
+	}
+	_ = c.ShouldBindJSON(&req)
+	h.decidir(c, discounts.AprovacaoAprovada, req.Percentual)
+}
+
+// RecusarDesconto recusa o pedido de desconto
+func (h *DiscountHandler) RecusarDesconto(c *gin.Context) {
+	h.decidir(c, discounts.AprovacaoRecusada, nil)
+}
+
+func (h *DiscountHandler) decidir(c *gin.Context, status string, percentual *float64) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "ID inválido",
+		})
+		return
+	}
+
+	admin, ok := usuarioLogado(c)
+	if !ok || !admin.IsAdmin {
+		c.JSON(http.StatusForbidden, gin.H{
+			"success": false,
+			"message": "Apenas administradores podem aprovar descontos",
+		})
+		return
+	}
+
+	var aprovacao models.AprovacaoDesconto
+	if err := h.DB.First(&aprovacao, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "Pedido de desconto não encontrado",
+		})
+		return
+	}
+	if aprovacao.Status != discounts.AprovacaoPendente {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Este pedido já foi decidido",
+		})
+		return
+	}
+
+	agora := time.Now()
+	aprovacao.Status = status
+	aprovacao.AprovadorID = &admin.ID
+	aprovacao.AprovadorNome = &admin.Nome
+	aprovacao.DecididoEm = &agora
+	if percentual != nil && *percentual > 0 && *percentual < aprovacao.Percentual {
+		aprovacao.Percentual = *percentual
+	}
+
+	if err := h.DB.Save(&aprovacao).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao salvar decisão",
+		})
+		return
+	}
+
+	mensagem := "Desconto aprovado"
+	if status == discounts.AprovacaoRecusada {
+		mensagem = "Desconto recusado"
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    aprovacao,
+		"message": mensagem,
+	})
+}
+
+// ResumoDescontos mede os descontos dados: valor de tabela, desconto de itens, da venda e de cupons,
+// por vendedor e por cupom. Filtros: dataInicio, dataFim, usuarioId
+func (h *DiscountHandler) ResumoDescontos(c *gin.Context) {
+	query := h.DB.Model(&models.HistoricoVenda{})
+	query = filtrarPeriodo(query, "createdAt", c.Query("dataInicio"), c.Query("dataFim"))
+	if usuarioID := c.Query("usuarioId"); usuarioID != "" {
+		query = query.Where("usuarioId = ?", usuarioID)
+	}
+
+	type linhaResumo struct {
+		Chave         *string        `gorm:"column:chave" json:"chave"`
+		Vendas        int            `gorm:"column:vendas" json:"vendas"`
+		ValorTabela   money.Dinheiro `gorm:"column:valor_tabela" json:"valorTabela"`
+		DescontoItem  money.Dinheiro `gorm:"column:desconto_item" json:"descontoItem"`
+		DescontoVenda money.Dinheiro `gorm:"column:desconto_venda" json:"descontoVenda"`
+		DescontoCupom money.Dinheiro `gorm:"column:desconto_cupom" json:"descontoCupom"`
+		ValorLiquido  money.Dinheiro `gorm:"column:valor_liquido" json:"valorLiquido"`
+		Percentual    float64        `gorm:"-" json:"percentual"`
+	}
+	// Vendas anteriores ao registro do preço de tabela usam o preço praticado
+	campos := "COUNT(DISTINCT vendaId) as vendas, " +
+		"SUM(CASE WHEN precoTabela > 0 THEN precoTabela ELSE precoUnitario END * quantidade) as valor_tabela, " +
+		"SUM(descontoItem) as desconto_item, SUM(descontoVenda) as desconto_venda, SUM(descontoCupom) as desconto_cupom, " +
+		"SUM(precoUnitario * quantidade - descontoVenda - descontoCupom) as valor_liquido"
+
+	resumir := func(chave, grupo string) ([]linhaResumo, error) {
+		var linhas []linhaResumo
+		q := query.Session(&gorm.Session{}).Select(chave + " as chave, " + campos)
+		if grupo != "" {
+			q = q.Group(grupo).Order("desconto_item + desconto_venda + desconto_cupom DESC")
+		}
+		if err := q.Scan(&linhas).Error; err != nil {
+			return nil, err
+		}
+		for i := range linhas {
+			l := &linhas[i]
+			l.Percentual = discounts.Percentual(l.DescontoItem+l.DescontoVenda+l.DescontoCupom, l.ValorTabela)
+		}
+		return linhas, nil
+	}
+
+	total, err := resumir("NULL", "")
+	if err == nil {
+		var porVendedor, porCupom []linhaResumo
+		porVendedor, err = resumir("vendedorNome", "vendedorNome")
+		if err == nil {
+			porCupom, err = resumir("cupomCodigo", "cupomCodigo")
+		}
+		if err == nil {
+			resumo := gin.H{"porVendedor": porVendedor, "porCupom": porCupom, "total": nil}
+			if len(total) > 0 {
+				resumo["total"] = total[0]
+			}
+			c.JSON(http.StatusOK, gin.H{
+				"success": true,
+				"data":    resumo,
+			})
+			return
+		}
+	}
+
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"success": false,
+		"message": "Erro ao gerar resumo de descontos",
+	})
+}
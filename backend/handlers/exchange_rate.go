@@ -0,0 +1,363 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"cmdimport/backend/exchange"
+	"cmdimport/backend/models"
+	"cmdimport/backend/money"
+	"cmdimport/backend/timezone"
+	"cmdimport/backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ExchangeRateHandler struct {
+	DB              *gorm.DB
+	ExchangeRateURL string
+}
+
+func NewExchangeRateHandler(db *gorm.DB, exchangeRateURL string) *ExchangeRateHandler {
+	return &ExchangeRateHandler{DB: db, ExchangeRateURL: exchangeRateURL}
+}
+
+type SalvarCotacaoRequest struct {
+	Data string  `json:"data" binding:"required"`
+	Taxa float64 `json:"taxa" binding:"required"`
+}
+
+// Listar lista as cotações cadastradas (mais recentes primeiro)
+func (h *ExchangeRateHandler) Listar(c *gin.Context) {
+	query := h.DB.Model(&models.CotacaoDolar{})
+
+	if dataInicio := c.Query("dataInicio"); dataInicio != "" {
+		query = query.Where("data >= ?", dataInicio)
+	}
+	if dataFim := c.Query("dataFim"); dataFim != "" {
+		query = query.Where("data <= ?", dataFim)
+	}
+
+	var cotacoes []models.CotacaoDolar
+	if err := query.Order("data DESC").Find(&cotacoes).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao buscar cotações",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    cotacoes,
+	})
+}
+
+// Salvar cria ou substitui a cotação de uma data
+func (h *ExchangeRateHandler) Salvar(c *gin.Context) {
+	var req SalvarCotacaoRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Data e taxa são obrigatórias",
+		})
+		return
+	}
+
+	data, err := exchange.ParseData(req.Data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Data inválida. Use o formato YYYY-MM-DD",
+		})
+		return
+	}
+
+	if req.Taxa <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "A taxa do dólar deve ser maior que zero",
+		})
+		return
+	}
+
+	cotacao := models.CotacaoDolar{
+		Data:  exchange.SomenteData(data),
+		Taxa:  req.Taxa,
+		Fonte: "manual",
+	}
+
+	if err := h.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "data"}},
+		DoUpdates: clause.AssignmentColumns([]string{"taxa", "fonte", "updatedAt"}),
+	}).Create(&cotacao).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao salvar cotação",
+		})
+		return
+	}
+
+	h.DB.Where("data = ?", cotacao.Data.Format("2006-01-02")).First(&cotacao)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    cotacao,
+		"message": "Cotação salva com sucesso",
+	})
+}
+
+// Deletar remove uma cotação
+func (h *ExchangeRateHandler) Deletar(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "ID inválido",
+		})
+		return
+	}
+
+	result := h.DB.Delete(&models.CotacaoDolar{}, id)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao deletar cotação",
+		})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "Cotação não encontrada",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Cotação deletada com sucesso",
+	})
+}
+
+// Vigente retorna a cotação válida em uma data (padrão: hoje)
+func (h *ExchangeRateHandler) Vigente(c *gin.Context) {
+	data := time.Now()
+	if dataStr := c.Query("data"); dataStr != "" {
+		parsed, err := exchange.ParseData(dataStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Data inválida. Use o formato YYYY-MM-DD",
+			})
+			return
+		}
+		data = timezone.InicioDaData(parsed)
+	}
+
+	cotacao, err := exchange.TaxaVigente(h.DB, data)
+	if err != nil {
+		if errors.Is(err, exchange.ErrSemCotacao) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"success": false,
+				"message": "Nenhuma cotação cadastrada até esta data",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao buscar cotação",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    cotacao,
+	})
+}
+
+// Importar importa cotações de um arquivo CSV (campo "arquivo") ou da fonte HTTP configurada (fonte=http)
+func (h *ExchangeRateHandler) Importar(c *gin.Context) {
+	var inicio, fim time.Time
+	if dataInicio := c.Query("dataInicio"); dataInicio != "" {
+		parsed, err := exchange.ParseData(dataInicio)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Data inicial inválida",
+			})
+			return
+		}
+		inicio = parsed
+	}
+	if dataFim := c.Query("dataFim"); dataFim != "" {
+		parsed, err := exchange.ParseData(dataFim)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Data final inválida",
+			})
+			return
+		}
+		fim = parsed
+	}
+
+	var provider exchange.Provider
+	if c.Query("fonte") == "http" {
+		provider = &exchange.HTTPProvider{URL: h.ExchangeRateURL}
+	} else {
+		file, _, err := c.Request.FormFile("arquivo")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Nenhum arquivo foi enviado",
+			})
+			return
+		}
+		defer file.Close()
+		provider = &exchange.CSVProvider{Reader: file}
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	total, err := exchange.Importar(ctx, h.DB, provider, inicio, fim)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Erro ao importar cotações: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Cotações importadas com sucesso",
+		"data": gin.H{
+			"fonte":      provider.Fonte(),
+			"importadas": total,
+		},
+	})
+}
+
+// Exposicao calcula quanto custaria o estoque atual se fosse comprado com outra taxa do dólar.
+// Sem o parâmetro taxa, usa a cotação vigente hoje.
+func (h *ExchangeRateHandler) Exposicao(c *gin.Context) {
+	var taxa float64
+	if taxaStr := c.Query("taxa"); taxaStr != "" {
+		parsed, err := utils.ParseFloatBR(taxaStr)
+		if err != nil || parsed <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Taxa inválida",
+			})
+			return
+		}
+		taxa = parsed
+	} else {
+		cotacao, err := exchange.TaxaVigente(h.DB, time.Now())
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Informe a taxa hipotética ou cadastre uma cotação",
+			})
+			return
+		}
+		taxa = cotacao.Taxa
+	}
+
+	type ExposicaoCategoria struct {
+		CategoriaID     *int           `gorm:"column:categoria_id"`
+		CategoriaNome   string         `gorm:"column:categoria_nome"`
+		Quantidade      int            `gorm:"column:quantidade"`
+		CustoDolar      money.Dinheiro `gorm:"column:custo_dolar"`
+		CustoRegistrado money.Dinheiro `gorm:"column:custo_registrado"`
+	}
+
+	// Estoque em mãos = estoque principal (não distribuído) + estoque distribuído ativo
+	query := `
+		SELECT
+			t.categoriaId as categoria_id,
+			COALESCE(cat.nome, 'Sem categoria') as categoria_nome,
+			SUM(t.quantidade) as quantidade,
+			SUM(t.custoDolar * t.quantidade) as custo_dolar,
+			SUM(t.preco * t.quantidade) as custo_registrado
+		FROM (
+			SELECT categoriaId, custoDolar, preco, quantidade FROM ProdutoComprado WHERE quantidade > 0 AND deletedAt IS NULL
+			UNION ALL
+			SELECT pc.categoriaId, pc.custoDolar, pc.preco, e.quantidade
+			FROM Estoque e
+			JOIN ProdutoComprado pc ON e.produtoCompradoId = pc.id
+			WHERE e.quantidade > 0 AND e.ativo = true AND e.deletedAt IS NULL
+		) as t
+		LEFT JOIN CategoriaProduto cat ON cat.id = t.categoriaId
+		GROUP BY t.categoriaId, cat.nome
+		ORDER BY custo_registrado DESC
+	`
+
+	var categorias []ExposicaoCategoria
+	if err := h.DB.Raw(query).Scan(&categorias).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao calcular exposição",
+		})
+		return
+	}
+
+	var totalQuantidade int
+	var totalDolar, totalRegistrado money.Dinheiro
+	porCategoria := make([]map[string]interface{}, 0, len(categorias))
+	for _, cat := range categorias {
+		custoHipotetico := cat.CustoDolar.Mul(taxa)
+		porCategoria = append(porCategoria, map[string]interface{}{
+			"categoriaId":     cat.CategoriaID,
+			"categoriaNome":   cat.CategoriaNome,
+			"quantidade":      cat.Quantidade,
+			"custoDolar":      cat.CustoDolar,
+			"custoRegistrado": cat.CustoRegistrado,
+			"custoHipotetico": custoHipotetico,
+			"diferenca":       custoHipotetico - cat.CustoRegistrado,
+			"taxaMedia":       taxaMedia(cat.CustoRegistrado, cat.CustoDolar),
+		})
+		totalQuantidade += cat.Quantidade
+		totalDolar += cat.CustoDolar
+		totalRegistrado += cat.CustoRegistrado
+	}
+
+	totalHipotetico := totalDolar.Mul(taxa)
+	variacao := 0.0
+	if totalRegistrado > 0 {
+		variacao = float64(totalHipotetico-totalRegistrado) / float64(totalRegistrado) * 100
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"taxaHipotetica": taxa,
+			"totais": gin.H{
+				"quantidade":         totalQuantidade,
+				"custoDolar":         totalDolar,
+				"custoRegistrado":    totalRegistrado,
+				"custoHipotetico":    totalHipotetico,
+				"diferenca":          totalHipotetico - totalRegistrado,
+				"variacaoPercentual": variacao,
+				"taxaMedia":          taxaMedia(totalRegistrado, totalDolar),
+			},
+			"porCategoria": porCategoria,
+		},
+	})
+}
+
+// taxaMedia é a taxa média ponderada pelo custo em dólar
+func taxaMedia(custoReais, custoDolar money.Dinheiro) float64 {
+	if custoDolar == 0 {
+		return 0
+	}
+	return float64(custoReais) / float64(custoDolar)
+}
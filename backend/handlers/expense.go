@@ -1,12 +1,14 @@
 package handlers
 
 import (
-	"fmt"
 	"net/http"
 	"strconv"
 	"time"
 
+	"cmdimport/backend/expenses"
 	"cmdimport/backend/models"
+	"cmdimport/backend/money"
+	"cmdimport/backend/timezone"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -26,19 +28,21 @@ type CriarCategoriaDespesaRequest struct {
 }
 
 type CriarDespesaRequest struct {
-	Nome        string  `json:"nome" binding:"required"`
-	Valor       float64 `json:"valor" binding:"required,min=0.01"`
-	CategoriaID int     `json:"categoriaId" binding:"required"`
-	Descricao   *string `json:"descricao"`
-	Data        string  `json:"data" binding:"required"`
+	Nome        string         `json:"nome" binding:"required"`
+	Valor       money.Dinheiro `json:"valor" binding:"required,gt=0"`
+	CategoriaID int            `json:"categoriaId" binding:"required"`
+	Descricao   *string        `json:"descricao"`
+	Data        string         `json:"data" binding:"required"`
+	Status      string         `json:"status"` // "paga" (padrão) ou "pendente" (a pagar, com vencimento em data)
 }
 
 type AtualizarDespesaRequest struct {
-	Nome        *string  `json:"nome"`
-	Valor       *float64 `json:"valor"`
-	CategoriaID *int     `json:"categoriaId"`
-	Descricao   *string  `json:"descricao"`
-	Data        *string  `json:"data"`
+	Nome        *string         `json:"nome"`
+	Valor       *money.Dinheiro `json:"valor"`
+	CategoriaID *int            `json:"categoriaId"`
+	Descricao   *string         `json:"descricao"`
+	Data        *string         `json:"data"`
+	Status      *string         `json:"status"`
 }
 
 // ListarCategorias lista todas as categorias de despesas
@@ -145,7 +149,7 @@ func (h *ExpenseHandler) AtualizarCategoria(c *gin.Context) {
 	})
 }
 
-// DeletarCategoria deleta uma categoria de despesas
+// DeletarCategoria move uma categoria de despesas para a lixeira, com as suas despesas
 func (h *ExpenseHandler) DeletarCategoria(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
@@ -173,17 +177,16 @@ func (h *ExpenseHandler) DeletarCategoria(c *gin.Context) {
 		return
 	}
 
-	// Deletar todas as despesas da categoria primeiro
-	if err := h.DB.Where("categoriaId = ?", id).Delete(&models.Despesa{}).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"message": "Erro ao deletar despesas da categoria",
-		})
-		return
-	}
-
-	// Deletar a categoria
-	if err := h.DB.Delete(&categoria).Error; err != nil {
+	// A categoria vai para a lixeira com as despesas, na mesma data, para que sejam restauradas juntas.
+	// Anexos e orçamentos ficam até a exclusão definitiva.
+	agora := time.Now()
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Despesa{}).Where("categoriaId = ?", id).Update("deletedAt", agora).Error; err != nil {
+			return err
+		}
+		return tx.Model(&categoria).Update("deletedAt", agora).Error
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
 			"message": "Erro ao deletar categoria",
@@ -193,25 +196,47 @@ func (h *ExpenseHandler) DeletarCategoria(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
-		"message": "Categoria deletada com sucesso",
+		"message": "Categoria e despesas movidas para a lixeira",
 	})
 }
 
-// ListarDespesas lista todas as despesas
+// TotalDespesas é a soma das despesas filtradas de uma categoria ou de um mês
+type TotalDespesas struct {
+	CategoriaID *int           `gorm:"column:categoriaId" json:"categoriaId,omitempty"`
+	Categoria   string         `gorm:"column:categoria" json:"categoria,omitempty"`
+	Mes         string         `gorm:"column:mes" json:"mes,omitempty"` // YYYY-MM
+	Quantidade  int64          `gorm:"column:quantidade" json:"quantidade"`
+	Total       money.Dinheiro `gorm:"column:total" json:"total"`
+}
+
+// ListarDespesas lista as despesas paginadas (parâmetros pagina e limite; filtros em filtrarDespesas), com os
+// totais de todas as despesas filtradas, por categoria e por mês
 func (h *ExpenseHandler) ListarDespesas(c *gin.Context) {
-	categoriaID := c.Query("categoriaId")
+	// Parâmetros de paginação
+	pagina, _ := strconv.Atoi(c.DefaultQuery("pagina", "1"))
+	limite, _ := strconv.Atoi(c.DefaultQuery("limite", "10"))
+	if pagina < 1 {
+		pagina = 1
+	}
+	if limite < 1 || limite > 500 {
+		limite = 10
+	}
 
-	query := h.DB.Model(&models.Despesa{}).Preload("Categoria")
+	offset := (pagina - 1) * limite
 
-	if categoriaID != "" {
-		id, err := strconv.Atoi(categoriaID)
-		if err == nil {
-			query = query.Where("categoriaId = ?", id)
-		}
-	}
+	query := filtrarDespesas(h.DB.Model(&models.Despesa{}), c)
+
+	// Contar total
+	var total int64
+	query.Count(&total)
+	totalPaginas := int((total + int64(limite) - 1) / int64(limite))
 
 	var despesas []models.Despesa
-	if err := query.Order("data DESC, createdAt DESC").Find(&despesas).Error; err != nil {
+	if err := query.Preload("Categoria").Preload("Anexos").
+		Order("data DESC, createdAt DESC, id DESC").
+		Offset(offset).
+		Limit(limite).
+		Find(&despesas).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
 			"message": "Erro ao buscar despesas",
@@ -219,12 +244,95 @@ func (h *ExpenseHandler) ListarDespesas(c *gin.Context) {
 		return
 	}
 
+	// Totais de todas as páginas
+	var porCategoria []TotalDespesas
+	if err := filtrarDespesas(h.DB.Model(&models.Despesa{}), c).
+		Select("Despesa.categoriaId as categoriaId, COALESCE(cd.nome, '') as categoria, COUNT(*) as quantidade, SUM(Despesa.valor) as total").
+		Joins("LEFT JOIN CategoriaDespesa cd ON cd.id = Despesa.categoriaId").
+		Group("Despesa.categoriaId, cd.nome").
+		Order("total DESC").
+		Scan(&porCategoria).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao totalizar despesas",
+		})
+		return
+	}
+	var porMes []TotalDespesas
+	if err := filtrarDespesas(h.DB.Model(&models.Despesa{}), c).
+		Select("DATE_FORMAT(Despesa.data, '%Y-%m') as mes, COUNT(*) as quantidade, SUM(Despesa.valor) as total").
+		Group("mes").
+		Order("mes DESC").
+		Scan(&porMes).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao totalizar despesas",
+		})
+		return
+	}
+
+	var valorTotal money.Dinheiro
+	for _, t := range porCategoria {
+		valorTotal += t.Total
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"data":    despesas,
+		"paginacao": gin.H{
+			"paginaAtual":  pagina,
+			"totalPaginas": totalPaginas,
+			"total":        total,
+			"limite":       limite,
+		},
+		"totais": gin.H{
+			"quantidade":   total,
+			"valor":        valorTotal,
+			"porCategoria": porCategoria,
+			"porMes":       porMes,
+		},
 	})
 }
 
+// filtrarDespesas aplica os filtros da listagem de despesas (categoria, período, status, busca no nome e na
+// descrição, faixa de valor). Compartilhado entre a listagem e a exportação.
+func filtrarDespesas(query *gorm.DB, c *gin.Context) *gorm.DB {
+	categoriaID := c.Query("categoriaId")
+
+	if categoriaID != "" {
+		id, err := strconv.Atoi(categoriaID)
+		if err == nil {
+			query = query.Where("Despesa.categoriaId = ?", id)
+		}
+	}
+
+	// Filtro de busca
+	if busca := c.Query("busca"); busca != "" {
+		query = query.Where("(Despesa.nome LIKE ? OR Despesa.descricao LIKE ?)", "%"+busca+"%", "%"+busca+"%")
+	}
+
+	// Filtro de período
+	if dataInicio := c.Query("dataInicio"); dataInicio != "" {
+		query = query.Where("Despesa.data >= ?", dataInicio)
+	}
+	if dataFim := c.Query("dataFim"); dataFim != "" {
+		query = query.Where("Despesa.data <= ?", dataFim)
+	}
+	if status := c.Query("status"); status != "" {
+		query = query.Where("Despesa.status = ?", status)
+	}
+
+	// Filtro de valor
+	if valorMin, err := money.Parse(c.Query("valorMin")); err == nil {
+		query = query.Where("Despesa.valor >= ?", valorMin)
+	}
+	if valorMax, err := money.Parse(c.Query("valorMax")); err == nil {
+		query = query.Where("Despesa.valor <= ?", valorMax)
+	}
+
+	return query
+}
+
 // CriarDespesa cria uma nova despesa
 func (h *ExpenseHandler) CriarDespesa(c *gin.Context) {
 	var req CriarDespesaRequest
@@ -253,20 +361,27 @@ func (h *ExpenseHandler) CriarDespesa(c *gin.Context) {
 		return
 	}
 
-	// Parse da data - usar time.Date para evitar problemas de timezone
-	// Parse a string no formato YYYY-MM-DD
-	var ano, mes, dia int
-	n, err := fmt.Sscanf(req.Data, "%d-%d-%d", &ano, &mes, &dia)
-	if err != nil || n != 3 {
+	status := req.Status
+	if status == "" {
+		status = expenses.StatusPaga
+	}
+	if status != expenses.StatusPaga && status != expenses.StatusPendente {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Status inválido. Use paga ou pendente",
+		})
+		return
+	}
+
+	// Data do calendário (YYYY-MM-DD) à 00:00 UTC: a conexão grava em UTC, então a coluna date recebe o dia informado
+	data, err := time.Parse("2006-01-02", req.Data)
+	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"success": false,
 			"message": "Data inválida. Use o formato YYYY-MM-DD",
 		})
 		return
 	}
-	// Criar data no timezone local para evitar conversões do GORM
-	loc, _ := time.LoadLocation("America/Sao_Paulo")
-	data := time.Date(ano, time.Month(mes), dia, 12, 0, 0, 0, loc) // Meio-dia para evitar problemas de timezone
 
 	despesa := models.Despesa{
 		Nome:        req.Nome,
@@ -274,9 +389,12 @@ func (h *ExpenseHandler) CriarDespesa(c *gin.Context) {
 		CategoriaID: req.CategoriaID,
 		Descricao:   req.Descricao,
 		Data:        data,
+		Status:      status,
+	}
+	if status == expenses.StatusPaga {
+		despesa.PagaEm = &data
 	}
 
-	// Criar a despesa primeiro
 	if err := h.DB.Create(&despesa).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
@@ -285,10 +403,6 @@ func (h *ExpenseHandler) CriarDespesa(c *gin.Context) {
 		return
 	}
 
-	// Atualizar a data usando SQL direto com DATE() para garantir que seja salva corretamente
-	// Isso evita problemas de conversão de timezone do GORM
-	h.DB.Exec("UPDATE Despesa SET data = DATE(?) WHERE id = ?", req.Data, despesa.ID)
-
 	// Carregar categoria para retornar
 	h.DB.Preload("Categoria").First(&despesa, despesa.ID)
 
@@ -365,10 +479,24 @@ func (h *ExpenseHandler) AtualizarDespesa(c *gin.Context) {
 	if req.Descricao != nil {
 		despesa.Descricao = req.Descricao
 	}
+	if req.Status != nil && *req.Status != despesa.Status {
+		switch *req.Status {
+		case expenses.StatusPaga:
+			pagaEm := timezone.Hoje()
+			despesa.PagaEm = &pagaEm
+		case expenses.StatusPendente:
+			despesa.PagaEm = nil
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Status inválido. Use paga ou pendente",
+			})
+			return
+		}
+		despesa.Status = *req.Status
+	}
 	if req.Data != nil {
-		// Parse da data - usar time.Date para evitar problemas de timezone
-		var ano, mes, dia int
-		_, err := fmt.Sscanf(*req.Data, "%d-%d-%d", &ano, &mes, &dia)
+		data, err := time.Parse("2006-01-02", *req.Data)
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{
 				"success": false,
@@ -376,12 +504,7 @@ func (h *ExpenseHandler) AtualizarDespesa(c *gin.Context) {
 			})
 			return
 		}
-		// Criar data no timezone local (meio-dia para evitar problemas de conversão)
-		loc, _ := time.LoadLocation("America/Sao_Paulo")
-		despesa.Data = time.Date(ano, time.Month(mes), dia, 12, 0, 0, 0, loc)
-		
-		// Atualizar usando SQL direto com DATE() para garantir que a data seja salva corretamente
-		h.DB.Exec("UPDATE Despesa SET data = DATE(?) WHERE id = ?", *req.Data, despesa.ID)
+		despesa.Data = data
 	}
 
 	if err := h.DB.Save(&despesa).Error; err != nil {
@@ -430,6 +553,7 @@ func (h *ExpenseHandler) DeletarDespesa(c *gin.Context) {
 		return
 	}
 
+	// Vai para a lixeira; os anexos ficam até a exclusão definitiva
 	if err := h.DB.Delete(&despesa).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
@@ -440,7 +564,63 @@ func (h *ExpenseHandler) DeletarDespesa(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
-		"message": "Despesa deletada com sucesso",
+		"message": "Despesa movida para a lixeira",
 	})
 }
 
+
+// PagarDespesa marca uma despesa pendente como paga ({"data": "YYYY-MM-DD"} opcional; padrão: hoje)
+func (h *ExpenseHandler) PagarDespesa(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "ID inválido",
+		})
+		return
+	}
+
+	var req struct {
+		Data string `json:"data"`
+	}
+	c.ShouldBindJSON(&req)
+
+	pagaEm := timezone.Hoje()
+	if req.Data != "" {
+		data, err := time.Parse("2006-01-02", req.Data)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Data inválida. Use o formato YYYY-MM-DD",
+			})
+			return
+		}
+		pagaEm = expenses.Dia(data)
+	}
+
+	result := h.DB.Model(&models.Despesa{}).
+		Where("id = ? AND status = ?", id, expenses.StatusPendente).
+		Updates(map[string]interface{}{
+			"status": expenses.StatusPaga,
+			"pagaEm": pagaEm,
+		})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao pagar despesa",
+		})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Só despesas pendentes podem ser pagas",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Despesa paga",
+	})
+}
@@ -0,0 +1,219 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"cmdimport/backend/models"
+	"cmdimport/backend/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxAnexosPorEnvio limita os arquivos de uma requisição de upload
+const maxAnexosPorEnvio = 10
+
+// removerArquivosAnexos apaga do disco os arquivos de anexos já removidos do banco. Falhas ficam no log:
+// o registro já não existe e o arquivo órfão não é acessível pela API.
+func removerArquivosAnexos(anexos []models.AnexoDespesa) {
+	for _, a := range anexos {
+		if err := storage.Remover(storage.RaizArquivos, a.Caminho); err != nil {
+			log.Printf("Erro ao remover anexo %s: %v", a.Caminho, err)
+		}
+	}
+}
+
+// buscarAnexo carrega o anexo da despesa a partir dos parâmetros id e anexoId, respondendo o erro se não existe
+func (h *ExpenseHandler) buscarAnexo(c *gin.Context) (*models.AnexoDespesa, bool) {
+	despesaID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "ID inválido",
+		})
+		return nil, false
+	}
+	anexoID, err := strconv.Atoi(c.Param("anexoId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "ID do anexo inválido",
+		})
+		return nil, false
+	}
+
+	var anexo models.AnexoDespesa
+	if err := h.DB.Where("id = ? AND despesaId = ?", anexoID, despesaID).First(&anexo).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "Anexo não encontrado",
+		})
+		return nil, false
+	}
+	return &anexo, true
+}
+
+// EnviarAnexos anexa comprovantes a uma despesa (multipart, um ou mais arquivos no campo "arquivo";
+// PDF, JPEG ou PNG de até 10MB cada)
+func (h *ExpenseHandler) EnviarAnexos(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "ID inválido",
+		})
+		return
+	}
+
+	var despesa models.Despesa
+	if err := h.DB.First(&despesa, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "Despesa não encontrada",
+		})
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxAnexosPorEnvio*tamanhoMaximoAnexo+(1<<20))
+	if err := c.Request.ParseMultipartForm(32 << 20); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Erro ao processar formulário. Tamanho máximo: 10MB por arquivo",
+		})
+		return
+	}
+	arquivos := c.Request.MultipartForm.File["arquivo"]
+	if len(arquivos) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Nenhum arquivo foi enviado",
+		})
+		return
+	}
+	if len(arquivos) > maxAnexosPorEnvio {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Envie no máximo 10 arquivos por vez",
+		})
+		return
+	}
+
+	var usuarioID *int
+	if usuario, ok := usuarioLogado(c); ok {
+		usuarioID = &usuario.ID
+	}
+
+	// Grava todos os arquivos antes de registrar; se algum falhar, os já gravados são apagados
+	anexos := make([]models.AnexoDespesa, 0, len(arquivos))
+	for _, cabecalho := range arquivos {
+		arquivo, err := storage.Salvar(storage.RaizArquivos, "despesas", cabecalho, storage.Documentos, tamanhoMaximoAnexo)
+		if err != nil {
+			removerArquivosAnexos(anexos)
+			status, msg := http.StatusInternalServerError, "Erro ao salvar arquivo"
+			if errors.Is(err, storage.ErrTipoNaoPermitido) {
+				status, msg = http.StatusBadRequest, "Tipo de arquivo não permitido em "+cabecalho.Filename+". Use PDF, JPEG ou PNG."
+			} else if errors.Is(err, storage.ErrArquivoGrande) {
+				status, msg = http.StatusBadRequest, "Arquivo muito grande: "+cabecalho.Filename+". Tamanho máximo: 10MB"
+			}
+			c.JSON(status, gin.H{
+				"success": false,
+				"message": msg,
+			})
+			return
+		}
+		anexos = append(anexos, models.AnexoDespesa{
+			DespesaID:    despesa.ID,
+			NomeOriginal: arquivo.NomeOriginal,
+			Caminho:      arquivo.Caminho,
+			TipoConteudo: arquivo.TipoConteudo,
+			Tamanho:      arquivo.Tamanho,
+			UsuarioID:    usuarioID,
+		})
+	}
+
+	if err := h.DB.Create(&anexos).Error; err != nil {
+		removerArquivosAnexos(anexos)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao registrar anexos",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    anexos,
+		"message": "Anexos enviados com sucesso",
+	})
+}
+
+// ListarAnexos lista os comprovantes de uma despesa
+func (h *ExpenseHandler) ListarAnexos(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "ID inválido",
+		})
+		return
+	}
+
+	var anexos []models.AnexoDespesa
+	if err := h.DB.Where("despesaId = ?", id).Order("id ASC").Find(&anexos).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao buscar anexos",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    anexos,
+	})
+}
+
+// BaixarAnexo envia o arquivo de um comprovante com o nome original
+func (h *ExpenseHandler) BaixarAnexo(c *gin.Context) {
+	anexo, ok := h.buscarAnexo(c)
+	if !ok {
+		return
+	}
+
+	caminho, err := storage.Caminho(storage.RaizArquivos, anexo.Caminho)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao localizar arquivo",
+		})
+		return
+	}
+
+	c.Header("Content-Type", anexo.TipoConteudo)
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.FileAttachment(caminho, anexo.NomeOriginal)
+}
+
+// DeletarAnexo remove um comprovante da despesa e o arquivo do disco
+func (h *ExpenseHandler) DeletarAnexo(c *gin.Context) {
+	anexo, ok := h.buscarAnexo(c)
+	if !ok {
+		return
+	}
+
+	if err := h.DB.Delete(anexo).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao deletar anexo",
+		})
+		return
+	}
+	removerArquivosAnexos([]models.AnexoDespesa{*anexo})
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Anexo deletado com sucesso",
+	})
+}
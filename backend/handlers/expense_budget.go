@@ -0,0 +1,227 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"cmdimport/backend/budgets"
+	"cmdimport/backend/models"
+	"cmdimport/backend/money"
+	"cmdimport/backend/timezone"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm/clause"
+)
+
+// maxMesesOrcamento limita quantos meses um cadastro de orçamento preenche de uma vez
+const maxMesesOrcamento = 24
+
+type SalvarOrcamentoDespesaRequest struct {
+	CategoriaID int            `json:"categoriaId" binding:"required"`
+	Mes         string         `json:"mes" binding:"required"` // YYYY-MM
+	MesFim      *string        `json:"mesFim"`                 // YYYY-MM, inclusive: repete o orçamento até este mês
+	Valor       money.Dinheiro `json:"valor" binding:"required,gt=0"`
+	Alertas     *[]float64     `json:"alertas"` // % do orçamento; vazio: 80 e 100
+}
+
+// ListarOrcamentosDespesa lista os orçamentos (filtros: mes, ou mesInicio e mesFim; categoriaId)
+func (h *ExpenseHandler) ListarOrcamentosDespesa(c *gin.Context) {
+	query := h.DB.Model(&models.OrcamentoDespesa{})
+	if mes := c.Query("mes"); mes != "" {
+		query = query.Where("mes = ?", mes)
+	}
+	if mesInicio := c.Query("mesInicio"); mesInicio != "" {
+		query = query.Where("mes >= ?", mesInicio)
+	}
+	if mesFim := c.Query("mesFim"); mesFim != "" {
+		query = query.Where("mes <= ?", mesFim)
+	}
+	if categoriaID := c.Query("categoriaId"); categoriaID != "" {
+		query = query.Where("categoriaId = ?", categoriaID)
+	}
+
+	var orcamentos []models.OrcamentoDespesa
+	if err := query.Order("mes ASC, categoriaId ASC").Find(&orcamentos).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao buscar orçamentos",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    orcamentos,
+	})
+}
+
+// SalvarOrcamentoDespesa define o orçamento de uma categoria em um mês, ou em cada mês de mes até mesFim.
+// Um mês que já tem orçamento tem o valor e os alertas substituídos.
+func (h *ExpenseHandler) SalvarOrcamentoDespesa(c *gin.Context) {
+	var req SalvarOrcamentoDespesaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Dados inválidos: " + err.Error(),
+		})
+		return
+	}
+
+	inicio, _, err := budgets.Intervalo(req.Mes)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Mês inválido. Use o formato YYYY-MM",
+		})
+		return
+	}
+	fim := inicio
+	if req.MesFim != nil && *req.MesFim != "" {
+		if fim, _, err = budgets.Intervalo(*req.MesFim); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Mês final inválido. Use o formato YYYY-MM",
+			})
+			return
+		}
+		if fim.Before(inicio) || !fim.Before(inicio.AddDate(0, maxMesesOrcamento, 0)) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "O mês final deve ser igual ou posterior ao inicial, em até 24 meses",
+			})
+			return
+		}
+	}
+
+	var alertas models.Percentuais
+	if req.Alertas != nil {
+		vistos := map[float64]bool{}
+		for _, a := range *req.Alertas {
+			if a <= 0 || a > 1000 {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"success": false,
+					"message": "Os alertas devem ser percentuais entre 0 e 1000",
+				})
+				return
+			}
+			if !vistos[a] {
+				vistos[a] = true
+				alertas = append(alertas, a)
+			}
+		}
+		sort.Float64s(alertas)
+	}
+
+	var count int64
+	h.DB.Model(&models.CategoriaDespesa{}).Where("id = ?", req.CategoriaID).Count(&count)
+	if count == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Categoria não encontrada",
+		})
+		return
+	}
+
+	var orcamentos []models.OrcamentoDespesa
+	for m := inicio; !m.After(fim); m = m.AddDate(0, 1, 0) {
+		orcamentos = append(orcamentos, models.OrcamentoDespesa{
+			CategoriaID: req.CategoriaID,
+			Mes:         budgets.Mes(m),
+			Valor:       req.Valor,
+			Alertas:     alertas,
+		})
+	}
+	err = h.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "categoriaId"}, {Name: "mes"}},
+		DoUpdates: clause.AssignmentColumns([]string{"valor", "alertas", "updatedAt"}),
+	}).Create(&orcamentos).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao salvar orçamento",
+		})
+		return
+	}
+
+	// Um orçamento menor pode já estar estourado: avisa sem esperar a próxima execução da tarefa
+	if _, err := budgets.Verificar(h.DB, time.Now()); err != nil {
+		log.Printf("Erro ao verificar orçamentos de despesas: %v", err)
+	}
+
+	var salvos []models.OrcamentoDespesa
+	h.DB.Where("categoriaId = ? AND mes >= ? AND mes <= ?", req.CategoriaID, budgets.Mes(inicio), budgets.Mes(fim)).
+		Order("mes ASC").Find(&salvos)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    salvos,
+		"message": "Orçamento salvo com sucesso",
+	})
+}
+
+// DeletarOrcamentoDespesa remove o orçamento de uma categoria em um mês
+func (h *ExpenseHandler) DeletarOrcamentoDespesa(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "ID inválido",
+		})
+		return
+	}
+
+	result := h.DB.Delete(&models.OrcamentoDespesa{}, id)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao deletar orçamento",
+		})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "Orçamento não encontrado",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Orçamento deletado com sucesso",
+	})
+}
+
+// RelatorioOrcamentosDespesa compara orçado e realizado por categoria no mês (parâmetro mes, padrão o atual) e
+// no acumulado do ano, com o mesmo período do ano anterior
+func (h *ExpenseHandler) RelatorioOrcamentosDespesa(c *gin.Context) {
+	mes := c.DefaultQuery("mes", budgets.Mes(timezone.Agora()))
+	if _, _, err := budgets.Intervalo(mes); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Mês inválido. Use o formato YYYY-MM",
+		})
+		return
+	}
+
+	categorias, total, err := budgets.Relatorio(h.DB, mes)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao gerar relatório de orçamentos",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"mes":        mes,
+			"categorias": categorias,
+			"total":      total,
+		},
+	})
+}
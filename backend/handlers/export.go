@@ -0,0 +1,254 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"cmdimport/backend/models"
+	"cmdimport/backend/money"
+	"cmdimport/backend/spreadsheet"
+	"cmdimport/backend/timezone"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ExportHandler gera planilhas CSV/XLSX com os mesmos filtros das listagens.
+// As linhas são lidas do banco com Rows() e gravadas uma a uma, sem carregar tudo em memória.
+type ExportHandler struct {
+	DB *gorm.DB
+}
+
+func NewExportHandler(db *gorm.DB) *ExportHandler {
+	return &ExportHandler{DB: db}
+}
+
+// Produtos exporta os produtos comprados (filtros: busca, dataInicio, dataFim, categoriaId, ocultarEstoqueZerado)
+func (h *ExportHandler) Produtos(c *gin.Context) {
+	type linhaProduto struct {
+		ID           int
+		Nome         string
+		Descricao    *string
+		Cor          *string
+		IMEI         *string `gorm:"column:imei"`
+		CodigoBarras *string `gorm:"column:codigoBarras"`
+		Categoria    *string `gorm:"column:categoria"`
+		Fornecedor   *string
+		DataCompra   time.Time      `gorm:"column:dataCompra"`
+		CustoDolar   money.Dinheiro `gorm:"column:custoDolar"`
+		TaxaDolar    float64        `gorm:"column:taxaDolar"`
+		Preco        money.Dinheiro
+		Quantidade   int
+	}
+
+	query := filtrarProdutos(h.DB.Model(&models.ProdutoComprado{}), c).
+		Select("ProdutoComprado.id, ProdutoComprado.nome, ProdutoComprado.descricao, ProdutoComprado.cor, " +
+			"ProdutoComprado.imei, ProdutoComprado.codigoBarras, cat.nome as categoria, ProdutoComprado.fornecedor, " +
+			"ProdutoComprado.dataCompra, ProdutoComprado.custoDolar, ProdutoComprado.taxaDolar, " +
+			"ProdutoComprado.preco, ProdutoComprado.quantidade").
+		Joins("LEFT JOIN CategoriaProduto cat ON cat.id = ProdutoComprado.categoriaId").
+		Order("ProdutoComprado.dataCompra DESC")
+
+	cabecalho := []string{"ID", "Nome", "Descrição", "Cor", "IMEI", "Código de Barras", "Categoria", "Fornecedor",
+		"Data da Compra", "Custo (US$)", "Taxa do Dólar", "Custo Unitário (R$)", "Quantidade", "Custo Total (R$)"}
+
+	h.exportar(c, "produtos", cabecalho, query, func(scan func(dest interface{}) error) ([]interface{}, error) {
+		var p linhaProduto
+		if err := scan(&p); err != nil {
+			return nil, err
+		}
+		return []interface{}{p.ID, p.Nome, p.Descricao, p.Cor, p.IMEI, p.CodigoBarras, p.Categoria, p.Fornecedor,
+			p.DataCompra, p.CustoDolar, spreadsheet.Taxa(p.TaxaDolar), p.Preco, p.Quantidade, p.Preco.Vezes(p.Quantidade)}, nil
+	})
+}
+
+// Estoque exporta o estoque distribuído ativo (filtros: usuarioId, ocultarEstoqueZerado).
+// Sem usuarioId, exporta o estoque de todos os usuários.
+func (h *ExportHandler) Estoque(c *gin.Context) {
+	type linhaEstoque struct {
+		ID            int
+		Usuario       string  `gorm:"column:usuario"`
+		Produto       string  `gorm:"column:produto"`
+		Cor           *string `gorm:"column:cor"`
+		IMEI          *string `gorm:"column:imei"`
+		CodigoBarras  *string `gorm:"column:codigoBarras"`
+		Quantidade    int
+		Preco         money.Dinheiro `gorm:"column:preco"`
+		AtendenteNome *string        `gorm:"column:atendenteNome"`
+	}
+
+	query := h.DB.Model(&models.Estoque{}).
+		Select("Estoque.id, u.nome as usuario, pc.nome as produto, pc.cor, pc.imei, pc.codigoBarras, "+
+			"Estoque.quantidade, pc.preco, Estoque.atendenteNome").
+		Joins("JOIN ProdutoComprado pc ON pc.id = Estoque.produtoCompradoId").
+		Joins("LEFT JOIN Usuario u ON u.id = Estoque.usuarioId").
+		Where("Estoque.ativo = ?", true)
+
+	if usuarioID := c.Query("usuarioId"); usuarioID != "" {
+		id, err := strconv.Atoi(usuarioID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "ID do usuário inválido",
+			})
+			return
+		}
+		query = query.Where("Estoque.usuarioId = ?", id)
+	}
+	if c.Query("ocultarEstoqueZerado") == "true" {
+		query = query.Where("Estoque.quantidade > ?", 0)
+	}
+	query = query.Order("u.nome ASC, Estoque.produtoCompradoId ASC")
+
+	cabecalho := []string{"ID", "Usuário", "Produto", "Cor", "IMEI", "Código de Barras", "Quantidade",
+		"Custo Unitário (R$)", "Custo Total (R$)", "Atendente"}
+
+	h.exportar(c, "estoque", cabecalho, query, func(scan func(dest interface{}) error) ([]interface{}, error) {
+		var e linhaEstoque
+		if err := scan(&e); err != nil {
+			return nil, err
+		}
+		return []interface{}{e.ID, e.Usuario, e.Produto, e.Cor, e.IMEI, e.CodigoBarras, e.Quantidade,
+			e.Preco, e.Preco.Vezes(e.Quantidade), e.AtendenteNome}, nil
+	})
+}
+
+// Vendas exporta o histórico de vendas (filtros: cliente, imeiCodigo, dataInicio, dataFim)
+func (h *ExportHandler) Vendas(c *gin.Context) {
+	type linhaVenda struct {
+		ID             int
+		VendaID        *string   `gorm:"column:vendaId"`
+		CreatedAt      time.Time `gorm:"column:createdAt"`
+		ClienteNome    string    `gorm:"column:clienteNome"`
+		Telefone       string
+		ProdutoNome    string  `gorm:"column:produtoNome"`
+		IMEI           *string `gorm:"column:imeiProduto"`
+		Quantidade     int
+		PrecoUnitario  money.Dinheiro  `gorm:"column:precoUnitario"`
+		ValorTotal     money.Dinheiro  `gorm:"column:valorTotal"`
+		FormaPagamento string          `gorm:"column:formaPagamento"`
+		ValorPix       *money.Dinheiro `gorm:"column:valorPix"`
+		ValorCartao    *money.Dinheiro `gorm:"column:valorCartao"`
+		ValorDinheiro  *money.Dinheiro `gorm:"column:valorDinheiro"`
+		VendedorNome   string          `gorm:"column:vendedorNome"`
+		Observacoes    *string
+	}
+
+	// Aliases próprios para não conflitar com os JOINs do filtro por IMEI
+	query := filtrarHistoricoVendas(h.DB.Model(&models.HistoricoVenda{}), c).
+		Select("HistoricoVenda.id, HistoricoVenda.vendaId, HistoricoVenda.createdAt, HistoricoVenda.clienteNome, " +
+			"HistoricoVenda.telefone, HistoricoVenda.produtoNome, exp_pc.imei as imeiProduto, HistoricoVenda.quantidade, " +
+			"HistoricoVenda.precoUnitario, HistoricoVenda.valorTotal, HistoricoVenda.formaPagamento, " +
+			"HistoricoVenda.valorPix, HistoricoVenda.valorCartao, HistoricoVenda.valorDinheiro, " +
+			"HistoricoVenda.vendedorNome, HistoricoVenda.observacoes").
+		Joins("LEFT JOIN Estoque exp_e ON exp_e.id = HistoricoVenda.estoqueId").
+		Joins("LEFT JOIN ProdutoComprado exp_pc ON exp_pc.id = exp_e.produtoCompradoId").
+		Order("HistoricoVenda.createdAt DESC, HistoricoVenda.id ASC")
+
+	cabecalho := []string{"ID", "Venda", "Data", "Cliente", "Telefone", "Produto", "IMEI", "Quantidade",
+		"Preço Unitário (R$)", "Valor Total (R$)", "Forma de Pagamento", "Pix (R$)", "Cartão (R$)", "Dinheiro (R$)",
+		"Vendedor", "Observações"}
+
+	h.exportar(c, "vendas", cabecalho, query, func(scan func(dest interface{}) error) ([]interface{}, error) {
+		var v linhaVenda
+		if err := scan(&v); err != nil {
+			return nil, err
+		}
+		return []interface{}{v.ID, v.VendaID, v.CreatedAt, v.ClienteNome, v.Telefone, v.ProdutoNome, v.IMEI,
+			v.Quantidade, v.PrecoUnitario, v.ValorTotal, v.FormaPagamento, v.ValorPix, v.ValorCartao, v.ValorDinheiro,
+			v.VendedorNome, v.Observacoes}, nil
+	})
+}
+
+// Despesas exporta as despesas (filtros: categoriaId, dataInicio, dataFim)
+func (h *ExportHandler) Despesas(c *gin.Context) {
+	type linhaDespesa struct {
+		ID        int
+		Data      time.Time
+		Nome      string
+		Categoria string `gorm:"column:categoria"`
+		Valor     money.Dinheiro
+		Descricao *string
+	}
+
+	query := filtrarDespesas(h.DB.Model(&models.Despesa{}), c).
+		Select("Despesa.id, Despesa.data, Despesa.nome, cat.nome as categoria, Despesa.valor, Despesa.descricao").
+		Joins("JOIN CategoriaDespesa cat ON cat.id = Despesa.categoriaId").
+		Order("Despesa.data DESC, Despesa.createdAt DESC")
+
+	cabecalho := []string{"ID", "Data", "Nome", "Categoria", "Valor (R$)", "Descrição"}
+
+	h.exportar(c, "despesas", cabecalho, query, func(scan func(dest interface{}) error) ([]interface{}, error) {
+		var d linhaDespesa
+		if err := scan(&d); err != nil {
+			return nil, err
+		}
+		return []interface{}{d.ID, d.Data, d.Nome, d.Categoria, d.Valor, d.Descricao}, nil
+	})
+}
+
+// exportar abre o cursor da consulta e grava cada linha convertida por converter no formato pedido (formato=csv|xlsx)
+func (h *ExportHandler) exportar(c *gin.Context, nome string, cabecalho []string, query *gorm.DB,
+	converter func(scan func(dest interface{}) error) ([]interface{}, error)) {
+	formato := c.DefaultQuery("formato", spreadsheet.FormatoCSV)
+	if formato != spreadsheet.FormatoCSV && formato != spreadsheet.FormatoXLSX {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Formato inválido. Use csv ou xlsx",
+		})
+		return
+	}
+
+	rows, err := query.Rows()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao buscar dados para exportação",
+		})
+		return
+	}
+	defer rows.Close()
+
+	nomeArquivo := fmt.Sprintf("%s-%s.%s", nome, timezone.Agora().Format("2006-01-02"), formato)
+	c.Header("Content-Type", spreadsheet.ContentType(formato))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", nomeArquivo))
+	c.Status(http.StatusOK)
+
+	escritor, err := spreadsheet.NovoEscritor(formato, c.Writer)
+	if err != nil {
+		log.Printf("Erro ao exportar %s: %v", nome, err)
+		return
+	}
+
+	// A partir daqui o cabeçalho HTTP já foi enviado; erros só podem ser registrados no log
+	if err := escritor.Cabecalho(cabecalho); err != nil {
+		log.Printf("Erro ao exportar %s: %v", nome, err)
+		return
+	}
+
+	scan := func(dest interface{}) error {
+		return query.ScanRows(rows, dest)
+	}
+	for rows.Next() {
+		valores, err := converter(scan)
+		if err != nil {
+			log.Printf("Erro ao exportar %s: %v", nome, err)
+			return
+		}
+		if err := escritor.Linha(valores); err != nil {
+			log.Printf("Erro ao exportar %s: %v", nome, err)
+			return
+		}
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("Erro ao exportar %s: %v", nome, err)
+		return
+	}
+
+	if err := escritor.Fechar(); err != nil {
+		log.Printf("Erro ao exportar %s: %v", nome, err)
+	}
+}
@@ -0,0 +1,372 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"cmdimport/backend/goals"
+	"cmdimport/backend/models"
+	"cmdimport/backend/money"
+	"cmdimport/backend/timezone"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type GoalHandler struct {
+	DB *gorm.DB
+}
+
+func NewGoalHandler(db *gorm.DB) *GoalHandler {
+	return &GoalHandler{DB: db}
+}
+
+type SalvarMetaRequest struct {
+	UsuarioID   *int            `json:"usuarioId"` // Informe o vendedor ou a loja; sem os dois, a meta é da empresa
+	LojaID      *int            `json:"lojaId"`
+	CategoriaID *int            `json:"categoriaId"`
+	DataInicio  string          `json:"dataInicio" binding:"required"` // YYYY-MM-DD
+	DataFim     string          `json:"dataFim" binding:"required"`    // YYYY-MM-DD, inclusive
+	Faturamento *money.Dinheiro `json:"faturamento"`
+	Unidades    *int            `json:"unidades"`
+	Margem      *money.Dinheiro `json:"margem"`
+	Descricao   *string         `json:"descricao"`
+}
+
+// validarMeta preenche a meta a partir da requisição e retorna a mensagem de erro para o cliente, ou "" se é válida
+func (h *GoalHandler) validarMeta(meta *models.Meta, req SalvarMetaRequest) string {
+	inicio, err := time.Parse("2006-01-02", req.DataInicio)
+	if err != nil {
+		return "Data de início inválida. Use o formato YYYY-MM-DD"
+	}
+	fim, err := time.Parse("2006-01-02", req.DataFim)
+	if err != nil {
+		return "Data de fim inválida. Use o formato YYYY-MM-DD"
+	}
+	if fim.Before(inicio) {
+		return "A data de fim deve ser igual ou posterior à de início"
+	}
+	if req.UsuarioID != nil && req.LojaID != nil {
+		return "Informe o vendedor ou a loja, não os dois"
+	}
+	if req.Faturamento == nil && req.Unidades == nil && req.Margem == nil {
+		return "Informe ao menos um objetivo: faturamento, unidades ou margem"
+	}
+	if (req.Faturamento != nil && *req.Faturamento <= 0) || (req.Unidades != nil && *req.Unidades <= 0) ||
+		(req.Margem != nil && *req.Margem <= 0) {
+		return "Os objetivos devem ser maiores que zero"
+	}
+
+	var count int64
+	if req.UsuarioID != nil {
+		h.DB.Model(&models.Usuario{}).Where("id = ?", *req.UsuarioID).Count(&count)
+		if count == 0 {
+			return "Vendedor não encontrado"
+		}
+	}
+	if req.LojaID != nil {
+		h.DB.Model(&models.Loja{}).Where("id = ?", *req.LojaID).Count(&count)
+		if count == 0 {
+			return "Loja não encontrada"
+		}
+	}
+	if req.CategoriaID != nil {
+		h.DB.Model(&models.CategoriaProduto{}).Where("id = ?", *req.CategoriaID).Count(&count)
+		if count == 0 {
+			return "Categoria não encontrada"
+		}
+	}
+
+	meta.UsuarioID = req.UsuarioID
+	meta.LojaID = req.LojaID
+	meta.CategoriaID = req.CategoriaID
+	meta.DataInicio = inicio
+	meta.DataFim = fim
+	meta.Faturamento = req.Faturamento
+	meta.Unidades = req.Unidades
+	meta.Margem = req.Margem
+	meta.Descricao = req.Descricao
+	return ""
+}
+
+// ListarMetas lista as metas (filtros: usuarioId, lojaId, data — metas vigentes no dia)
+func (h *GoalHandler) ListarMetas(c *gin.Context) {
+	if !exigirAdmin(c, "Apenas administradores podem gerenciar metas") {
+		return
+	}
+
+	query := h.DB.Model(&models.Meta{})
+	if usuarioID := c.Query("usuarioId"); usuarioID != "" {
+		query = query.Where("usuarioId = ?", usuarioID)
+	}
+	if lojaID := c.Query("lojaId"); lojaID != "" {
+		query = query.Where("lojaId = ?", lojaID)
+	}
+	if data := c.Query("data"); data != "" {
+		query = query.Where("dataInicio <= ? AND dataFim >= ?", data, data)
+	}
+
+	var metas []models.Meta
+	if err := query.Order("dataInicio DESC, id ASC").Find(&metas).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao buscar metas",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    metas,
+	})
+}
+
+// CriarMeta cadastra a meta de um vendedor, de uma loja ou da empresa em um período
+func (h *GoalHandler) CriarMeta(c *gin.Context) {
+	if !exigirAdmin(c, "Apenas administradores podem gerenciar metas") {
+		return
+	}
+
+	var req SalvarMetaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Informe o período da meta (dataInicio e dataFim)",
+		})
+		return
+	}
+
+	var meta models.Meta
+	if msg := h.validarMeta(&meta, req); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": msg,
+		})
+		return
+	}
+	if err := h.DB.Create(&meta).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao cadastrar meta",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    meta,
+		"message": "Meta cadastrada com sucesso",
+	})
+}
+
+// AtualizarMeta altera uma meta
+func (h *GoalHandler) AtualizarMeta(c *gin.Context) {
+	if !exigirAdmin(c, "Apenas administradores podem gerenciar metas") {
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "ID inválido",
+		})
+		return
+	}
+
+	var req SalvarMetaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Informe o período da meta (dataInicio e dataFim)",
+		})
+		return
+	}
+
+	var meta models.Meta
+	if err := h.DB.First(&meta, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "Meta não encontrada",
+		})
+		return
+	}
+	if msg := h.validarMeta(&meta, req); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": msg,
+		})
+		return
+	}
+	if err := h.DB.Save(&meta).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao atualizar meta",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    meta,
+		"message": "Meta atualizada com sucesso",
+	})
+}
+
+// DeletarMeta remove uma meta
+func (h *GoalHandler) DeletarMeta(c *gin.Context) {
+	if !exigirAdmin(c, "Apenas administradores podem gerenciar metas") {
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "ID inválido",
+		})
+		return
+	}
+
+	result := h.DB.Delete(&models.Meta{}, id)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao deletar meta",
+		})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "Meta não encontrada",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Meta deletada com sucesso",
+	})
+}
+
+// progresso responde o acompanhamento das metas vigentes no dia informado (parâmetro data; padrão: hoje)
+func (h *GoalHandler) progresso(c *gin.Context, query *gorm.DB) {
+	agora := time.Now()
+	data := agora.In(timezone.Local()).Format("2006-01-02")
+	if d := c.Query("data"); d != "" {
+		fimDia, err := timezone.ParseDiaSeguinte(d)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Data inválida. Use o formato YYYY-MM-DD",
+			})
+			return
+		}
+		// Para uma data passada, o acompanhamento é o do fim daquele dia
+		if fimDia.Before(agora) {
+			agora = fimDia.Add(-time.Second)
+		}
+		data = d
+	}
+
+	var metas []models.Meta
+	if err := query.Where("dataInicio <= ? AND dataFim >= ?", data, data).
+		Order("dataFim ASC, id ASC").Find(&metas).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao buscar metas",
+		})
+		return
+	}
+
+	// Nomes de vendedores, lojas e categorias para exibição
+	nomesUsuarios := make(map[int]string)
+	nomesLojas := make(map[int]string)
+	nomesCategorias := make(map[int]string)
+	var usuarios []models.Usuario
+	h.DB.Select("id, nome").Find(&usuarios)
+	for _, u := range usuarios {
+		nomesUsuarios[u.ID] = u.Nome
+	}
+	var lojas []models.Loja
+	h.DB.Find(&lojas)
+	for _, l := range lojas {
+		nomesLojas[l.ID] = l.Nome
+	}
+	var categorias []models.CategoriaProduto
+	h.DB.Find(&categorias)
+	for _, cat := range categorias {
+		nomesCategorias[cat.ID] = cat.Nome
+	}
+
+	lista := make([]gin.H, 0, len(metas))
+	for _, meta := range metas {
+		realizado, err := goals.Apurar(h.DB, meta, agora)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "Erro ao calcular progresso das metas",
+			})
+			return
+		}
+
+		item := gin.H{
+			"meta":      meta,
+			"realizado": realizado,
+			"progresso": goals.Calcular(meta, realizado, agora),
+		}
+		if meta.UsuarioID != nil {
+			item["vendedorNome"] = nomesUsuarios[*meta.UsuarioID]
+		}
+		if meta.LojaID != nil {
+			item["lojaNome"] = nomesLojas[*meta.LojaID]
+		}
+		if meta.CategoriaID != nil {
+			item["categoriaNome"] = nomesCategorias[*meta.CategoriaID]
+		}
+		lista = append(lista, item)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    lista,
+	})
+}
+
+// Progresso acompanha as metas vigentes (filtros: usuarioId, lojaId, data)
+func (h *GoalHandler) Progresso(c *gin.Context) {
+	if !exigirAdmin(c, "Apenas administradores podem gerenciar metas") {
+		return
+	}
+
+	query := h.DB.Model(&models.Meta{})
+	if usuarioID := c.Query("usuarioId"); usuarioID != "" {
+		query = query.Where("usuarioId = ?", usuarioID)
+	}
+	if lojaID := c.Query("lojaId"); lojaID != "" {
+		query = query.Where("lojaId = ?", lojaID)
+	}
+	h.progresso(c, query)
+}
+
+// MeuProgresso acompanha as metas do vendedor logado, da sua loja e da empresa (parâmetro: data)
+func (h *GoalHandler) MeuProgresso(c *gin.Context) {
+	vendedor, ok := usuarioLogado(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "Usuário não autenticado",
+		})
+		return
+	}
+
+	query := h.DB.Model(&models.Meta{})
+	if vendedor.LojaID != nil {
+		query = query.Where("(usuarioId = ? OR lojaId = ? OR (usuarioId IS NULL AND lojaId IS NULL))", vendedor.ID, *vendedor.LojaID)
+	} else {
+		query = query.Where("(usuarioId = ? OR (usuarioId IS NULL AND lojaId IS NULL))", vendedor.ID)
+	}
+	h.progresso(c, query)
+}
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"cmdimport/backend/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type NotificationHandler struct {
+	DB *gorm.DB
+}
+
+func NewNotificationHandler(db *gorm.DB) *NotificationHandler {
+	return &NotificationHandler{DB: db}
+}
+
+// Listar lista as notificações do usuário logado, das mais recentes (filtros: naoLidas, tipo; limite, padrão 50)
+func (h *NotificationHandler) Listar(c *gin.Context) {
+	usuario, ok := usuarioLogado(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "Usuário não autenticado",
+		})
+		return
+	}
+
+	limite := 50
+	if l := c.Query("limite"); l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil || n < 1 || n > 200 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Informe limite entre 1 e 200",
+			})
+			return
+		}
+		limite = n
+	}
+
+	query := h.DB.Model(&models.Notificacao{}).Where("usuarioId = ?", usuario.ID)
+	if c.Query("naoLidas") == "true" {
+		query = query.Where("lidaEm IS NULL")
+	}
+	if tipo := c.Query("tipo"); tipo != "" {
+		query = query.Where("tipo = ?", tipo)
+	}
+
+	var notificacoes []models.Notificacao
+	if err := query.Order("createdAt DESC, id DESC").Limit(limite).Find(&notificacoes).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao buscar notificações",
+		})
+		return
+	}
+
+	var naoLidas int64
+	h.DB.Model(&models.Notificacao{}).Where("usuarioId = ? AND lidaEm IS NULL", usuario.ID).Count(&naoLidas)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"data":     notificacoes,
+		"naoLidas": naoLidas,
+	})
+}
+
+// MarcarLida marca uma notificação do usuário logado como lida
+func (h *NotificationHandler) MarcarLida(c *gin.Context) {
+	usuario, ok := usuarioLogado(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "Usuário não autenticado",
+		})
+		return
+	}
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "ID inválido",
+		})
+		return
+	}
+
+	var notificacao models.Notificacao
+	if err := h.DB.Where("id = ? AND usuarioId = ?", id, usuario.ID).First(&notificacao).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "Notificação não encontrada",
+		})
+		return
+	}
+	if notificacao.LidaEm == nil {
+		agora := time.Now()
+		if err := h.DB.Model(&notificacao).Update("lidaEm", agora).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "Erro ao marcar notificação como lida",
+			})
+			return
+		}
+		notificacao.LidaEm = &agora
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    notificacao,
+	})
+}
+
+// MarcarTodasLidas marca como lidas todas as notificações do usuário logado
+func (h *NotificationHandler) MarcarTodasLidas(c *gin.Context) {
+	usuario, ok := usuarioLogado(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "Usuário não autenticado",
+		})
+		return
+	}
+
+	result := h.DB.Model(&models.Notificacao{}).Where("usuarioId = ? AND lidaEm IS NULL", usuario.ID).
+		Update("lidaEm", time.Now())
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao marcar notificações como lidas",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    gin.H{"marcadas": result.RowsAffected},
+	})
+}
@@ -0,0 +1,225 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"cmdimport/backend/models"
+	"cmdimport/backend/pricing"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type PaymentPlanHandler struct {
+	DB *gorm.DB
+}
+
+func NewPaymentPlanHandler(db *gorm.DB) *PaymentPlanHandler {
+	return &PaymentPlanHandler{DB: db}
+}
+
+type SalvarPlanoRequest struct {
+	Codigo    string  `json:"codigo"` // Opcional: gerado a partir do método e das parcelas (ex.: credito_3x)
+	Nome      string  `json:"nome"`
+	Metodo    string  `json:"metodo" binding:"required,oneof=dinheiro_pix debito credito"`
+	Parcelas  int     `json:"parcelas" binding:"min=0,max=24"`
+	Acrescimo float64 `json:"acrescimo" binding:"min=0"`
+	Ativo     *bool   `json:"ativo"`
+	Ordem     int     `json:"ordem"`
+}
+
+// Listar retorna os planos de pagamento aceitos na venda (ativos; ?todos=true inclui os inativos)
+func (h *PaymentPlanHandler) Listar(c *gin.Context) {
+	planos, err := pricing.CarregarPlanos(h.DB, c.Query("todos") != "true")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao buscar planos de pagamento",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    planos,
+	})
+}
+
+// Criar cadastra um plano de pagamento, ex.: crédito em 3x
+func (h *PaymentPlanHandler) Criar(c *gin.Context) {
+	var req SalvarPlanoRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Dados inválidos. O método deve ser dinheiro_pix, debito ou credito",
+		})
+		return
+	}
+
+	plano := models.PlanoPagamento{Ativo: true}
+	preencherPlano(&plano, req)
+
+	var count int64
+	h.DB.Model(&models.PlanoPagamento{}).Where("codigo = ?", plano.Codigo).Count(&count)
+	if count > 0 || (pricing.PlanoComColuna(plano.Codigo) && h.semPlanosCadastrados()) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Já existe um plano com este código",
+		})
+		return
+	}
+
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := pricing.GarantirPlanosPadrao(tx); err != nil {
+			return err
+		}
+		return tx.Create(&plano).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao cadastrar plano de pagamento",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    plano,
+		"message": "Plano de pagamento cadastrado com sucesso",
+	})
+}
+
+// Atualizar altera nome, acréscimo, ordem ou situação de um plano. O código não muda,
+// pois é gravado nas vendas e nos preços.
+func (h *PaymentPlanHandler) Atualizar(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "ID inválido",
+		})
+		return
+	}
+
+	var req SalvarPlanoRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Dados inválidos. O método deve ser dinheiro_pix, debito ou credito",
+		})
+		return
+	}
+
+	var plano models.PlanoPagamento
+	if err := h.DB.First(&plano, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "Plano de pagamento não encontrado",
+		})
+		return
+	}
+
+	codigo := plano.Codigo
+	preencherPlano(&plano, req)
+	plano.Codigo = codigo
+
+	if err := h.DB.Save(&plano).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao atualizar plano de pagamento",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    plano,
+		"message": "Plano de pagamento atualizado com sucesso",
+	})
+}
+
+// Deletar remove um plano de pagamento. Os planos com coluna própria na precificação
+// e os já usados em vendas só podem ser desativados.
+func (h *PaymentPlanHandler) Deletar(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "ID inválido",
+		})
+		return
+	}
+
+	var plano models.PlanoPagamento
+	if err := h.DB.First(&plano, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "Plano de pagamento não encontrado",
+		})
+		return
+	}
+
+	var vendas int64
+	h.DB.Model(&models.HistoricoVenda{}).Where("formaPagamento = ?", plano.Codigo).Count(&vendas)
+	if pricing.PlanoComColuna(plano.Codigo) || vendas > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Este plano não pode ser deletado. Desative-o",
+		})
+		return
+	}
+
+	if err := h.DB.Delete(&plano).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao deletar plano de pagamento",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Plano de pagamento deletado com sucesso",
+	})
+}
+
+// semPlanosCadastrados indica que ainda valem os planos padrão
+func (h *PaymentPlanHandler) semPlanosCadastrados() bool {
+	var count int64
+	h.DB.Model(&models.PlanoPagamento{}).Count(&count)
+	return count == 0
+}
+
+func preencherPlano(plano *models.PlanoPagamento, req SalvarPlanoRequest) {
+	plano.Metodo = req.Metodo
+	plano.Parcelas = req.Parcelas
+	if plano.Parcelas < 1 {
+		plano.Parcelas = 1
+	}
+	plano.Acrescimo = req.Acrescimo
+	plano.Ordem = req.Ordem
+	if req.Ativo != nil {
+		plano.Ativo = *req.Ativo
+	}
+
+	codigo := strings.ToLower(strings.TrimSpace(req.Codigo))
+	if codigo == "" {
+		switch {
+		case plano.Metodo == pricing.MetodoCredito && plano.Parcelas == 1:
+			codigo = pricing.PlanoCreditoVista
+		case plano.Metodo == pricing.MetodoCredito:
+			codigo = "credito_" + strconv.Itoa(plano.Parcelas) + "x"
+		default:
+			codigo = plano.Metodo
+		}
+	}
+	plano.Codigo = codigo
+
+	plano.Nome = strings.TrimSpace(req.Nome)
+	if plano.Nome == "" {
+		plano.Nome = codigo
+	}
+}
@@ -0,0 +1,19 @@
+package handlers
+
+import (
+	"cmdimport/backend/timezone"
+
+	"gorm.io/gorm"
+)
+
+// filtrarPeriodo filtra uma coluna datetime (gravada em UTC) pelos dias dataInicio e dataFim (YYYY-MM-DD,
+// inclusive), contados no fuso do negócio. Datas vazias ou fora do formato são ignoradas.
+func filtrarPeriodo(query *gorm.DB, coluna, dataInicio, dataFim string) *gorm.DB {
+	if inicio, err := timezone.ParseData(dataInicio); err == nil {
+		query = query.Where(coluna+" >= ?", inicio)
+	}
+	if fim, err := timezone.ParseDiaSeguinte(dataFim); err == nil {
+		query = query.Where(coluna+" < ?", fim)
+	}
+	return query
+}
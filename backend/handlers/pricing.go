@@ -3,9 +3,14 @@ package handlers
 import (
 	"errors"
 	"net/http"
+	"strconv"
 	"time"
 
+	"cmdimport/backend/catalog"
 	"cmdimport/backend/models"
+	"cmdimport/backend/money"
+	"cmdimport/backend/pricing"
+	"cmdimport/backend/timezone"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -20,38 +25,41 @@ func NewPricingHandler(db *gorm.DB) *PricingHandler {
 }
 
 // ListarCombos retorna uma lista unificada de produtos com suas precificações.
-// Ele busca todos os nomes de produtos distintos do estoque e cruza com a tabela de precificação.
+// Produtos com SKU são agrupados pelo SKU; os que ainda não têm SKU continuam agrupados pelo nome.
 func (h *PricingHandler) ListarCombos(c *gin.Context) {
-	// 1. Buscar todos os produtos do estoque agrupados por nome para saber quais existem
+	// 1. Buscar todos os produtos do estoque agrupados por SKU (ou nome) para saber quais existem
 	type ProdutoAgrupado struct {
-		NomeProduto       string  `gorm:"column:nome_produto"`
-		TotalQuantidade   int     `gorm:"column:total_quantidade"`
-		Variacoes         int     `gorm:"column:variacoes"`
-		PrecoMedio        float64 `gorm:"column:preco_medio"`
-		ValorTotalEstoque float64 `gorm:"column:valor_total_estoque"`
+		SKUID             *int           `gorm:"column:sku_id"`
+		NomeProduto       string         `gorm:"column:nome_produto"`
+		TotalQuantidade   int            `gorm:"column:total_quantidade"`
+		Variacoes         int            `gorm:"column:variacoes"`
+		PrecoMedio        money.Dinheiro `gorm:"column:preco_medio"`
+		ValorTotalEstoque money.Dinheiro `gorm:"column:valor_total_estoque"`
 	}
 
 	var produtosEstoque []ProdutoAgrupado
-	// Esta query busca nomes distintos e soma quantidades de todo o estoque (principal + distribuído)
+	// Esta query busca SKUs/nomes distintos e soma quantidades de todo o estoque (principal + distribuído)
 	// Usamos uma subquery com UNION ALL para pegar o estoque de ProdutoComprado e o estoque de Estoque
 	query := `
 		SELECT 
-			t.nome as nome_produto, 
+			t.skuId as sku_id,
+			COALESCE(MAX(s.nome), MAX(t.nome)) as nome_produto, 
 			SUM(t.quantidade) as total_quantidade, 
 			COUNT(*) as variacoes, 
 			AVG(t.preco) as preco_medio, 
 			SUM(t.preco * t.quantidade) as valor_total_estoque
 		FROM (
 			-- Estoque principal (não distribuído)
-			SELECT nome, quantidade, preco FROM ProdutoComprado WHERE quantidade > 0
+			SELECT skuId, nome, quantidade, preco FROM ProdutoComprado WHERE quantidade > 0 AND deletedAt IS NULL
 			UNION ALL
 			-- Estoque distribuído para usuários
-			SELECT pc.nome, e.quantidade, pc.preco 
+			SELECT pc.skuId, pc.nome, e.quantidade, pc.preco 
 			FROM Estoque e 
 			JOIN ProdutoComprado pc ON e.produtoCompradoId = pc.id 
-			WHERE e.quantidade > 0 AND e.ativo = true
+			WHERE e.quantidade > 0 AND e.ativo = true AND e.deletedAt IS NULL
 		) as t
-		GROUP BY t.nome
+		LEFT JOIN ProdutoSKU s ON s.id = t.skuId
+		GROUP BY t.skuId, CASE WHEN t.skuId IS NULL THEN t.nome END
 	`
 
 	if err := h.DB.Raw(query).Scan(&produtosEstoque).Error; err != nil {
@@ -74,10 +82,15 @@ func (h *PricingHandler) ListarCombos(c *gin.Context) {
 		return
 	}
 
-	// Criar um mapa para acesso rápido às precificações
+	// Criar mapas para acesso rápido às precificações (por SKU e, nas antigas, por nome)
 	precificacoesMap := make(map[string]models.Precificacao)
+	precificacoesPorSKU := make(map[int]models.Precificacao)
 	for _, p := range precificacoes {
-		precificacoesMap[p.NomeProduto] = p
+		if p.SKUID != nil {
+			precificacoesPorSKU[*p.SKUID] = p
+		} else {
+			precificacoesMap[p.NomeProduto] = p
+		}
 	}
 
 	// 3. Montar a resposta combinando estoque e precificação
@@ -88,6 +101,7 @@ func (h *PricingHandler) ListarCombos(c *gin.Context) {
 		// Por padrão, vamos mostrar todos que estão na tabela ProdutoComprado (que teoricamente são o catálogo).
 		
 		item := map[string]interface{}{
+			"skuId":             p.SKUID,
 			"nomeProduto":       p.NomeProduto,
 			"totalQuantidade":   p.TotalQuantidade,
 			"variacoes":         p.Variacoes,
@@ -96,7 +110,15 @@ func (h *PricingHandler) ListarCombos(c *gin.Context) {
 		}
 
 
-		if prec, ok := precificacoesMap[p.NomeProduto]; ok {
+		var prec models.Precificacao
+		var ok bool
+		if p.SKUID != nil {
+			prec, ok = precificacoesPorSKU[*p.SKUID]
+		} else {
+			prec, ok = precificacoesMap[p.NomeProduto]
+		}
+
+		if ok {
 			item["id"] = prec.ID
 			item["valorDinheiroPix"] = prec.ValorDinheiroPix
 			item["valorDebito"] = prec.ValorDebito
@@ -105,6 +127,8 @@ func (h *PricingHandler) ListarCombos(c *gin.Context) {
 			item["valorCredito10x"] = prec.ValorCredito10x
 			item["valorCredito12x"] = prec.ValorCredito12x
 			item["updatedAt"] = prec.UpdatedAt
+			item["precosPlanos"] = prec.PrecosPlanos
+			item["manual"] = prec.Manual
 		} else {
 			// Se não existir precificação, retornamos valores zerados
 			item["id"] = nil // Indica que precisará criar
@@ -115,6 +139,8 @@ func (h *PricingHandler) ListarCombos(c *gin.Context) {
 			item["valorCredito10x"] = 0
 			item["valorCredito12x"] = 0
 			item["updatedAt"] = nil
+			item["precosPlanos"] = nil
+			item["manual"] = false
 		}
 		
 		resultado = append(resultado, item)
@@ -126,16 +152,21 @@ func (h *PricingHandler) ListarCombos(c *gin.Context) {
 	})
 }
 
-// Atualizar ou Criar precificação para um produto
+// Atualizar ou Criar precificação para um produto.
+// Cada alteração vira uma versão no histórico; com vigenteDe no futuro, a mudança fica agendada.
 func (h *PricingHandler) Atualizar(c *gin.Context) {
 	var input struct {
-		NomeProduto       string  `json:"nomeProduto" binding:"required"`
-		ValorDinheiroPix  float64 `json:"valorDinheiroPix"`
-		ValorDebito       float64 `json:"valorDebito"`
-		ValorCartaoVista  float64 `json:"valorCartaoVista"`
-		ValorCredito5x    float64 `json:"valorCredito5x"`
-		ValorCredito10x   float64 `json:"valorCredito10x"`
-		ValorCredito12x   float64 `json:"valorCredito12x"`
+		SKUID             *int           `json:"skuId"`       // Preço do SKU (preferencial)
+		NomeProduto       string         `json:"nomeProduto"` // Preço por nome, para produtos ainda sem SKU
+		ValorDinheiroPix  money.Dinheiro `json:"valorDinheiroPix"`
+		ValorDebito       money.Dinheiro `json:"valorDebito"`
+		ValorCartaoVista  money.Dinheiro `json:"valorCartaoVista"`
+		ValorCredito5x    money.Dinheiro `json:"valorCredito5x"`
+		ValorCredito10x   money.Dinheiro `json:"valorCredito10x"`
+		ValorCredito12x   money.Dinheiro `json:"valorCredito12x"`
+		PrecosPlanos      map[string]money.Dinheiro `json:"precosPlanos"` // Preço por código de plano, ex.: {"credito_3x": 1099.90}
+		VigenteDe         *string        `json:"vigenteDe"` // Opcional: início da vigência (padrão: agora)
+		Manual            *bool          `json:"manual"`    // Padrão true: as regras de precificação não sobrescrevem
 	}
 
 	if err := c.ShouldBindJSON(&input); err != nil {
@@ -147,58 +178,326 @@ func (h *PricingHandler) Atualizar(c *gin.Context) {
 		return
 	}
 
+	if input.SKUID == nil && input.NomeProduto == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Informe o SKU ou o nome do produto",
+		})
+		return
+	}
+
+	agora := time.Now()
+	vigenteDe, mensagemErro := lerVigencia(input.VigenteDe, agora)
+	if mensagemErro != "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": mensagemErro,
+		})
+		return
+	}
+
+	// Preços de SKU usam a chave do SKU em nomeProduto
+	busca := h.DB.Where("nomeProduto = ?", input.NomeProduto)
+	if input.SKUID != nil {
+		var sku models.ProdutoSKU
+		if err := h.DB.First(&sku, *input.SKUID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"success": false,
+				"message": "SKU não encontrado",
+			})
+			return
+		}
+		input.NomeProduto = catalog.ChavePrecificacao(&sku)
+		busca = h.DB.Where("skuId = ?", sku.ID)
+	}
+
 	// Verificar se já existe
 	var precificacao models.Precificacao
-	err := h.DB.Where("nomeProduto = ?", input.NomeProduto).First(&precificacao).Error
-
-	if errors.Is(err, gorm.ErrRecordNotFound) {
-		// Criar novo registro
-		novaPrecificacao := models.Precificacao{
-			NomeProduto:      input.NomeProduto,
-			ValorDinheiroPix: input.ValorDinheiroPix,
-			ValorDebito:      input.ValorDebito,
-			ValorCartaoVista: input.ValorCartaoVista,
-			ValorCredito5x:   input.ValorCredito5x,
-			ValorCredito10x:  input.ValorCredito10x,
-			ValorCredito12x:  input.ValorCredito12x,
-			CreatedAt:        time.Now(),
-			UpdatedAt:        time.Now(),
-		}
-		if err := h.DB.Create(&novaPrecificacao).Error; err != nil {
+	err := busca.First(&precificacao).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao verificar precificação",
+		})
+		return
+	}
+
+	valores := pricing.Valores{
+		ValorDinheiroPix: input.ValorDinheiroPix,
+		ValorDebito:      input.ValorDebito,
+		ValorCartaoVista: input.ValorCartaoVista,
+		ValorCredito5x:   input.ValorCredito5x,
+		ValorCredito10x:  input.ValorCredito10x,
+		ValorCredito12x:  input.ValorCredito12x,
+	}
+
+	// Preços por plano: os planos com coluna própria também podem vir por código
+	if len(input.PrecosPlanos) > 0 {
+		planos, err := pricing.CarregarPlanos(h.DB, false)
+		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{
 				"success": false,
-				"message": "Erro ao criar precificação",
+				"message": "Erro ao buscar planos de pagamento",
 			})
 			return
 		}
-	} else if err == nil {
-		// Atualizar registro existente
-		precificacao.ValorDinheiroPix = input.ValorDinheiroPix
-		precificacao.ValorDebito = input.ValorDebito
-		precificacao.ValorCartaoVista = input.ValorCartaoVista
-		precificacao.ValorCredito5x = input.ValorCredito5x
-		precificacao.ValorCredito10x = input.ValorCredito10x
-		precificacao.ValorCredito12x = input.ValorCredito12x
-		precificacao.UpdatedAt = time.Now()
-
-		if err := h.DB.Save(&precificacao).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
+		for codigo, preco := range input.PrecosPlanos {
+			if _, ok := pricing.BuscarPlano(planos, codigo); !ok {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"success": false,
+					"message": "Plano de pagamento não cadastrado: " + codigo,
+				})
+				return
+			}
+			if preco < 0 {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"success": false,
+					"message": "Preço inválido para o plano " + codigo,
+				})
+				return
+			}
+			valores.DefinirPreco(pricing.CodigoDaForma(codigo), preco)
+		}
+	}
+
+	var autor *models.Usuario
+	if usuario, ok := usuarioLogado(c); ok {
+		autor = &usuario
+	}
+
+	var versao *models.PrecificacaoVersao
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		if precificacao.ID == 0 {
+			// Criar novo registro; os preços são preenchidos quando a versão entra em vigor
+			precificacao = models.Precificacao{
+				NomeProduto: input.NomeProduto,
+				SKUID:       input.SKUID,
+				CreatedAt:   agora,
+				UpdatedAt:   agora,
+			}
+			if err := tx.Create(&precificacao).Error; err != nil {
+				return err
+			}
+		}
+
+		manual := input.Manual == nil || *input.Manual
+		if precificacao.Manual != manual {
+			if err := tx.Model(&precificacao).UpdateColumn("manual", manual).Error; err != nil {
+				return err
+			}
+		}
+
+		var err error
+		versao, err = pricing.SalvarVersao(tx, &precificacao, valores, vigenteDe, autor, pricing.OrigemManual)
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao salvar precificação",
+		})
+		return
+	}
+
+	mensagem := "Precificação salva com sucesso"
+	if !versao.Aplicada {
+		mensagem = "Preço agendado para " + versao.VigenteDe.In(timezone.Local()).Format("02/01/2006 15:04")
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": mensagem,
+		"data":    versao,
+	})
+}
+
+// lerVigencia interpreta o início de vigência informado (padrão: agora).
+// Retorna a mensagem de erro para o cliente quando a data é inválida ou está no passado.
+func lerVigencia(valor *string, agora time.Time) (time.Time, string) {
+	if valor == nil || *valor == "" {
+		return agora, ""
+	}
+	parsed, somenteData, err := pricing.ParseVigencia(*valor)
+	if err != nil {
+		return time.Time{}, "Data de vigência inválida. Use YYYY-MM-DD ou YYYY-MM-DDTHH:MM"
+	}
+	// Uma data sem horário igual a hoje vale a partir de agora
+	mesmoDia := somenteData && parsed.Equal(timezone.InicioDoDia(agora))
+	if parsed.Before(agora.Add(-time.Minute)) && !mesmoDia {
+		return time.Time{}, "A vigência não pode estar no passado"
+	}
+	return parsed, ""
+}
+
+// Historico lista as versões de preço de uma precificação (mais recentes primeiro)
+func (h *PricingHandler) Historico(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "ID inválido",
+		})
+		return
+	}
+
+	var precificacao models.Precificacao
+	if err := h.DB.Preload("SKU").First(&precificacao, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "Precificação não encontrada",
+		})
+		return
+	}
+
+	var versoes []models.PrecificacaoVersao
+	if err := h.DB.Where("precificacaoId = ?", id).Order("vigenteDe DESC, id DESC").Find(&versoes).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao buscar histórico de preços",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"precificacao": precificacao,
+			"versoes":      versoes,
+		},
+	})
+}
+
+// Vigente retorna os preços válidos em um instante (parâmetro data, padrão: agora)
+func (h *PricingHandler) Vigente(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "ID inválido",
+		})
+		return
+	}
+
+	em := time.Now()
+	if dataStr := c.Query("data"); dataStr != "" {
+		parsed, somenteData, err := pricing.ParseVigencia(dataStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
 				"success": false,
-				"message": "Erro ao atualizar precificação",
+				"message": "Data inválida. Use YYYY-MM-DD ou YYYY-MM-DDTHH:MM",
 			})
 			return
 		}
-	} else {
+		// Sem horário, considerar o fim do dia
+		if somenteData {
+			parsed = parsed.AddDate(0, 0, 1).Add(-time.Second)
+		}
+		em = parsed
+	}
+
+	var precificacao models.Precificacao
+	if err := h.DB.First(&precificacao, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "Precificação não encontrada",
+		})
+		return
+	}
+
+	versao, err := pricing.VersaoVigente(h.DB, id, em)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
-			"message": "Erro ao verificar precificação",
+			"message": "Erro ao buscar preço vigente",
+		})
+		return
+	}
+
+	valores := pricing.ValoresDe(precificacao)
+	if versao != nil {
+		valores = pricing.ValoresDaVersao(*versao)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"data":    em,
+			"valores": valores,
+			"versao":  versao,
+		},
+	})
+}
+
+// Agendadas lista as mudanças de preço que ainda não entraram em vigor
+func (h *PricingHandler) Agendadas(c *gin.Context) {
+	type VersaoAgendada struct {
+		models.PrecificacaoVersao
+		NomeProduto string  `gorm:"column:nomeProduto" json:"nomeProduto"`
+		SKUID       *int    `gorm:"column:skuId" json:"skuId"`
+		SKUNome     *string `gorm:"column:skuNome" json:"skuNome"`
+	}
+
+	var versoes []VersaoAgendada
+	if err := h.DB.Model(&models.PrecificacaoVersao{}).
+		Select("PrecificacaoVersao.*, p.nomeProduto, p.skuId, s.nome as skuNome").
+		Joins("JOIN Precificacao p ON p.id = PrecificacaoVersao.precificacaoId").
+		Joins("LEFT JOIN ProdutoSKU s ON s.id = p.skuId").
+		Where("PrecificacaoVersao.aplicada = ? AND PrecificacaoVersao.vigenteDe > ?", false, time.Now()).
+		Order("PrecificacaoVersao.vigenteDe ASC").
+		Scan(&versoes).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao buscar preços agendados",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    versoes,
+	})
+}
+
+// CancelarVersao remove uma mudança de preço agendada
+func (h *PricingHandler) CancelarVersao(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "ID inválido",
+		})
+		return
+	}
+
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		_, err := pricing.CancelarVersao(tx, id)
+		return err
+	})
+	if errors.Is(err, pricing.ErrVersaoNaoEncontrada) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "Versão de preço não encontrada",
+		})
+		return
+	}
+	if errors.Is(err, pricing.ErrVersaoEmVigor) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Esta versão já entrou em vigor e não pode ser cancelada",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao cancelar preço agendado",
 		})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
-		"message": "Precificação salva com sucesso",
+		"message": "Preço agendado cancelado com sucesso",
 	})
 }
 
@@ -214,24 +513,43 @@ func (h *PricingHandler) Consultar(c *gin.Context) {
 		return
 	}
 
-	// Set de nomes únicos encontrados
+	// Set de nomes únicos encontrados (produtos sem SKU) e de SKUs encontrados
 	nomesEncontrados := make(map[string]bool)
+	skusEncontrados := make(map[int]bool)
 
-	// 1. Buscar nomes na tabela ProdutoComprado (por Nome, IMEI ou Código de Barras)
-	var nomesProdutoComprado []string
+	// 1. Buscar na tabela ProdutoComprado (por Nome, IMEI ou Código de Barras)
+	type produtoEncontrado struct {
+		Nome  string
+		SKUID *int `gorm:"column:skuId"`
+	}
+	var produtosEncontrados []produtoEncontrado
 	if err := h.DB.Model(&models.ProdutoComprado{}).
 		Where("nome LIKE ? OR imei = ? OR codigoBarras = ?", "%"+termo+"%", termo, termo).
-		Distinct("nome").
-		Pluck("nome", &nomesProdutoComprado).Error; err == nil {
-		for _, nome := range nomesProdutoComprado {
-			nomesEncontrados[nome] = true
+		Distinct("nome", "skuId").
+		Scan(&produtosEncontrados).Error; err == nil {
+		for _, p := range produtosEncontrados {
+			if p.SKUID != nil {
+				skusEncontrados[*p.SKUID] = true
+			} else {
+				nomesEncontrados[p.Nome] = true
+			}
+		}
+	}
+
+	// 2. Buscar no catálogo (por nome ou código do SKU)
+	var skuIDs []int
+	if err := h.DB.Model(&models.ProdutoSKU{}).
+		Where("nome LIKE ? OR codigo LIKE ?", "%"+termo+"%", "%"+termo+"%").
+		Pluck("id", &skuIDs).Error; err == nil {
+		for _, id := range skuIDs {
+			skusEncontrados[id] = true
 		}
 	}
 
-	// 2. Buscar nomes na tabela Precificacao (por Nome)
+	// 3. Buscar nomes na tabela Precificacao (por Nome, apenas preços antigos sem SKU)
 	var nomesPrecificacao []string
 	if err := h.DB.Model(&models.Precificacao{}).
-		Where("nomeProduto LIKE ?", "%"+termo+"%").
+		Where("nomeProduto LIKE ? AND skuId IS NULL", "%"+termo+"%").
 		Distinct("nomeProduto").
 		Pluck("nomeProduto", &nomesPrecificacao).Error; err == nil {
 		for _, nome := range nomesPrecificacao {
@@ -240,7 +558,7 @@ func (h *PricingHandler) Consultar(c *gin.Context) {
 	}
 
 	// Se não achou nada
-	if len(nomesEncontrados) == 0 {
+	if len(nomesEncontrados) == 0 && len(skusEncontrados) == 0 {
 		c.JSON(http.StatusOK, gin.H{
 			"success": true,
 			"data":    []models.Precificacao{},
@@ -248,21 +566,72 @@ func (h *PricingHandler) Consultar(c *gin.Context) {
 		return
 	}
 
+	var resultado []models.Precificacao
+
+	// 4. Precificações dos SKUs encontrados
+	if len(skusEncontrados) > 0 {
+		listaSKUs := make([]int, 0, len(skusEncontrados))
+		for id := range skusEncontrados {
+			listaSKUs = append(listaSKUs, id)
+		}
+
+		var skus []models.ProdutoSKU
+		var precificacoesSKU []models.Precificacao
+		if err := h.DB.Where("id IN ?", listaSKUs).Order("nome ASC").Find(&skus).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "Erro ao buscar catálogo",
+				"error":   err.Error(),
+			})
+			return
+		}
+		if err := h.DB.Where("skuId IN ?", listaSKUs).Find(&precificacoesSKU).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "Erro ao buscar precificações",
+				"error":   err.Error(),
+			})
+			return
+		}
+
+		mapPorSKU := make(map[int]models.Precificacao)
+		for _, p := range precificacoesSKU {
+			mapPorSKU[*p.SKUID] = p
+		}
+
+		for i := range skus {
+			sku := skus[i]
+			if p, ok := mapPorSKU[sku.ID]; ok {
+				p.SKU = &sku
+				resultado = append(resultado, p)
+			} else {
+				// Se não tem precificação, cria objeto zerado apenas para visualização
+				resultado = append(resultado, models.Precificacao{
+					NomeProduto: catalog.ChavePrecificacao(&sku),
+					SKUID:       &sku.ID,
+					SKU:         &sku,
+				})
+			}
+		}
+	}
+
 	// Converter map para slice de nomes
 	var listaNomes []string
 	for nome := range nomesEncontrados {
 		listaNomes = append(listaNomes, nome)
 	}
 
-	// 3. Buscar as precificações existentes para esses nomes
+	// 5. Buscar as precificações existentes para os nomes sem SKU
 	var precificacoesExistentes []models.Precificacao
-	if err := h.DB.Where("nomeProduto IN ?", listaNomes).Find(&precificacoesExistentes).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"message": "Erro ao buscar precificações",
-			"error":   err.Error(),
-		})
-		return
+	if len(listaNomes) > 0 {
+		if err := h.DB.Where("nomeProduto IN ? AND skuId IS NULL", listaNomes).Find(&precificacoesExistentes).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "Erro ao buscar precificações",
+				"error":   err.Error(),
+			})
+			return
+		}
 	}
 
 	// Criar mapa de precificações para acesso rápido
@@ -271,9 +640,7 @@ func (h *PricingHandler) Consultar(c *gin.Context) {
 		mapPrecificacao[p.NomeProduto] = p
 	}
 
-	// 4. Montar resultado final (garantindo que todos os nomes encontrados apareçam)
-	var resultado []models.Precificacao
-
+	// 6. Montar resultado final (garantindo que todos os nomes encontrados apareçam)
 	for _, nome := range listaNomes {
 		if p, ok := mapPrecificacao[nome]; ok {
 			resultado = append(resultado, p)
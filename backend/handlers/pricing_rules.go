@@ -0,0 +1,442 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"cmdimport/backend/models"
+	"cmdimport/backend/money"
+	"cmdimport/backend/pricing"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type SalvarRegraRequest struct {
+	CategoriaID    *int           `json:"categoriaId"` // Nulo: regra padrão
+	Markup         float64        `json:"markup"`
+	CustoAdicional float64        `json:"custoAdicional"`
+	CustoFixo      money.Dinheiro `json:"custoFixo"`
+	Multiplo       money.Dinheiro `json:"multiplo"`
+	Terminacao     money.Dinheiro `json:"terminacao"`
+	Ativo          *bool          `json:"ativo"`
+}
+
+// validarRegra retorna a mensagem de erro para o cliente, ou "" se a regra é válida
+func validarRegra(req SalvarRegraRequest) string {
+	if req.Markup < 0 || req.CustoAdicional < 0 || req.CustoFixo < 0 {
+		return "Markup e custos não podem ser negativos"
+	}
+	if req.Multiplo < 0 || req.Terminacao < 0 {
+		return "Múltiplo e terminação não podem ser negativos"
+	}
+	if req.Multiplo > 0 && req.Terminacao >= req.Multiplo {
+		return "A terminação deve ser menor que o múltiplo (ex.: múltiplo 10, terminação 9,90)"
+	}
+	return ""
+}
+
+func preencherRegra(regra *models.RegraPrecificacao, req SalvarRegraRequest) {
+	regra.CategoriaID = req.CategoriaID
+	regra.Markup = req.Markup
+	regra.CustoAdicional = req.CustoAdicional
+	regra.CustoFixo = req.CustoFixo
+	regra.Multiplo = req.Multiplo
+	regra.Terminacao = req.Terminacao
+	if req.Ativo != nil {
+		regra.Ativo = *req.Ativo
+	}
+}
+
+// ListarRegras lista as regras de precificação por categoria
+func (h *PricingHandler) ListarRegras(c *gin.Context) {
+	var regras []models.RegraPrecificacao
+	if err := h.DB.Preload("Categoria").Order("categoriaId ASC").Find(&regras).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao buscar regras de precificação",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    regras,
+	})
+}
+
+// CriarRegra cadastra a regra de uma categoria (ou a regra padrão, sem categoria)
+func (h *PricingHandler) CriarRegra(c *gin.Context) {
+	if !exigirAdmin(c, "Apenas administradores podem alterar a precificação") {
+		return
+	}
+
+	var req SalvarRegraRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Dados inválidos",
+		})
+		return
+	}
+	if msg := validarRegra(req); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": msg,
+		})
+		return
+	}
+
+	if h.existeRegra(req.CategoriaID, 0) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Já existe uma regra para esta categoria",
+		})
+		return
+	}
+
+	regra := models.RegraPrecificacao{Ativo: true}
+	preencherRegra(&regra, req)
+	if err := h.DB.Create(&regra).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao cadastrar regra",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    regra,
+		"message": "Regra cadastrada com sucesso",
+	})
+}
+
+// AtualizarRegra altera uma regra de precificação
+func (h *PricingHandler) AtualizarRegra(c *gin.Context) {
+	if !exigirAdmin(c, "Apenas administradores podem alterar a precificação") {
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "ID inválido",
+		})
+		return
+	}
+
+	var req SalvarRegraRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Dados inválidos",
+		})
+		return
+	}
+	if msg := validarRegra(req); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": msg,
+		})
+		return
+	}
+
+	var regra models.RegraPrecificacao
+	if err := h.DB.First(&regra, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "Regra não encontrada",
+		})
+		return
+	}
+
+	if h.existeRegra(req.CategoriaID, id) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Já existe uma regra para esta categoria",
+		})
+		return
+	}
+
+	preencherRegra(&regra, req)
+	if err := h.DB.Save(&regra).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao atualizar regra",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    regra,
+		"message": "Regra atualizada com sucesso",
+	})
+}
+
+// DeletarRegra remove uma regra de precificação. Os preços já aplicados não mudam.
+func (h *PricingHandler) DeletarRegra(c *gin.Context) {
+	if !exigirAdmin(c, "Apenas administradores podem alterar a precificação") {
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "ID inválido",
+		})
+		return
+	}
+
+	result := h.DB.Delete(&models.RegraPrecificacao{}, id)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao deletar regra",
+		})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "Regra não encontrada",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Regra deletada com sucesso",
+	})
+}
+
+// existeRegra indica se outra regra já usa a categoria (nil = regra padrão)
+func (h *PricingHandler) existeRegra(categoriaID *int, ignorarID int) bool {
+	query := h.DB.Model(&models.RegraPrecificacao{}).Where("id != ?", ignorarID)
+	if categoriaID == nil {
+		query = query.Where("categoriaId IS NULL")
+	} else {
+		query = query.Where("categoriaId = ?", *categoriaID)
+	}
+	var count int64
+	query.Count(&count)
+	return count > 0
+}
+
+// ListarTaxas retorna a tabela de taxas da maquininha
+func (h *PricingHandler) ListarTaxas(c *gin.Context) {
+	var taxas []models.TaxaAdquirente
+	if err := h.DB.Order("metodo ASC, parcelas ASC").Find(&taxas).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao buscar taxas",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    taxas,
+	})
+}
+
+// SalvarTaxas substitui a tabela de taxas da maquininha
+func (h *PricingHandler) SalvarTaxas(c *gin.Context) {
+	if !exigirAdmin(c, "Apenas administradores podem alterar a precificação") {
+		return
+	}
+
+	var req struct {
+		Taxas []struct {
+			Metodo     string  `json:"metodo" binding:"required,oneof=debito credito"`
+			Parcelas   int     `json:"parcelas" binding:"min=1,max=24"`
+			Percentual float64 `json:"percentual" binding:"min=0,max=99"`
+		} `json:"taxas" binding:"required,dive"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Taxas inválidas. Informe método (debito ou credito), parcelas e percentual",
+		})
+		return
+	}
+
+	vistas := make(map[string]bool)
+	taxas := make([]models.TaxaAdquirente, 0, len(req.Taxas))
+	for _, t := range req.Taxas {
+		chave := t.Metodo + "/" + strconv.Itoa(t.Parcelas)
+		if vistas[chave] {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Taxa repetida: " + t.Metodo + " em " + strconv.Itoa(t.Parcelas) + "x",
+			})
+			return
+		}
+		vistas[chave] = true
+		taxas = append(taxas, models.TaxaAdquirente{
+			Metodo:     t.Metodo,
+			Parcelas:   t.Parcelas,
+			Percentual: t.Percentual,
+		})
+	}
+
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("1 = 1").Delete(&models.TaxaAdquirente{}).Error; err != nil {
+			return err
+		}
+		if len(taxas) == 0 {
+			return nil
+		}
+		return tx.Create(&taxas).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao salvar taxas",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    taxas,
+		"message": "Taxas salvas com sucesso",
+	})
+}
+
+// Simular mostra os preços que as regras gerariam e as margens por forma de pagamento,
+// ao lado dos preços e margens atuais, sem gravar nada.
+// Filtros: categoriaId, skuId, nomeProduto, incluirManuais
+func (h *PricingHandler) Simular(c *gin.Context) {
+	filtro := pricing.FiltroRegras{IncluirManuais: c.Query("incluirManuais") == "true"}
+	if v := c.Query("categoriaId"); v != "" {
+		if id, err := strconv.Atoi(v); err == nil {
+			filtro.CategoriaID = &id
+		}
+	}
+	if v := c.Query("skuId"); v != "" {
+		if id, err := strconv.Atoi(v); err == nil {
+			filtro.SKUID = &id
+		}
+	}
+	if v := c.Query("nomeProduto"); v != "" {
+		filtro.NomesProdutos = []string{v}
+	}
+
+	simulacoes, err := pricing.Simular(h.DB, filtro)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao simular precificação",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    simulacoes,
+	})
+}
+
+// AplicarRegras grava os preços calculados pelas regras como nova versão de preço.
+// Sem skuIds/nomesProdutos, aplica a todos os produtos em estoque (ou da categoria informada).
+// Preços manuais só são sobrescritos com incluirManuais.
+func (h *PricingHandler) AplicarRegras(c *gin.Context) {
+	if !exigirAdmin(c, "Apenas administradores podem alterar a precificação") {
+		return
+	}
+
+	var req struct {
+		CategoriaID    *int     `json:"categoriaId"`
+		SKUIDs         []int    `json:"skuIds"`
+		NomesProdutos  []string `json:"nomesProdutos"`
+		IncluirManuais bool     `json:"incluirManuais"`
+		VigenteDe      *string  `json:"vigenteDe"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Dados inválidos",
+		})
+		return
+	}
+
+	vigenteDe, mensagemErro := lerVigencia(req.VigenteDe, time.Now())
+	if mensagemErro != "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": mensagemErro,
+		})
+		return
+	}
+
+	simulacoes, err := pricing.Simular(h.DB, pricing.FiltroRegras{
+		CategoriaID:    req.CategoriaID,
+		IncluirManuais: req.IncluirManuais,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao calcular preços",
+		})
+		return
+	}
+
+	// Selecionar os produtos pedidos
+	if len(req.SKUIDs) > 0 || len(req.NomesProdutos) > 0 {
+		skus := make(map[int]bool, len(req.SKUIDs))
+		for _, id := range req.SKUIDs {
+			skus[id] = true
+		}
+		nomes := make(map[string]bool, len(req.NomesProdutos))
+		for _, nome := range req.NomesProdutos {
+			nomes[nome] = true
+		}
+		selecionadas := simulacoes[:0]
+		for _, s := range simulacoes {
+			if (s.SKUID != nil && skus[*s.SKUID]) || (s.SKUID == nil && nomes[s.NomeProduto]) {
+				selecionadas = append(selecionadas, s)
+			}
+		}
+		simulacoes = selecionadas
+	}
+
+	var autor *models.Usuario
+	if usuario, ok := usuarioLogado(c); ok {
+		autor = &usuario
+	}
+
+	var aplicados int
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		aplicados, err = pricing.AplicarRegras(tx, simulacoes, vigenteDe, autor)
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao aplicar regras de precificação",
+		})
+		return
+	}
+
+	ignorados := make([]pricing.Simulacao, 0)
+	for _, s := range simulacoes {
+		if s.Ignorado != "" {
+			ignorados = append(ignorados, s)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": strconv.Itoa(aplicados) + " produto(s) precificado(s) pelas regras",
+		"data": gin.H{
+			"aplicados": aplicados,
+			"ignorados": ignorados,
+		},
+	})
+}
@@ -1,11 +1,16 @@
 package handlers
 
 import (
+	"fmt"
 	"net/http"
 	"strconv"
 	"time"
 
+	"cmdimport/backend/exchange"
+	"cmdimport/backend/importer"
 	"cmdimport/backend/models"
+	"cmdimport/backend/money"
+	"cmdimport/backend/timezone"
 	"cmdimport/backend/utils"
 
 	"github.com/gin-gonic/gin"
@@ -21,60 +26,28 @@ func NewProductHandler(db *gorm.DB) *ProductHandler {
 }
 
 type CadastrarProdutoRequest struct {
-	Nome              string  `json:"nome" binding:"required"`
-	Descricao         *string `json:"descricao"`
-	Cor               *string `json:"cor"`
-	IMEI              *string `json:"imei"`
-	CodigoBarras      *string `json:"codigoBarras"`
-	CustoDolar        float64 `json:"custoDolar" binding:"required"`
-	TaxaDolar         float64 `json:"taxaDolar" binding:"required"`
-	Quantidade        int     `json:"quantidade" binding:"required"`
-	TipoIdentificacao string  `json:"tipoIdentificacao"`
-	CategoriaID       *int    `json:"categoriaId"` // Opcional
+	Nome              string         `json:"nome" binding:"required"`
+	Descricao         *string        `json:"descricao"`
+	Cor               *string        `json:"cor"`
+	IMEI              *string        `json:"imei"`
+	CodigoBarras      *string        `json:"codigoBarras"`
+	CustoDolar        money.Dinheiro `json:"custoDolar" binding:"required"`
+	TaxaDolar         float64        `json:"taxaDolar"` // Opcional: padrão é a cotação vigente na data da compra
+	Quantidade        int            `json:"quantidade" binding:"required"`
+	TipoIdentificacao string         `json:"tipoIdentificacao"`
+	CategoriaID       *int           `json:"categoriaId"` // Opcional
+	DataCompra        *string        `json:"dataCompra"`  // Opcional (YYYY-MM-DD), padrão é hoje
+	SKUID             *int           `json:"skuId"`       // Opcional: modelo do catálogo
 }
 
 func (h *ProductHandler) Listar(c *gin.Context) {
 	// Parâmetros de paginação
 	pagina, _ := strconv.Atoi(c.DefaultQuery("pagina", "1"))
 	limite, _ := strconv.Atoi(c.DefaultQuery("limite", "10"))
-	busca := c.Query("busca")
-	dataInicio := c.Query("dataInicio")
-	dataFim := c.Query("dataFim")
-	ocultarEstoqueZerado := c.Query("ocultarEstoqueZerado") == "true"
 
 	offset := (pagina - 1) * limite
 
-	query := h.DB.Model(&models.ProdutoComprado{})
-
-	// Filtro de busca
-	if busca != "" {
-		query = query.Where("nome LIKE ? OR imei LIKE ? OR codigoBarras LIKE ?", 
-			"%"+busca+"%", "%"+busca+"%", "%"+busca+"%")
-	}
-
-	// Filtro de data - usar DATE() para comparar apenas a parte da data, ignorando hora
-	if dataInicio != "" {
-		// Usar DATE() para extrair apenas a parte da data e comparar
-		query = query.Where("DATE(dataCompra) >= ?", dataInicio)
-	}
-	if dataFim != "" {
-		// Usar DATE() para extrair apenas a parte da data e comparar
-		query = query.Where("DATE(dataCompra) <= ?", dataFim)
-	}
-
-	// Filtro de categoria
-	categoriaID := c.Query("categoriaId")
-	if categoriaID != "" {
-		id, err := strconv.Atoi(categoriaID)
-		if err == nil {
-			query = query.Where("categoriaId = ?", id)
-		}
-	}
-
-	// Filtro de estoque zerado
-	if ocultarEstoqueZerado {
-		query = query.Where("quantidade > ?", 0)
-	}
+	query := filtrarProdutos(h.DB.Model(&models.ProdutoComprado{}), c)
 
 	// Contar total
 	var total int64
@@ -83,7 +56,7 @@ func (h *ProductHandler) Listar(c *gin.Context) {
 
 	// Buscar produtos usando Select explícito para garantir que os campos sejam lidos
 	var produtos []models.ProdutoComprado
-	if err := query.Select("id", "nome", "descricao", "cor", "imei", "codigoBarras", 
+	if err := query.Select("id", "nome", "descricao", "cor", "imei", "codigoBarras", "skuId",
 		"custoDolar", "taxaDolar", "preco", "quantidade", "quantidadeBackup", 
 		"fornecedor", "dataCompra", "createdAt", "updatedAt").
 		Preload("Estoque", "ativo = ?", true).
@@ -118,9 +91,10 @@ func (h *ProductHandler) Listar(c *gin.Context) {
 			"cor":               produto.Cor,
 			"imei":              produto.IMEI,
 			"codigoBarras":      produto.CodigoBarras,
-			"custoDolar":        float64(produto.CustoDolar),
-			"taxaDolar":         float64(produto.TaxaDolar),
-			"preco":             float64(produto.Preco),
+			"skuId":             produto.SKUID,
+			"custoDolar":        produto.CustoDolar,
+			"taxaDolar":         produto.TaxaDolar,
+			"preco":             produto.Preco,
 			"quantidade":        produto.Quantidade,
 			"quantidadeBackup":  produto.QuantidadeBackup,
 			"fornecedor":        produto.Fornecedor,
@@ -142,6 +116,48 @@ func (h *ProductHandler) Listar(c *gin.Context) {
 	})
 }
 
+// mensagemIMEIDuplicado explica o conflito de IMEI, inclusive com um produto que está na lixeira
+func mensagemIMEIDuplicado(existente models.ProdutoComprado) string {
+	if existente.DeletedAt.Valid {
+		return "Já existe um produto com este IMEI na lixeira. Restaure-o em vez de cadastrar de novo"
+	}
+	return "Já existe um produto com este IMEI"
+}
+
+// filtrarProdutos aplica os filtros da listagem de produtos (busca, período, categoria, estoque zerado).
+// Compartilhado entre a listagem e a exportação.
+func filtrarProdutos(query *gorm.DB, c *gin.Context) *gorm.DB {
+	busca := c.Query("busca")
+	dataInicio := c.Query("dataInicio")
+	dataFim := c.Query("dataFim")
+	ocultarEstoqueZerado := c.Query("ocultarEstoqueZerado") == "true"
+
+	// Filtro de busca
+	if busca != "" {
+		query = query.Where("ProdutoComprado.nome LIKE ? OR ProdutoComprado.imei LIKE ? OR ProdutoComprado.codigoBarras LIKE ?",
+			"%"+busca+"%", "%"+busca+"%", "%"+busca+"%")
+	}
+
+	// Filtro de data - dias inteiros no fuso do negócio, qualquer que seja a hora da compra
+	query = filtrarPeriodo(query, "ProdutoComprado.dataCompra", dataInicio, dataFim)
+
+	// Filtro de categoria
+	categoriaID := c.Query("categoriaId")
+	if categoriaID != "" {
+		id, err := strconv.Atoi(categoriaID)
+		if err == nil {
+			query = query.Where("ProdutoComprado.categoriaId = ?", id)
+		}
+	}
+
+	// Filtro de estoque zerado
+	if ocultarEstoqueZerado {
+		query = query.Where("ProdutoComprado.quantidade > ?", 0)
+	}
+
+	return query
+}
+
 func (h *ProductHandler) Cadastrar(c *gin.Context) {
 	var req CadastrarProdutoRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -161,6 +177,33 @@ func (h *ProductHandler) Cadastrar(c *gin.Context) {
 		return
 	}
 
+	// Data da compra (padrão: agora)
+	dataCompra := time.Now()
+	if req.DataCompra != nil && *req.DataCompra != "" {
+		parsed, err := exchange.ParseData(*req.DataCompra)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Data da compra inválida. Use o formato YYYY-MM-DD",
+			})
+			return
+		}
+		dataCompra = timezone.InicioDaData(parsed)
+	}
+
+	// Sem taxa informada, usar a cotação vigente na data da compra
+	if req.TaxaDolar == 0 {
+		cotacao, err := exchange.TaxaVigente(h.DB, dataCompra)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Nenhuma cotação do dólar cadastrada para a data da compra. Informe a taxa do dólar",
+			})
+			return
+		}
+		req.TaxaDolar = cotacao.Taxa
+	}
+
 	if req.TaxaDolar <= 0 {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"success": false,
@@ -210,17 +253,32 @@ func (h *ProductHandler) Cadastrar(c *gin.Context) {
 	// Verificar IMEI duplicado se fornecido
 	if req.IMEI != nil && *req.IMEI != "" && (req.TipoIdentificacao == "imei" || req.TipoIdentificacao == "ambos") {
 		var produtoExistente models.ProdutoComprado
-		if err := h.DB.Where("imei = ?", *req.IMEI).First(&produtoExistente).Error; err == nil {
+		if err := h.DB.Unscoped().Where("imei = ?", *req.IMEI).First(&produtoExistente).Error; err == nil {
 			c.JSON(http.StatusBadRequest, gin.H{
 				"success": false,
-				"message": "Já existe um produto com este IMEI",
+				"message": mensagemIMEIDuplicado(produtoExistente),
 			})
 			return
 		}
 	}
 
+	// Validar SKU do catálogo; sem categoria informada, usar a do SKU
+	if req.SKUID != nil {
+		var sku models.ProdutoSKU
+		if err := h.DB.First(&sku, *req.SKUID).Error; err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "SKU não encontrado",
+			})
+			return
+		}
+		if req.CategoriaID == nil {
+			req.CategoriaID = sku.CategoriaID
+		}
+	}
+
 	// Calcular preço
-	precoCalculado := req.CustoDolar * req.TaxaDolar
+	precoCalculado := req.CustoDolar.Mul(req.TaxaDolar)
 
 	// Criar produto
 	produto := models.ProdutoComprado{
@@ -232,8 +290,9 @@ func (h *ProductHandler) Cadastrar(c *gin.Context) {
 		Preco:            precoCalculado,
 		Quantidade:       req.Quantidade,
 		QuantidadeBackup: req.Quantidade, // Salvar backup
-		DataCompra:       time.Now(),
+		DataCompra:       dataCompra,
 		CategoriaID:      req.CategoriaID, // Adicionar categoria
+		SKUID:            req.SKUID,
 	}
 
 	// Definir IMEI e código de barras baseado no tipo
@@ -262,6 +321,7 @@ func (h *ProductHandler) Cadastrar(c *gin.Context) {
 			"cor":               produto.Cor,
 			"imei":              produto.IMEI,
 			"codigoBarras":      produto.CodigoBarras,
+			"skuId":             produto.SKUID,
 			"custoDolar":        produto.CustoDolar,
 			"taxaDolar":         produto.TaxaDolar,
 			"preco":             produto.Preco,
@@ -273,6 +333,59 @@ func (h *ProductHandler) Cadastrar(c *gin.Context) {
 	})
 }
 
+// Importar cadastra produtos em lote a partir de uma planilha CSV ou XLSX (campo "arquivo").
+// Com dryRun=true apenas valida as linhas; sem ele, grava tudo ou nada.
+func (h *ProductHandler) Importar(c *gin.Context) {
+	dryRun := c.Query("dryRun") == "true"
+
+	file, header, err := c.Request.FormFile("arquivo")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Nenhum arquivo foi enviado",
+		})
+		return
+	}
+	defer file.Close()
+
+	if header.Size > 10*1024*1024 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Arquivo muito grande. Tamanho máximo: 10MB",
+		})
+		return
+	}
+
+	resultado, err := importer.ImportarArquivo(h.DB, header.Filename, file, dryRun)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Erro ao importar produtos: " + err.Error(),
+		})
+		return
+	}
+
+	if len(resultado.Erros) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "A planilha contém erros. Nenhum produto foi cadastrado",
+			"data":    resultado,
+		})
+		return
+	}
+
+	message := fmt.Sprintf("%d produtos importados com sucesso", resultado.Importados)
+	if dryRun {
+		message = fmt.Sprintf("Planilha válida: %d produtos prontos para importar", resultado.LinhasValidas)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": message,
+		"data":    resultado,
+	})
+}
+
 func (h *ProductHandler) BuscarPorID(c *gin.Context) {
 	id := c.Param("id")
 	
@@ -320,13 +433,15 @@ func (h *ProductHandler) Atualizar(c *gin.Context) {
 		Cor          *string
 		IMEI         *string
 		CodigoBarras *string
-		CustoDolar   *float64
+		CustoDolar   *money.Dinheiro
 		TaxaDolar    *float64
-		Preco         *float64
+		Preco         *money.Dinheiro
 		Quantidade    *int
 		Fornecedor    *string
 		DataCompra    *string
 		CategoriaID   *int
+		SKUID         *int
+		RemoverSKU    bool
 	}
 
 	req := UpdateRequest{}
@@ -376,7 +491,15 @@ func (h *ProductHandler) Atualizar(c *gin.Context) {
 	// Converter campos numéricos (aceita string ou número)
 	if v, ok := reqRaw["custoDolar"]; ok && v != nil {
 		if f, err := utils.ParseFloatFlexible(v); err == nil && f != nil {
-			req.CustoDolar = f
+			custo, err := money.DeReais(*f)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"success": false,
+					"message": "Custo em dólar fora do limite",
+				})
+				return
+			}
+			req.CustoDolar = &custo
 		}
 	}
 	if v, ok := reqRaw["taxaDolar"]; ok && v != nil {
@@ -386,7 +509,15 @@ func (h *ProductHandler) Atualizar(c *gin.Context) {
 	}
 	if v, ok := reqRaw["preco"]; ok && v != nil {
 		if f, err := utils.ParseFloatFlexible(v); err == nil && f != nil {
-			req.Preco = f
+			preco, err := money.DeReais(*f)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"success": false,
+					"message": "Preço fora do limite",
+				})
+				return
+			}
+			req.Preco = &preco
 		}
 	}
 	if v, ok := reqRaw["quantidade"]; ok && v != nil {
@@ -403,6 +534,15 @@ func (h *ProductHandler) Atualizar(c *gin.Context) {
 		}
 	}
 
+	if v, ok := reqRaw["skuId"]; ok {
+		if v == nil {
+			// null explícito desassocia o produto do SKU
+			req.RemoverSKU = true
+		} else if i, err := utils.ParseIntFlexible(v); err == nil && i != nil {
+			req.SKUID = i
+		}
+	}
+
 	// Verificar se produto existe
 	var produto models.ProdutoComprado
 	if err := h.DB.First(&produto, produtoID).Error; err != nil {
@@ -416,10 +556,22 @@ func (h *ProductHandler) Atualizar(c *gin.Context) {
 	// Verificar IMEI duplicado se fornecido
 	if req.IMEI != nil && *req.IMEI != "" && (produto.IMEI == nil || *req.IMEI != *produto.IMEI) {
 		var produtoExistente models.ProdutoComprado
-		if err := h.DB.Where("imei = ? AND id != ?", *req.IMEI, produtoID).First(&produtoExistente).Error; err == nil {
+		if err := h.DB.Unscoped().Where("imei = ? AND id != ?", *req.IMEI, produtoID).First(&produtoExistente).Error; err == nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": mensagemIMEIDuplicado(produtoExistente),
+			})
+			return
+		}
+	}
+
+	if req.SKUID != nil {
+		var count int64
+		h.DB.Model(&models.ProdutoSKU{}).Where("id = ?", *req.SKUID).Count(&count)
+		if count == 0 {
 			c.JSON(http.StatusBadRequest, gin.H{
 				"success": false,
-				"message": "Já existe um produto com este IMEI",
+				"message": "SKU não encontrado",
 			})
 			return
 		}
@@ -474,7 +626,7 @@ func (h *ProductHandler) Atualizar(c *gin.Context) {
 		updates["fornecedor"] = *req.Fornecedor
 	}
 	if req.DataCompra != nil && *req.DataCompra != "" {
-		dataCompra, err := time.Parse("2006-01-02", *req.DataCompra)
+		dataCompra, err := timezone.ParseData(*req.DataCompra)
 		if err == nil && !dataCompra.IsZero() {
 			updates["dataCompra"] = dataCompra
 		}
@@ -482,6 +634,11 @@ func (h *ProductHandler) Atualizar(c *gin.Context) {
 	if req.CategoriaID != nil {
 		updates["categoriaId"] = *req.CategoriaID
 	}
+	if req.SKUID != nil {
+		updates["skuId"] = *req.SKUID
+	} else if req.RemoverSKU {
+		updates["skuId"] = nil
+	}
 
 	// Atualizar
 	if err := h.DB.Model(&produto).Updates(updates).Error; err != nil {
@@ -505,6 +662,7 @@ func (h *ProductHandler) Atualizar(c *gin.Context) {
 			"cor":               produto.Cor,
 			"imei":              produto.IMEI,
 			"codigoBarras":      produto.CodigoBarras,
+			"skuId":             produto.SKUID,
 			"custoDolar":        produto.CustoDolar,
 			"taxaDolar":         produto.TaxaDolar,
 			"preco":             produto.Preco,
@@ -747,19 +905,14 @@ func (h *ProductHandler) Deletar(c *gin.Context) {
 		return
 	}
 
-	// Deletar em transação para garantir integridade
+	// O produto vai para a lixeira com os estoques distribuídos, na mesma data, para que sejam restaurados
+	// juntos. As vendas já feitas continuam apontando para eles.
+	agora := time.Now()
 	err = h.DB.Transaction(func(tx *gorm.DB) error {
-		// Deletar estoques relacionados
-		if err := tx.Where("produtoCompradoId = ?", produtoID).Delete(&models.Estoque{}).Error; err != nil {
-			return err
-		}
-
-		// Deletar produto
-		if err := tx.Delete(&produto).Error; err != nil {
+		if err := tx.Model(&models.Estoque{}).Where("produtoCompradoId = ?", produtoID).Update("deletedAt", agora).Error; err != nil {
 			return err
 		}
-
-		return nil
+		return tx.Model(&produto).Update("deletedAt", agora).Error
 	})
 
 	if err != nil {
@@ -772,7 +925,7 @@ func (h *ProductHandler) Deletar(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
-		"message": "Produto deletado com sucesso",
+		"message": "Produto movido para a lixeira",
 	})
 }
 
@@ -780,15 +933,10 @@ func (h *ProductHandler) ListarHistoricoDistribuicaoGlobal(c *gin.Context) {
 	var historico []models.HistoricoDistribuicao
 	
 	// Buscar todo o histórico, ordenado por data decrescente
-	query := h.DB.Preload("ProdutoComprado").Preload("Usuario").Order("data DESC")
+	query := h.DB.Preload("ProdutoComprado", incluirLixeira).Preload("Usuario").Order("data DESC")
 
 	// Filtros opcionais podem ser adicionados aqui (data, usuario, produto)
-	if dataInicio := c.Query("dataInicio"); dataInicio != "" {
-		query = query.Where("DATE(data) >= ?", dataInicio)
-	}
-	if dataFim := c.Query("dataFim"); dataFim != "" {
-		query = query.Where("DATE(data) <= ?", dataFim)
-	}
+	query = filtrarPeriodo(query, "data", c.Query("dataInicio"), c.Query("dataFim"))
 
 	if err := query.Find(&historico).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -61,6 +61,16 @@ func (h *ProductCategoryHandler) CriarCategoria(c *gin.Context) {
 		return
 	}
 
+	// O nome é único também entre as categorias na lixeira
+	var existente models.CategoriaProduto
+	if err := h.DB.Unscoped().Where("nome = ? AND deletedAt IS NOT NULL", req.Nome).First(&existente).Error; err == nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Já existe uma categoria com este nome na lixeira. Restaure-a em vez de criar de novo",
+		})
+		return
+	}
+
 	categoria := models.CategoriaProduto{
 		Nome:      req.Nome,
 		Descricao: req.Descricao,
@@ -153,7 +163,7 @@ func (h *ProductCategoryHandler) AtualizarCategoria(c *gin.Context) {
 	})
 }
 
-// DeletarCategoria deleta uma categoria de produtos
+// DeletarCategoria move uma categoria de produtos para a lixeira
 func (h *ProductCategoryHandler) DeletarCategoria(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
@@ -192,7 +202,7 @@ func (h *ProductCategoryHandler) DeletarCategoria(c *gin.Context) {
 		return
 	}
 
-	// Deletar a categoria
+	// Vai para a lixeira
 	if err := h.DB.Delete(&categoria).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
@@ -203,6 +213,6 @@ func (h *ProductCategoryHandler) DeletarCategoria(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
-		"message": "Categoria deletada com sucesso",
+		"message": "Categoria movida para a lixeira",
 	})
 }
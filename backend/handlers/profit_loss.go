@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"cmdimport/backend/models"
+	"cmdimport/backend/profitloss"
+	"cmdimport/backend/timezone"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type ProfitLossHandler struct {
+	DB *gorm.DB
+}
+
+func NewProfitLossHandler(db *gorm.DB) *ProfitLossHandler {
+	return &ProfitLossHandler{DB: db}
+}
+
+// periodoDRE lê dataInicio e dataFim (YYYY-MM-DD, inclusive; padrão: o mês atual) e retorna o período [inicio, fim)
+// no fuso do negócio
+func periodoDRE(c *gin.Context) (time.Time, time.Time, string) {
+	inicio := timezone.InicioDoMes(time.Now())
+	fim := timezone.InicioDoMes(inicio.AddDate(0, 1, 0))
+	if d := c.Query("dataInicio"); d != "" {
+		t, err := timezone.ParseData(d)
+		if err != nil {
+			return inicio, fim, "Data de início inválida. Use o formato YYYY-MM-DD"
+		}
+		inicio = t
+	}
+	if d := c.Query("dataFim"); d != "" {
+		t, err := timezone.ParseDiaSeguinte(d)
+		if err != nil {
+			return inicio, fim, "Data de fim inválida. Use o formato YYYY-MM-DD"
+		}
+		fim = t
+	}
+	if !fim.After(inicio) {
+		return inicio, fim, "A data de fim deve ser igual ou posterior à de início"
+	}
+	return inicio, fim, ""
+}
+
+// DRE monta a demonstração do resultado do período (parâmetros dataInicio e dataFim; padrão: o mês atual)
+// com o total, as despesas por categoria e as quebras por mês, loja e vendedor
+func (h *ProfitLossHandler) DRE(c *gin.Context) {
+	inicio, fim, msg := periodoDRE(c)
+	if msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": msg,
+		})
+		return
+	}
+
+	dre, err := profitloss.Calcular(h.DB, inicio, fim)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao calcular DRE",
+		})
+		return
+	}
+	grupos := make(map[string][]profitloss.Linha, 3)
+	for _, agrupar := range []string{profitloss.PorMes, profitloss.PorLoja, profitloss.PorVendedor} {
+		linhas, err := profitloss.Agrupar(h.DB, agrupar, inicio, fim)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "Erro ao calcular DRE",
+			})
+			return
+		}
+		grupos[agrupar] = linhas
+	}
+
+	// Nomes de lojas e vendedores para exibição
+	nomesLojas := make(map[string]string)
+	var lojas []models.Loja
+	h.DB.Find(&lojas)
+	for _, l := range lojas {
+		nomesLojas[strconv.Itoa(l.ID)] = l.Nome
+	}
+	nomesUsuarios := make(map[string]string)
+	var usuarios []models.Usuario
+	h.DB.Select("id, nome").Find(&usuarios)
+	for _, u := range usuarios {
+		nomesUsuarios[strconv.Itoa(u.ID)] = u.Nome
+	}
+	for i, l := range grupos[profitloss.PorLoja] {
+		nome, ok := nomesLojas[l.Chave]
+		if !ok {
+			nome = "Sem loja"
+		}
+		grupos[profitloss.PorLoja][i].Nome = nome
+	}
+	for i, l := range grupos[profitloss.PorVendedor] {
+		grupos[profitloss.PorVendedor][i].Nome = nomesUsuarios[l.Chave]
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"dataInicio":  inicio.Format("2006-01-02"),
+			"dataFim":     timezone.InicioDoDia(fim.Add(-time.Second)).Format("2006-01-02"),
+			"total":       dre.Total,
+			"despesas":    dre.Despesas,
+			"porMes":      grupos[profitloss.PorMes],
+			"porLoja":     grupos[profitloss.PorLoja],
+			"porVendedor": grupos[profitloss.PorVendedor],
+		},
+	})
+}
+
+// ListarDevolucoes lista as devoluções do período (parâmetros dataInicio, dataFim e usuarioId)
+func (h *ProfitLossHandler) ListarDevolucoes(c *gin.Context) {
+	inicio, fim, msg := periodoDRE(c)
+	if msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": msg,
+		})
+		return
+	}
+
+	query := h.DB.Model(&models.DevolucaoVenda{}).Where("createdAt >= ? AND createdAt < ?", inicio, fim)
+	if usuarioID := c.Query("usuarioId"); usuarioID != "" {
+		query = query.Where("usuarioId = ?", usuarioID)
+	}
+
+	var devolucoes []models.DevolucaoVenda
+	if err := query.Order("createdAt DESC").Find(&devolucoes).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao buscar devoluções",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    devolucoes,
+	})
+}
@@ -0,0 +1,410 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"cmdimport/backend/models"
+	"cmdimport/backend/payments"
+	"cmdimport/backend/quotes"
+	"cmdimport/backend/reservations"
+	"cmdimport/backend/timezone"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type QuoteHandler struct {
+	DB *gorm.DB
+}
+
+func NewQuoteHandler(db *gorm.DB) *QuoteHandler {
+	return &QuoteHandler{DB: db}
+}
+
+// SalvarOrcamentoRequest tem os mesmos campos da venda (/vendas/cadastrar) mais a validade
+type SalvarOrcamentoRequest struct {
+	CreateSaleRequest
+	ValidadeDias    int  `json:"validadeDias"`    // Padrão: 7 dias
+	ReservarEstoque bool `json:"reservarEstoque"` // Separa as quantidades até o fim da validade
+}
+
+// errSimulacao desfaz a venda registrada só para calcular preços e validar o orçamento
+var errSimulacao = errors.New("simulação de venda")
+
+// simularVenda roda registrarVenda e desfaz tudo, retornando os preços, descontos e total calculados.
+// Dentro de uma transação, usa um savepoint e mantém as travas de estoque.
+func simularVenda(db *gorm.DB, req CreateSaleRequest, operador models.Usuario) (*vendaRegistrada, error) {
+	var venda *vendaRegistrada
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		venda, err = registrarVenda(tx, req, operador)
+		if err != nil {
+			return err
+		}
+		return errSimulacao
+	})
+	if errors.Is(err, errSimulacao) {
+		return venda, nil
+	}
+	return nil, err
+}
+
+// Criar registra um orçamento. Os preços e o estoque são validados como em uma venda,
+// sem baixar o estoque; com reservarEstoque, as quantidades ficam separadas até o fim da validade.
+func (h *QuoteHandler) Criar(c *gin.Context) {
+	var req SalvarOrcamentoRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Todos os campos obrigatórios devem ser preenchidos",
+		})
+		return
+	}
+	if req.ValidadeDias < 0 || req.ValidadeDias > 90 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "A validade deve ser de 1 a 90 dias",
+		})
+		return
+	}
+
+	operador, ok := usuarioLogado(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "Usuário não autenticado",
+		})
+		return
+	}
+
+	dados, err := json.Marshal(req.CreateSaleRequest)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao registrar orçamento",
+		})
+		return
+	}
+
+	agora := time.Now()
+	var orcamento models.Orcamento
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		venda, err := simularVenda(tx, req.CreateSaleRequest, operador)
+		if err != nil {
+			return err
+		}
+
+		resumo, err := json.Marshal(gin.H{
+			"produtos":   venda.Produtos,
+			"descontos":  venda.Descontos,
+			"pagamentos": venda.Pagamentos,
+		})
+		if err != nil {
+			return err
+		}
+
+		var vendedorNome string
+		if len(venda.Historico) > 0 {
+			vendedorNome = venda.Historico[0].VendedorNome
+		}
+		orcamento = models.Orcamento{
+			ClienteNome:     req.ClienteNome,
+			Telefone:        req.Telefone,
+			UsuarioID:       req.UsuarioID,
+			VendedorNome:    vendedorNome,
+			Dados:           dados,
+			Resumo:          resumo,
+			ValorTotal:      venda.ValorTotal,
+			ValidoAte:       quotes.ValidoAte(agora, req.ValidadeDias),
+			ReservarEstoque: req.ReservarEstoque,
+			Status:          quotes.StatusAberto,
+		}
+		if err := tx.Create(&orcamento).Error; err != nil {
+			return err
+		}
+		if !req.ReservarEstoque {
+			return nil
+		}
+
+		// A simulação já conferiu o estoque livre com as linhas travadas
+		porEstoque := make(map[int]int)
+		ordem := make([]int, 0, len(req.Produtos))
+		for _, p := range req.Produtos {
+			estoqueID, _ := strconv.Atoi(p.Produto)
+			quantidade, _ := strconv.Atoi(p.Quantidade)
+			if _, ok := porEstoque[estoqueID]; !ok {
+				ordem = append(ordem, estoqueID)
+			}
+			porEstoque[estoqueID] += quantidade
+		}
+		reservas := make([]models.ReservaEstoque, 0, len(ordem))
+		for _, estoqueID := range ordem {
+			reservas = append(reservas, models.ReservaEstoque{
+				EstoqueID:   estoqueID,
+				Quantidade:  porEstoque[estoqueID],
+				OrcamentoID: &orcamento.ID,
+				UsuarioID:   operador.ID,
+				Status:      reservations.StatusAtiva,
+				ExpiraEm:    orcamento.ValidoAte,
+			})
+		}
+		return tx.Create(&reservas).Error
+	})
+	var ev *erroVenda
+	if errors.As(err, &ev) {
+		c.JSON(ev.status, gin.H{
+			"success": false,
+			"message": ev.mensagem,
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao registrar orçamento",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    orcamento,
+		"message": "Orçamento registrado com sucesso",
+	})
+}
+
+// Listar lista os orçamentos (filtros: usuarioId, status, cliente)
+func (h *QuoteHandler) Listar(c *gin.Context) {
+	query := h.DB.Model(&models.Orcamento{})
+	if usuarioID := c.Query("usuarioId"); usuarioID != "" {
+		query = query.Where("usuarioId = ?", usuarioID)
+	}
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if cliente := c.Query("cliente"); cliente != "" {
+		query = query.Where("clienteNome LIKE ?", "%"+cliente+"%")
+	}
+
+	var orcamentos []models.Orcamento
+	if err := query.Order("createdAt DESC").Limit(200).Find(&orcamentos).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao buscar orçamentos",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    orcamentos,
+	})
+}
+
+// BuscarPorID retorna o orçamento com as reservas de estoque
+func (h *QuoteHandler) BuscarPorID(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "ID inválido",
+		})
+		return
+	}
+
+	var orcamento models.Orcamento
+	if err := h.DB.First(&orcamento, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "Orçamento não encontrado",
+		})
+		return
+	}
+
+	var reservas []models.ReservaEstoque
+	if err := h.DB.Where("orcamentoId = ?", id).Find(&reservas).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao buscar reservas do orçamento",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"orcamento": orcamento,
+			"reservas":  reservas,
+		},
+	})
+}
+
+// Cancelar encerra um orçamento aberto e libera as reservas
+func (h *QuoteHandler) Cancelar(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "ID inválido",
+		})
+		return
+	}
+
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		var orcamento models.Orcamento
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&orcamento, id).Error; err != nil {
+			return &erroVenda{http.StatusNotFound, "Orçamento não encontrado"}
+		}
+		if orcamento.Status != quotes.StatusAberto {
+			return &erroVenda{http.StatusBadRequest, "Só orçamentos abertos podem ser cancelados"}
+		}
+		if err := tx.Model(&orcamento).Update("status", quotes.StatusCancelado).Error; err != nil {
+			return err
+		}
+		return reservations.Encerrar(tx, orcamento.ID, reservations.StatusLiberada)
+	})
+	var ev *erroVenda
+	if errors.As(err, &ev) {
+		c.JSON(ev.status, gin.H{
+			"success": false,
+			"message": ev.mensagem,
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao cancelar orçamento",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Orçamento cancelado",
+	})
+}
+
+// Converter transforma o orçamento em venda. Estoque e preços são validados de novo na transação
+// da venda; se o total mudou desde o orçamento, a conversão exige confirmarPrecos.
+// O corpo pode trazer outras formas de pagamento (pagamentos ou formaPagamento).
+func (h *QuoteHandler) Converter(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "ID inválido",
+		})
+		return
+	}
+
+	operador, ok := usuarioLogado(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "Usuário não autenticado",
+		})
+		return
+	}
+
+	var req struct {
+		Pagamentos      []payments.Linha `json:"pagamentos"`
+		FormaPagamento  *string          `json:"formaPagamento"`
+		ConfirmarPrecos bool             `json:"confirmarPrecos"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Dados inválidos",
+			})
+			return
+		}
+	}
+
+	var orcamento models.Orcamento
+	var venda *vendaRegistrada
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&orcamento, id).Error; err != nil {
+			return &erroVenda{http.StatusNotFound, "Orçamento não encontrado"}
+		}
+		if orcamento.Status != quotes.StatusAberto {
+			return &erroVenda{http.StatusBadRequest, "Orçamento " + orcamento.Status + ", não pode ser convertido"}
+		}
+		if !time.Now().Before(orcamento.ValidoAte) {
+			return &erroVenda{http.StatusBadRequest, "Orçamento expirado em " + orcamento.ValidoAte.In(timezone.Local()).Format("02/01/2006")}
+		}
+
+		var vendaReq CreateSaleRequest
+		if err := json.Unmarshal(orcamento.Dados, &vendaReq); err != nil {
+			return err
+		}
+		if len(req.Pagamentos) > 0 {
+			vendaReq.Pagamentos = req.Pagamentos
+			vendaReq.ValorPix, vendaReq.ValorCartao, vendaReq.ValorDinheiro = nil, nil, nil
+		}
+		if req.FormaPagamento != nil {
+			vendaReq.FormaPagamento = *req.FormaPagamento
+		}
+		vendaReq.orcamentoID = orcamento.ID
+
+		var err error
+		venda, err = registrarVenda(tx, vendaReq, operador)
+		if err != nil {
+			return err
+		}
+
+		if !req.ConfirmarPrecos && venda.ValorTotal != orcamento.ValorTotal {
+			return &erroVenda{http.StatusConflict, fmt.Sprintf(
+				"O total mudou de R$ %s para R$ %s desde o orçamento. Envie confirmarPrecos para continuar",
+				orcamento.ValorTotal.BR(), venda.ValorTotal.BR())}
+		}
+
+		if err := tx.Model(&orcamento).Updates(map[string]interface{}{
+			"status":  quotes.StatusConvertido,
+			"vendaId": venda.VendaID,
+		}).Error; err != nil {
+			return err
+		}
+		return reservations.Encerrar(tx, orcamento.ID, reservations.StatusConvertida)
+	})
+	var ev *erroVenda
+	if errors.As(err, &ev) {
+		c.JSON(ev.status, gin.H{
+			"success": false,
+			"message": ev.mensagem,
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao converter orçamento em venda",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "Orçamento convertido em venda",
+		"data": gin.H{
+			"orcamentoId": orcamento.ID,
+			"venda": gin.H{
+				"vendaId":     venda.VendaID,
+				"clienteNome": orcamento.ClienteNome,
+				"produtos":    venda.Produtos,
+				"valorTotal":  venda.ValorTotal,
+				"descontos":   venda.Descontos,
+				"pagamentos":  venda.Pagamentos,
+				"troco":       payments.Troco(venda.Pagamentos),
+				"trocas":      venda.Trocas,
+			},
+		},
+	})
+}
@@ -0,0 +1,308 @@
+package handlers
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"cmdimport/backend/expenses"
+	"cmdimport/backend/models"
+	"cmdimport/backend/money"
+	"cmdimport/backend/timezone"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type SalvarDespesaRecorrenteRequest struct {
+	Nome        string         `json:"nome" binding:"required"`
+	Valor       money.Dinheiro `json:"valor" binding:"required,gt=0"`
+	CategoriaID int            `json:"categoriaId" binding:"required"`
+	Descricao   *string        `json:"descricao"`
+	Frequencia  string         `json:"frequencia" binding:"required"` // "semanal", "mensal" ou "anual"
+	DataInicio  string         `json:"dataInicio" binding:"required"` // YYYY-MM-DD, primeira ocorrência
+	DataFim     *string        `json:"dataFim"`                       // YYYY-MM-DD, inclusive; vazio: sem fim
+	Ativo       *bool          `json:"ativo"`
+}
+
+// validarDespesaRecorrente preenche a recorrência a partir da requisição e retorna a mensagem de erro para o cliente,
+// ou "" se é válida
+func (h *ExpenseHandler) validarDespesaRecorrente(r *models.DespesaRecorrente, req SalvarDespesaRecorrenteRequest) string {
+	if !expenses.FrequenciaValida(req.Frequencia) {
+		return "Frequência inválida. Use semanal, mensal ou anual"
+	}
+	inicio, err := time.Parse("2006-01-02", req.DataInicio)
+	if err != nil {
+		return "Data de início inválida. Use o formato YYYY-MM-DD"
+	}
+	inicio = expenses.Dia(inicio)
+	var fim *time.Time
+	if req.DataFim != nil && *req.DataFim != "" {
+		f, err := time.Parse("2006-01-02", *req.DataFim)
+		if err != nil {
+			return "Data de fim inválida. Use o formato YYYY-MM-DD"
+		}
+		f = expenses.Dia(f)
+		if f.Before(inicio) {
+			return "A data de fim deve ser igual ou posterior à de início"
+		}
+		fim = &f
+	}
+	// Mudar a agenda de uma recorrência que já gerou despesas refaria as ocorrências passadas
+	if r.Geradas > 0 && (req.Frequencia != r.Frequencia || !inicio.Equal(expenses.Dia(r.DataInicio))) {
+		return "A recorrência já gerou despesas: para mudar a frequência ou o início, encerre-a com dataFim e cadastre outra"
+	}
+
+	var count int64
+	h.DB.Model(&models.CategoriaDespesa{}).Where("id = ?", req.CategoriaID).Count(&count)
+	if count == 0 {
+		return "Categoria não encontrada"
+	}
+
+	r.Nome = req.Nome
+	r.Valor = req.Valor
+	r.CategoriaID = req.CategoriaID
+	r.Descricao = req.Descricao
+	r.Frequencia = req.Frequencia
+	r.DataInicio = inicio
+	r.DataFim = fim
+	if req.Ativo != nil {
+		r.Ativo = *req.Ativo
+	}
+	r.ProximaData = expenses.Ocorrencia(*r, r.Geradas)
+	return ""
+}
+
+// ListarDespesasRecorrentes lista as recorrências (filtro: ativo)
+func (h *ExpenseHandler) ListarDespesasRecorrentes(c *gin.Context) {
+	query := h.DB.Model(&models.DespesaRecorrente{})
+	if ativo := c.Query("ativo"); ativo != "" {
+		query = query.Where("ativo = ?", ativo == "true")
+	}
+
+	var recorrentes []models.DespesaRecorrente
+	if err := query.Order("proximaData ASC, nome ASC").Find(&recorrentes).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao buscar despesas recorrentes",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    recorrentes,
+	})
+}
+
+// CriarDespesaRecorrente cadastra uma recorrência. As ocorrências até hoje são geradas na próxima execução da tarefa.
+func (h *ExpenseHandler) CriarDespesaRecorrente(c *gin.Context) {
+	var req SalvarDespesaRecorrenteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Dados inválidos: " + err.Error(),
+		})
+		return
+	}
+
+	recorrente := models.DespesaRecorrente{Ativo: true}
+	if msg := h.validarDespesaRecorrente(&recorrente, req); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": msg,
+		})
+		return
+	}
+	if err := h.DB.Create(&recorrente).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao cadastrar despesa recorrente",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    recorrente,
+		"message": "Despesa recorrente cadastrada com sucesso",
+	})
+}
+
+// AtualizarDespesaRecorrente altera uma recorrência. Vale para as ocorrências ainda não geradas.
+func (h *ExpenseHandler) AtualizarDespesaRecorrente(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "ID inválido",
+		})
+		return
+	}
+
+	var req SalvarDespesaRecorrenteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Dados inválidos: " + err.Error(),
+		})
+		return
+	}
+
+	var recorrente models.DespesaRecorrente
+	if err := h.DB.First(&recorrente, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "Despesa recorrente não encontrada",
+		})
+		return
+	}
+	if msg := h.validarDespesaRecorrente(&recorrente, req); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": msg,
+		})
+		return
+	}
+	if err := h.DB.Save(&recorrente).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao atualizar despesa recorrente",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    recorrente,
+		"message": "Despesa recorrente atualizada com sucesso",
+	})
+}
+
+// DeletarDespesaRecorrente remove uma recorrência. As despesas já geradas continuam cadastradas.
+func (h *ExpenseHandler) DeletarDespesaRecorrente(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "ID inválido",
+		})
+		return
+	}
+
+	var encontrada bool
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&models.DespesaRecorrente{}, id)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		encontrada = true
+		return tx.Unscoped().Model(&models.Despesa{}).Where("despesaRecorrenteId = ?", id).
+			Update("despesaRecorrenteId", nil).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao deletar despesa recorrente",
+		})
+		return
+	}
+	if !encontrada {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "Despesa recorrente não encontrada",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Despesa recorrente deletada com sucesso",
+	})
+}
+
+// ProximasDespesas mostra o que há para pagar nos próximos dias (parâmetro dias, padrão 30): as despesas
+// pendentes, inclusive as atrasadas, e as ocorrências das recorrências que ainda serão geradas
+func (h *ExpenseHandler) ProximasDespesas(c *gin.Context) {
+	dias := 30
+	if d := c.Query("dias"); d != "" {
+		n, err := strconv.Atoi(d)
+		if err != nil || n < 0 || n > 366 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Informe dias entre 0 e 366",
+			})
+			return
+		}
+		dias = n
+	}
+	hoje := timezone.Hoje()
+	ate := hoje.AddDate(0, 0, dias)
+
+	var pendentes []models.Despesa
+	if err := h.DB.Preload("Categoria").
+		Where("status = ? AND data <= ?", expenses.StatusPendente, ate).
+		Order("data ASC, id ASC").Find(&pendentes).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao buscar despesas pendentes",
+		})
+		return
+	}
+
+	var recorrentes []models.DespesaRecorrente
+	if err := h.DB.Where("ativo = ? AND proximaData <= ?", true, ate).
+		Where("categoriaId IN (SELECT id FROM CategoriaDespesa WHERE deletedAt IS NULL)").
+		Find(&recorrentes).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao buscar despesas recorrentes",
+		})
+		return
+	}
+	nomesCategorias := make(map[int]string)
+	var categorias []models.CategoriaDespesa
+	h.DB.Find(&categorias)
+	for _, cat := range categorias {
+		nomesCategorias[cat.ID] = cat.Nome
+	}
+
+	var totalAtrasado, totalPendente, totalPrevisto money.Dinheiro
+	for _, d := range pendentes {
+		if expenses.Dia(d.Data).Before(hoje) {
+			totalAtrasado += d.Valor
+		} else {
+			totalPendente += d.Valor
+		}
+	}
+	previstas := make([]gin.H, 0)
+	for _, r := range recorrentes {
+		for _, data := range expenses.Previstas(r, ate) {
+			previstas = append(previstas, gin.H{
+				"despesaRecorrenteId": r.ID,
+				"nome":                r.Nome,
+				"valor":               r.Valor,
+				"categoriaId":         r.CategoriaID,
+				"categoriaNome":       nomesCategorias[r.CategoriaID],
+				"data":                data.Format("2006-01-02"),
+			})
+			totalPrevisto += r.Valor
+		}
+	}
+	sort.SliceStable(previstas, func(i, j int) bool {
+		return previstas[i]["data"].(string) < previstas[j]["data"].(string)
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"ate":           ate.Format("2006-01-02"),
+			"pendentes":     pendentes,
+			"previstas":     previstas,
+			"totalAtrasado": totalAtrasado,
+			"totalPendente": totalPendente,
+			"totalPrevisto": totalPrevisto,
+		},
+	})
+}
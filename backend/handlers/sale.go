@@ -1,17 +1,30 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"math/rand"
 	"net/http"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
+	"cmdimport/backend/cashregister"
+	"cmdimport/backend/discounts"
 	"cmdimport/backend/models"
+	"cmdimport/backend/money"
+	"cmdimport/backend/payments"
+	"cmdimport/backend/pricing"
+	"cmdimport/backend/profitloss"
+	"cmdimport/backend/receivables"
+	"cmdimport/backend/reservations"
+	"cmdimport/backend/tradein"
 	"cmdimport/backend/utils"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type SaleHandler struct {
@@ -30,11 +43,19 @@ type CreateSaleRequest struct {
 	Observacoes     *string                  `json:"observacoes"`
 	UsuarioID       int                      `json:"usuarioId" binding:"required"`
 	FormaPagamento  string                   `json:"formaPagamento"`
-	ValorPix        *float64                 `json:"valorPix"`
-	ValorCartao     *float64                 `json:"valorCartao"`
-	ValorDinheiro   *float64                 `json:"valorDinheiro"`
+	ValorPix        *money.Dinheiro          `json:"valorPix"`
+	ValorCartao     *money.Dinheiro          `json:"valorCartao"`
+	ValorDinheiro   *money.Dinheiro          `json:"valorDinheiro"`
+	Pagamentos      []payments.Linha         `json:"pagamentos"` // Linhas de pagamento; sem elas, valem os campos acima
+	Desconto        *discounts.Desconto      `json:"desconto"`    // Desconto sobre o total da venda
+	CupomCodigo     string                   `json:"cupomCodigo"`
+	MotivoDesconto  *string                  `json:"motivoDesconto"`
+	AprovacaoDescontoID *int                 `json:"aprovacaoDescontoId"` // Aprovação do admin para desconto acima do limite
+	ReservaID       *int                     `json:"reservaId"` // Reserva do cliente: libera as unidades reservadas e o sinal (linha "sinal")
 	FotoProduto     *string                  `json:"fotoProduto"`
 	TipoCliente     *string                  `json:"tipoCliente"`
+
+	orcamentoID int // Orçamento sendo convertido: as reservas dele não contam como indisponíveis
 }
 
 type SaleProductRequest struct {
@@ -43,6 +64,28 @@ type SaleProductRequest struct {
 	Quantidade             string  `json:"quantidade" binding:"required"`
 	UsarPrecoPersonalizado bool    `json:"usarPrecoPersonalizado"`
 	PrecoPersonalizado     *string `json:"precoPersonalizado"`
+	Desconto               *discounts.Desconto `json:"desconto"` // Desconto por unidade sobre o preço de tabela
+}
+
+// erroVenda é um erro de validação da venda, devolvido ao cliente com o status indicado
+type erroVenda struct {
+	status   int
+	mensagem string
+}
+
+func (e *erroVenda) Error() string {
+	return e.mensagem
+}
+
+// vendaRegistrada é o resultado de registrarVenda
+type vendaRegistrada struct {
+	VendaID    string
+	Produtos   []map[string]interface{}
+	ValorTotal money.Dinheiro
+	Descontos  gin.H
+	Pagamentos []models.PagamentoVenda
+	Trocas     []models.AparelhoTroca
+	Historico  []models.HistoricoVenda
 }
 
 func (h *SaleHandler) Cadastrar(c *gin.Context) {
@@ -54,22 +97,92 @@ func (h *SaleHandler) Cadastrar(c *gin.Context) {
 		return
 	}
 
-	if len(req.Produtos) == 0 {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"message": "Pelo menos um produto deve ser informado",
+	// O limite de desconto é o de quem registra a venda
+	operador, ok := usuarioLogado(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"message": "Usuário não autenticado",
 		})
 		return
 	}
 
-	// Buscar usuário vendedor
-	var vendedor models.Usuario
-	if err := h.DB.First(&vendedor, req.UsuarioID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"message": "Usuário não encontrado",
+	var venda *vendaRegistrada
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		venda, err = registrarVenda(tx, req, operador)
+		return err
+	})
+	var ev *erroVenda
+	if errors.As(err, &ev) {
+		c.JSON(ev.status, gin.H{
+			"message": ev.mensagem,
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Erro ao registrar venda",
 		})
 		return
 	}
 
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "Venda cadastrada com sucesso!",
+		"data": map[string]interface{}{
+			"venda": map[string]interface{}{
+				"vendaId":     venda.VendaID,
+				"clienteNome": req.ClienteNome,
+				"produtos":    venda.Produtos,
+				"valorTotal":  venda.ValorTotal,
+				"descontos":   venda.Descontos,
+				"pagamentos":  venda.Pagamentos,
+				"troco":       payments.Troco(venda.Pagamentos),
+				"trocas":      venda.Trocas,
+			},
+		},
+	})
+}
+
+// registrarVenda valida estoque, preços, descontos e pagamentos, grava o histórico e as linhas de pagamento
+// e baixa o estoque. operador é quem registra a venda (seu papel define o limite de desconto).
+// Deve ser chamada dentro de uma transação; erros de validação são *erroVenda.
+func registrarVenda(tx *gorm.DB, req CreateSaleRequest, operador models.Usuario) (*vendaRegistrada, error) {
+	if len(req.Produtos) == 0 {
+		return nil, &erroVenda{http.StatusBadRequest, "Pelo menos um produto deve ser informado"}
+	}
+
+	// Buscar usuário vendedor
+	var vendedor models.Usuario
+	if err := tx.First(&vendedor, req.UsuarioID).Error; err != nil {
+		return nil, &erroVenda{http.StatusNotFound, "Usuário não encontrado"}
+	}
+
+	// Sem formaPagamento, o preço segue as linhas de pagamento (ex.: uma linha de crédito em 3x usa credito_3x)
+	formaPagamento := req.FormaPagamento
+	if formaPagamento == "" && len(req.Pagamentos) > 0 {
+		formaPagamento = payments.FormaDasLinhas(req.Pagamentos)
+	}
+
+	// Reserva do cliente usada nesta venda
+	var reserva *models.ReservaEstoque
+	if req.ReservaID != nil {
+		reserva = &models.ReservaEstoque{}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(reserva, *req.ReservaID).Error; err != nil {
+			return nil, &erroVenda{http.StatusNotFound, "Reserva não encontrada"}
+		}
+		if !reservations.Ativa(*reserva, time.Now()) {
+			return nil, &erroVenda{http.StatusBadRequest, "A reserva não está mais ativa"}
+		}
+		if reserva.OrcamentoID != nil {
+			return nil, &erroVenda{http.StatusBadRequest, "Reserva de orçamento: converta o orçamento em venda"}
+		}
+	}
+	ignorarReservas := reservations.Ignorar{OrcamentoID: req.orcamentoID}
+	if reserva != nil {
+		ignorarReservas.ReservaID = reserva.ID
+	}
+
 	// Validar e buscar produtos no estoque
 	type ProdutoEstoqueComVenda struct {
 		Estoque          models.Estoque
@@ -77,39 +190,38 @@ func (h *SaleHandler) Cadastrar(c *gin.Context) {
 	}
 
 	produtosEstoque := make([]ProdutoEstoqueComVenda, 0)
+	vendidoPorEstoque := make(map[int]int)
 
 	for _, produtoReq := range req.Produtos {
 		estoqueID, err := strconv.Atoi(produtoReq.Produto)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"message": fmt.Sprintf("ID de produto inválido: %s", produtoReq.Produto),
-			})
-			return
+			return nil, &erroVenda{http.StatusBadRequest, fmt.Sprintf("ID de produto inválido: %s", produtoReq.Produto)}
 		}
 
+		// Travar a linha do estoque até o fim da venda
 		var estoque models.Estoque
-		if err := h.DB.Where("id = ? AND ativo = ? AND quantidade > ?", estoqueID, true, 0).
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND ativo = ? AND quantidade > ?", estoqueID, true, 0).
 			Preload("ProdutoComprado").
 			First(&estoque).Error; err != nil {
-			c.JSON(http.StatusNotFound, gin.H{
-				"message": fmt.Sprintf("Produto %s não encontrado no estoque", produtoReq.Nome),
-			})
-			return
+			return nil, &erroVenda{http.StatusNotFound, fmt.Sprintf("Produto %s não encontrado no estoque", produtoReq.Nome)}
 		}
 
 		quantidadeVendida, err := strconv.Atoi(produtoReq.Quantidade)
+		if err != nil || quantidadeVendida <= 0 {
+			return nil, &erroVenda{http.StatusBadRequest, "Quantidade inválida"}
+		}
+
+		// Quantidades reservadas para outros orçamentos e clientes não podem ser vendidas
+		reservado, err := reservations.Reservado(tx, []int{estoque.ID}, ignorarReservas, time.Now())
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"message": "Quantidade inválida",
-			})
-			return
+			return nil, err
 		}
 
-		if estoque.Quantidade < quantidadeVendida {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"message": fmt.Sprintf("Quantidade insuficiente em estoque para %s", produtoReq.Nome),
-			})
-			return
+		vendidoPorEstoque[estoque.ID] += quantidadeVendida
+		if estoque.Quantidade-reservado[estoque.ID] < vendidoPorEstoque[estoque.ID] {
+			return nil, &erroVenda{http.StatusBadRequest, semEstoque(
+				fmt.Sprintf("Quantidade insuficiente em estoque para %s", produtoReq.Nome), reservado[estoque.ID])}
 		}
 
 		produtosEstoque = append(produtosEstoque, ProdutoEstoqueComVenda{
@@ -118,69 +230,94 @@ func (h *SaleHandler) Cadastrar(c *gin.Context) {
 		})
 	}
 
+	if reserva != nil && vendidoPorEstoque[reserva.EstoqueID] == 0 {
+		return nil, &erroVenda{http.StatusBadRequest, "O produto reservado não está na venda"}
+	}
+
 	// Calcular valores
-	var valorTotal float64
+	var valorTotal money.Dinheiro
 	produtosComPrecos := make([]map[string]interface{}, 0)
 
-	// Buscar precificações para os produtos vendidos
+	// Buscar precificações para os produtos vendidos (por SKU e, nos produtos sem SKU, por nome)
 	nomesProdutos := make([]string, 0)
+	skuIDs := make([]int, 0)
 	for _, p := range produtosEstoque {
 		nomesProdutos = append(nomesProdutos, p.Estoque.ProdutoComprado.Nome)
+		if p.Estoque.ProdutoComprado.SKUID != nil {
+			skuIDs = append(skuIDs, *p.Estoque.ProdutoComprado.SKUID)
+		}
 	}
 
 	var precificacoes []models.Precificacao
-	h.DB.Where("nomeProduto IN ?", nomesProdutos).Find(&precificacoes)
+	if err := tx.Where("nomeProduto IN ? OR skuId IN ?", nomesProdutos, skuIDs).Find(&precificacoes).Error; err != nil {
+		return nil, err
+	}
+
+	// Preços válidos no momento da venda (versões agendadas já em vigor, mesmo antes da tarefa aplicá-las)
+	valoresVigentes, err := pricing.ValoresVigentes(tx, precificacoes, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	// Plano de pagamento da venda; formas sem plano (ex.: crediário) usam o preço do produto
+	planos, err := pricing.CarregarPlanos(tx, true)
+	if err != nil {
+		return nil, err
+	}
+	plano, temPlano := pricing.BuscarPlano(planos, formaPagamento)
 
 	precificacaoMap := make(map[string]models.Precificacao)
+	precificacaoPorSKU := make(map[int]models.Precificacao)
 	for _, p := range precificacoes {
-		precificacaoMap[p.NomeProduto] = p
+		if p.SKUID != nil {
+			precificacaoPorSKU[*p.SKUID] = p
+		} else {
+			precificacaoMap[p.NomeProduto] = p
+		}
 	}
 
+	precosTabela := make([]money.Dinheiro, len(req.Produtos))
 	for i, produtoReq := range req.Produtos {
-		var precoUnitario float64
+		var precoUnitario money.Dinheiro
 		itemEstoque := produtosEstoque[i]
 		nomeProduto := itemEstoque.Estoque.ProdutoComprado.Nome
 
+		prec, exists := precificacaoMap[nomeProduto]
+		if skuID := itemEstoque.Estoque.ProdutoComprado.SKUID; skuID != nil {
+			if precSKU, ok := precificacaoPorSKU[*skuID]; ok {
+				prec, exists = precSKU, true
+			}
+		}
+
+		// Preço de tabela: precificação centralizada do plano de pagamento
+		precoTabela := itemEstoque.Estoque.ProdutoComprado.Preco
+		if exists && temPlano {
+			// Se o preço na precificação for 0, usa o preco do produto como fallback
+			if preco := valoresVigentes[prec.ID].Preco(plano); preco > 0 {
+				precoTabela = preco
+			}
+		}
+		precosTabela[i] = precoTabela
+		precoUnitario = precoTabela
+
 		if produtoReq.UsarPrecoPersonalizado && produtoReq.PrecoPersonalizado != nil {
 			// Parse do preço personalizado (remover formatação)
 			precoStr := *produtoReq.PrecoPersonalizado
 			precoStr = removeFormatting(precoStr)
-			var err error
-			precoUnitario, err = utils.ParseFloatBR(precoStr)
-			if err != nil {
-				precoUnitario = itemEstoque.Estoque.ProdutoComprado.Preco
-			}
-		} else {
-			// Usar precificação centralizada
-			if prec, exists := precificacaoMap[nomeProduto]; exists {
-				switch req.FormaPagamento {
-				case "pix", "dinheiro":
-					precoUnitario = prec.ValorDinheiroPix
-				case "debito":
-					precoUnitario = prec.ValorDebito
-				case "credito_vista":
-					precoUnitario = prec.ValorCartaoVista
-				case "credito_5x":
-					precoUnitario = prec.ValorCredito5x
-				case "credito_10x":
-					precoUnitario = prec.ValorCredito10x
-				case "credito_12x":
-					precoUnitario = prec.ValorCredito12x
-				default:
-					precoUnitario = itemEstoque.Estoque.ProdutoComprado.Preco
-				}
-				
-				// Se o preço na precificação for 0, usa o preco do produto como fallback
-				if precoUnitario == 0 {
-					precoUnitario = itemEstoque.Estoque.ProdutoComprado.Preco
+			if preco, err := utils.ParseFloatBR(precoStr); err == nil {
+				if precoUnitario, err = money.DeReais(preco); err != nil {
+					return nil, &erroVenda{http.StatusBadRequest, fmt.Sprintf("Preço personalizado inválido em %s", nomeProduto)}
 				}
-			} else {
-				precoUnitario = itemEstoque.Estoque.ProdutoComprado.Preco
 			}
+		} else if produtoReq.Desconto != nil {
+			if err := produtoReq.Desconto.Validar(); err != nil {
+				return nil, &erroVenda{http.StatusBadRequest, fmt.Sprintf("Desconto inválido em %s: %s", nomeProduto, err.Error())}
+			}
+			precoUnitario = precoTabela - produtoReq.Desconto.Sobre(precoTabela)
 		}
 
-		quantidade, _ := strconv.Atoi(produtoReq.Quantidade)
-		subtotal := precoUnitario * float64(quantidade)
+		quantidade := itemEstoque.QuantidadeVendida
+		subtotal := precoUnitario.Vezes(quantidade)
 		valorTotal += subtotal
 
 		produtosComPrecos = append(produtosComPrecos, map[string]interface{}{
@@ -192,6 +329,103 @@ func (h *SaleHandler) Cadastrar(c *gin.Context) {
 		})
 	}
 
+	// Descontos sobre o total: primeiro o da venda, depois o cupom sobre o que sobrou
+	subtotal := valorTotal
+	var descontoVenda, descontoCupom money.Dinheiro
+	if req.Desconto != nil {
+		if err := req.Desconto.Validar(); err != nil {
+			return nil, &erroVenda{http.StatusBadRequest, "Desconto inválido: " + err.Error()}
+		}
+		descontoVenda = req.Desconto.Sobre(subtotal)
+	}
+
+	var cupom *models.Cupom
+	if strings.TrimSpace(req.CupomCodigo) != "" {
+		var err error
+		cupom, err = discounts.BuscarCupom(tx.Clauses(clause.Locking{Strength: "UPDATE"}), req.CupomCodigo)
+		if err == nil {
+			descontoCupom, err = discounts.ValidarCupom(cupom, subtotal-descontoVenda, time.Now())
+		}
+		if errors.Is(err, discounts.ErrCupomInvalido) {
+			return nil, &erroVenda{http.StatusBadRequest, "Cupom " + discounts.NormalizarCodigo(req.CupomCodigo) + ": " + err.Error()}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	valorTotal = subtotal - descontoVenda - descontoCupom
+
+	// Limite de desconto do papel: conta o desconto dado pelo vendedor (itens e venda), não o cupom
+	var valorTabela, descontoItens money.Dinheiro
+	for i, p := range produtosComPrecos {
+		quantidade := p["quantidade"].(int)
+		valorTabela += precosTabela[i].Vezes(quantidade)
+		descontoItens += discounts.DoItem(precosTabela[i], p["precoUnitario"].(money.Dinheiro), quantidade)
+	}
+	percentualDesconto := discounts.Percentual(descontoItens+descontoVenda, valorTabela)
+
+	var aprovacao *models.AprovacaoDesconto
+	limite, temLimite, err := discounts.LimiteDoPapel(tx, discounts.Papel(operador))
+	if err != nil {
+		return nil, err
+	}
+	if discounts.AcimaDoLimite(percentualDesconto, limite, temLimite) {
+		mensagem := fmt.Sprintf("Desconto de %s%% acima do limite de %s%%. Solicite a aprovação do admin",
+			utils.FormatFloatBR(percentualDesconto, 2), utils.FormatFloatBR(limite, 2))
+		if req.AprovacaoDescontoID == nil {
+			return nil, &erroVenda{http.StatusForbidden, mensagem}
+		}
+		aprovacao = &models.AprovacaoDesconto{}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(aprovacao, *req.AprovacaoDescontoID).Error; err != nil {
+			return nil, &erroVenda{http.StatusForbidden, "Aprovação de desconto não encontrada"}
+		}
+		if aprovacao.Status != discounts.AprovacaoAprovada ||
+			(aprovacao.SolicitanteID != operador.ID && aprovacao.SolicitanteID != req.UsuarioID) {
+			return nil, &erroVenda{http.StatusForbidden, "Aprovação de desconto inválida ou já utilizada"}
+		}
+		if percentualDesconto > aprovacao.Percentual {
+			return nil, &erroVenda{http.StatusForbidden, fmt.Sprintf("Desconto de %s%% acima dos %s%% aprovados",
+				utils.FormatFloatBR(percentualDesconto, 2), utils.FormatFloatBR(aprovacao.Percentual, 2))}
+		}
+	}
+
+	// Ratear os descontos da venda e do cupom entre os itens
+	valoresItens := make([]money.Dinheiro, len(produtosComPrecos))
+	for i, p := range produtosComPrecos {
+		valoresItens[i] = p["subtotal"].(money.Dinheiro)
+	}
+	rateiosVenda := discounts.Ratear(descontoVenda, valoresItens)
+	rateiosCupom := discounts.Ratear(descontoCupom, valoresItens)
+
+	// Validar os pagamentos contra o total (vendas no formato antigo viram linhas)
+	linhas := req.Pagamentos
+	valorPix, valorCartao, valorDinheiro := req.ValorPix, req.ValorCartao, req.ValorDinheiro
+	if len(linhas) == 0 {
+		linhas = payments.DaFormaLegada(formaPagamento, req.ValorPix, req.ValorCartao, req.ValorDinheiro, valorTotal)
+	}
+	pagamentosVenda, err := payments.Validar(linhas, valorTotal)
+	if err != nil {
+		return nil, &erroVenda{http.StatusBadRequest, "Pagamento inválido: " + err.Error()}
+	}
+	if len(req.Pagamentos) > 0 {
+		valorPix, valorCartao, valorDinheiro = payments.Resumo(pagamentosVenda)
+	}
+
+	// O sinal só pode ser usado com a reserva do cliente, até o valor pago nela
+	var sinal money.Dinheiro
+	for _, p := range pagamentosVenda {
+		if p.Metodo == payments.MetodoSinal {
+			sinal += p.Valor
+		}
+	}
+	if sinal > 0 && reserva == nil {
+		return nil, &erroVenda{http.StatusBadRequest, "Pagamento inválido: informe a reserva (reservaId) para usar o sinal"}
+	}
+	if reserva != nil && sinal > reserva.ValorSinal {
+		return nil, &erroVenda{http.StatusBadRequest, fmt.Sprintf("Pagamento inválido: o sinal da reserva é de R$ %s",
+			reserva.ValorSinal.BR())}
+	}
+
 	// Gerar ID único para a venda
 	vendaID := fmt.Sprintf("venda_%d_%s", time.Now().Unix(), randomString(9))
 
@@ -201,7 +435,11 @@ func (h *SaleHandler) Cadastrar(c *gin.Context) {
 	for i, produtoComPreco := range produtosComPrecos {
 		produtoEstoque := produtosEstoque[i]
 		quantidade := int(produtoComPreco["quantidade"].(int))
-		precoUnitario := produtoComPreco["precoUnitario"].(float64)
+		precoUnitario := produtoComPreco["precoUnitario"].(money.Dinheiro)
+		descontoItem := (precosTabela[i] - precoUnitario).Vezes(quantidade)
+		if descontoItem < 0 {
+			descontoItem = 0
+		}
 
 
 		historicoVenda := models.HistoricoVenda{
@@ -216,30 +454,98 @@ func (h *SaleHandler) Cadastrar(c *gin.Context) {
 			Observacoes:    req.Observacoes,
 			VendedorNome:   vendedor.Nome,
 			VendedorEmail:  vendedor.Email,
-			FormaPagamento: req.FormaPagamento,
-			ValorPix:       req.ValorPix,
-			ValorCartao:    req.ValorCartao,
-			ValorDinheiro:  req.ValorDinheiro,
+			FormaPagamento: formaPagamento,
+			ValorPix:       valorPix,
+			ValorCartao:    valorCartao,
+			ValorDinheiro:  valorDinheiro,
 			FotoProduto:    req.FotoProduto,
 			TipoCliente:    req.TipoCliente,
 			EstoqueID:      produtoEstoque.Estoque.ID,
 			UsuarioID:      req.UsuarioID,
+			LojaID:         vendedor.LojaID,
+			PrecoTabela:    precosTabela[i],
+			DescontoItem:   descontoItem,
+			DescontoVenda:  rateiosVenda[i],
+			DescontoCupom:  rateiosCupom[i],
+			MotivoDesconto: req.MotivoDesconto,
+		}
+		if cupom != nil {
+			historicoVenda.CupomCodigo = &cupom.Codigo
+		}
+		if aprovacao != nil {
+			historicoVenda.AprovacaoDescontoID = &aprovacao.ID
 		}
 
-		if err := h.DB.Create(&historicoVenda).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"message": "Erro ao registrar venda",
-			})
-			return
+		if err := tx.Create(&historicoVenda).Error; err != nil {
+			return nil, err
 		}
 
 		historicoVendas = append(historicoVendas, historicoVenda)
 	}
 
+	for i := range pagamentosVenda {
+		pagamentosVenda[i].VendaID = vendaID
+	}
+	if err := tx.Create(&pagamentosVenda).Error; err != nil {
+		return nil, err
+	}
+	// O dinheiro recebido entra no caixa aberto do vendedor; cartão, crediário e boleto viram recebíveis
+	if err := sincronizarPagamentos(tx, vendaID); err != nil {
+		return nil, err
+	}
+
+	// Aparelhos recebidos em troca entram no estoque de seminovos (as linhas seguem a ordem de pagamentosVenda)
+	trocas := make([]models.AparelhoTroca, 0)
+	for i, l := range linhas {
+		if pagamentosVenda[i].Metodo != payments.MetodoTroca {
+			continue
+		}
+		troca, err := tradein.Receber(tx, *l.Aparelho, pagamentosVenda[i], req.ClienteNome, vendedor)
+		if errors.Is(err, tradein.ErrIMEIDuplicado) {
+			return nil, &erroVenda{http.StatusBadRequest, fmt.Sprintf("Pagamento %d: %s", i+1, err.Error())}
+		}
+		if err != nil {
+			return nil, err
+		}
+		trocas = append(trocas, *troca)
+	}
+
+	if cupom != nil {
+		if err := discounts.UsarCupom(tx, cupom); err != nil {
+			if errors.Is(err, discounts.ErrCupomInvalido) {
+				return nil, &erroVenda{http.StatusBadRequest, "Cupom " + cupom.Codigo + ": " + err.Error()}
+			}
+			return nil, err
+		}
+	}
+	if reserva != nil {
+		campos := map[string]interface{}{
+			"status":      reservations.StatusConvertida,
+			"vendaId":     vendaID,
+			"encerradaEm": time.Now(),
+		}
+		if reserva.ValorSinal > 0 {
+			campos["destinoSinal"] = reservations.SinalAbatido
+		}
+		if err := tx.Model(reserva).Updates(campos).Error; err != nil {
+			return nil, err
+		}
+	}
+	if aprovacao != nil {
+		if err := tx.Model(aprovacao).Updates(map[string]interface{}{
+			"status":  discounts.AprovacaoUsada,
+			"vendaId": vendaID,
+		}).Error; err != nil {
+			return nil, err
+		}
+	}
+
 	// Atualizar estoque
 	for _, produtoEstoque := range produtosEstoque {
-		h.DB.Model(&produtoEstoque.Estoque).
-			Update("quantidade", produtoEstoque.Estoque.Quantidade-produtoEstoque.QuantidadeVendida)
+		if err := tx.Model(&models.Estoque{}).Where("id = ?", produtoEstoque.Estoque.ID).
+			Update("quantidade", gorm.Expr("quantidade - ?", produtoEstoque.QuantidadeVendida)).Error; err != nil {
+			return nil, err
+		}
 	}
 
 	// Formatar resposta
@@ -249,22 +555,27 @@ func (h *SaleHandler) Cadastrar(c *gin.Context) {
 			"id":            p["produto"],
 			"nome":          p["nome"],
 			"quantidade":    p["quantidade"],
+			"precoTabela":   precosTabela[i],
 			"precoUnitario": p["precoUnitario"],
 			"subtotal":      p["subtotal"],
 		}
 	}
 
-	c.JSON(http.StatusCreated, gin.H{
-		"success": true,
-		"message": "Venda cadastrada com sucesso!",
-		"data": map[string]interface{}{
-			"venda": map[string]interface{}{
-				"clienteNome": req.ClienteNome,
-				"produtos":    produtosResposta,
-				"valorTotal":  valorTotal,
-			},
+	return &vendaRegistrada{
+		VendaID:    vendaID,
+		Produtos:   produtosResposta,
+		ValorTotal: valorTotal,
+		Descontos: gin.H{
+			"valorTabela": valorTabela,
+			"itens":       descontoItens,
+			"venda":       descontoVenda,
+			"cupom":       descontoCupom,
+			"percentual":  discounts.Percentual(descontoItens+descontoVenda+descontoCupom, valorTabela),
 		},
-	})
+		Pagamentos: pagamentosVenda,
+		Trocas:     trocas,
+		Historico:  historicoVendas,
+	}, nil
 }
 
 func (h *SaleHandler) Historico(c *gin.Context) {
@@ -294,13 +605,8 @@ func (h *SaleHandler) Historico(c *gin.Context) {
 		query = query.Where("clienteNome LIKE ?", "%"+cliente+"%")
 	}
 
-	// Filtro por data
-	if dataInicio != "" {
-		query = query.Where("createdAt >= ?", dataInicio)
-	}
-	if dataFim != "" {
-		query = query.Where("createdAt <= ?", dataFim+" 23:59:59")
-	}
+	// Filtro por data (dias no fuso do negócio)
+	query = filtrarPeriodo(query, "createdAt", dataInicio, dataFim)
 
 	// Contar total
 	var total int64
@@ -323,7 +629,8 @@ func (h *SaleHandler) Historico(c *gin.Context) {
 	// Buscar vendas ordenadas por data/hora (mais recente primeiro por padrão)
 	var historico []models.HistoricoVenda
 	if err := query.Preload("Usuario").
-		Preload("Estoque.ProdutoComprado").
+		Preload("Estoque", incluirLixeira).
+		Preload("Estoque.ProdutoComprado", incluirLixeira).
 		Order(orderBy).
 		Offset(offset).
 		Limit(limite * 10). // Buscar mais registros para agrupar corretamente
@@ -339,7 +646,7 @@ func (h *SaleHandler) Historico(c *gin.Context) {
 	type VendaAgrupada struct {
 		VendaID string
 		Data    time.Time
-		Valor   float64
+		Valor   money.Dinheiro
 		Venda   *map[string]interface{}
 	}
 	vendasAgrupadas := make(map[string]*VendaAgrupada)
@@ -426,8 +733,8 @@ func (h *SaleHandler) Historico(c *gin.Context) {
 		// Ordenar por valor total DESC
 		for i := 0; i < len(vendasFormatadas)-1; i++ {
 			for j := i + 1; j < len(vendasFormatadas); j++ {
-				valI := vendasFormatadas[i]["valorTotal"].(float64)
-				valJ := vendasFormatadas[j]["valorTotal"].(float64)
+				valI := vendasFormatadas[i]["valorTotal"].(money.Dinheiro)
+				valJ := vendasFormatadas[j]["valorTotal"].(money.Dinheiro)
 				if valI < valJ {
 					vendasFormatadas[i], vendasFormatadas[j] = vendasFormatadas[j], vendasFormatadas[i]
 				}
@@ -437,8 +744,8 @@ func (h *SaleHandler) Historico(c *gin.Context) {
 		// Ordenar por valor total ASC
 		for i := 0; i < len(vendasFormatadas)-1; i++ {
 			for j := i + 1; j < len(vendasFormatadas); j++ {
-				valI := vendasFormatadas[i]["valorTotal"].(float64)
-				valJ := vendasFormatadas[j]["valorTotal"].(float64)
+				valI := vendasFormatadas[i]["valorTotal"].(money.Dinheiro)
+				valJ := vendasFormatadas[j]["valorTotal"].(money.Dinheiro)
 				if valI > valJ {
 					vendasFormatadas[i], vendasFormatadas[j] = vendasFormatadas[j], vendasFormatadas[i]
 				}
@@ -468,34 +775,11 @@ func (h *SaleHandler) HistoricoAdmin(c *gin.Context) {
 	pagina, _ := strconv.Atoi(c.DefaultQuery("pagina", "1"))
 	limite, _ := strconv.Atoi(c.DefaultQuery("limite", "10"))
 	ordenacao := c.DefaultQuery("ordenacao", "data")
-	cliente := c.Query("cliente")
 	imeiCodigo := c.Query("imeiCodigo")
-	dataInicio := c.Query("dataInicio")
-	dataFim := c.Query("dataFim")
 
 	offset := (pagina - 1) * limite
 
-	query := h.DB.Model(&models.HistoricoVenda{})
-
-	// Filtro por cliente
-	if cliente != "" {
-		query = query.Where("clienteNome LIKE ?", "%"+cliente+"%")
-	}
-
-	// Filtro por IMEI/código de barras
-	if imeiCodigo != "" {
-		query = query.Joins("JOIN Estoque ON HistoricoVenda.estoqueId = Estoque.id").
-			Joins("JOIN ProdutoComprado ON Estoque.produtoCompradoId = ProdutoComprado.id").
-			Where("ProdutoComprado.imei LIKE ? OR ProdutoComprado.codigoBarras LIKE ?", "%"+imeiCodigo+"%", "%"+imeiCodigo+"%")
-	}
-
-	// Filtro por data
-	if dataInicio != "" {
-		query = query.Where("HistoricoVenda.createdAt >= ?", dataInicio)
-	}
-	if dataFim != "" {
-		query = query.Where("HistoricoVenda.createdAt <= ?", dataFim+" 23:59:59")
-	}
+	query := filtrarHistoricoVendas(h.DB.Model(&models.HistoricoVenda{}), c)
 
 	// Contar total
 	var total int64
@@ -513,7 +797,8 @@ func (h *SaleHandler) HistoricoAdmin(c *gin.Context) {
 	// Buscar vendas
 	var historico []models.HistoricoVenda
 	if err := query.Preload("Usuario").
-		Preload("Estoque.ProdutoComprado").
+		Preload("Estoque", incluirLixeira).
+		Preload("Estoque.ProdutoComprado", incluirLixeira).
 		Order(orderBy).
 		Offset(offset).
 		Limit(limite).
@@ -552,7 +837,7 @@ func (h *SaleHandler) HistoricoAdmin(c *gin.Context) {
 		if _, exists := vendasAgrupadas[vendaID]; !exists {
 			vendaIDsAppeared = append(vendaIDsAppeared, vendaID) // Salva ID na ordem que aparece
 			
-			valorTotal := 0.0
+			var valorTotal money.Dinheiro
 			var primeiraVenda models.HistoricoVenda
 			if err := h.DB.Where("vendaId = ?", vendaID).First(&primeiraVenda).Error; err == nil {
 				valorTotal = primeiraVenda.ValorTotal
@@ -639,19 +924,40 @@ func (h *SaleHandler) HistoricoAdmin(c *gin.Context) {
 	})
 }
 
+// filtrarHistoricoVendas aplica os filtros do histórico de vendas do admin (cliente, IMEI/código, período).
+// Compartilhado entre o histórico e a exportação.
+func filtrarHistoricoVendas(query *gorm.DB, c *gin.Context) *gorm.DB {
+	cliente := c.Query("cliente")
+	imeiCodigo := c.Query("imeiCodigo")
+	dataInicio := c.Query("dataInicio")
+	dataFim := c.Query("dataFim")
+
+	// Filtro por cliente
+	if cliente != "" {
+		query = query.Where("HistoricoVenda.clienteNome LIKE ?", "%"+cliente+"%")
+	}
+
+	// Filtro por IMEI/código de barras
+	if imeiCodigo != "" {
+		query = query.Joins("JOIN Estoque ON HistoricoVenda.estoqueId = Estoque.id").
+			Joins("JOIN ProdutoComprado ON Estoque.produtoCompradoId = ProdutoComprado.id").
+			Where("ProdutoComprado.imei LIKE ? OR ProdutoComprado.codigoBarras LIKE ?", "%"+imeiCodigo+"%", "%"+imeiCodigo+"%")
+	}
+
+	// Filtro por data (dias no fuso do negócio)
+	query = filtrarPeriodo(query, "HistoricoVenda.createdAt", dataInicio, dataFim)
+
+	return query
+}
+
 func (h *SaleHandler) ResumoVendedores(c *gin.Context) {
 	dataInicio := c.Query("dataInicio")
 	dataFim := c.Query("dataFim")
 
 	query := h.DB.Model(&models.HistoricoVenda{})
 
-	// Filtro por data
-	if dataInicio != "" {
-		query = query.Where("createdAt >= ?", dataInicio)
-	}
-	if dataFim != "" {
-		query = query.Where("createdAt <= ?", dataFim+" 23:59:59")
-	}
+	// Filtro por data (dias no fuso do negócio)
+	query = filtrarPeriodo(query, "createdAt", dataInicio, dataFim)
 
 	var vendas []models.HistoricoVenda
 	if err := query.Select("vendedorNome, vendedorEmail, valorTotal, quantidade, produtoNome").
@@ -675,7 +981,7 @@ func (h *SaleHandler) ResumoVendedores(c *gin.Context) {
 				"vendedorNome":      venda.VendedorNome,
 				"vendedorEmail":      venda.VendedorEmail,
 				"totalVendas":        0,
-				"totalValor":         0.0,
+				"totalValor":         money.Dinheiro(0),
 				"quantidadeProdutos": 0,
 			}
 			resumoPorVendedor[chave] = &resumoMap
@@ -683,7 +989,7 @@ func (h *SaleHandler) ResumoVendedores(c *gin.Context) {
 		}
 
 		(*resumoPorVendedor[chave])["totalVendas"] = (*resumoPorVendedor[chave])["totalVendas"].(int) + 1
-		(*resumoPorVendedor[chave])["totalValor"] = (*resumoPorVendedor[chave])["totalValor"].(float64) + venda.ValorTotal
+		(*resumoPorVendedor[chave])["totalValor"] = (*resumoPorVendedor[chave])["totalValor"].(money.Dinheiro) + venda.ValorTotal
 		(*resumoPorVendedor[chave])["quantidadeProdutos"] = (*resumoPorVendedor[chave])["quantidadeProdutos"].(int) + venda.Quantidade
 
 		// Contar produtos únicos
@@ -703,7 +1009,7 @@ func (h *SaleHandler) ResumoVendedores(c *gin.Context) {
 	// Ordenar por valor total (maior primeiro)
 	for i := 0; i < len(resumoArray)-1; i++ {
 		for j := i + 1; j < len(resumoArray); j++ {
-			if resumoArray[i]["totalValor"].(float64) < resumoArray[j]["totalValor"].(float64) {
+			if resumoArray[i]["totalValor"].(money.Dinheiro) < resumoArray[j]["totalValor"].(money.Dinheiro) {
 				resumoArray[i], resumoArray[j] = resumoArray[j], resumoArray[i]
 			}
 		}
@@ -715,6 +1021,210 @@ func (h *SaleHandler) ResumoVendedores(c *gin.Context) {
 	})
 }
 
+// ListarTrocas lista os aparelhos recebidos em troca, com a situação no estoque e a margem da revenda.
+// Filtros: dataInicio, dataFim, usuarioId
+func (h *SaleHandler) ListarTrocas(c *gin.Context) {
+	query := h.DB.Model(&models.AparelhoTroca{})
+	query = filtrarPeriodo(query, "createdAt", c.Query("dataInicio"), c.Query("dataFim"))
+	if usuarioID := c.Query("usuarioId"); usuarioID != "" {
+		query = query.Where("usuarioId = ?", usuarioID)
+	}
+
+	var trocas []models.AparelhoTroca
+	if err := query.Order("createdAt DESC").Find(&trocas).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao buscar trocas",
+		})
+		return
+	}
+
+	produtoIDs := make([]int, len(trocas))
+	for i, t := range trocas {
+		produtoIDs[i] = t.ProdutoCompradoID
+	}
+
+	// Revenda de cada aparelho: vendas dos estoques criados a partir do produto
+	type revenda struct {
+		ProdutoCompradoID int            `gorm:"column:produtoCompradoId"`
+		Quantidade        int            `gorm:"column:quantidade"`
+		Receita           money.Dinheiro `gorm:"column:receita"`
+	}
+	var revendas []revenda
+	if len(produtoIDs) > 0 {
+		if err := h.DB.Table("HistoricoVenda hv").
+			Select("e.produtoCompradoId, SUM(hv.quantidade) as quantidade, "+
+				"SUM(hv.precoUnitario * hv.quantidade - hv.descontoVenda - hv.descontoCupom) as receita").
+			Joins("JOIN Estoque e ON e.id = hv.estoqueId").
+			Where("e.produtoCompradoId IN ?", produtoIDs).
+			Group("e.produtoCompradoId").
+			Scan(&revendas).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "Erro ao buscar revendas",
+			})
+			return
+		}
+	}
+	porProduto := make(map[int]revenda, len(revendas))
+	for _, r := range revendas {
+		porProduto[r.ProdutoCompradoID] = r
+	}
+
+	var totalAvaliado, totalReceita, totalMargem money.Dinheiro
+	lista := make([]gin.H, len(trocas))
+	for i, t := range trocas {
+		situacao := "em_estoque"
+		var receita, margem money.Dinheiro
+		if r, ok := porProduto[t.ProdutoCompradoID]; ok && r.Quantidade > 0 {
+			situacao = "revendido"
+			receita = r.Receita
+			margem = receita - t.ValorAvaliado
+			totalReceita += receita
+			totalMargem += margem
+		}
+		totalAvaliado += t.ValorAvaliado
+		lista[i] = gin.H{
+			"troca":        t,
+			"situacao":     situacao,
+			"valorRevenda": receita,
+			"margem":       margem,
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"trocas": lista,
+			"totais": gin.H{
+				"aparelhos":     len(trocas),
+				"valorAvaliado": totalAvaliado,
+				"valorRevenda":  totalReceita,
+				"margem":        totalMargem,
+			},
+		},
+	})
+}
+
+// ResumoPagamentos soma os valores recebidos por método de pagamento, por parcelas no crédito e por bandeira.
+// Vendas anteriores às linhas de pagamento entram pelos campos antigos (valorPix, valorCartao, valorDinheiro).
+// Filtros: dataInicio, dataFim, usuarioId
+func (h *SaleHandler) ResumoPagamentos(c *gin.Context) {
+	dataInicio := c.Query("dataInicio")
+	dataFim := c.Query("dataFim")
+	usuarioID := c.Query("usuarioId")
+
+	vendasFiltradas := h.DB.Model(&models.HistoricoVenda{}).Select("vendaId").Where("vendaId IS NOT NULL")
+	vendasFiltradas = filtrarPeriodo(vendasFiltradas, "createdAt", dataInicio, dataFim)
+	if usuarioID != "" {
+		vendasFiltradas = vendasFiltradas.Where("usuarioId = ?", usuarioID)
+	}
+
+	var linhas []models.PagamentoVenda
+	if err := h.DB.Where("vendaId IN (?)", vendasFiltradas).Find(&linhas).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao buscar pagamentos",
+		})
+		return
+	}
+
+	// Vendas sem linhas de pagamento (registradas antes delas)
+	type vendaAntiga struct {
+		VendaID        string          `gorm:"column:vendaId"`
+		FormaPagamento string          `gorm:"column:formaPagamento"`
+		ValorPix       *money.Dinheiro `gorm:"column:valorPix"`
+		ValorCartao    *money.Dinheiro `gorm:"column:valorCartao"`
+		ValorDinheiro  *money.Dinheiro `gorm:"column:valorDinheiro"`
+		ValorTotal     money.Dinheiro  `gorm:"column:valorTotal"`
+	}
+	var antigas []vendaAntiga
+	if err := h.DB.Model(&models.HistoricoVenda{}).
+		Select("vendaId, MAX(formaPagamento) as formaPagamento, MAX(valorPix) as valorPix, "+
+			"MAX(valorCartao) as valorCartao, MAX(valorDinheiro) as valorDinheiro, MAX(valorTotal) as valorTotal").
+		Where("vendaId IN (?)", vendasFiltradas).
+		Where("NOT EXISTS (SELECT 1 FROM PagamentoVenda pv WHERE pv.vendaId = HistoricoVenda.vendaId)").
+		Group("vendaId").
+		Scan(&antigas).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao buscar pagamentos",
+		})
+		return
+	}
+	for _, v := range antigas {
+		for _, l := range payments.DaFormaLegada(v.FormaPagamento, v.ValorPix, v.ValorCartao, v.ValorDinheiro, v.ValorTotal) {
+			linhas = append(linhas, models.PagamentoVenda{
+				VendaID:  v.VendaID,
+				Metodo:   l.Metodo,
+				Valor:    l.Valor,
+				Parcelas: l.Parcelas,
+			})
+		}
+	}
+
+	type totalGrupo struct {
+		Chave  string         `json:"chave"`
+		Total  money.Dinheiro `json:"total"`
+		Linhas int            `json:"linhas"`
+		Vendas int            `json:"vendas"`
+
+		vendas map[string]bool
+	}
+	somar := func(grupos map[string]*totalGrupo, ordem *[]string, chave string, p models.PagamentoVenda) {
+		g, ok := grupos[chave]
+		if !ok {
+			g = &totalGrupo{Chave: chave, vendas: make(map[string]bool)}
+			grupos[chave] = g
+			*ordem = append(*ordem, chave)
+		}
+		g.Total += p.Valor
+		g.Linhas++
+		if !g.vendas[p.VendaID] {
+			g.vendas[p.VendaID] = true
+			g.Vendas++
+		}
+	}
+
+	porMetodo, porParcelas, porBandeira := make(map[string]*totalGrupo), make(map[string]*totalGrupo), make(map[string]*totalGrupo)
+	var ordemMetodo, ordemParcelas, ordemBandeira []string
+	var total, troco money.Dinheiro
+	vendas := make(map[string]bool)
+	for _, p := range linhas {
+		total += p.Valor
+		troco += p.Troco
+		vendas[p.VendaID] = true
+		somar(porMetodo, &ordemMetodo, p.Metodo, p)
+		if p.Metodo == payments.MetodoCredito {
+			somar(porParcelas, &ordemParcelas, strconv.Itoa(p.Parcelas)+"x", p)
+		}
+		if p.Bandeira != nil {
+			somar(porBandeira, &ordemBandeira, *p.Bandeira, p)
+		}
+	}
+
+	listar := func(grupos map[string]*totalGrupo, ordem []string) []*totalGrupo {
+		lista := make([]*totalGrupo, 0, len(ordem))
+		for _, chave := range ordem {
+			lista = append(lista, grupos[chave])
+		}
+		sort.Slice(lista, func(i, j int) bool { return lista[i].Total > lista[j].Total })
+		return lista
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"total":       total,
+			"troco":       troco,
+			"vendas":      len(vendas),
+			"porMetodo":   listar(porMetodo, ordemMetodo),
+			"porParcelas": listar(porParcelas, ordemParcelas),
+			"porBandeira": listar(porBandeira, ordemBandeira),
+		},
+	})
+}
+
 func (h *SaleHandler) BuscarPorID(c *gin.Context) {
 	id := c.Param("id")
 	vendaIDInt, err := strconv.Atoi(id)
@@ -745,7 +1255,8 @@ func (h *SaleHandler) BuscarPorID(c *gin.Context) {
 
 	if err := h.DB.Where("vendaId = ?", vendaIDStr).
 		Preload("Usuario").
-		Preload("Estoque.ProdutoComprado").
+		Preload("Estoque", incluirLixeira).
+		Preload("Estoque.ProdutoComprado", incluirLixeira).
 		Order("id ASC").
 		Find(&vendas).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -765,6 +1276,24 @@ func (h *SaleHandler) BuscarPorID(c *gin.Context) {
 
 	vendaBase := vendas[0]
 
+	var pagamentos []models.PagamentoVenda
+	if err := h.DB.Where("vendaId = ?", vendaIDStr).Order("id ASC").Find(&pagamentos).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao buscar pagamentos da venda",
+		})
+		return
+	}
+
+	var trocas []models.AparelhoTroca
+	if err := h.DB.Where("vendaId = ?", vendaIDStr).Order("id ASC").Find(&trocas).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao buscar aparelhos recebidos em troca",
+		})
+		return
+	}
+
 	// Formatar produtos
 	produtos := make([]map[string]interface{}, len(vendas))
 	for i, venda := range vendas {
@@ -773,6 +1302,10 @@ func (h *SaleHandler) BuscarPorID(c *gin.Context) {
 			"produtoNome":   venda.ProdutoNome,
 			"quantidade":    venda.Quantidade,
 			"precoUnitario": venda.PrecoUnitario,
+			"precoTabela":   venda.PrecoTabela,
+			"descontoItem":  venda.DescontoItem,
+			"descontoVenda": venda.DescontoVenda,
+			"descontoCupom": venda.DescontoCupom,
 		}
 
 		if venda.Estoque.ProdutoComprado.IMEI != nil || venda.Estoque.ProdutoComprado.Cor != nil {
@@ -793,9 +1326,9 @@ func (h *SaleHandler) BuscarPorID(c *gin.Context) {
 	}
 
 	// Calcular valor total somando todos os produtos
-	valorTotalCalculado := 0.0
+	var valorTotalCalculado money.Dinheiro
 	for _, venda := range vendas {
-		valorTotalCalculado += venda.PrecoUnitario * float64(venda.Quantidade)
+		valorTotalCalculado += venda.PrecoUnitario.Vezes(venda.Quantidade) - venda.DescontoVenda - venda.DescontoCupom
 	}
 
 	vendaFormatada := map[string]interface{}{
@@ -813,6 +1346,11 @@ func (h *SaleHandler) BuscarPorID(c *gin.Context) {
 		"valorPix":       vendaBase.ValorPix,
 		"valorCartao":    vendaBase.ValorCartao,
 		"valorDinheiro":  vendaBase.ValorDinheiro,
+		"pagamentos":     pagamentos,
+		"troco":          payments.Troco(pagamentos),
+		"trocas":         trocas,
+		"cupomCodigo":    vendaBase.CupomCodigo,
+		"motivoDesconto": vendaBase.MotivoDesconto,
 		"produtos":       produtos,
 		"valorTotal":     valorTotalCalculado,
 		"transferida":      vendaBase.Transferida,
@@ -843,19 +1381,153 @@ func (h *SaleHandler) buscarPrimeiroRegistroVenda(id int) (*models.HistoricoVend
 	return &registro, nil
 }
 
-// recalcularValorTotalVenda recalcula e atualiza o valor total da venda
-func (h *SaleHandler) recalcularValorTotalVenda(tx *gorm.DB, vendaId string) error {
-	var totalVenda float64
+// sincronizarPagamentos atualiza o caixa e os recebíveis depois que as linhas de pagamento da venda mudam
+func sincronizarPagamentos(tx *gorm.DB, vendaID string) error {
+	if err := cashregister.SincronizarVenda(tx, vendaID); err != nil {
+		return err
+	}
+	return receivables.SincronizarVenda(tx, vendaID)
+}
+
+// recalcularValorTotalVenda recalcula e atualiza o valor total da venda. Com uma única forma de pagamento, ela
+// acompanha o novo total, exceto a troca e o sinal, que têm valor definido; nos demais casos, linhas traz as novas
+// formas de pagamento (sem a troca e o sinal, que não mudam) e, sem elas, a alteração é recusada para que os
+// pagamentos continuem somando o total.
+func (h *SaleHandler) recalcularValorTotalVenda(tx *gorm.DB, vendaId string, linhas []payments.Linha) error {
+	var totalVenda money.Dinheiro
 	if err := tx.Model(&models.HistoricoVenda{}).
 		Where("vendaId = ?", vendaId).
-		Select("COALESCE(SUM(precoUnitario * quantidade), 0)").
+		Select("COALESCE(SUM(precoUnitario * quantidade - descontoVenda - descontoCupom), 0)").
 		Scan(&totalVenda).Error; err != nil {
 		return err
 	}
 
-	return tx.Model(&models.HistoricoVenda{}).
+	if err := tx.Model(&models.HistoricoVenda{}).
 		Where("vendaId = ?", vendaId).
-		Update("valorTotal", totalVenda).Error
+		Update("valorTotal", totalVenda).Error; err != nil {
+		return err
+	}
+
+	var pagamentos []models.PagamentoVenda
+	if err := tx.Where("vendaId = ?", vendaId).Order("id ASC").Find(&pagamentos).Error; err != nil {
+		return err
+	}
+	if len(linhas) > 0 {
+		return substituirPagamentos(tx, vendaId, pagamentos, linhas, totalVenda)
+	}
+
+	// Vendas anteriores às linhas de pagamento não têm o que ajustar
+	var soma money.Dinheiro
+	for _, p := range pagamentos {
+		soma += p.Valor
+	}
+	if len(pagamentos) == 0 || soma == totalVenda {
+		return nil
+	}
+
+	// O valor da troca é o avaliado do aparelho (e o custo dele no estoque); o do sinal, o pago na reserva
+	if len(pagamentos) != 1 || linhaFixa(pagamentos[0].Metodo) {
+		var fixas money.Dinheiro
+		for _, p := range pagamentos {
+			if linhaFixa(p.Metodo) {
+				fixas += p.Valor
+			}
+		}
+		// Sobrou só a troca e o sinal: as outras formas de pagamento deixam de existir (abaixo deles, é recusado)
+		if totalVenda <= fixas {
+			return substituirPagamentos(tx, vendaId, pagamentos, nil, totalVenda)
+		}
+		return &erroVenda{http.StatusBadRequest, fmt.Sprintf("Os pagamentos da venda não acompanham o novo total: informe em "+
+			"pagamentos as novas formas (sem a troca e o sinal), somando R$ %s", (totalVenda - fixas).BR())}
+	}
+
+	pagamento := pagamentos[0]
+	pagamento.Valor = totalVenda
+	pagamento.Troco = 0
+	if pagamento.ValorRecebido != nil {
+		if *pagamento.ValorRecebido >= totalVenda {
+			pagamento.Troco = *pagamento.ValorRecebido - totalVenda
+		} else {
+			pagamento.ValorRecebido = nil
+		}
+	}
+	if err := tx.Save(&pagamento).Error; err != nil {
+		return err
+	}
+	return sincronizarPagamentos(tx, vendaId)
+}
+
+// linhaFixa indica as formas de pagamento com valor já definido, que não acompanham mudanças nos itens da venda:
+// o aparelho recebido em troca e o sinal pago na reserva
+func linhaFixa(metodo string) bool {
+	return metodo == payments.MetodoTroca || metodo == payments.MetodoSinal
+}
+
+// substituirPagamentos troca as linhas de pagamento da venda pelas informadas, que com a troca e o sinal
+// (mantidos) devem somar o total
+func substituirPagamentos(tx *gorm.DB, vendaID string, atuais []models.PagamentoVenda, linhas []payments.Linha, total money.Dinheiro) error {
+	var fixas money.Dinheiro
+	for _, p := range atuais {
+		if linhaFixa(p.Metodo) {
+			fixas += p.Valor
+		}
+	}
+	for i, l := range linhas {
+		if linhaFixa(strings.ToLower(strings.TrimSpace(l.Metodo))) {
+			return &erroVenda{http.StatusBadRequest, fmt.Sprintf("Pagamento %d: a troca e o sinal da venda não podem ser alterados", i+1)}
+		}
+	}
+	if total < fixas {
+		return &erroVenda{http.StatusBadRequest, fmt.Sprintf("O novo total da venda (R$ %s) fica abaixo da troca e do sinal já recebidos (R$ %s)",
+			total.BR(), fixas.BR())}
+	}
+
+	novos := make([]models.PagamentoVenda, 0, len(linhas))
+	if total > fixas || len(linhas) > 0 {
+		var err error
+		if novos, err = payments.Validar(linhas, total-fixas); err != nil {
+			return &erroVenda{http.StatusBadRequest, "Pagamento inválido: " + err.Error()}
+		}
+	}
+
+	if err := tx.Where("vendaId = ? AND metodo NOT IN ?", vendaID, []string{payments.MetodoTroca, payments.MetodoSinal}).
+		Delete(&models.PagamentoVenda{}).Error; err != nil {
+		return err
+	}
+	if len(novos) > 0 {
+		for i := range novos {
+			novos[i].VendaID = vendaID
+		}
+		if err := tx.Create(&novos).Error; err != nil {
+			return err
+		}
+	}
+	return atualizarResumoPagamentos(tx, vendaID)
+}
+
+// atualizarResumoPagamentos grava nos campos antigos da venda (formaPagamento, valorPix, valorCartao e
+// valorDinheiro) o resumo das linhas de pagamento e atualiza o caixa e os recebíveis
+func atualizarResumoPagamentos(tx *gorm.DB, vendaID string) error {
+	var pagamentos []models.PagamentoVenda
+	if err := tx.Where("vendaId = ?", vendaID).Order("id ASC").Find(&pagamentos).Error; err != nil {
+		return err
+	}
+	linhas := make([]payments.Linha, 0, len(pagamentos))
+	for _, p := range pagamentos {
+		linhas = append(linhas, payments.Linha{Metodo: p.Metodo, Valor: p.Valor, Parcelas: p.Parcelas})
+	}
+	valorPix, valorCartao, valorDinheiro := payments.Resumo(pagamentos)
+	if err := tx.Model(&models.HistoricoVenda{}).
+		Where("vendaId = ?", vendaID).
+		Updates(map[string]interface{}{
+			"formaPagamento": payments.FormaDasLinhas(linhas),
+			"valorPix":       valorPix,
+			"valorCartao":    valorCartao,
+			"valorDinheiro":  valorDinheiro,
+		}).Error; err != nil {
+		return err
+	}
+	return sincronizarPagamentos(tx, vendaID)
 }
 
 func removeFormatting(s string) string {
@@ -898,9 +1570,10 @@ func contains(s, substr string) bool {
 // TrocarProduto substitui um produto em uma venda
 func (h *SaleHandler) TrocarProduto(c *gin.Context) {
 	var req struct {
-		HistoricoVendaID int `json:"historicoVendaId" binding:"required"`
-		NovoEstoqueID    int `json:"novoEstoqueId" binding:"required"`
-		PrecoUnitario    float64 `json:"precoUnitario"`
+		HistoricoVendaID int              `json:"historicoVendaId" binding:"required"`
+		NovoEstoqueID    int              `json:"novoEstoqueId" binding:"required"`
+		PrecoUnitario    money.Dinheiro   `json:"precoUnitario"`
+		Pagamentos       []payments.Linha `json:"pagamentos"` // Novas formas de pagamento, quando a venda tem várias
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -913,8 +1586,8 @@ func (h *SaleHandler) TrocarProduto(c *gin.Context) {
 
 	// Buscar registro da venda original
 	var historicoVenda models.HistoricoVenda
-	if err := h.DB.Preload("Estoque").
-		Preload("Estoque.ProdutoComprado").
+	if err := h.DB.Preload("Estoque", incluirLixeira).
+		Preload("Estoque.ProdutoComprado", incluirLixeira).
 		First(&historicoVenda, req.HistoricoVendaID).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{
 			"success": false,
@@ -923,31 +1596,33 @@ func (h *SaleHandler) TrocarProduto(c *gin.Context) {
 		return
 	}
 
-	// Buscar o novo produto de estoque
-	var novoEstoque models.Estoque
-	if err := h.DB.Where("id = ? AND ativo = ?", req.NovoEstoqueID, true).
-		Preload("ProdutoComprado").
-		First(&novoEstoque).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"success": false,
-			"message": "Novo produto não encontrado no estoque",
-		})
-		return
-	}
-
-	// Verificar se há quantidade disponível do novo produto
-	if novoEstoque.Quantidade < historicoVenda.Quantidade {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "Quantidade insuficiente do novo produto em estoque",
-		})
-		return
-	}
-
 	// Iniciar transação
+	var novoEstoque models.Estoque
 	err := h.DB.Transaction(func(tx *gorm.DB) error {
-		// 1. Devolver o produto antigo ao estoque
-		if err := tx.Model(&historicoVenda.Estoque).
+		// Travar a linha do novo produto e conferir a quantidade disponível, sem as reservas de outros clientes
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND ativo = ?", req.NovoEstoqueID, true).
+			Preload("ProdutoComprado").
+			First(&novoEstoque).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return &erroVenda{http.StatusNotFound, "Novo produto não encontrado no estoque"}
+			}
+			return err
+		}
+		reservado, err := reservations.Reservado(tx, []int{novoEstoque.ID}, reservations.Ignorar{}, time.Now())
+		if err != nil {
+			return err
+		}
+		if novoEstoque.Quantidade-reservado[novoEstoque.ID] < historicoVenda.Quantidade {
+			return &erroVenda{http.StatusBadRequest, semEstoque(
+				"Quantidade insuficiente do novo produto em estoque", reservado[novoEstoque.ID])}
+		}
+
+		// 1. Devolver o produto antigo ao estoque (na DRE, a troca é uma devolução seguida da venda do novo)
+		if err := profitloss.RegistrarDevolucao(tx, historicoVenda, historicoVenda.Quantidade); err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Model(&historicoVenda.Estoque).
 			Update("quantidade", gorm.Expr("quantidade + ?", historicoVenda.Quantidade)).Error; err != nil {
 			return err
 		}
@@ -963,8 +1638,6 @@ func (h *SaleHandler) TrocarProduto(c *gin.Context) {
 		if novoPreco == 0 {
 			novoPreco = novoEstoque.ProdutoComprado.Preco
 		}
-		novoValorTotal := novoPreco * float64(historicoVenda.Quantidade)
-
 
 		// 4. Atualizar o registro da venda
 		// Usar struct para garantir mapeamento correto
@@ -972,19 +1645,30 @@ func (h *SaleHandler) TrocarProduto(c *gin.Context) {
 			EstoqueID:     novoEstoque.ID,
 			ProdutoNome:   novoEstoque.ProdutoComprado.Nome,
 			PrecoUnitario: novoPreco,
-			ValorTotal:    novoValorTotal,
 		}
 
 		// Forçar atualização dos campos específicos
 		if err := tx.Model(&historicoVenda).
-			Select("EstoqueID", "ProdutoNome", "PrecoUnitario", "ValorTotal").
+			Select("EstoqueID", "ProdutoNome", "PrecoUnitario").
 			Updates(dadosAtualizados).Error; err != nil {
 			return err
 		}
 
-		return nil
+		// 5. Recalcular o total da venda e os pagamentos
+		if historicoVenda.VendaID == nil {
+			return nil
+		}
+		return h.recalcularValorTotalVenda(tx, *historicoVenda.VendaID, req.Pagamentos)
 	})
 
+	var ev *erroVenda
+	if errors.As(err, &ev) {
+		c.JSON(ev.status, gin.H{
+			"success": false,
+			"message": ev.mensagem,
+		})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
@@ -1080,6 +1764,14 @@ func (h *SaleHandler) AtualizarVenda(c *gin.Context) {
 	})
 }
 
+// semEstoque monta a mensagem de quantidade insuficiente, indicando quantas unidades estão reservadas
+func semEstoque(mensagem string, reservado int) string {
+	if reservado > 0 {
+		mensagem += fmt.Sprintf(" (%d reservada(s))", reservado)
+	}
+	return mensagem
+}
+
 // DeletarVenda deleta uma venda e devolve os produtos ao estoque
 func (h *SaleHandler) DeletarVenda(c *gin.Context) {
 	idStr := c.Param("id")
@@ -1111,7 +1803,7 @@ func (h *SaleHandler) DeletarVenda(c *gin.Context) {
 
 	// Buscar todos os registros da venda com estoque
 	var historicoVendas []models.HistoricoVenda
-	if err := h.DB.Preload("Estoque").Where("vendaId = ?", *primeiroRegistro.VendaID).Find(&historicoVendas).Error; err != nil {
+	if err := h.DB.Preload("Estoque", incluirLixeira).Where("vendaId = ?", *primeiroRegistro.VendaID).Find(&historicoVendas).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
 			"message": "Erro ao buscar registros da venda",
@@ -1123,8 +1815,11 @@ func (h *SaleHandler) DeletarVenda(c *gin.Context) {
 	err = h.DB.Transaction(func(tx *gorm.DB) error {
 		// 1. Devolver produtos ao estoque
 		for _, hv := range historicoVendas {
+			if err := profitloss.RegistrarDevolucao(tx, hv, hv.Quantidade); err != nil {
+				return err
+			}
 			if hv.Estoque.ID > 0 {
-				if err := tx.Model(&hv.Estoque).
+				if err := tx.Unscoped().Model(&hv.Estoque).
 					Update("quantidade", gorm.Expr("quantidade + ?", hv.Quantidade)).Error; err != nil {
 					return err
 				}
@@ -1136,9 +1831,40 @@ func (h *SaleHandler) DeletarVenda(c *gin.Context) {
 			return err
 		}
 
-		return nil
+		// 3. Deletar as linhas de pagamento
+		if err := tx.Where("vendaId = ?", *primeiroRegistro.VendaID).Delete(&models.PagamentoVenda{}).Error; err != nil {
+			return err
+		}
+		if err := sincronizarPagamentos(tx, *primeiroRegistro.VendaID); err != nil {
+			return err
+		}
+
+		// 4. Devolver o uso do cupom e liberar a aprovação de desconto
+		if cupom := primeiroRegistro.CupomCodigo; cupom != nil {
+			if err := discounts.DevolverCupom(tx, *cupom); err != nil {
+				return err
+			}
+		}
+		if err := discounts.LiberarAprovacao(tx, *primeiroRegistro.VendaID); err != nil {
+			return err
+		}
+
+		// 5. Devolver a reserva convertida na venda, com o sinal que foi abatido nela
+		if err := reservations.Reabrir(tx, *primeiroRegistro.VendaID, time.Now()); err != nil {
+			return err
+		}
+
+		// 6. Remover os aparelhos recebidos em troca, se ainda estiverem no estoque central
+		return tradein.Desfazer(tx, *primeiroRegistro.VendaID)
 	})
 
+	if errors.Is(err, tradein.ErrAparelhoMovimentado) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Não é possível deletar a venda: " + err.Error(),
+		})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
@@ -1177,8 +1903,9 @@ func (h *SaleHandler) AtualizarProdutoVenda(c *gin.Context) {
 	}
 
 	var req struct {
-		Quantidade    int     `json:"quantidade" binding:"required,min=1"`
-		PrecoUnitario float64 `json:"precoUnitario" binding:"required,min=0.01"`
+		Quantidade    int              `json:"quantidade" binding:"required,min=1"`
+		PrecoUnitario money.Dinheiro   `json:"precoUnitario" binding:"required,gt=0"`
+		Pagamentos    []payments.Linha `json:"pagamentos"` // Novas formas de pagamento, quando a venda tem várias
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -1208,7 +1935,7 @@ func (h *SaleHandler) AtualizarProdutoVenda(c *gin.Context) {
 
 	// Buscar o registro do produto
 	var historicoVenda models.HistoricoVenda
-	if err := h.DB.Preload("Estoque").First(&historicoVenda, produtoId).Error; err != nil {
+	if err := h.DB.Preload("Estoque", incluirLixeira).First(&historicoVenda, produtoId).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{
 			"success": false,
 			"message": "Produto da venda não encontrado",
@@ -1233,9 +1960,21 @@ func (h *SaleHandler) AtualizarProdutoVenda(c *gin.Context) {
 		// 1. Ajustar estoque se a quantidade mudou
 		if diferencaQuantidade != 0 && historicoVenda.Estoque.ID > 0 {
 			if diferencaQuantidade > 0 {
-				// Reduzir estoque
-				if historicoVenda.Estoque.Quantidade < diferencaQuantidade {
-					return fmt.Errorf("quantidade insuficiente em estoque")
+				// Reduzir estoque, travando a linha e sem usar as unidades reservadas para outros clientes
+				var estoque models.Estoque
+				if err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).
+					First(&estoque, historicoVenda.Estoque.ID).Error; err != nil {
+					return err
+				}
+				if estoque.DeletedAt.Valid {
+					return &erroVenda{http.StatusBadRequest, "O produto está na lixeira"}
+				}
+				reservado, err := reservations.Reservado(tx, []int{estoque.ID}, reservations.Ignorar{}, time.Now())
+				if err != nil {
+					return err
+				}
+				if estoque.Quantidade-reservado[estoque.ID] < diferencaQuantidade {
+					return &erroVenda{http.StatusBadRequest, semEstoque("Quantidade insuficiente em estoque", reservado[estoque.ID])}
 				}
 				if err := tx.Model(&historicoVenda.Estoque).
 					Update("quantidade", gorm.Expr("quantidade - ?", diferencaQuantidade)).Error; err != nil {
@@ -1243,25 +1982,41 @@ func (h *SaleHandler) AtualizarProdutoVenda(c *gin.Context) {
 				}
 			} else {
 				// Devolver ao estoque
-				if err := tx.Model(&historicoVenda.Estoque).
+				if err := profitloss.RegistrarDevolucao(tx, historicoVenda, -diferencaQuantidade); err != nil {
+					return err
+				}
+				if err := tx.Unscoped().Model(&historicoVenda.Estoque).
 					Update("quantidade", gorm.Expr("quantidade + ?", -diferencaQuantidade)).Error; err != nil {
 					return err
 				}
 			}
 		}
 
-		// 2. Atualizar o registro do produto
+		// 2. Atualizar o registro do produto (o desconto do item segue o novo preço)
+		var descontoItem money.Dinheiro
+		if historicoVenda.PrecoTabela > req.PrecoUnitario {
+			descontoItem = (historicoVenda.PrecoTabela - req.PrecoUnitario).Vezes(req.Quantidade)
+		}
 		if err := tx.Model(&historicoVenda).Updates(map[string]interface{}{
 			"quantidade":    req.Quantidade,
 			"precoUnitario": req.PrecoUnitario,
+			"descontoItem":  descontoItem,
 		}).Error; err != nil {
 			return err
 		}
 
 		// 3. Recalcular e atualizar valor total da venda
-		return h.recalcularValorTotalVenda(tx, *historicoVenda.VendaID)
+		return h.recalcularValorTotalVenda(tx, *historicoVenda.VendaID, req.Pagamentos)
 	})
 
+	var ev *erroVenda
+	if errors.As(err, &ev) {
+		c.JSON(ev.status, gin.H{
+			"success": false,
+			"message": ev.mensagem,
+		})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
@@ -1299,6 +2054,20 @@ func (h *SaleHandler) DeletarProdutoVenda(c *gin.Context) {
 		return
 	}
 
+	// Quando a venda tem várias formas de pagamento, o corpo traz as novas linhas
+	var req struct {
+		Pagamentos []payments.Linha `json:"pagamentos"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Dados inválidos",
+			})
+			return
+		}
+	}
+
 	// Buscar o primeiro registro da venda para obter o VendaID
 	primeiroRegistro, err := h.buscarPrimeiroRegistroVenda(id)
 	if err != nil {
@@ -1318,7 +2087,7 @@ func (h *SaleHandler) DeletarProdutoVenda(c *gin.Context) {
 
 	// Buscar o registro do produto
 	var historicoVenda models.HistoricoVenda
-	if err := h.DB.Preload("Estoque").First(&historicoVenda, produtoId).Error; err != nil {
+	if err := h.DB.Preload("Estoque", incluirLixeira).First(&historicoVenda, produtoId).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{
 			"success": false,
 			"message": "Produto da venda não encontrado",
@@ -1351,8 +2120,11 @@ func (h *SaleHandler) DeletarProdutoVenda(c *gin.Context) {
 	// Iniciar transação
 	err = h.DB.Transaction(func(tx *gorm.DB) error {
 		// 1. Devolver produto ao estoque
+		if err := profitloss.RegistrarDevolucao(tx, historicoVenda, historicoVenda.Quantidade); err != nil {
+			return err
+		}
 		if historicoVenda.Estoque.ID > 0 {
-			if err := tx.Model(&historicoVenda.Estoque).
+			if err := tx.Unscoped().Model(&historicoVenda.Estoque).
 				Update("quantidade", gorm.Expr("quantidade + ?", historicoVenda.Quantidade)).Error; err != nil {
 				return err
 			}
@@ -1365,12 +2137,34 @@ func (h *SaleHandler) DeletarProdutoVenda(c *gin.Context) {
 
 		// 3. Se ainda houver produtos, recalcular valor total da venda
 		if totalProdutos > 1 {
-			return h.recalcularValorTotalVenda(tx, vendaIdStr)
+			return h.recalcularValorTotalVenda(tx, vendaIdStr, req.Pagamentos)
 		}
 
-		return nil
+		// Último produto: a venda deixa de existir junto com os pagamentos e as trocas
+		if err := tradein.Desfazer(tx, vendaIdStr); err != nil {
+			return err
+		}
+		if err := tx.Where("vendaId = ?", vendaIdStr).Delete(&models.PagamentoVenda{}).Error; err != nil {
+			return err
+		}
+		return sincronizarPagamentos(tx, vendaIdStr)
 	})
 
+	if errors.Is(err, tradein.ErrAparelhoMovimentado) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Não é possível deletar a venda: " + err.Error(),
+		})
+		return
+	}
+	var ev *erroVenda
+	if errors.As(err, &ev) {
+		c.JSON(ev.status, gin.H{
+			"success": false,
+			"message": ev.mensagem,
+		})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
@@ -3,8 +3,10 @@ package handlers
 import (
 	"net/http"
 	"strconv"
+	"time"
 
 	"cmdimport/backend/models"
+	"cmdimport/backend/reservations"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -59,12 +61,36 @@ func (h *StockHandler) Listar(c *gin.Context) {
 		return
 	}
 
-	estoqueFormatado := make([]map[string]interface{}, len(estoque))
+	// Quantidades reservadas não estão disponíveis para venda
+	estoqueIDs := make([]int, len(estoque))
 	for i, item := range estoque {
+		estoqueIDs[i] = item.ID
+	}
+	reservado, err := reservations.Reservado(h.DB, estoqueIDs, reservations.Ignorar{}, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao buscar reservas do estoque",
+		})
+		return
+	}
+
+	estoqueFormatado := make([]map[string]interface{}, 0, len(estoque))
+	for _, item := range estoque {
+		disponivel := item.Quantidade - reservado[item.ID]
+		if disponivel < 0 {
+			disponivel = 0
+		}
+		if ocultarEstoqueZerado && disponivel == 0 {
+			continue
+		}
+
 		itemMap := map[string]interface{}{
 			"id":          item.ID,
 			"nome":        item.ProdutoComprado.Nome,
-			"quantidade":  item.Quantidade,
+			"quantidade":  disponivel,
+			"quantidadeFisica":    item.Quantidade,
+			"quantidadeReservada": reservado[item.ID],
 			"preco":       item.ProdutoComprado.Preco,
 			"atendenteNome": item.AtendenteNome,
 		}
@@ -92,7 +118,7 @@ func (h *StockHandler) Listar(c *gin.Context) {
 			itemMap["usuario"] = nil
 		}
 
-		estoqueFormatado[i] = itemMap
+		estoqueFormatado = append(estoqueFormatado, itemMap)
 	}
 
 	c.JSON(http.StatusOK, gin.H{
@@ -0,0 +1,353 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"cmdimport/backend/cashregister"
+	"cmdimport/backend/models"
+	"cmdimport/backend/money"
+	"cmdimport/backend/payments"
+	"cmdimport/backend/reservations"
+	"cmdimport/backend/timezone"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type CriarReservaRequest struct {
+	EstoqueID   *int           `json:"estoqueId"` // Estoque reservado; ou informe o IMEI da unidade
+	IMEI        *string        `json:"imei"`
+	Quantidade  int            `json:"quantidade"` // Padrão: 1
+	ClienteNome string         `json:"clienteNome" binding:"required"`
+	Telefone    string         `json:"telefone" binding:"required"`
+	ValorSinal  money.Dinheiro `json:"valorSinal" binding:"min=0"`
+	FormaSinal  *string        `json:"formaSinal"` // dinheiro, pix, debito, credito ou outro; obrigatória com sinal
+	Horas       int            `json:"horas"`      // Duração da reserva; padrão: 48 horas
+	Observacoes *string        `json:"observacoes"`
+}
+
+// CriarReserva separa unidades de um estoque para um cliente até o fim do prazo.
+// A quantidade reservada deixa de aparecer como disponível e não pode ser vendida por outra venda.
+func (h *StockHandler) CriarReserva(c *gin.Context) {
+	operador, ok := usuarioLogado(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "Usuário não autenticado",
+		})
+		return
+	}
+
+	var req CriarReservaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Informe o cliente, o telefone e o produto reservado",
+		})
+		return
+	}
+	if req.EstoqueID == nil && (req.IMEI == nil || strings.TrimSpace(*req.IMEI) == "") {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Informe o estoque (estoqueId) ou o IMEI da unidade",
+		})
+		return
+	}
+	if req.Quantidade == 0 {
+		req.Quantidade = 1
+	}
+	if req.Horas == 0 {
+		req.Horas = reservations.HorasPadrao
+	}
+	if req.Quantidade < 0 || req.Horas < 1 || req.Horas > 24*30 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Quantidade e prazo (1 hora a 30 dias) inválidos",
+		})
+		return
+	}
+	if req.ValorSinal == 0 {
+		req.FormaSinal = nil
+	} else {
+		forma := ""
+		if req.FormaSinal != nil {
+			forma = strings.ToLower(strings.TrimSpace(*req.FormaSinal))
+		}
+		if !formaSinalValida(forma) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Informe a forma do sinal: dinheiro, pix, debito, credito ou outro",
+			})
+			return
+		}
+		req.FormaSinal = &forma
+	}
+
+	agora := time.Now()
+	var reserva models.ReservaEstoque
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		// Travar a linha do estoque para que duas reservas ou uma venda não peguem a mesma unidade
+		var estoque models.Estoque
+		query := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("ProdutoComprado").
+			Where("Estoque.ativo = ? AND Estoque.quantidade > ?", true, 0)
+		if req.EstoqueID != nil {
+			query = query.Where("Estoque.id = ?", *req.EstoqueID)
+		} else {
+			query = query.Joins("JOIN ProdutoComprado pc ON pc.id = Estoque.produtoCompradoId").
+				Where("pc.imei = ?", strings.TrimSpace(*req.IMEI))
+		}
+		if err := query.First(&estoque).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return &erroVenda{http.StatusNotFound, "Produto não encontrado no estoque"}
+			}
+			return err
+		}
+
+		reservado, err := reservations.Reservado(tx, []int{estoque.ID}, reservations.Ignorar{}, agora)
+		if err != nil {
+			return err
+		}
+		if disponivel := estoque.Quantidade - reservado[estoque.ID]; disponivel < req.Quantidade {
+			return &erroVenda{http.StatusBadRequest, fmt.Sprintf("Só há %d unidade(s) disponível(is) de %s",
+				disponivel, estoque.ProdutoComprado.Nome)}
+		}
+
+		clienteNome := strings.TrimSpace(req.ClienteNome)
+		telefone := strings.TrimSpace(req.Telefone)
+		reserva = models.ReservaEstoque{
+			EstoqueID:   estoque.ID,
+			Quantidade:  req.Quantidade,
+			IMEI:        estoque.ProdutoComprado.IMEI,
+			ClienteNome: &clienteNome,
+			Telefone:    &telefone,
+			ValorSinal:  req.ValorSinal,
+			FormaSinal:  req.FormaSinal,
+			Observacoes: req.Observacoes,
+			UsuarioID:   operador.ID,
+			Status:      reservations.StatusAtiva,
+			ExpiraEm:    agora.Add(time.Duration(req.Horas) * time.Hour),
+		}
+		if err := tx.Create(&reserva).Error; err != nil {
+			return err
+		}
+		// Sinal em dinheiro entra no caixa aberto de quem reservou
+		return cashregister.SincronizarReserva(tx, reserva, operador)
+	})
+	var ev *erroVenda
+	if errors.As(err, &ev) {
+		c.JSON(ev.status, gin.H{
+			"success": false,
+			"message": ev.mensagem,
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao reservar produto",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    reserva,
+		"message": "Produto reservado até " + reserva.ExpiraEm.In(timezone.Local()).Format("02/01/2006 15:04"),
+	})
+}
+
+// ListarReservas lista as reservas (filtros: status, usuarioId, estoqueId, cliente)
+func (h *StockHandler) ListarReservas(c *gin.Context) {
+	query := h.DB.Model(&models.ReservaEstoque{})
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if usuarioID := c.Query("usuarioId"); usuarioID != "" {
+		query = query.Where("usuarioId = ?", usuarioID)
+	}
+	if estoqueID := c.Query("estoqueId"); estoqueID != "" {
+		query = query.Where("estoqueId = ?", estoqueID)
+	}
+	if cliente := c.Query("cliente"); cliente != "" {
+		query = query.Where("clienteNome LIKE ?", "%"+cliente+"%")
+	}
+
+	var reservas []models.ReservaEstoque
+	if err := query.Order("expiraEm ASC").Limit(200).Find(&reservas).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao buscar reservas",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    reservas,
+	})
+}
+
+// formaSinalValida diz se a forma de pagamento do sinal é aceita
+func formaSinalValida(forma string) bool {
+	switch forma {
+	case payments.MetodoDinheiro, payments.MetodoPix, payments.MetodoDebito, payments.MetodoCredito, payments.MetodoOutro:
+		return true
+	}
+	return false
+}
+
+type LiberarReservaRequest struct {
+	DestinoSinal string `json:"destinoSinal"` // "devolvido" ou "retido"; obrigatório se a reserva tem sinal
+}
+
+// LiberarReserva cancela uma reserva ativa e devolve as unidades à venda.
+// Com sinal, informe se ele foi devolvido ao cliente ou ficou com a loja; o sinal em dinheiro devolvido sai do caixa.
+// Reservas de orçamento são liberadas cancelando o orçamento.
+func (h *StockHandler) LiberarReserva(c *gin.Context) {
+	operador, ok := usuarioLogado(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "Usuário não autenticado",
+		})
+		return
+	}
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "ID inválido",
+		})
+		return
+	}
+
+	var req LiberarReservaRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Dados inválidos",
+			})
+			return
+		}
+	}
+
+	var reserva models.ReservaEstoque
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&reserva, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return &erroVenda{http.StatusNotFound, "Reserva não encontrada"}
+			}
+			return err
+		}
+		if reserva.OrcamentoID != nil {
+			return &erroVenda{http.StatusBadRequest, "Reserva de orçamento: cancele o orçamento para liberá-la"}
+		}
+		if reserva.Status != reservations.StatusAtiva {
+			return &erroVenda{http.StatusBadRequest, "A reserva não está mais ativa"}
+		}
+
+		campos := map[string]interface{}{"status": reservations.StatusLiberada, "encerradaEm": time.Now()}
+		if reserva.ValorSinal > 0 {
+			if req.DestinoSinal != reservations.SinalDevolvido && req.DestinoSinal != reservations.SinalRetido {
+				return &erroVenda{http.StatusBadRequest, "A reserva tem sinal: informe o destino (devolvido ou retido)"}
+			}
+			campos["destinoSinal"] = req.DestinoSinal
+		}
+		if err := tx.Model(&reserva).Updates(campos).Error; err != nil {
+			return err
+		}
+		return cashregister.SincronizarReserva(tx, reserva, operador)
+	})
+	var ev *erroVenda
+	if errors.As(err, &ev) {
+		c.JSON(ev.status, gin.H{
+			"success": false,
+			"message": ev.mensagem,
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao liberar reserva",
+		})
+		return
+	}
+
+	mensagem := "Reserva liberada"
+	switch {
+	case reserva.ValorSinal == 0:
+	case req.DestinoSinal == reservations.SinalDevolvido:
+		mensagem += fmt.Sprintf("; sinal de R$ %s devolvido", reserva.ValorSinal.BR())
+	default:
+		mensagem += fmt.Sprintf("; sinal de R$ %s retido", reserva.ValorSinal.BR())
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    reserva,
+		"message": mensagem,
+	})
+}
+
+// DevolverSinal registra a devolução do sinal retido de uma reserva expirada ou liberada (apenas administradores).
+// O sinal em dinheiro sai do caixa em que entrou, se ainda aberto, ou do caixa aberto do mesmo operador.
+func (h *StockHandler) DevolverSinal(c *gin.Context) {
+	if !exigirAdmin(c, "Apenas administradores podem devolver sinais retidos") {
+		return
+	}
+	operador, _ := usuarioLogado(c)
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "ID inválido",
+		})
+		return
+	}
+
+	var reserva models.ReservaEstoque
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&reserva, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return &erroVenda{http.StatusNotFound, "Reserva não encontrada"}
+			}
+			return err
+		}
+		if (reserva.Status != reservations.StatusExpirada && reserva.Status != reservations.StatusLiberada) ||
+			reserva.DestinoSinal == nil || *reserva.DestinoSinal != reservations.SinalRetido {
+			return &erroVenda{http.StatusBadRequest, "Só o sinal retido de reserva expirada ou liberada pode ser devolvido"}
+		}
+		if err := tx.Model(&reserva).Update("destinoSinal", reservations.SinalDevolvido).Error; err != nil {
+			return err
+		}
+		return cashregister.SincronizarReserva(tx, reserva, operador)
+	})
+	var ev *erroVenda
+	if errors.As(err, &ev) {
+		c.JSON(ev.status, gin.H{
+			"success": false,
+			"message": ev.mensagem,
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao devolver sinal",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    reserva,
+		"message": fmt.Sprintf("Sinal de R$ %s devolvido", reserva.ValorSinal.BR()),
+	})
+}
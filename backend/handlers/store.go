@@ -0,0 +1,283 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"cmdimport/backend/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type StoreHandler struct {
+	DB *gorm.DB
+}
+
+func NewStoreHandler(db *gorm.DB) *StoreHandler {
+	return &StoreHandler{DB: db}
+}
+
+type SalvarLojaRequest struct {
+	Nome  string `json:"nome" binding:"required"`
+	Ativo *bool  `json:"ativo"`
+}
+
+// ListarLojas lista as lojas com a quantidade de vendedores de cada uma
+func (h *StoreHandler) ListarLojas(c *gin.Context) {
+	var lojas []models.Loja
+	if err := h.DB.Order("nome ASC").Find(&lojas).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao buscar lojas",
+		})
+		return
+	}
+
+	var contagens []struct {
+		LojaID     int   `gorm:"column:lojaId"`
+		Vendedores int64 `gorm:"column:vendedores"`
+	}
+	h.DB.Model(&models.Usuario{}).Select("lojaId, COUNT(*) as vendedores").
+		Where("lojaId IS NOT NULL").Group("lojaId").Scan(&contagens)
+	porLoja := make(map[int]int64, len(contagens))
+	for _, ct := range contagens {
+		porLoja[ct.LojaID] = ct.Vendedores
+	}
+
+	lista := make([]gin.H, len(lojas))
+	for i, loja := range lojas {
+		lista[i] = gin.H{
+			"id":         loja.ID,
+			"nome":       loja.Nome,
+			"ativo":      loja.Ativo,
+			"vendedores": porLoja[loja.ID],
+			"createdAt":  loja.CreatedAt,
+			"updatedAt":  loja.UpdatedAt,
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    lista,
+	})
+}
+
+// CriarLoja cadastra uma loja
+func (h *StoreHandler) CriarLoja(c *gin.Context) {
+	if !exigirAdmin(c, "Apenas administradores podem gerenciar lojas") {
+		return
+	}
+
+	var req SalvarLojaRequest
+	if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Nome) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Nome da loja é obrigatório",
+		})
+		return
+	}
+
+	nome := strings.TrimSpace(req.Nome)
+	var count int64
+	h.DB.Model(&models.Loja{}).Where("nome = ?", nome).Count(&count)
+	if count > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Já existe uma loja com este nome",
+		})
+		return
+	}
+
+	loja := models.Loja{Nome: nome, Ativo: true}
+	if req.Ativo != nil {
+		loja.Ativo = *req.Ativo
+	}
+	if err := h.DB.Create(&loja).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao cadastrar loja",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    loja,
+		"message": "Loja cadastrada com sucesso",
+	})
+}
+
+// AtualizarLoja altera o nome ou a situação de uma loja
+func (h *StoreHandler) AtualizarLoja(c *gin.Context) {
+	if !exigirAdmin(c, "Apenas administradores podem gerenciar lojas") {
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "ID inválido",
+		})
+		return
+	}
+
+	var req SalvarLojaRequest
+	if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Nome) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Nome da loja é obrigatório",
+		})
+		return
+	}
+
+	var loja models.Loja
+	if err := h.DB.First(&loja, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "Loja não encontrada",
+		})
+		return
+	}
+
+	nome := strings.TrimSpace(req.Nome)
+	var count int64
+	h.DB.Model(&models.Loja{}).Where("nome = ? AND id != ?", nome, id).Count(&count)
+	if count > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Já existe uma loja com este nome",
+		})
+		return
+	}
+
+	loja.Nome = nome
+	if req.Ativo != nil {
+		loja.Ativo = *req.Ativo
+	}
+	if err := h.DB.Save(&loja).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao atualizar loja",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    loja,
+		"message": "Loja atualizada com sucesso",
+	})
+}
+
+// DeletarLoja remove uma loja sem vendedores e sem metas
+func (h *StoreHandler) DeletarLoja(c *gin.Context) {
+	if !exigirAdmin(c, "Apenas administradores podem gerenciar lojas") {
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "ID inválido",
+		})
+		return
+	}
+
+	var vendedores, metas int64
+	h.DB.Model(&models.Usuario{}).Where("lojaId = ?", id).Count(&vendedores)
+	h.DB.Model(&models.Meta{}).Where("lojaId = ?", id).Count(&metas)
+	if vendedores > 0 || metas > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "A loja tem vendedores ou metas. Mova os vendedores e remova as metas, ou desative a loja",
+		})
+		return
+	}
+
+	result := h.DB.Delete(&models.Loja{}, id)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao deletar loja",
+		})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "Loja não encontrada",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Loja deletada com sucesso",
+	})
+}
+
+// DefinirLoja vincula o vendedor a uma loja ({"lojaId": 1}) ou o desvincula ({"lojaId": null})
+func (h *StoreHandler) DefinirLoja(c *gin.Context) {
+	if !exigirAdmin(c, "Apenas administradores podem gerenciar lojas") {
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "ID inválido",
+		})
+		return
+	}
+
+	var req struct {
+		LojaID *int `json:"lojaId"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Dados inválidos",
+		})
+		return
+	}
+	if req.LojaID != nil {
+		var loja models.Loja
+		if err := h.DB.First(&loja, *req.LojaID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"success": false,
+				"message": "Loja não encontrada",
+			})
+			return
+		}
+	}
+
+	result := h.DB.Model(&models.Usuario{}).Where("id = ?", id).Update("lojaId", req.LojaID)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao definir loja do vendedor",
+		})
+		return
+	}
+	if result.RowsAffected == 0 {
+		var count int64
+		h.DB.Model(&models.Usuario{}).Where("id = ?", id).Count(&count)
+		if count == 0 {
+			c.JSON(http.StatusNotFound, gin.H{
+				"success": false,
+				"message": "Usuário não encontrado",
+			})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Loja do vendedor atualizada",
+	})
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"cmdimport/backend/trash"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type TrashHandler struct {
+	DB       *gorm.DB
+	Retencao time.Duration
+}
+
+func NewTrashHandler(db *gorm.DB, retencaoDias int) *TrashHandler {
+	return &TrashHandler{DB: db, Retencao: time.Duration(retencaoDias) * 24 * time.Hour}
+}
+
+// incluirLixeira é usado em Preload de históricos (vendas, garantias, distribuições): o produto e o estoque
+// continuam aparecendo mesmo depois de irem para a lixeira
+func incluirLixeira(db *gorm.DB) *gorm.DB {
+	return db.Unscoped()
+}
+
+// Listar lista o que está na lixeira (filtro: tipo), com a data da exclusão definitiva de cada item
+func (h *TrashHandler) Listar(c *gin.Context) {
+	if !exigirAdmin(c, "Apenas administradores podem acessar a lixeira") {
+		return
+	}
+
+	itens, err := trash.Listar(h.DB, c.Query("tipo"), h.Retencao)
+	if errors.Is(err, trash.ErrTipoInvalido) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Tipo inválido. Use categoria-despesa, despesa, categoria-produto ou produto",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao buscar lixeira",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":      true,
+		"data":         itens,
+		"retencaoDias": int(h.Retencao.Hours() / 24),
+	})
+}
+
+// Restaurar tira um item da lixeira (parâmetros tipo e id), com o que foi junto com ele
+func (h *TrashHandler) Restaurar(c *gin.Context) {
+	if !exigirAdmin(c, "Apenas administradores podem acessar a lixeira") {
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "ID inválido",
+		})
+		return
+	}
+
+	err = trash.Restaurar(h.DB, c.Param("tipo"), id)
+	switch {
+	case errors.Is(err, trash.ErrTipoInvalido):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Tipo inválido. Use categoria-despesa, despesa, categoria-produto ou produto",
+		})
+		return
+	case errors.Is(err, trash.ErrNaoEncontrado):
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "Item não encontrado na lixeira",
+		})
+		return
+	case errors.Is(err, trash.ErrCategoriaNaLixeira):
+		c.JSON(http.StatusConflict, gin.H{
+			"success": false,
+			"message": "A categoria deste item está na lixeira. Restaure a categoria primeiro",
+		})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao restaurar item",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Item restaurado com sucesso",
+	})
+}
@@ -1,29 +1,33 @@
 package handlers
 
 import (
-	"fmt"
-	"io"
+	"errors"
 	"net/http"
-	"os"
-	"path/filepath"
-	"strings"
+
+	"cmdimport/backend/storage"
 
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
+)
+
+// Limites de upload
+const (
+	tamanhoMaximoFoto  = 5 << 20  // 5MB
+	tamanhoMaximoAnexo = 10 << 20 // 10MB por arquivo
 )
 
 func UploadFoto(c *gin.Context) {
 	// Parse multipart form
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, tamanhoMaximoFoto+(1<<20))
 	err := c.Request.ParseMultipartForm(10 << 20) // 10MB max
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"success": false,
-			"message": "Erro ao processar formulário",
+			"message": "Erro ao processar formulário. Tamanho máximo: 5MB",
 		})
 		return
 	}
 
-	file, header, err := c.Request.FormFile("foto")
+	_, header, err := c.Request.FormFile("foto")
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"success": false,
@@ -31,61 +35,23 @@ func UploadFoto(c *gin.Context) {
 		})
 		return
 	}
-	defer file.Close()
 
-	// Verificar tipo de arquivo
-	contentType := header.Header.Get("Content-Type")
-	allowed := strings.Contains(contentType, "image/png") || 
-		strings.Contains(contentType, "image/jpeg") || 
-		strings.Contains(contentType, "image/jpg")
-
-	if !allowed {
+	// O tipo é conferido pelo conteúdo do arquivo e a extensão gravada vem dele
+	arquivo, err := storage.Salvar(storage.RaizPublica, "vendas", header, storage.Imagens, tamanhoMaximoFoto)
+	if errors.Is(err, storage.ErrTipoNaoPermitido) {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"success": false,
 			"message": "Tipo de arquivo não permitido. Use apenas PNG ou JPG.",
 		})
 		return
 	}
-
-	// Verificar tamanho (máximo 5MB)
-	if header.Size > 5*1024*1024 {
+	if errors.Is(err, storage.ErrArquivoGrande) {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"success": false,
 			"message": "Arquivo muito grande. Tamanho máximo: 5MB",
 		})
 		return
 	}
-
-	// Gerar nome único
-	fileExtension := filepath.Ext(header.Filename)
-	if fileExtension == "" {
-		// Tentar detectar extensão pelo content type
-		if strings.Contains(contentType, "jpeg") || strings.Contains(contentType, "jpg") {
-			fileExtension = ".jpg"
-		} else if strings.Contains(contentType, "png") {
-			fileExtension = ".png"
-		} else {
-			fileExtension = ".jpg"
-		}
-	}
-
-	fileName := fmt.Sprintf("%s%s", uuid.New().String(), fileExtension)
-	
-	// Criar diretório se não existir
-	uploadDir := filepath.Join("public", "uploads", "vendas")
-	if err := os.MkdirAll(uploadDir, 0755); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"message": "Erro ao criar diretório",
-		})
-		return
-	}
-
-	// Caminho completo do arquivo
-	filePath := filepath.Join(uploadDir, fileName)
-
-	// Criar arquivo
-	dst, err := os.Create(filePath)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
@@ -93,24 +59,10 @@ func UploadFoto(c *gin.Context) {
 		})
 		return
 	}
-	defer dst.Close()
-
-	// Copiar conteúdo
-	if _, err := io.Copy(dst, file); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"message": "Erro ao salvar arquivo",
-		})
-		return
-	}
-
-	// Retornar caminho relativo
-	relativePath := fmt.Sprintf("/uploads/vendas/%s", fileName)
 
 	c.JSON(http.StatusOK, gin.H{
 		"success":  true,
 		"message":  "Foto enviada com sucesso",
-		"filePath": relativePath,
+		"filePath": "/uploads/" + arquivo.Caminho,
 	})
 }
-
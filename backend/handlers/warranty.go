@@ -0,0 +1,553 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"cmdimport/backend/models"
+	"cmdimport/backend/timezone"
+	"cmdimport/backend/warranty"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type WarrantyHandler struct {
+	DB *gorm.DB
+}
+
+func NewWarrantyHandler(db *gorm.DB) *WarrantyHandler {
+	return &WarrantyHandler{DB: db}
+}
+
+type SalvarTermoRequest struct {
+	SKUID       *int    `json:"skuId"` // Informe o SKU ou a categoria; sem os dois, é o termo padrão
+	CategoriaID *int    `json:"categoriaId"`
+	Dias        int     `json:"dias" binding:"required,min=1,max=3650"`
+	Descricao   *string `json:"descricao"`
+	Ativo       *bool   `json:"ativo"`
+}
+
+func preencherTermo(termo *models.GarantiaTermo, req SalvarTermoRequest) {
+	termo.SKUID = req.SKUID
+	termo.CategoriaID = req.CategoriaID
+	termo.Dias = req.Dias
+	termo.Descricao = req.Descricao
+	if req.Ativo != nil {
+		termo.Ativo = *req.Ativo
+	}
+}
+
+// ListarTermos lista os prazos de garantia por SKU, por categoria e o padrão
+func (h *WarrantyHandler) ListarTermos(c *gin.Context) {
+	var termos []models.GarantiaTermo
+	if err := h.DB.Order("skuId ASC, categoriaId ASC").Find(&termos).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao buscar termos de garantia",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    termos,
+	})
+}
+
+// CriarTermo cadastra o prazo de garantia de um SKU, de uma categoria ou o padrão da loja
+func (h *WarrantyHandler) CriarTermo(c *gin.Context) {
+	var req SalvarTermoRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Informe o prazo de garantia em dias",
+		})
+		return
+	}
+	if msg := h.validarTermo(req, 0); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": msg,
+		})
+		return
+	}
+
+	termo := models.GarantiaTermo{Ativo: true}
+	preencherTermo(&termo, req)
+	if err := h.DB.Create(&termo).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao cadastrar termo de garantia",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    termo,
+		"message": "Termo de garantia cadastrado com sucesso",
+	})
+}
+
+// AtualizarTermo altera um termo de garantia. O novo prazo vale também para as vendas anteriores.
+func (h *WarrantyHandler) AtualizarTermo(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "ID inválido",
+		})
+		return
+	}
+
+	var req SalvarTermoRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Informe o prazo de garantia em dias",
+		})
+		return
+	}
+
+	var termo models.GarantiaTermo
+	if err := h.DB.First(&termo, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "Termo de garantia não encontrado",
+		})
+		return
+	}
+
+	if msg := h.validarTermo(req, id); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": msg,
+		})
+		return
+	}
+
+	preencherTermo(&termo, req)
+	if err := h.DB.Save(&termo).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao atualizar termo de garantia",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    termo,
+		"message": "Termo de garantia atualizado com sucesso",
+	})
+}
+
+// DeletarTermo remove um termo de garantia
+func (h *WarrantyHandler) DeletarTermo(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "ID inválido",
+		})
+		return
+	}
+
+	result := h.DB.Delete(&models.GarantiaTermo{}, id)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao deletar termo de garantia",
+		})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "Termo de garantia não encontrado",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Termo de garantia deletado com sucesso",
+	})
+}
+
+// validarTermo retorna a mensagem de erro para o cliente, ou "" se o termo é válido
+func (h *WarrantyHandler) validarTermo(req SalvarTermoRequest, ignorarID int) string {
+	if req.SKUID != nil && req.CategoriaID != nil {
+		return "Informe o SKU ou a categoria, não os dois"
+	}
+
+	query := h.DB.Model(&models.GarantiaTermo{}).Where("id != ?", ignorarID)
+	switch {
+	case req.SKUID != nil:
+		var count int64
+		h.DB.Model(&models.ProdutoSKU{}).Where("id = ?", *req.SKUID).Count(&count)
+		if count == 0 {
+			return "SKU não encontrado"
+		}
+		query = query.Where("skuId = ?", *req.SKUID)
+	case req.CategoriaID != nil:
+		var count int64
+		h.DB.Model(&models.CategoriaProduto{}).Where("id = ?", *req.CategoriaID).Count(&count)
+		if count == 0 {
+			return "Categoria não encontrada"
+		}
+		query = query.Where("categoriaId = ?", *req.CategoriaID)
+	default:
+		query = query.Where("skuId IS NULL AND categoriaId IS NULL")
+	}
+
+	var count int64
+	query.Count(&count)
+	if count > 0 {
+		return "Já existe um termo de garantia para este SKU, categoria ou o padrão"
+	}
+	return ""
+}
+
+// ConsultarIMEI mostra, para a equipe, as vendas do aparelho com a garantia e os chamados de cada uma
+func (h *WarrantyHandler) ConsultarIMEI(c *gin.Context) {
+	unidades, err := warranty.BuscarPorIMEI(h.DB, c.Param("imei"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao buscar vendas do aparelho",
+		})
+		return
+	}
+	if len(unidades) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "Nenhuma venda encontrada para este IMEI",
+		})
+		return
+	}
+
+	termos, err := warranty.CarregarTermos(h.DB)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao buscar termos de garantia",
+		})
+		return
+	}
+
+	agora := time.Now()
+	resultado := make([]gin.H, len(unidades))
+	for i, u := range unidades {
+		var chamados []models.GarantiaChamado
+		if err := h.DB.Where("historicoVendaId = ?", u.Venda.ID).Order("createdAt DESC").Find(&chamados).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "Erro ao buscar chamados de garantia",
+			})
+			return
+		}
+		resultado[i] = gin.H{
+			"historicoVendaId": u.Venda.ID,
+			"vendaId":          u.Venda.VendaID,
+			"produtoNome":      u.Venda.ProdutoNome,
+			"clienteNome":      u.Venda.ClienteNome,
+			"telefone":         u.Venda.Telefone,
+			"vendedorNome":     u.Venda.VendedorNome,
+			"dataVenda":        u.Venda.CreatedAt.Format(time.RFC3339),
+			"garantia":         termos.Calcular(u.Produto, u.Venda.CreatedAt, agora),
+			"chamados":         chamados,
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    resultado,
+	})
+}
+
+// AbrirChamado registra um pedido de garantia de uma unidade vendida. Fora do prazo,
+// só o admin pode abrir o chamado.
+func (h *WarrantyHandler) AbrirChamado(c *gin.Context) {
+	usuario, ok := usuarioLogado(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "Usuário não autenticado",
+		})
+		return
+	}
+
+	var req struct {
+		HistoricoVendaID int    `json:"historicoVendaId" binding:"required"`
+		Defeito          string `json:"defeito" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Defeito) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Informe a venda (historicoVendaId) e o defeito",
+		})
+		return
+	}
+
+	unidade, err := warranty.BuscarUnidade(h.DB, req.HistoricoVendaID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "Venda não encontrada",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao buscar venda",
+		})
+		return
+	}
+
+	termos, err := warranty.CarregarTermos(h.DB)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao buscar termos de garantia",
+		})
+		return
+	}
+	garantia := termos.Calcular(unidade.Produto, unidade.Venda.CreatedAt, time.Now())
+	if !garantia.Ativa && !usuario.IsAdmin {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Garantia encerrada em " + garantia.Fim.Format("02/01/2006") + ". Só o admin pode abrir o chamado",
+		})
+		return
+	}
+
+	var abertos int64
+	h.DB.Model(&models.GarantiaChamado{}).
+		Where("historicoVendaId = ? AND status IN ?", req.HistoricoVendaID, []string{warranty.StatusAberto, warranty.StatusEmReparo}).
+		Count(&abertos)
+	if abertos > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Já existe um chamado em andamento para esta unidade",
+		})
+		return
+	}
+
+	chamado := models.GarantiaChamado{
+		HistoricoVendaID:  unidade.Venda.ID,
+		VendaID:           unidade.Venda.VendaID,
+		ProdutoCompradoID: unidade.Produto.ID,
+		ProdutoNome:       unidade.Venda.ProdutoNome,
+		IMEI:              unidade.Produto.IMEI,
+		ClienteNome:       unidade.Venda.ClienteNome,
+		Telefone:          unidade.Venda.Telefone,
+		Defeito:           strings.TrimSpace(req.Defeito),
+		Status:            warranty.StatusAberto,
+		ForaDaGarantia:    !garantia.Ativa,
+		UsuarioID:         usuario.ID,
+		UsuarioNome:       usuario.Nome,
+	}
+	if err := h.DB.Create(&chamado).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao abrir chamado de garantia",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data": gin.H{
+			"chamado":  chamado,
+			"garantia": garantia,
+		},
+		"message": "Chamado de garantia aberto com sucesso",
+	})
+}
+
+// ListarChamados lista os chamados de garantia (filtros: status, imei, dataInicio, dataFim)
+func (h *WarrantyHandler) ListarChamados(c *gin.Context) {
+	query := h.DB.Model(&models.GarantiaChamado{})
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if imei := c.Query("imei"); imei != "" {
+		query = query.Where("imei = ?", imei)
+	}
+	query = filtrarPeriodo(query, "createdAt", c.Query("dataInicio"), c.Query("dataFim"))
+
+	var chamados []models.GarantiaChamado
+	if err := query.Order("createdAt DESC").Find(&chamados).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao buscar chamados de garantia",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    chamados,
+	})
+}
+
+// AtualizarChamado muda a situação de um chamado (aberto → em_reparo → trocado, reparado ou recusado)
+func (h *WarrantyHandler) AtualizarChamado(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "ID inválido",
+		})
+		return
+	}
+
+	var req struct {
+		Status    string  `json:"status" binding:"required"`
+		Resolucao *string `json:"resolucao"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || !warranty.StatusValido(req.Status) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Status inválido. Use em_reparo, trocado, reparado ou recusado",
+		})
+		return
+	}
+
+	var chamado models.GarantiaChamado
+	if err := h.DB.First(&chamado, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "Chamado de garantia não encontrado",
+		})
+		return
+	}
+
+	if !warranty.PodeMudar(chamado.Status, req.Status) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Não é possível passar o chamado de " + chamado.Status + " para " + req.Status,
+		})
+		return
+	}
+	if req.Status == warranty.StatusRecusado && (req.Resolucao == nil || strings.TrimSpace(*req.Resolucao) == "") {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Informe o motivo da recusa em resolucao",
+		})
+		return
+	}
+
+	chamado.Status = req.Status
+	if req.Resolucao != nil {
+		resolucao := strings.TrimSpace(*req.Resolucao)
+		chamado.Resolucao = &resolucao
+	}
+	if warranty.Encerrado(req.Status) {
+		agora := time.Now()
+		chamado.EncerradoEm = &agora
+	}
+
+	if err := h.DB.Save(&chamado).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao atualizar chamado de garantia",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    chamado,
+		"message": "Chamado de garantia atualizado com sucesso",
+	})
+}
+
+// ConsultaPublica é a consulta do cliente, sem login: com o IMEI e o telefone da venda,
+// retorna o prazo de garantia e a situação dos chamados. Não expõe dados do cliente.
+func (h *WarrantyHandler) ConsultaPublica(c *gin.Context) {
+	var req struct {
+		IMEI     string `json:"imei" binding:"required"`
+		Telefone string `json:"telefone" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Informe o IMEI e o telefone usado na compra",
+		})
+		return
+	}
+
+	unidades, err := warranty.BuscarPorIMEI(h.DB, req.IMEI)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao consultar garantia",
+		})
+		return
+	}
+
+	// Só a venda feita para o telefone informado; IMEI inexistente e telefone diferente têm a mesma resposta
+	var unidade *warranty.Unidade
+	for i := range unidades {
+		if warranty.MesmoTelefone(unidades[i].Venda.Telefone, req.Telefone) {
+			unidade = &unidades[i]
+			break
+		}
+	}
+	if unidade == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "Nenhuma compra encontrada para este IMEI e telefone",
+		})
+		return
+	}
+
+	termos, err := warranty.CarregarTermos(h.DB)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao consultar garantia",
+		})
+		return
+	}
+
+	var chamados []models.GarantiaChamado
+	if err := h.DB.Where("historicoVendaId = ?", unidade.Venda.ID).Order("createdAt DESC").Find(&chamados).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Erro ao consultar garantia",
+		})
+		return
+	}
+	listaChamados := make([]gin.H, len(chamados))
+	for i, ch := range chamados {
+		listaChamados[i] = gin.H{
+			"status":      ch.Status,
+			"abertoEm":    ch.CreatedAt.Format(time.RFC3339),
+			"encerradoEm": ch.EncerradoEm,
+			"resolucao":   ch.Resolucao,
+		}
+	}
+
+	garantia := termos.Calcular(unidade.Produto, unidade.Venda.CreatedAt, time.Now())
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"produto":       unidade.Venda.ProdutoNome,
+			"dataCompra":    unidade.Venda.CreatedAt.In(timezone.Local()).Format("2006-01-02"),
+			"garantiaAte":   garantia.Fim.Format("2006-01-02"),
+			"dias":          garantia.Dias,
+			"ativa":         garantia.Ativa,
+			"diasRestantes": garantia.DiasRestantes,
+			"condicoes":     garantia.Descricao,
+			"chamados":      listaChamados,
+		},
+	})
+}
@@ -0,0 +1,339 @@
+// Package importer cadastra produtos comprados em lote a partir de planilhas CSV/XLSX.
+// É usado pelo endpoint POST /api/admin/produtos/importar e pelo comando cmd/importar-produtos.
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"cmdimport/backend/exchange"
+	"cmdimport/backend/models"
+	"cmdimport/backend/money"
+	"cmdimport/backend/spreadsheet"
+	"cmdimport/backend/timezone"
+	"cmdimport/backend/utils"
+
+	"gorm.io/gorm"
+)
+
+// MaxLinhas limita o tamanho de uma importação
+const MaxLinhas = 5000
+
+// ErroLinha descreve um problema encontrado em uma linha da planilha
+type ErroLinha struct {
+	Linha    int    `json:"linha"`
+	Campo    string `json:"campo,omitempty"`
+	Mensagem string `json:"mensagem"`
+}
+
+// Resultado é o relatório da importação
+type Resultado struct {
+	DryRun        bool        `json:"dryRun"`
+	TotalLinhas   int         `json:"totalLinhas"`
+	LinhasValidas int         `json:"linhasValidas"`
+	Importados    int         `json:"importados"`
+	Erros         []ErroLinha `json:"erros"`
+}
+
+// colunas aceitas (cabeçalho normalizado -> campo)
+var colunas = map[string]string{
+	"nome":           "nome",
+	"produto":        "nome",
+	"descricao":      "descricao",
+	"cor":            "cor",
+	"imei":           "imei",
+	"codigobarras":   "codigoBarras",
+	"codigodebarras": "codigoBarras",
+	"ean":            "codigoBarras",
+	"custodolar":     "custoDolar",
+	"custousd":       "custoDolar",
+	"custoemdolar":   "custoDolar",
+	"usd":            "custoDolar",
+	"taxadolar":      "taxaDolar",
+	"taxadodolar":    "taxaDolar",
+	"taxa":           "taxaDolar",
+	"cotacao":        "taxaDolar",
+	"quantidade":     "quantidade",
+	"qtd":            "quantidade",
+	"qtde":           "quantidade",
+	"categoria":      "categoria",
+	"fornecedor":     "fornecedor",
+	"datacompra":     "dataCompra",
+	"datadacompra":   "dataCompra",
+	"sku":            "sku",
+	"codigosku":      "sku",
+}
+
+var colunasObrigatorias = []string{"nome", "custoDolar", "quantidade"}
+
+// ImportarArquivo valida a planilha inteira e, se não houver erros e dryRun for falso,
+// cadastra todos os produtos em uma única transação (tudo ou nada).
+func ImportarArquivo(db *gorm.DB, nomeArquivo string, r io.Reader, dryRun bool) (*Resultado, error) {
+	registros, err := spreadsheet.Ler(nomeArquivo, r)
+	if err != nil {
+		return nil, err
+	}
+
+	resultado := &Resultado{DryRun: dryRun, Erros: []ErroLinha{}}
+
+	produtos, total, erros, err := validar(db, registros)
+	if err != nil {
+		return nil, err
+	}
+	resultado.TotalLinhas = total
+	resultado.LinhasValidas = len(produtos)
+	resultado.Erros = erros
+
+	if dryRun || len(erros) > 0 {
+		return resultado, nil
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		return tx.CreateInBatches(&produtos, 100).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("erro ao cadastrar produtos: %w", err)
+	}
+
+	resultado.Importados = len(produtos)
+	return resultado, nil
+}
+
+// validar converte as linhas da planilha em produtos, acumulando todos os erros encontrados.
+// Retorna também o total de linhas de dados (ignorando linhas em branco).
+func validar(db *gorm.DB, registros [][]string) ([]models.ProdutoComprado, int, []ErroLinha, error) {
+	erros := []ErroLinha{}
+
+	if len(registros) == 0 {
+		return nil, 0, append(erros, ErroLinha{Linha: 1, Mensagem: "Planilha vazia"}), nil
+	}
+
+	// Mapear cabeçalho
+	indices := make(map[string]int)
+	for i, titulo := range registros[0] {
+		if campo, ok := colunas[spreadsheet.NormalizarCabecalho(titulo)]; ok {
+			if _, repetida := indices[campo]; !repetida {
+				indices[campo] = i
+			}
+		}
+	}
+	for _, campo := range colunasObrigatorias {
+		if _, ok := indices[campo]; !ok {
+			erros = append(erros, ErroLinha{Linha: 1, Campo: campo, Mensagem: "Coluna obrigatória ausente"})
+		}
+	}
+	if len(erros) > 0 {
+		return nil, 0, erros, nil
+	}
+
+	dados := registros[1:]
+	if len(dados) > MaxLinhas {
+		return nil, 0, append(erros, ErroLinha{Linha: 1, Mensagem: fmt.Sprintf("A planilha excede o limite de %d linhas", MaxLinhas)}), nil
+	}
+
+	// Categorias por nome
+	var categorias []models.CategoriaProduto
+	if err := db.Find(&categorias).Error; err != nil {
+		return nil, 0, nil, err
+	}
+	categoriasPorNome := make(map[string]int)
+	for _, cat := range categorias {
+		categoriasPorNome[strings.ToLower(strings.TrimSpace(cat.Nome))] = cat.ID
+	}
+
+	// SKUs do catálogo por código
+	var skus []models.ProdutoSKU
+	if err := db.Find(&skus).Error; err != nil {
+		return nil, 0, nil, err
+	}
+	skusPorCodigo := make(map[string]models.ProdutoSKU)
+	for _, sku := range skus {
+		skusPorCodigo[strings.ToUpper(sku.Codigo)] = sku
+	}
+
+	// IMEIs já cadastrados
+	imeisPlanilha := make([]string, 0)
+	if idx, ok := indices["imei"]; ok {
+		for _, registro := range dados {
+			if imei := celula(registro, idx); imei != "" {
+				imeisPlanilha = append(imeisPlanilha, imei)
+			}
+		}
+	}
+	imeisExistentes := make(map[string]bool)
+	if len(imeisPlanilha) > 0 {
+		var existentes []string
+		if err := db.Unscoped().Model(&models.ProdutoComprado{}).Where("imei IN ?", imeisPlanilha).Pluck("imei", &existentes).Error; err != nil {
+			return nil, 0, nil, err
+		}
+		for _, imei := range existentes {
+			imeisExistentes[imei] = true
+		}
+	}
+
+	taxasPorData := make(map[string]float64)
+	imeisVistos := make(map[string]int)
+	hoje := time.Now()
+
+	total := 0
+	produtos := make([]models.ProdutoComprado, 0, len(dados))
+	for i, registro := range dados {
+		numero := i + 2 // linha 1 é o cabeçalho
+		if linhaVazia(registro) {
+			continue
+		}
+		total++
+
+		errosLinha := len(erros)
+		valor := func(campo string) string {
+			idx, ok := indices[campo]
+			if !ok {
+				return ""
+			}
+			return celula(registro, idx)
+		}
+		addErro := func(campo, mensagem string) {
+			erros = append(erros, ErroLinha{Linha: numero, Campo: campo, Mensagem: mensagem})
+		}
+
+		produto := models.ProdutoComprado{DataCompra: hoje}
+
+		produto.Nome = valor("nome")
+		if produto.Nome == "" {
+			addErro("nome", "Nome é obrigatório")
+		}
+		produto.Descricao = opcional(valor("descricao"))
+		produto.Cor = opcional(valor("cor"))
+		produto.CodigoBarras = opcional(valor("codigoBarras"))
+		produto.Fornecedor = opcional(valor("fornecedor"))
+
+		if imei := valor("imei"); imei != "" {
+			if linhaAnterior, repetido := imeisVistos[imei]; repetido {
+				addErro("imei", fmt.Sprintf("IMEI duplicado na planilha (linha %d)", linhaAnterior))
+			} else if imeisExistentes[imei] {
+				addErro("imei", "Já existe um produto com este IMEI")
+			}
+			imeisVistos[imei] = numero
+			produto.IMEI = &imei
+		}
+
+		if custoStr := valor("custoDolar"); custoStr == "" {
+			addErro("custoDolar", "Custo em dólar é obrigatório")
+		} else if custo, err := utils.ParseFloatBR(custoStr); err != nil {
+			addErro("custoDolar", fmt.Sprintf("Número inválido: %q", custoStr))
+		} else if custoDolar, err := money.DeReais(custo); err != nil {
+			addErro("custoDolar", fmt.Sprintf("Número inválido: %q", custoStr))
+		} else if custoDolar <= 0 {
+			addErro("custoDolar", "O custo em dólar deve ser maior que zero")
+		} else {
+			produto.CustoDolar = custoDolar
+		}
+
+		if qtdStr := valor("quantidade"); qtdStr == "" {
+			addErro("quantidade", "Quantidade é obrigatória")
+		} else if qtd, err := strconv.Atoi(qtdStr); err != nil {
+			addErro("quantidade", fmt.Sprintf("Quantidade inválida: %q", qtdStr))
+		} else if qtd < 0 {
+			addErro("quantidade", "A quantidade não pode ser negativa")
+		} else {
+			produto.Quantidade = qtd
+			produto.QuantidadeBackup = qtd
+		}
+
+		if dataStr := valor("dataCompra"); dataStr != "" {
+			data, err := exchange.ParseData(dataStr)
+			if err == nil {
+				produto.DataCompra = timezone.InicioDaData(data)
+			} else if serial, ok := spreadsheet.DataSerial(dataStr); ok {
+				produto.DataCompra = timezone.InicioDaData(serial)
+			} else {
+				addErro("dataCompra", "Data inválida. Use YYYY-MM-DD ou DD/MM/YYYY")
+			}
+		}
+
+		if taxaStr := valor("taxaDolar"); taxaStr != "" {
+			taxa, err := utils.ParseFloatBR(taxaStr)
+			if err != nil {
+				addErro("taxaDolar", fmt.Sprintf("Número inválido: %q", taxaStr))
+			} else if taxa <= 0 {
+				addErro("taxaDolar", "A taxa do dólar deve ser maior que zero")
+			} else {
+				produto.TaxaDolar = taxa
+			}
+		} else {
+			// Sem taxa na planilha, usar a cotação vigente na data da compra
+			chave := produto.DataCompra.In(timezone.Local()).Format("2006-01-02")
+			taxa, ok := taxasPorData[chave]
+			if !ok {
+				cotacao, err := exchange.TaxaVigente(db, produto.DataCompra)
+				if err == nil {
+					taxa = cotacao.Taxa
+				} else if !errors.Is(err, exchange.ErrSemCotacao) {
+					return nil, 0, nil, err
+				}
+				taxasPorData[chave] = taxa
+			}
+			if taxa == 0 {
+				addErro("taxaDolar", "Taxa não informada e não há cotação cadastrada para a data da compra")
+			}
+			produto.TaxaDolar = taxa
+		}
+
+		if categoria := valor("categoria"); categoria != "" {
+			id, ok := categoriasPorNome[strings.ToLower(categoria)]
+			if !ok {
+				addErro("categoria", fmt.Sprintf("Categoria não encontrada: %q", categoria))
+			} else {
+				produto.CategoriaID = &id
+			}
+		}
+
+		if codigo := valor("sku"); codigo != "" {
+			sku, ok := skusPorCodigo[strings.ToUpper(codigo)]
+			if !ok {
+				addErro("sku", fmt.Sprintf("SKU não encontrado: %q", codigo))
+			} else {
+				produto.SKUID = &sku.ID
+				if produto.CategoriaID == nil {
+					produto.CategoriaID = sku.CategoriaID
+				}
+			}
+		}
+
+		if len(erros) > errosLinha {
+			continue
+		}
+
+		produto.Preco = produto.CustoDolar.Mul(produto.TaxaDolar)
+		produtos = append(produtos, produto)
+	}
+
+	return produtos, total, erros, nil
+}
+
+func celula(registro []string, idx int) string {
+	if idx >= len(registro) {
+		return ""
+	}
+	return strings.TrimSpace(registro[idx])
+}
+
+func opcional(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+func linhaVazia(registro []string) bool {
+	for _, v := range registro {
+		if strings.TrimSpace(v) != "" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,84 @@
+// Package jobs executa tarefas periódicas em segundo plano junto com o servidor.
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"cmdimport/backend/budgets"
+	"cmdimport/backend/config"
+	"cmdimport/backend/expenses"
+	"cmdimport/backend/pricing"
+	"cmdimport/backend/quotes"
+	"cmdimport/backend/reservations"
+	"cmdimport/backend/trash"
+
+	"gorm.io/gorm"
+)
+
+// Iniciar dispara as tarefas periódicas. Elas param quando ctx é cancelado.
+func Iniciar(ctx context.Context, db *gorm.DB, cfg *config.Config) {
+	go executarPeriodicamente(ctx, "aplicar preços agendados", time.Minute, func(agora time.Time) error {
+		total, err := pricing.AplicarAgendadas(db, agora)
+		if err == nil && total > 0 {
+			log.Printf("Preços agendados aplicados em %d precificações", total)
+		}
+		return err
+	})
+	go executarPeriodicamente(ctx, "liberar reservas vencidas", time.Minute, func(agora time.Time) error {
+		total, err := reservations.Expirar(db, agora)
+		if err == nil && total > 0 {
+			log.Printf("%d reservas de estoque expiradas", total)
+		}
+		return err
+	})
+	go executarPeriodicamente(ctx, "expirar orçamentos", 10*time.Minute, func(agora time.Time) error {
+		total, err := quotes.Expirar(db, agora)
+		if err == nil && total > 0 {
+			log.Printf("%d orçamentos expirados", total)
+		}
+		return err
+	})
+	go executarPeriodicamente(ctx, "gerar despesas recorrentes", time.Hour, func(agora time.Time) error {
+		total, err := expenses.Gerar(db, agora)
+		if err == nil && total > 0 {
+			log.Printf("%d despesas recorrentes geradas", total)
+		}
+		return err
+	})
+	go executarPeriodicamente(ctx, "verificar orçamentos de despesas", 10*time.Minute, func(agora time.Time) error {
+		total, err := budgets.Verificar(db, agora)
+		if err == nil && total > 0 {
+			log.Printf("%d notificações de orçamento de despesas enviadas", total)
+		}
+		return err
+	})
+	retencao := time.Duration(cfg.LixeiraRetencaoDias) * 24 * time.Hour
+	go executarPeriodicamente(ctx, "esvaziar lixeira", time.Hour, func(agora time.Time) error {
+		total, err := trash.Purgar(db, agora.Add(-retencao))
+		if err == nil && total > 0 {
+			log.Printf("%d itens excluídos definitivamente da lixeira", total)
+		}
+		return err
+	})
+}
+
+// executarPeriodicamente roda tarefa na inicialização e depois a cada intervalo.
+// Erros são registrados no log e a tarefa continua agendada.
+func executarPeriodicamente(ctx context.Context, nome string, intervalo time.Duration, tarefa func(agora time.Time) error) {
+	ticker := time.NewTicker(intervalo)
+	defer ticker.Stop()
+
+	agora := time.Now()
+	for {
+		if err := tarefa(agora); err != nil {
+			log.Printf("Erro na tarefa %q: %v", nome, err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case agora = <-ticker.C:
+		}
+	}
+}
@@ -1,12 +1,15 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
 
 	"cmdimport/backend/config"
 	"cmdimport/backend/database"
+	"cmdimport/backend/jobs"
 	"cmdimport/backend/routes"
+	"cmdimport/backend/timezone"
 
 	"github.com/gin-gonic/gin"
 )
@@ -15,12 +18,20 @@ func main() {
 	// Carregar configurações
 	cfg := config.Load()
 
+	// Fuso horário do negócio (datas dos filtros e relatórios)
+	if err := timezone.Configurar(cfg.Timezone); err != nil {
+		log.Fatalf("Erro ao configurar TIMEZONE: %v", err)
+	}
+
 	// Conectar ao banco de dados
 	db, err := database.Connect(cfg.DatabaseURL)
 	if err != nil {
 		log.Fatalf("Erro ao conectar ao banco de dados: %v", err)
 	}
 
+	// Tarefas periódicas (preços agendados, ...)
+	jobs.Iniciar(context.Background(), db, cfg)
+
 	// Configurar Gin
 	ginMode := os.Getenv("GIN_MODE")
 	if ginMode == "" {
@@ -31,6 +42,12 @@ func main() {
 	// Criar router
 	router := gin.Default()
 
+	// Só os proxies configurados definem o IP do cliente (X-Forwarded-For); sem eles, vale o IP da conexão,
+	// usado no limite de consultas das rotas públicas
+	if err := router.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Fatalf("Erro ao configurar TRUSTED_PROXIES: %v", err)
+	}
+
 	// Configurar rotas
 	routes.SetupRoutes(router, db, cfg)
 
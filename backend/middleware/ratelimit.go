@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// janelaIP conta as requisições de um IP na janela atual
+type janelaIP struct {
+	inicio time.Time
+	total  int
+}
+
+// RateLimit limita cada IP a max requisições por janela. Usado nas rotas públicas,
+// que não passam pelo AuthMiddleware. Os contadores ficam em memória (por instância).
+func RateLimit(max int, janela time.Duration) gin.HandlerFunc {
+	var mu sync.Mutex
+	ips := make(map[string]*janelaIP)
+	ultimaLimpeza := time.Now()
+
+	return func(c *gin.Context) {
+		agora := time.Now()
+		ip := c.ClientIP()
+
+		mu.Lock()
+		// Descartar janelas vencidas de tempos em tempos para o mapa não crescer sem limite
+		if agora.Sub(ultimaLimpeza) > janela {
+			for chave, j := range ips {
+				if agora.Sub(j.inicio) >= janela {
+					delete(ips, chave)
+				}
+			}
+			ultimaLimpeza = agora
+		}
+
+		j, ok := ips[ip]
+		if !ok || agora.Sub(j.inicio) >= janela {
+			j = &janelaIP{inicio: agora}
+			ips[ip] = j
+		}
+		j.total++
+		excedeu := j.total > max
+		espera := j.inicio.Add(janela).Sub(agora)
+		mu.Unlock()
+
+		if excedeu {
+			c.Header("Retry-After", strconv.Itoa(int(espera.Seconds())+1))
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"success": false,
+				"message": "Muitas consultas. Tente novamente em alguns minutos",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
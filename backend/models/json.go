@@ -0,0 +1,123 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+
+	"cmdimport/backend/money"
+)
+
+// PrecosPlanos guarda em uma coluna JSON o preço de cada plano de pagamento (chave: código do plano)
+type PrecosPlanos map[string]money.Dinheiro
+
+// Value grava o mapa como JSON
+func (p PrecosPlanos) Value() (driver.Value, error) {
+	if len(p) == 0 {
+		return nil, nil
+	}
+	b, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// Scan lê o JSON gravado na coluna
+func (p *PrecosPlanos) Scan(valor interface{}) error {
+	var b []byte
+	switch v := valor.(type) {
+	case nil:
+		*p = nil
+		return nil
+	case []byte:
+		b = v
+	case string:
+		b = []byte(v)
+	default:
+		return fmt.Errorf("tipo inválido para PrecosPlanos: %T", valor)
+	}
+	if len(b) == 0 {
+		*p = nil
+		return nil
+	}
+	return json.Unmarshal(b, p)
+}
+
+// FaixaComissao é uma faixa mensal de comissão: a partir de ValorMinimo vendido no mês, vale Percentual
+type FaixaComissao struct {
+	ValorMinimo money.Dinheiro `json:"valorMinimo"`
+	Percentual  float64        `json:"percentual"`
+}
+
+// FaixasComissao guarda em uma coluna JSON as faixas mensais de uma regra de comissão
+type FaixasComissao []FaixaComissao
+
+// Value grava as faixas como JSON
+func (f FaixasComissao) Value() (driver.Value, error) {
+	if len(f) == 0 {
+		return nil, nil
+	}
+	b, err := json.Marshal(f)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// Scan lê o JSON gravado na coluna
+func (f *FaixasComissao) Scan(valor interface{}) error {
+	var b []byte
+	switch v := valor.(type) {
+	case nil:
+		*f = nil
+		return nil
+	case []byte:
+		b = v
+	case string:
+		b = []byte(v)
+	default:
+		return fmt.Errorf("tipo inválido para FaixasComissao: %T", valor)
+	}
+	if len(b) == 0 {
+		*f = nil
+		return nil
+	}
+	return json.Unmarshal(b, f)
+}
+
+// Percentuais guarda em uma coluna JSON uma lista de percentuais (ex.: limites de alerta de um orçamento)
+type Percentuais []float64
+
+// Value grava a lista como JSON
+func (p Percentuais) Value() (driver.Value, error) {
+	if len(p) == 0 {
+		return nil, nil
+	}
+	b, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// Scan lê o JSON gravado na coluna
+func (p *Percentuais) Scan(valor interface{}) error {
+	var b []byte
+	switch v := valor.(type) {
+	case nil:
+		*p = nil
+		return nil
+	case []byte:
+		b = v
+	case string:
+		b = []byte(v)
+	default:
+		return fmt.Errorf("tipo inválido para Percentuais: %T", valor)
+	}
+	if len(b) == 0 {
+		*p = nil
+		return nil
+	}
+	return json.Unmarshal(b, p)
+}
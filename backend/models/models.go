@@ -1,7 +1,12 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
+
+	"cmdimport/backend/money"
+
+	"gorm.io/gorm"
 )
 
 // Usuario representa um usuário do sistema
@@ -11,6 +16,7 @@ type Usuario struct {
 	Email          string         `gorm:"type:varchar(255);uniqueIndex;not null" json:"email"`
 	Senha          string         `gorm:"type:varchar(255);not null" json:"-"` // Não serializar senha
 	IsAdmin        bool           `gorm:"default:false;column:isAdmin" json:"isAdmin"`
+	LojaID         *int           `gorm:"column:lojaId" json:"lojaId"` // Loja em que o vendedor trabalha
 	CreatedAt      time.Time      `gorm:"column:createdAt" json:"createdAt"`
 	UpdatedAt      time.Time      `gorm:"column:updatedAt" json:"updatedAt"`
 	HistoricoVendas []HistoricoVenda `gorm:"foreignKey:UsuarioID" json:"-"`
@@ -31,17 +37,20 @@ type ProdutoComprado struct {
 	Cor               *string        `json:"cor"`
 	IMEI              *string        `gorm:"type:varchar(255);uniqueIndex" json:"imei"`
 	CodigoBarras      *string        `gorm:"column:codigoBarras" json:"codigoBarras"`
-	CustoDolar        float64        `gorm:"type:decimal(10,2);not null;column:custoDolar" json:"custoDolar"`
+	CustoDolar        money.Dinheiro        `gorm:"type:decimal(10,2);not null;column:custoDolar" json:"custoDolar"`
 	TaxaDolar         float64        `gorm:"type:decimal(10,4);not null;column:taxaDolar" json:"taxaDolar"`
-	Preco             float64        `gorm:"type:decimal(10,2);not null;column:preco" json:"preco"`
+	Preco             money.Dinheiro        `gorm:"type:decimal(10,2);not null;column:preco" json:"preco"`
 	Quantidade        int            `gorm:"default:0" json:"quantidade"`
 	QuantidadeBackup  int            `gorm:"default:0;column:quantidadeBackup" json:"quantidadeBackup"`
 	Fornecedor        *string        `json:"fornecedor"`
 	DataCompra        time.Time      `gorm:"type:datetime;column:dataCompra" json:"dataCompra"`
 	CategoriaID       *int           `gorm:"column:categoriaId" json:"categoriaId"`
 	Categoria         *CategoriaProduto `gorm:"foreignKey:CategoriaID" json:"categoria,omitempty"`
+	SKUID             *int           `gorm:"column:skuId;index" json:"skuId"`
+	SKU               *ProdutoSKU    `gorm:"foreignKey:SKUID" json:"sku,omitempty"`
 	CreatedAt         time.Time      `gorm:"column:createdAt" json:"createdAt"`
 	UpdatedAt         time.Time      `gorm:"column:updatedAt" json:"updatedAt"`
+	DeletedAt         gorm.DeletedAt `gorm:"column:deletedAt;index" json:"deletedAt,omitempty"` // Na lixeira desde; o estoque distribuído vai junto
 	Estoque           []Estoque      `gorm:"foreignKey:ProdutoCompradoID" json:"estoque,omitempty"`
 	HistoricoDistribuicao []HistoricoDistribuicao `gorm:"foreignKey:ProdutoCompradoID" json:"-"`
 }
@@ -63,6 +72,7 @@ type Estoque struct {
 	AtendenteNome    *string        `gorm:"column:atendenteNome" json:"atendenteNome"`
 	CreatedAt        time.Time      `gorm:"column:createdAt" json:"createdAt"`
 	UpdatedAt        time.Time      `gorm:"column:updatedAt" json:"updatedAt"`
+	DeletedAt        gorm.DeletedAt `gorm:"column:deletedAt;index" json:"deletedAt,omitempty"` // Mesma data do produto quando ele vai para a lixeira
 	HistoricoVendas  []HistoricoVenda `gorm:"foreignKey:EstoqueID" json:"-"`
 }
 
@@ -80,23 +90,31 @@ type HistoricoVenda struct {
 	Endereco        string         `gorm:"not null" json:"endereco"`
 	ProdutoNome     string         `gorm:"not null;column:produtoNome" json:"produtoNome"`
 	Quantidade      int            `gorm:"not null" json:"quantidade"`
-	PrecoUnitario   float64        `gorm:"type:decimal(10,2);not null;column:precoUnitario" json:"precoUnitario"`
-	ValorTotal      float64        `gorm:"type:decimal(10,2);not null;column:valorTotal" json:"valorTotal"`
+	PrecoUnitario   money.Dinheiro        `gorm:"type:decimal(10,2);not null;column:precoUnitario" json:"precoUnitario"`
+	ValorTotal      money.Dinheiro        `gorm:"type:decimal(10,2);not null;column:valorTotal" json:"valorTotal"`
 	Observacoes     *string        `json:"observacoes"`
 	VendedorNome     string         `gorm:"not null;column:vendedorNome" json:"vendedorNome"`
 	VendedorEmail    string         `gorm:"not null;column:vendedorEmail" json:"vendedorEmail"`
 	FormaPagamento  string         `gorm:"not null;column:formaPagamento" json:"formaPagamento"`
-	ValorPix         *float64      `gorm:"type:decimal(10,2);column:valorPix" json:"valorPix"`
-	ValorCartao      *float64      `gorm:"type:decimal(10,2);column:valorCartao" json:"valorCartao"`
-	ValorDinheiro    *float64      `gorm:"type:decimal(10,2);column:valorDinheiro" json:"valorDinheiro"`
+	ValorPix         *money.Dinheiro      `gorm:"type:decimal(10,2);column:valorPix" json:"valorPix"`
+	ValorCartao      *money.Dinheiro      `gorm:"type:decimal(10,2);column:valorCartao" json:"valorCartao"`
+	ValorDinheiro    *money.Dinheiro      `gorm:"type:decimal(10,2);column:valorDinheiro" json:"valorDinheiro"`
 	FotoProduto      *string        `gorm:"column:fotoProduto" json:"fotoProduto"`
 	TipoCliente      *string        `gorm:"column:tipoCliente" json:"tipoCliente"`
 	EstoqueID        int            `gorm:"not null;column:estoqueId" json:"estoqueId"`
 	Estoque          Estoque        `gorm:"foreignKey:EstoqueID" json:"-"`
 	UsuarioID        int            `gorm:"not null;column:usuarioId" json:"usuarioId"`
 	Usuario          Usuario        `gorm:"foreignKey:UsuarioID" json:"-"`
+	LojaID           *int           `gorm:"index;column:lojaId" json:"lojaId"` // Loja do vendedor no momento da venda; não muda na transferência
 	Transferida      bool           `gorm:"default:false;column:transferida" json:"transferida"`
 	VendedorOriginal *string        `gorm:"column:vendedorOriginal" json:"vendedorOriginal"`
+	PrecoTabela      money.Dinheiro        `gorm:"type:decimal(10,2);default:0;column:precoTabela" json:"precoTabela"`       // Preço unitário de tabela, antes dos descontos
+	DescontoItem     money.Dinheiro        `gorm:"type:decimal(10,2);default:0;column:descontoItem" json:"descontoItem"`     // Desconto do item (todas as unidades)
+	DescontoVenda    money.Dinheiro        `gorm:"type:decimal(10,2);default:0;column:descontoVenda" json:"descontoVenda"`   // Parte do desconto da venda rateada para o item
+	DescontoCupom    money.Dinheiro        `gorm:"type:decimal(10,2);default:0;column:descontoCupom" json:"descontoCupom"`   // Parte do desconto do cupom rateada para o item
+	CupomCodigo      *string        `gorm:"type:varchar(50);column:cupomCodigo" json:"cupomCodigo"`
+	MotivoDesconto   *string        `gorm:"column:motivoDesconto" json:"motivoDesconto"`
+	AprovacaoDescontoID *int        `gorm:"column:aprovacaoDescontoId" json:"aprovacaoDescontoId"`
 	CreatedAt        time.Time      `gorm:"column:createdAt" json:"createdAt"`
 }
 
@@ -105,14 +123,142 @@ func (HistoricoVenda) TableName() string {
 	return "HistoricoVenda"
 }
 
+// DevolucaoVenda registra produtos que voltaram ao estoque depois da venda (item ou venda deletada, quantidade
+// reduzida ou produto trocado). A DRE lança a devolução no mês em que aconteceu.
+type DevolucaoVenda struct {
+	ID               int            `gorm:"primaryKey" json:"id"`
+	HistoricoVendaID int            `gorm:"not null;index;column:historicoVendaId" json:"historicoVendaId"` // O item pode já ter sido deletado
+	VendaID          *string        `gorm:"type:varchar(191);index;column:vendaId" json:"vendaId"`
+	UsuarioID        int            `gorm:"not null;index;column:usuarioId" json:"usuarioId"` // Vendedor da venda
+	LojaID           *int           `gorm:"index;column:lojaId" json:"lojaId"`                // Loja da venda
+	EstoqueID        int            `gorm:"not null;column:estoqueId" json:"estoqueId"`
+	ProdutoNome      string         `gorm:"not null;column:produtoNome" json:"produtoNome"`
+	Quantidade       int            `gorm:"not null" json:"quantidade"`
+	Valor            money.Dinheiro `gorm:"type:decimal(10,2);not null" json:"valor"` // Receita estornada
+	Custo            money.Dinheiro `gorm:"type:decimal(10,2);not null" json:"custo"` // Custo dos produtos devolvidos ao estoque
+	DataVenda        time.Time      `gorm:"not null;index;column:dataVenda" json:"dataVenda"`
+	CreatedAt        time.Time      `gorm:"index;column:createdAt" json:"createdAt"`
+}
+
+// TableName especifica o nome da tabela no banco
+func (DevolucaoVenda) TableName() string {
+	return "DevolucaoVenda"
+}
+
+// PagamentoVenda é uma linha de pagamento de uma venda (uma venda pode ser paga em várias formas).
+// A soma das linhas é igual ao valor total da venda.
+
+type PagamentoVenda struct {
+	ID            int             `gorm:"primaryKey" json:"id"`
+	VendaID       string          `gorm:"type:varchar(191);not null;index;column:vendaId" json:"vendaId"`
+	Metodo        string          `gorm:"type:varchar(20);not null" json:"metodo"` // "dinheiro", "pix", "debito", "credito", "crediario", "boleto", "troca" ou "outro"
+	Valor         money.Dinheiro  `gorm:"type:decimal(10,2);not null" json:"valor"`
+	Parcelas      int             `gorm:"default:1" json:"parcelas"`
+	ValorRecebido *money.Dinheiro `gorm:"type:decimal(10,2);column:valorRecebido" json:"valorRecebido"` // Dinheiro entregue pelo cliente
+	Troco         money.Dinheiro  `gorm:"type:decimal(10,2);default:0" json:"troco"`
+	NSU           *string         `gorm:"type:varchar(50);column:nsu" json:"nsu"`
+	Autorizacao   *string         `gorm:"type:varchar(50)" json:"autorizacao"`
+	Bandeira      *string         `gorm:"type:varchar(30)" json:"bandeira"`
+	Vencimento    *time.Time      `gorm:"type:date" json:"vencimento"` // Pix ou boleto a prazo: data combinada com o cliente
+	CreatedAt     time.Time       `gorm:"column:createdAt" json:"createdAt"`
+}
+
+// TableName especifica o nome da tabela no banco
+func (PagamentoVenda) TableName() string {
+	return "PagamentoVenda"
+}
+
+// AparelhoTroca é um aparelho usado recebido como parte do pagamento de uma venda.
+// Entra no estoque como ProdutoComprado com custo igual ao valor avaliado.
+type AparelhoTroca struct {
+	ID                int            `gorm:"primaryKey" json:"id"`
+	VendaID           string         `gorm:"type:varchar(191);not null;index;column:vendaId" json:"vendaId"`
+	PagamentoVendaID  int            `gorm:"not null;column:pagamentoVendaId" json:"pagamentoVendaId"`
+	ProdutoCompradoID int            `gorm:"not null;column:produtoCompradoId" json:"produtoCompradoId"`
+	Modelo            string         `gorm:"type:varchar(255);not null" json:"modelo"`
+	IMEI              *string        `gorm:"type:varchar(255);column:imei" json:"imei"`
+	Cor               *string        `gorm:"type:varchar(100)" json:"cor"`
+	Condicao          string         `gorm:"type:varchar(20);not null" json:"condicao"` // "excelente", "bom", "regular" ou "defeito"
+	Observacoes       *string        `gorm:"type:text" json:"observacoes"`
+	ValorAvaliado     money.Dinheiro `gorm:"type:decimal(10,2);not null;column:valorAvaliado" json:"valorAvaliado"`
+	ClienteNome       string         `gorm:"not null;column:clienteNome" json:"clienteNome"`
+	UsuarioID         int            `gorm:"not null;column:usuarioId" json:"usuarioId"` // Vendedor que recebeu o aparelho
+	CreatedAt         time.Time      `gorm:"column:createdAt" json:"createdAt"`
+}
+
+// TableName especifica o nome da tabela no banco
+func (AparelhoTroca) TableName() string {
+	return "AparelhoTroca"
+}
+
+// Cupom é um código de desconto aplicado sobre o total da venda
+type Cupom struct {
+	ID          int            `gorm:"primaryKey" json:"id"`
+	Codigo      string         `gorm:"type:varchar(50);uniqueIndex;not null" json:"codigo"`
+	Descricao   *string        `json:"descricao"`
+	Tipo        string         `gorm:"type:varchar(20);not null" json:"tipo"` // "percentual" ou "valor"
+	Valor       float64        `gorm:"type:decimal(10,2);not null" json:"valor"`
+	ValidoDe    *time.Time     `gorm:"type:datetime;column:validoDe" json:"validoDe"`
+	ValidoAte   *time.Time     `gorm:"type:datetime;column:validoAte" json:"validoAte"`
+	LimiteUsos  *int           `gorm:"column:limiteUsos" json:"limiteUsos"` // nil = sem limite
+	Usos        int            `gorm:"default:0" json:"usos"`
+	ValorMinimo money.Dinheiro `gorm:"type:decimal(10,2);default:0;column:valorMinimo" json:"valorMinimo"`
+	Ativo       bool           `gorm:"default:true" json:"ativo"`
+	CreatedAt   time.Time      `gorm:"column:createdAt" json:"createdAt"`
+	UpdatedAt   time.Time      `gorm:"column:updatedAt" json:"updatedAt"`
+}
+
+// TableName especifica o nome da tabela no banco
+func (Cupom) TableName() string {
+	return "Cupom"
+}
+
+// LimiteDesconto é o desconto máximo (%) que cada papel pode dar sem aprovação do admin
+type LimiteDesconto struct {
+	ID               int       `gorm:"primaryKey" json:"id"`
+	Papel            string    `gorm:"type:varchar(20);uniqueIndex;not null" json:"papel"` // "vendedor" ou "admin"
+	PercentualMaximo float64   `gorm:"type:decimal(5,2);not null;column:percentualMaximo" json:"percentualMaximo"`
+	CreatedAt        time.Time `gorm:"column:createdAt" json:"createdAt"`
+	UpdatedAt        time.Time `gorm:"column:updatedAt" json:"updatedAt"`
+}
+
+// TableName especifica o nome da tabela no banco
+func (LimiteDesconto) TableName() string {
+	return "LimiteDesconto"
+}
+
+// AprovacaoDesconto é o pedido do vendedor para dar um desconto acima do seu limite.
+// Depois de aprovada, vale para uma única venda do solicitante.
+type AprovacaoDesconto struct {
+	ID              int        `gorm:"primaryKey" json:"id"`
+	SolicitanteID   int        `gorm:"not null;index;column:solicitanteId" json:"solicitanteId"`
+	SolicitanteNome string     `gorm:"not null;column:solicitanteNome" json:"solicitanteNome"`
+	ClienteNome     *string    `gorm:"column:clienteNome" json:"clienteNome"`
+	Percentual      float64    `gorm:"type:decimal(5,2);not null" json:"percentual"` // Desconto máximo autorizado
+	Motivo          string     `gorm:"type:text;not null" json:"motivo"`
+	Status          string     `gorm:"type:varchar(20);not null;default:pendente;index" json:"status"` // "pendente", "aprovada", "recusada" ou "usada"
+	AprovadorID     *int       `gorm:"column:aprovadorId" json:"aprovadorId"`
+	AprovadorNome   *string    `gorm:"column:aprovadorNome" json:"aprovadorNome"`
+	DecididoEm      *time.Time `gorm:"type:datetime;column:decididoEm" json:"decididoEm"`
+	VendaID         *string    `gorm:"column:vendaId" json:"vendaId"`
+	CreatedAt       time.Time  `gorm:"column:createdAt" json:"createdAt"`
+	UpdatedAt       time.Time  `gorm:"column:updatedAt" json:"updatedAt"`
+}
+
+// TableName especifica o nome da tabela no banco
+func (AprovacaoDesconto) TableName() string {
+	return "AprovacaoDesconto"
+}
+
 // CategoriaDespesa representa uma categoria de despesas
 type CategoriaDespesa struct {
-	ID        int       `gorm:"primaryKey" json:"id"`
-	Nome      string    `gorm:"type:varchar(255);not null" json:"nome"`
-	Descricao *string   `gorm:"type:text" json:"descricao"`
-	CreatedAt time.Time `gorm:"column:createdAt" json:"createdAt"`
-	UpdatedAt time.Time `gorm:"column:updatedAt" json:"updatedAt"`
-	Despesas  []Despesa `gorm:"foreignKey:CategoriaID" json:"-"`
+	ID        int            `gorm:"primaryKey" json:"id"`
+	Nome      string         `gorm:"type:varchar(255);not null" json:"nome"`
+	Descricao *string        `gorm:"type:text" json:"descricao"`
+	CreatedAt time.Time      `gorm:"column:createdAt" json:"createdAt"`
+	UpdatedAt time.Time      `gorm:"column:updatedAt" json:"updatedAt"`
+	DeletedAt gorm.DeletedAt `gorm:"column:deletedAt;index" json:"deletedAt,omitempty"` // Na lixeira desde; as despesas vão junto
+	Despesas  []Despesa      `gorm:"foreignKey:CategoriaID" json:"-"`
 }
 
 // TableName especifica o nome da tabela no banco
@@ -122,15 +268,20 @@ func (CategoriaDespesa) TableName() string {
 
 // Despesa representa uma despesa
 type Despesa struct {
-	ID          int             `gorm:"primaryKey" json:"id"`
-	Nome        string          `gorm:"type:varchar(255);not null" json:"nome"`
-	Valor       float64         `gorm:"type:decimal(10,2);not null" json:"valor"`
-	CategoriaID int             `gorm:"not null;column:categoriaId" json:"categoriaId"`
-	Categoria   CategoriaDespesa `gorm:"foreignKey:CategoriaID" json:"categoria,omitempty"`
-	Descricao   *string         `gorm:"type:text" json:"descricao"`
-	Data        time.Time       `gorm:"type:date;not null" json:"data"`
-	CreatedAt   time.Time       `gorm:"column:createdAt" json:"createdAt"`
-	UpdatedAt   time.Time       `gorm:"column:updatedAt" json:"updatedAt"`
+	ID                  int              `gorm:"primaryKey" json:"id"`
+	Nome                string           `gorm:"type:varchar(255);not null" json:"nome"`
+	Valor               money.Dinheiro   `gorm:"type:decimal(10,2);not null" json:"valor"`
+	CategoriaID         int              `gorm:"not null;column:categoriaId" json:"categoriaId"`
+	Categoria           CategoriaDespesa `gorm:"foreignKey:CategoriaID" json:"categoria,omitempty"`
+	Descricao           *string          `gorm:"type:text" json:"descricao"`
+	Data                time.Time        `gorm:"type:date;not null;uniqueIndex:idx_despesa_recorrente_data" json:"data"` // Competência; para despesas a pagar, o vencimento
+	Status              string           `gorm:"type:varchar(20);not null;default:paga" json:"status"`                   // "pendente" ou "paga"
+	PagaEm              *time.Time       `gorm:"type:date;column:pagaEm" json:"pagaEm"`
+	DespesaRecorrenteID *int             `gorm:"column:despesaRecorrenteId;uniqueIndex:idx_despesa_recorrente_data" json:"despesaRecorrenteId"` // Recorrência que gerou a despesa
+	Anexos              []AnexoDespesa   `gorm:"foreignKey:DespesaID" json:"anexos,omitempty"`
+	CreatedAt           time.Time        `gorm:"column:createdAt" json:"createdAt"`
+	UpdatedAt           time.Time        `gorm:"column:updatedAt" json:"updatedAt"`
+	DeletedAt           gorm.DeletedAt   `gorm:"column:deletedAt;index" json:"deletedAt,omitempty"` // Na lixeira desde
 }
 
 // TableName especifica o nome da tabela no banco
@@ -138,16 +289,75 @@ func (Despesa) TableName() string {
 	return "Despesa"
 }
 
+// AnexoDespesa é um comprovante (nota fiscal, recibo) de uma despesa: PDF, JPEG ou PNG guardado fora da
+// pasta pública e baixado só com autenticação
+type AnexoDespesa struct {
+	ID           int       `gorm:"primaryKey" json:"id"`
+	DespesaID    int       `gorm:"not null;index;column:despesaId" json:"despesaId"`
+	NomeOriginal string    `gorm:"type:varchar(255);not null;column:nomeOriginal" json:"nomeOriginal"`
+	Caminho      string    `gorm:"type:varchar(255);not null" json:"-"` // Relativo à pasta de arquivos
+	TipoConteudo string    `gorm:"type:varchar(100);not null;column:tipoConteudo" json:"tipoConteudo"`
+	Tamanho      int64     `gorm:"not null" json:"tamanho"`           // Em bytes
+	UsuarioID    *int      `gorm:"column:usuarioId" json:"usuarioId"` // Quem enviou
+	CreatedAt    time.Time `gorm:"column:createdAt" json:"createdAt"`
+}
+
+// TableName especifica o nome da tabela no banco
+func (AnexoDespesa) TableName() string {
+	return "AnexoDespesa"
+}
+
+// OrcamentoDespesa é o orçamento mensal de uma categoria de despesas. Ao atingir cada percentual de Alertas,
+// os administradores recebem uma notificação.
+type OrcamentoDespesa struct {
+	ID          int            `gorm:"primaryKey" json:"id"`
+	CategoriaID int            `gorm:"not null;column:categoriaId;uniqueIndex:idx_orcamento_categoria_mes" json:"categoriaId"`
+	Mes         string         `gorm:"type:varchar(7);not null;uniqueIndex:idx_orcamento_categoria_mes" json:"mes"` // YYYY-MM
+	Valor       money.Dinheiro `gorm:"type:decimal(10,2);not null" json:"valor"`
+	Alertas     Percentuais    `gorm:"type:json" json:"alertas"` // Percentuais do orçamento que disparam alerta, ex.: [80, 100]
+	CreatedAt   time.Time      `gorm:"column:createdAt" json:"createdAt"`
+	UpdatedAt   time.Time      `gorm:"column:updatedAt" json:"updatedAt"`
+}
+
+// TableName especifica o nome da tabela no banco
+func (OrcamentoDespesa) TableName() string {
+	return "OrcamentoDespesa"
+}
+
+// DespesaRecorrente é o modelo de uma despesa que se repete (aluguel, salários, assinaturas). Uma tarefa em
+// segundo plano gera a Despesa de cada ocorrência, como pendente, quando chega a data.
+type DespesaRecorrente struct {
+	ID          int            `gorm:"primaryKey" json:"id"`
+	Nome        string         `gorm:"type:varchar(255);not null" json:"nome"`
+	Valor       money.Dinheiro `gorm:"type:decimal(10,2);not null" json:"valor"`
+	CategoriaID int            `gorm:"not null;column:categoriaId" json:"categoriaId"`
+	Descricao   *string        `gorm:"type:text" json:"descricao"`
+	Frequencia  string         `gorm:"type:varchar(20);not null" json:"frequencia"`            // "semanal", "mensal" ou "anual"
+	DataInicio  time.Time      `gorm:"type:date;not null;column:dataInicio" json:"dataInicio"` // Primeira ocorrência; define o dia das seguintes
+	DataFim     *time.Time     `gorm:"type:date;column:dataFim" json:"dataFim"`                // Última data possível, inclusive
+	Geradas     int            `gorm:"not null;default:0" json:"geradas"`                      // Ocorrências já geradas
+	ProximaData time.Time      `gorm:"type:date;not null;index;column:proximaData" json:"proximaData"`
+	Ativo       bool           `gorm:"default:true" json:"ativo"`
+	CreatedAt   time.Time      `gorm:"column:createdAt" json:"createdAt"`
+	UpdatedAt   time.Time      `gorm:"column:updatedAt" json:"updatedAt"`
+}
+
+// TableName especifica o nome da tabela no banco
+func (DespesaRecorrente) TableName() string {
+	return "DespesaRecorrente"
+}
+
 // CategoriaProduto representa uma categoria de produtos
 type CategoriaProduto struct {
-	ID        int       `gorm:"primaryKey" json:"id"`
-	Nome      string    `gorm:"type:varchar(255);uniqueIndex;not null" json:"nome"`
-	Descricao *string   `gorm:"type:text" json:"descricao"`
-	Icone     *string   `gorm:"type:varchar(100)" json:"icone"`
-	Cor       *string   `gorm:"type:varchar(20)" json:"cor"`
-	Ativo     bool      `gorm:"default:true" json:"ativo"`
-	CreatedAt time.Time `gorm:"column:createdAt" json:"createdAt"`
-	UpdatedAt time.Time `gorm:"column:updatedAt" json:"updatedAt"`
+	ID        int               `gorm:"primaryKey" json:"id"`
+	Nome      string            `gorm:"type:varchar(255);uniqueIndex;not null" json:"nome"`
+	Descricao *string           `gorm:"type:text" json:"descricao"`
+	Icone     *string           `gorm:"type:varchar(100)" json:"icone"`
+	Cor       *string           `gorm:"type:varchar(20)" json:"cor"`
+	Ativo     bool              `gorm:"default:true" json:"ativo"`
+	CreatedAt time.Time         `gorm:"column:createdAt" json:"createdAt"`
+	UpdatedAt time.Time         `gorm:"column:updatedAt" json:"updatedAt"`
+	DeletedAt gorm.DeletedAt    `gorm:"column:deletedAt;index" json:"deletedAt,omitempty"` // Na lixeira desde
 	Produtos  []ProdutoComprado `gorm:"foreignKey:CategoriaID" json:"-"`
 }
 
@@ -176,12 +386,16 @@ func (HistoricoDistribuicao) TableName() string {
 type Precificacao struct {
 	ID                int       `gorm:"primaryKey" json:"id"`
 	NomeProduto       string    `gorm:"type:varchar(255);uniqueIndex;not null;column:nomeProduto" json:"nomeProduto"`
-	ValorDinheiroPix  float64   `gorm:"type:decimal(10,2);default:0;column:valorDinheiroPix" json:"valorDinheiroPix"`
-	ValorDebito       float64   `gorm:"type:decimal(10,2);default:0;column:valorDebito" json:"valorDebito"`
-	ValorCartaoVista  float64   `gorm:"type:decimal(10,2);default:0;column:valorCartaoVista" json:"valorCartaoVista"`
-	ValorCredito5x    float64   `gorm:"type:decimal(10,2);default:0;column:valorCredito5x" json:"valorCredito5x"`
-	ValorCredito10x   float64   `gorm:"type:decimal(10,2);default:0;column:valorCredito10x" json:"valorCredito10x"`
-	ValorCredito12x   float64   `gorm:"type:decimal(10,2);default:0;column:valorCredito12x" json:"valorCredito12x"`
+	SKUID             *int      `gorm:"uniqueIndex;column:skuId" json:"skuId"` // Preços por SKU; nomeProduto fica para registros antigos
+	SKU               *ProdutoSKU `gorm:"foreignKey:SKUID" json:"sku,omitempty"`
+	ValorDinheiroPix  money.Dinheiro   `gorm:"type:decimal(10,2);default:0;column:valorDinheiroPix" json:"valorDinheiroPix"`
+	ValorDebito       money.Dinheiro   `gorm:"type:decimal(10,2);default:0;column:valorDebito" json:"valorDebito"`
+	ValorCartaoVista  money.Dinheiro   `gorm:"type:decimal(10,2);default:0;column:valorCartaoVista" json:"valorCartaoVista"`
+	ValorCredito5x    money.Dinheiro   `gorm:"type:decimal(10,2);default:0;column:valorCredito5x" json:"valorCredito5x"`
+	ValorCredito10x   money.Dinheiro   `gorm:"type:decimal(10,2);default:0;column:valorCredito10x" json:"valorCredito10x"`
+	ValorCredito12x   money.Dinheiro   `gorm:"type:decimal(10,2);default:0;column:valorCredito12x" json:"valorCredito12x"`
+	PrecosPlanos      PrecosPlanos `gorm:"type:json;column:precosPlanos" json:"precosPlanos"` // Preços dos planos sem coluna própria (ex.: credito_3x)
+	Manual            bool      `gorm:"default:false;column:manual" json:"manual"` // Preços digitados pelo admin; as regras não sobrescrevem
 	CreatedAt         time.Time `gorm:"column:createdAt" json:"createdAt"`
 	UpdatedAt         time.Time `gorm:"column:updatedAt" json:"updatedAt"`
 }
@@ -191,3 +405,448 @@ func (Precificacao) TableName() string {
 	return "Precificacao"
 }
 
+
+// PrecificacaoVersao guarda cada versão de preço de uma precificação com o período em que vale.
+// Versões com vigenteDe no futuro são mudanças agendadas; ao entrarem em vigor são copiadas
+// para a Precificacao (aplicada = true), que mantém os preços atuais.
+type PrecificacaoVersao struct {
+	ID               int        `gorm:"primaryKey" json:"id"`
+	PrecificacaoID   int        `gorm:"not null;index;column:precificacaoId" json:"precificacaoId"`
+	ValorDinheiroPix money.Dinheiro    `gorm:"type:decimal(10,2);default:0;column:valorDinheiroPix" json:"valorDinheiroPix"`
+	ValorDebito      money.Dinheiro    `gorm:"type:decimal(10,2);default:0;column:valorDebito" json:"valorDebito"`
+	ValorCartaoVista money.Dinheiro    `gorm:"type:decimal(10,2);default:0;column:valorCartaoVista" json:"valorCartaoVista"`
+	ValorCredito5x   money.Dinheiro    `gorm:"type:decimal(10,2);default:0;column:valorCredito5x" json:"valorCredito5x"`
+	ValorCredito10x  money.Dinheiro    `gorm:"type:decimal(10,2);default:0;column:valorCredito10x" json:"valorCredito10x"`
+	ValorCredito12x  money.Dinheiro    `gorm:"type:decimal(10,2);default:0;column:valorCredito12x" json:"valorCredito12x"`
+	PrecosPlanos     PrecosPlanos `gorm:"type:json;column:precosPlanos" json:"precosPlanos"`
+	VigenteDe        time.Time  `gorm:"type:datetime;not null;column:vigenteDe" json:"vigenteDe"`
+	VigenteAte       *time.Time `gorm:"type:datetime;column:vigenteAte" json:"vigenteAte"` // Início da versão seguinte; nil = sem fim
+	AutorID          *int       `gorm:"column:autorId" json:"autorId"`
+	AutorNome        *string    `gorm:"type:varchar(255);column:autorNome" json:"autorNome"`
+	Aplicada         bool       `gorm:"default:false" json:"aplicada"`
+	Origem           string     `gorm:"type:varchar(20);default:manual" json:"origem"` // "manual" ou "regra"
+	CreatedAt        time.Time  `gorm:"column:createdAt" json:"createdAt"`
+}
+
+// TableName especifica o nome da tabela no banco
+func (PrecificacaoVersao) TableName() string {
+	return "PrecificacaoVersao"
+}
+
+// PlanoPagamento é uma forma de pagamento aceita na venda, como "credito_3x".
+// Os planos dinheiro_pix, debito, credito_vista, credito_5x, credito_10x e credito_12x
+// guardam o preço nas colunas próprias de Precificacao; os demais em precosPlanos.
+type PlanoPagamento struct {
+	ID        int       `gorm:"primaryKey" json:"id"`
+	Codigo    string    `gorm:"type:varchar(50);uniqueIndex;not null" json:"codigo"`
+	Nome      string    `gorm:"type:varchar(100);not null" json:"nome"`
+	Metodo    string    `gorm:"type:varchar(20);not null" json:"metodo"` // "dinheiro_pix", "debito" ou "credito"
+	Parcelas  int       `gorm:"default:1" json:"parcelas"`
+	Acrescimo float64   `gorm:"type:decimal(7,2);default:0" json:"acrescimo"` // % sobre o preço à vista quando não há preço próprio
+	Ativo     bool      `gorm:"default:true" json:"ativo"`
+	Ordem     int       `gorm:"default:0" json:"ordem"`
+	CreatedAt time.Time `gorm:"column:createdAt" json:"createdAt"`
+	UpdatedAt time.Time `gorm:"column:updatedAt" json:"updatedAt"`
+}
+
+// TableName especifica o nome da tabela no banco
+func (PlanoPagamento) TableName() string {
+	return "PlanoPagamento"
+}
+
+// RegraPrecificacao define como os preços são calculados a partir do custo.
+// A regra sem categoria (categoriaId nulo) é a padrão para categorias sem regra própria.
+type RegraPrecificacao struct {
+	ID             int               `gorm:"primaryKey" json:"id"`
+	CategoriaID    *int              `gorm:"uniqueIndex;column:categoriaId" json:"categoriaId"`
+	Categoria      *CategoriaProduto `gorm:"foreignKey:CategoriaID" json:"categoria,omitempty"`
+	Markup         float64           `gorm:"type:decimal(7,2);not null" json:"markup"`                              // % sobre o custo final
+	CustoAdicional float64           `gorm:"type:decimal(7,2);default:0;column:custoAdicional" json:"custoAdicional"` // % de frete/impostos sobre o custo do lote
+	CustoFixo      money.Dinheiro           `gorm:"type:decimal(10,2);default:0;column:custoFixo" json:"custoFixo"`          // R$ por unidade
+	Multiplo       money.Dinheiro           `gorm:"type:decimal(10,2);default:0" json:"multiplo"`                        // Arredondamento: 10 com terminação 9,90 gera ...9,90
+	Terminacao     money.Dinheiro           `gorm:"type:decimal(10,2);default:0" json:"terminacao"`
+	Ativo          bool              `gorm:"default:true" json:"ativo"`
+	CreatedAt      time.Time         `gorm:"column:createdAt" json:"createdAt"`
+	UpdatedAt      time.Time         `gorm:"column:updatedAt" json:"updatedAt"`
+}
+
+// TableName especifica o nome da tabela no banco
+func (RegraPrecificacao) TableName() string {
+	return "RegraPrecificacao"
+}
+
+// TaxaAdquirente é a taxa cobrada pela maquininha por método e número de parcelas
+type TaxaAdquirente struct {
+	ID         int       `gorm:"primaryKey" json:"id"`
+	Metodo     string    `gorm:"type:varchar(20);not null;uniqueIndex:idx_taxa_metodo_parcelas" json:"metodo"` // "debito" ou "credito"
+	Parcelas   int       `gorm:"not null;default:1;uniqueIndex:idx_taxa_metodo_parcelas" json:"parcelas"`
+	Percentual float64   `gorm:"type:decimal(5,2);not null" json:"percentual"`
+	CreatedAt  time.Time `gorm:"column:createdAt" json:"createdAt"`
+	UpdatedAt  time.Time `gorm:"column:updatedAt" json:"updatedAt"`
+}
+
+// TableName especifica o nome da tabela no banco
+func (TaxaAdquirente) TableName() string {
+	return "TaxaAdquirente"
+}
+
+// CotacaoDolar representa a cotação do dólar válida a partir de uma data
+type CotacaoDolar struct {
+	ID        int       `gorm:"primaryKey" json:"id"`
+	Data      time.Time `gorm:"type:date;uniqueIndex;not null" json:"data"`
+	Taxa      float64   `gorm:"type:decimal(10,4);not null" json:"taxa"`
+	Fonte     string    `gorm:"type:varchar(50);not null;default:manual" json:"fonte"` // "manual", "csv" ou "http"
+	CreatedAt time.Time `gorm:"column:createdAt" json:"createdAt"`
+	UpdatedAt time.Time `gorm:"column:updatedAt" json:"updatedAt"`
+}
+
+// TableName especifica o nome da tabela no banco
+func (CotacaoDolar) TableName() string {
+	return "CotacaoDolar"
+}
+
+// ProdutoSKU representa um modelo do catálogo (marca, modelo, armazenamento e cor).
+// Lotes comprados apontam para o SKU e a precificação é feita por SKU.
+type ProdutoSKU struct {
+	ID            int               `gorm:"primaryKey" json:"id"`
+	Codigo        string            `gorm:"type:varchar(100);uniqueIndex;not null" json:"codigo"`
+	Nome          string            `gorm:"type:varchar(255);not null" json:"nome"` // Nome de exibição montado a partir dos atributos
+	Marca         string            `gorm:"type:varchar(100);not null" json:"marca"`
+	Modelo        string            `gorm:"type:varchar(255);not null" json:"modelo"`
+	Armazenamento *string           `gorm:"type:varchar(50)" json:"armazenamento"`
+	Cor           *string           `gorm:"type:varchar(50)" json:"cor"`
+	CategoriaID   *int              `gorm:"column:categoriaId" json:"categoriaId"`
+	Categoria     *CategoriaProduto `gorm:"foreignKey:CategoriaID" json:"categoria,omitempty"`
+	Ativo         bool              `gorm:"default:true" json:"ativo"`
+	CreatedAt     time.Time         `gorm:"column:createdAt" json:"createdAt"`
+	UpdatedAt     time.Time         `gorm:"column:updatedAt" json:"updatedAt"`
+}
+
+// TableName especifica o nome da tabela no banco
+func (ProdutoSKU) TableName() string {
+	return "ProdutoSKU"
+}
+
+// GarantiaTermo é o prazo de garantia de um SKU ou de uma categoria.
+// O termo sem SKU e sem categoria é o padrão da loja.
+type GarantiaTermo struct {
+	ID          int       `gorm:"primaryKey" json:"id"`
+	SKUID       *int      `gorm:"uniqueIndex;column:skuId" json:"skuId"`
+	CategoriaID *int      `gorm:"uniqueIndex;column:categoriaId" json:"categoriaId"`
+	Dias        int       `gorm:"not null" json:"dias"`
+	Descricao   *string   `gorm:"type:text" json:"descricao"` // Condições exibidas ao cliente
+	Ativo       bool      `gorm:"default:true" json:"ativo"`
+	CreatedAt   time.Time `gorm:"column:createdAt" json:"createdAt"`
+	UpdatedAt   time.Time `gorm:"column:updatedAt" json:"updatedAt"`
+}
+
+// TableName especifica o nome da tabela no banco
+func (GarantiaTermo) TableName() string {
+	return "GarantiaTermo"
+}
+
+// GarantiaChamado é um pedido de garantia de uma unidade vendida (linha de HistoricoVenda)
+type GarantiaChamado struct {
+	ID                int        `gorm:"primaryKey" json:"id"`
+	HistoricoVendaID  int        `gorm:"not null;index;column:historicoVendaId" json:"historicoVendaId"`
+	VendaID           *string    `gorm:"type:varchar(191);column:vendaId" json:"vendaId"`
+	ProdutoCompradoID int        `gorm:"not null;column:produtoCompradoId" json:"produtoCompradoId"`
+	ProdutoNome       string     `gorm:"not null;column:produtoNome" json:"produtoNome"`
+	IMEI              *string    `gorm:"type:varchar(255);index;column:imei" json:"imei"`
+	ClienteNome       string     `gorm:"not null;column:clienteNome" json:"clienteNome"`
+	Telefone          string     `gorm:"not null" json:"telefone"`
+	Defeito           string     `gorm:"type:text;not null" json:"defeito"`
+	Status            string     `gorm:"type:varchar(20);not null;default:aberto;index" json:"status"` // "aberto", "em_reparo", "trocado", "reparado" ou "recusado"
+	Resolucao         *string    `gorm:"type:text" json:"resolucao"`
+	ForaDaGarantia    bool       `gorm:"default:false;column:foraDaGarantia" json:"foraDaGarantia"` // Aberto pelo admin após o fim do prazo
+	UsuarioID         int        `gorm:"not null;column:usuarioId" json:"usuarioId"`                // Quem abriu o chamado
+	UsuarioNome       string     `gorm:"not null;column:usuarioNome" json:"usuarioNome"`
+	EncerradoEm       *time.Time `gorm:"column:encerradoEm" json:"encerradoEm"`
+	CreatedAt         time.Time  `gorm:"column:createdAt" json:"createdAt"`
+	UpdatedAt         time.Time  `gorm:"column:updatedAt" json:"updatedAt"`
+}
+
+// TableName especifica o nome da tabela no banco
+func (GarantiaChamado) TableName() string {
+	return "GarantiaChamado"
+}
+
+// Orcamento é uma proposta de venda enviada ao cliente. Guarda a requisição de venda
+// (produtos, pagamentos e descontos) para ser convertida em venda sem redigitar.
+type Orcamento struct {
+	ID              int             `gorm:"primaryKey" json:"id"`
+	ClienteNome     string          `gorm:"not null;column:clienteNome" json:"clienteNome"`
+	Telefone        string          `gorm:"not null" json:"telefone"`
+	UsuarioID       int             `gorm:"not null;index;column:usuarioId" json:"usuarioId"` // Vendedor
+	VendedorNome    string          `gorm:"not null;column:vendedorNome" json:"vendedorNome"`
+	Dados           json.RawMessage `gorm:"type:json;not null" json:"dados"` // Requisição de venda (mesmo formato de /vendas/cadastrar)
+	Resumo          json.RawMessage `gorm:"type:json" json:"resumo"` // Produtos, preços e descontos cotados
+	ValorTotal      money.Dinheiro         `gorm:"type:decimal(10,2);not null;column:valorTotal" json:"valorTotal"`
+	ValidoAte       time.Time       `gorm:"not null;column:validoAte" json:"validoAte"`
+	ReservarEstoque bool            `gorm:"default:false;column:reservarEstoque" json:"reservarEstoque"`
+	Status          string          `gorm:"type:varchar(20);not null;default:aberto;index" json:"status"` // "aberto", "convertido", "expirado" ou "cancelado"
+	VendaID         *string         `gorm:"type:varchar(191);column:vendaId" json:"vendaId"`
+	CreatedAt       time.Time       `gorm:"column:createdAt" json:"createdAt"`
+	UpdatedAt       time.Time       `gorm:"column:updatedAt" json:"updatedAt"`
+}
+
+// TableName especifica o nome da tabela no banco
+func (Orcamento) TableName() string {
+	return "Orcamento"
+}
+
+// ReservaEstoque separa uma quantidade de um estoque até expiraEm, para um orçamento ou para um
+// cliente que deu sinal. Enquanto ativa, a quantidade reservada não pode ser vendida por outra venda.
+type ReservaEstoque struct {
+	ID          int        `gorm:"primaryKey" json:"id"`
+	EstoqueID   int        `gorm:"not null;index;column:estoqueId" json:"estoqueId"`
+	Quantidade  int        `gorm:"not null" json:"quantidade"`
+	IMEI        *string    `gorm:"type:varchar(255);column:imei" json:"imei"` // Unidade reservada, quando o produto tem IMEI
+	OrcamentoID *int       `gorm:"index;column:orcamentoId" json:"orcamentoId"`
+	ClienteNome *string    `gorm:"column:clienteNome" json:"clienteNome"`
+	Telefone    *string    `json:"telefone"`
+	ValorSinal  money.Dinheiro    `gorm:"type:decimal(10,2);default:0;column:valorSinal" json:"valorSinal"` // Sinal pago pelo cliente
+	FormaSinal  *string    `gorm:"type:varchar(20);column:formaSinal" json:"formaSinal"`
+	Observacoes *string    `gorm:"type:text" json:"observacoes"`
+	UsuarioID   int        `gorm:"not null;column:usuarioId" json:"usuarioId"` // Quem reservou
+	Status      string     `gorm:"type:varchar(20);not null;default:ativa;index" json:"status"` // "ativa", "convertida", "liberada" ou "expirada"
+	ExpiraEm    time.Time  `gorm:"not null;column:expiraEm" json:"expiraEm"`
+	VendaID     *string    `gorm:"type:varchar(191);column:vendaId" json:"vendaId"` // Venda que usou a reserva
+	EncerradaEm *time.Time `gorm:"column:encerradaEm" json:"encerradaEm"`
+	DestinoSinal *string   `gorm:"type:varchar(20);column:destinoSinal" json:"destinoSinal"` // Ao encerrar com sinal: "abatido" (na venda), "devolvido" ou "retido"
+	CreatedAt   time.Time  `gorm:"column:createdAt" json:"createdAt"`
+	UpdatedAt   time.Time  `gorm:"column:updatedAt" json:"updatedAt"`
+}
+
+// TableName especifica o nome da tabela no banco
+func (ReservaEstoque) TableName() string {
+	return "ReservaEstoque"
+}
+
+// RegraComissao define a comissão de um vendedor ou de um papel, opcionalmente por categoria.
+// Sem vendedor e sem papel, vale para todos. A regra mais específica é a usada.
+type RegraComissao struct {
+	ID          int            `gorm:"primaryKey" json:"id"`
+	UsuarioID   *int           `gorm:"index;column:usuarioId" json:"usuarioId"`
+	Papel       *string        `gorm:"type:varchar(20)" json:"papel"` // "vendedor" ou "admin"
+	CategoriaID *int           `gorm:"column:categoriaId" json:"categoriaId"`
+	Base        string         `gorm:"type:varchar(20);not null;default:valor" json:"base"` // "valor" (valor vendido) ou "margem"
+	Percentual  float64        `gorm:"type:decimal(5,2);not null" json:"percentual"`
+	Faixas      FaixasComissao `gorm:"type:json" json:"faixas"` // Faixas pelo total vendido no mês; substituem o percentual
+	Ativo       bool           `gorm:"default:true" json:"ativo"`
+	CreatedAt   time.Time      `gorm:"column:createdAt" json:"createdAt"`
+	UpdatedAt   time.Time      `gorm:"column:updatedAt" json:"updatedAt"`
+}
+
+// TableName especifica o nome da tabela no banco
+func (RegraComissao) TableName() string {
+	return "RegraComissao"
+}
+
+// FechamentoComissao é o extrato mensal de comissão de um vendedor
+type FechamentoComissao struct {
+	ID            int        `gorm:"primaryKey" json:"id"`
+	UsuarioID     int        `gorm:"not null;uniqueIndex:idx_fechamento_usuario_mes;column:usuarioId" json:"usuarioId"`
+	Mes           string     `gorm:"type:varchar(7);not null;uniqueIndex:idx_fechamento_usuario_mes" json:"mes"` // YYYY-MM
+	Status        string     `gorm:"type:varchar(20);not null;default:aberto" json:"status"`                    // "aberto", "fechado" ou "pago"
+	TotalVendas   money.Dinheiro    `gorm:"type:decimal(12,2);default:0;column:totalVendas" json:"totalVendas"`
+	TotalComissao money.Dinheiro    `gorm:"type:decimal(12,2);default:0;column:totalComissao" json:"totalComissao"`
+	FechadoEm     *time.Time `gorm:"column:fechadoEm" json:"fechadoEm"`
+	FechadoPor    *string    `gorm:"column:fechadoPor" json:"fechadoPor"`
+	PagoEm        *time.Time `gorm:"column:pagoEm" json:"pagoEm"`
+	PagoPor       *string    `gorm:"column:pagoPor" json:"pagoPor"`
+	CreatedAt     time.Time  `gorm:"column:createdAt" json:"createdAt"`
+	UpdatedAt     time.Time  `gorm:"column:updatedAt" json:"updatedAt"`
+}
+
+// TableName especifica o nome da tabela no banco
+func (FechamentoComissao) TableName() string {
+	return "FechamentoComissao"
+}
+
+// LancamentoComissao é uma linha do extrato de comissão: a comissão de um item vendido,
+// o estorno de um item devolvido ou alterado depois do fechamento, ou um ajuste manual
+type LancamentoComissao struct {
+	ID               int            `gorm:"primaryKey" json:"id"`
+	FechamentoID     int            `gorm:"not null;index;column:fechamentoId" json:"fechamentoId"`
+	UsuarioID        int            `gorm:"not null;column:usuarioId" json:"usuarioId"`
+	HistoricoVendaID *int           `gorm:"index;column:historicoVendaId" json:"historicoVendaId"`
+	VendaID          *string        `gorm:"type:varchar(191);column:vendaId" json:"vendaId"`
+	Tipo             string         `gorm:"type:varchar(20);not null" json:"tipo"` // "venda", "estorno" ou "ajuste"
+	Base             string         `gorm:"type:varchar(20)" json:"base"`          // "valor" ou "margem"
+	ValorBase        money.Dinheiro `gorm:"type:decimal(12,2);default:0;column:valorBase" json:"valorBase"`
+	Percentual       float64        `gorm:"type:decimal(5,2);default:0" json:"percentual"`
+	Valor            money.Dinheiro `gorm:"type:decimal(12,2);not null" json:"valor"`
+	Descricao        string         `gorm:"not null" json:"descricao"`
+	CreatedAt        time.Time      `gorm:"column:createdAt" json:"createdAt"`
+}
+
+// TableName especifica o nome da tabela no banco
+func (LancamentoComissao) TableName() string {
+	return "LancamentoComissao"
+}
+
+// Loja agrupa vendedores para metas e relatórios por loja
+type Loja struct {
+	ID        int       `gorm:"primaryKey" json:"id"`
+	Nome      string    `gorm:"type:varchar(100);uniqueIndex;not null" json:"nome"`
+	Ativo     bool      `gorm:"default:true" json:"ativo"`
+	CreatedAt time.Time `gorm:"column:createdAt" json:"createdAt"`
+	UpdatedAt time.Time `gorm:"column:updatedAt" json:"updatedAt"`
+}
+
+// TableName especifica o nome da tabela no banco
+func (Loja) TableName() string {
+	return "Loja"
+}
+
+// Meta é o objetivo de vendas de um vendedor ou de uma loja em um período (sem vendedor e loja: a empresa toda).
+// Com categoria, conta só as vendas dos produtos da categoria. Cada objetivo nulo não é acompanhado.
+type Meta struct {
+	ID          int             `gorm:"primaryKey" json:"id"`
+	UsuarioID   *int            `gorm:"index;column:usuarioId" json:"usuarioId"`
+	LojaID      *int            `gorm:"index;column:lojaId" json:"lojaId"`
+	CategoriaID *int            `gorm:"column:categoriaId" json:"categoriaId"`
+	DataInicio  time.Time       `gorm:"type:date;not null;column:dataInicio" json:"dataInicio"`
+	DataFim     time.Time       `gorm:"type:date;not null;column:dataFim" json:"dataFim"` // Último dia do período, inclusive
+	Faturamento *money.Dinheiro `gorm:"type:decimal(12,2)" json:"faturamento"`            // Valor vendido, já sem os descontos
+	Unidades    *int            `json:"unidades"`
+	Margem      *money.Dinheiro `gorm:"type:decimal(12,2)" json:"margem"` // Valor vendido menos o custo dos produtos
+	Descricao   *string         `json:"descricao"`
+	CreatedAt   time.Time       `gorm:"column:createdAt" json:"createdAt"`
+	UpdatedAt   time.Time       `gorm:"column:updatedAt" json:"updatedAt"`
+}
+
+// TableName especifica o nome da tabela no banco
+func (Meta) TableName() string {
+	return "Meta"
+}
+
+// SessaoCaixa é um período de uso da gaveta de dinheiro, de um vendedor ou de uma loja (informe um dos dois).
+// O valor esperado no fechamento é a abertura mais os movimentos; a diferença é o contado menos o esperado.
+type SessaoCaixa struct {
+	ID                    int             `gorm:"primaryKey" json:"id"`
+	UsuarioID             *int            `gorm:"index;column:usuarioId" json:"usuarioId"`
+	LojaID                *int            `gorm:"index;column:lojaId" json:"lojaId"`
+	Status                string          `gorm:"type:varchar(20);not null;default:aberta" json:"status"` // "aberta", "fechada" ou "aprovada"
+	ValorAbertura         money.Dinheiro  `gorm:"type:decimal(10,2);not null;column:valorAbertura" json:"valorAbertura"`
+	AbertaEm              time.Time       `gorm:"not null;column:abertaEm" json:"abertaEm"`
+	AbertaPor             string          `gorm:"not null;column:abertaPor" json:"abertaPor"`
+	FechadaEm             *time.Time      `gorm:"column:fechadaEm" json:"fechadaEm"`
+	FechadaPor            *string         `gorm:"column:fechadaPor" json:"fechadaPor"`
+	ValorEsperado         *money.Dinheiro `gorm:"type:decimal(10,2);column:valorEsperado" json:"valorEsperado"`
+	ValorContado          *money.Dinheiro `gorm:"type:decimal(10,2);column:valorContado" json:"valorContado"`
+	Diferenca             *money.Dinheiro `gorm:"type:decimal(10,2)" json:"diferenca"` // Positiva: sobra; negativa: falta
+	ObservacoesFechamento *string         `gorm:"column:observacoesFechamento" json:"observacoesFechamento"`
+	AprovadaEm            *time.Time      `gorm:"column:aprovadaEm" json:"aprovadaEm"`
+	AprovadaPor           *string         `gorm:"column:aprovadaPor" json:"aprovadaPor"`
+	ObservacoesAprovacao  *string         `gorm:"column:observacoesAprovacao" json:"observacoesAprovacao"`
+	CreatedAt             time.Time       `gorm:"column:createdAt" json:"createdAt"`
+	UpdatedAt             time.Time       `gorm:"column:updatedAt" json:"updatedAt"`
+}
+
+// TableName especifica o nome da tabela no banco
+func (SessaoCaixa) TableName() string {
+	return "SessaoCaixa"
+}
+
+// MovimentoCaixa é uma entrada ou saída de dinheiro na sessão de caixa: venda em dinheiro, estorno de venda,
+// sangria (retirada), suprimento (reforço) ou sinal de reserva. O valor tem sinal: saídas são negativas.
+type MovimentoCaixa struct {
+	ID            int            `gorm:"primaryKey" json:"id"`
+	SessaoCaixaID int            `gorm:"not null;index;column:sessaoCaixaId" json:"sessaoCaixaId"`
+	Tipo          string         `gorm:"type:varchar(20);not null" json:"tipo"` // "venda", "estorno", "sangria", "suprimento" ou "sinal"
+	Valor         money.Dinheiro `gorm:"type:decimal(10,2);not null" json:"valor"`
+	VendaID       *string        `gorm:"type:varchar(191);index;column:vendaId" json:"vendaId"`
+	ReservaID     *int           `gorm:"index;column:reservaId" json:"reservaId"` // Reserva do sinal recebido ou devolvido
+	Descricao     *string        `json:"descricao"`
+	UsuarioID     int            `gorm:"not null;column:usuarioId" json:"usuarioId"`
+	UsuarioNome   string         `gorm:"not null;column:usuarioNome" json:"usuarioNome"`
+	CreatedAt     time.Time      `gorm:"column:createdAt" json:"createdAt"`
+}
+
+// TableName especifica o nome da tabela no banco
+func (MovimentoCaixa) TableName() string {
+	return "MovimentoCaixa"
+}
+
+// RegraLiquidacao define quando a adquirente paga as vendas no cartão: a primeira parcela em D+PrazoDias
+// e as seguintes a cada IntervaloDias, ou todas juntas em D+PrazoDias com antecipação.
+// A regra sem bandeira vale para as bandeiras sem regra própria.
+type RegraLiquidacao struct {
+	ID            int       `gorm:"primaryKey" json:"id"`
+	Metodo        string    `gorm:"type:varchar(20);not null;uniqueIndex:idx_liquidacao_metodo_bandeira" json:"metodo"` // "debito" ou "credito"
+	Bandeira      *string   `gorm:"type:varchar(30);uniqueIndex:idx_liquidacao_metodo_bandeira" json:"bandeira"`
+	PrazoDias     int       `gorm:"not null;column:prazoDias" json:"prazoDias"`
+	IntervaloDias int       `gorm:"not null;default:30;column:intervaloDias" json:"intervaloDias"`
+	Antecipado    bool      `gorm:"default:false" json:"antecipado"`
+	CreatedAt     time.Time `gorm:"column:createdAt" json:"createdAt"`
+	UpdatedAt     time.Time `gorm:"column:updatedAt" json:"updatedAt"`
+}
+
+// TableName especifica o nome da tabela no banco
+func (RegraLiquidacao) TableName() string {
+	return "RegraLiquidacao"
+}
+
+// Recebivel é uma parcela a receber de uma linha de pagamento: repasse da adquirente (cartão),
+// parcela do crediário ou pix/boleto a prazo
+type Recebivel struct {
+	ID               int             `gorm:"primaryKey" json:"id"`
+	VendaID          string          `gorm:"type:varchar(191);not null;index;column:vendaId" json:"vendaId"`
+	PagamentoVendaID int             `gorm:"not null;index;column:pagamentoVendaId" json:"pagamentoVendaId"`
+	Metodo           string          `gorm:"type:varchar(20);not null" json:"metodo"`
+	Bandeira         *string         `gorm:"type:varchar(30)" json:"bandeira"`
+	ClienteNome      string          `gorm:"not null;column:clienteNome" json:"clienteNome"`
+	TipoCliente      *string         `gorm:"column:tipoCliente" json:"tipoCliente"`
+	Parcela          int             `gorm:"not null" json:"parcela"`
+	TotalParcelas    int             `gorm:"not null;column:totalParcelas" json:"totalParcelas"`
+	ValorBruto       money.Dinheiro  `gorm:"type:decimal(10,2);not null;column:valorBruto" json:"valorBruto"`
+	Taxa             money.Dinheiro  `gorm:"type:decimal(10,2);default:0" json:"taxa"` // Taxa da adquirente em reais
+	ValorLiquido     money.Dinheiro  `gorm:"type:decimal(10,2);not null;column:valorLiquido" json:"valorLiquido"`
+	Vencimento       time.Time       `gorm:"type:date;not null;index" json:"vencimento"`
+	Status           string          `gorm:"type:varchar(20);not null;default:pendente" json:"status"` // "pendente", "liquidado" ou "cancelado"
+	LiquidadoEm      *time.Time      `gorm:"column:liquidadoEm" json:"liquidadoEm"`
+	ValorRecebido    *money.Dinheiro `gorm:"type:decimal(10,2);column:valorRecebido" json:"valorRecebido"`
+	LiquidadoPor     *string         `gorm:"column:liquidadoPor" json:"liquidadoPor"`
+	CreatedAt        time.Time       `gorm:"column:createdAt" json:"createdAt"`
+	UpdatedAt        time.Time       `gorm:"column:updatedAt" json:"updatedAt"`
+}
+
+// TableName especifica o nome da tabela no banco
+func (Recebivel) TableName() string {
+	return "Recebivel"
+}
+
+// Notificacao é um aviso para um usuário (ex.: orçamento de despesas estourado). Chave identifica o evento,
+// para que o mesmo aviso não seja enviado duas vezes ao mesmo usuário.
+type Notificacao struct {
+	ID        int        `gorm:"primaryKey" json:"id"`
+	UsuarioID int        `gorm:"not null;column:usuarioId;uniqueIndex:idx_notificacao_usuario_chave" json:"usuarioId"`
+	Tipo      string     `gorm:"type:varchar(50);not null" json:"tipo"`
+	Titulo    string     `gorm:"type:varchar(255);not null" json:"titulo"`
+	Mensagem  string     `gorm:"type:text;not null" json:"mensagem"`
+	Chave     string     `gorm:"type:varchar(191);not null;uniqueIndex:idx_notificacao_usuario_chave" json:"chave"`
+	LidaEm    *time.Time `gorm:"column:lidaEm" json:"lidaEm"`
+	CreatedAt time.Time  `gorm:"column:createdAt;index" json:"createdAt"`
+}
+
+// TableName especifica o nome da tabela no banco
+func (Notificacao) TableName() string {
+	return "Notificacao"
+}
+
+// ConversaoUTC registra cada coluna datetime já convertida do horário local para UTC (cmd/converter-utc),
+// para que rodar o comando de novo não converta a mesma coluna duas vezes
+type ConversaoUTC struct {
+	Tabela       string    `gorm:"primaryKey;type:varchar(64)" json:"tabela"`
+	Coluna       string    `gorm:"primaryKey;type:varchar(64)" json:"coluna"`
+	Fuso         string    `gorm:"type:varchar(64);not null" json:"fuso"` // Fuso em que os horários estavam gravados
+	Linhas       int64     `gorm:"not null" json:"linhas"`
+	ConvertidaEm time.Time `gorm:"column:convertidaEm" json:"convertidaEm"`
+}
+
+// TableName especifica o nome da tabela no banco
+func (ConversaoUTC) TableName() string {
+	return "ConversaoUTC"
+}
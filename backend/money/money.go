@@ -0,0 +1,303 @@
+// Package money representa valores em dinheiro sem os erros de arredondamento do float64: o valor é guardado
+// em centavos (int64) e as contas com percentuais e divisões arredondam aos centavos pela regra brasileira
+// (ABNT NBR 5891: acima da metade sobe, abaixo desce e, exatamente na metade, fica o centavo par).
+//
+// No banco, as colunas decimal(..., 2) são lidas e gravadas como texto decimal. No JSON o valor continua um
+// número em reais (12.5, 1999.9), como os clientes já recebem e enviam.
+package money
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"cmdimport/backend/utils"
+)
+
+// Dinheiro é um valor em reais (ou em dólares, no custo de compra) guardado em centavos
+type Dinheiro int64
+
+// Maximo é o maior valor aceito (R$ 10 trilhões), com folga para multiplicar por quantidades sem estourar o int64
+const Maximo Dinheiro = 1_000_000_000_000_000
+
+// ErrForaDoLimite indica um valor acima de Maximo (ou abaixo de -Maximo)
+var ErrForaDoLimite = errors.New("valor fora do limite")
+
+// Centavos cria um valor a partir de centavos
+func Centavos(c int64) Dinheiro {
+	return Dinheiro(c)
+}
+
+// Reais converte um número em reais, arredondando aos centavos. O número vale pelo decimal que ele representa
+// (2.675 é 2,675, e não o binário 2,67499...), então 2.675 vira 2,68. Fora do limite, fica em ±Maximo; para
+// valores informados pelo cliente, use DeReais.
+func Reais(v float64) Dinheiro {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0
+	}
+	return arredondar(racional(v))
+}
+
+// DeReais converte como Reais, mas recusa NaN, infinito e valores fora do limite
+func DeReais(v float64) (Dinheiro, error) {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, fmt.Errorf("valor inválido: %v", v)
+	}
+	return converter(racional(v))
+}
+
+// Parse lê um valor decimal com ponto ("12.345", "-3", "1e3"), arredondando aos centavos. Valores fora do limite
+// retornam ErrForaDoLimite.
+func Parse(s string) (Dinheiro, error) {
+	s = strings.TrimSpace(s)
+	// big.Rat também aceita frações ("1/3") e expoentes que custariam muita memória ("1e999999999")
+	if len(s) > 64 || strings.Contains(s, "/") {
+		return 0, fmt.Errorf("valor inválido: %q", s)
+	}
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		if exp, err := strconv.Atoi(s[i+1:]); err != nil || exp > 30 || exp < -30 {
+			return 0, fmt.Errorf("valor inválido: %q", s)
+		}
+	}
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return 0, fmt.Errorf("valor inválido: %q", s)
+	}
+	return converter(r)
+}
+
+// racional retorna o decimal representado por v, sem o erro binário
+func racional(v float64) *big.Rat {
+	r, _ := new(big.Rat).SetString(strconv.FormatFloat(v, 'f', -1, 64))
+	return r
+}
+
+// arredondar converte reais em centavos pela NBR 5891; fora do limite, fica em ±Maximo
+func arredondar(reais *big.Rat) Dinheiro {
+	d, _ := converter(reais)
+	return d
+}
+
+// converter converte reais em centavos pela NBR 5891, recusando valores fora do limite
+func converter(reais *big.Rat) (Dinheiro, error) {
+	x := new(big.Rat).Mul(reais, big.NewRat(100, 1))
+	q, resto := new(big.Int).QuoRem(x.Num(), x.Denom(), new(big.Int))
+	if resto.Sign() != 0 {
+		dobro := new(big.Int).Mul(new(big.Int).Abs(resto), big.NewInt(2))
+		if c := dobro.Cmp(x.Denom()); c > 0 || (c == 0 && q.Bit(0) == 1) {
+			if resto.Sign() > 0 {
+				q.Add(q, big.NewInt(1))
+			} else {
+				q.Sub(q, big.NewInt(1))
+			}
+		}
+	}
+	if q.CmpAbs(big.NewInt(int64(Maximo))) > 0 {
+		if q.Sign() < 0 {
+			return -Maximo, ErrForaDoLimite
+		}
+		return Maximo, ErrForaDoLimite
+	}
+	return Dinheiro(q.Int64()), nil
+}
+
+// Centavos retorna o valor em centavos
+func (d Dinheiro) Centavos() int64 {
+	return int64(d)
+}
+
+// Float64 retorna o valor em reais, para razões e percentuais (não para somar dinheiro)
+func (d Dinheiro) Float64() float64 {
+	return float64(d) / 100
+}
+
+// Vezes multiplica por uma quantidade
+func (d Dinheiro) Vezes(n int) Dinheiro {
+	return d * Dinheiro(n)
+}
+
+// Mul multiplica por um fator (uma taxa de câmbio, 1 + markup), arredondando aos centavos
+func (d Dinheiro) Mul(fator float64) Dinheiro {
+	if math.IsNaN(fator) || math.IsInf(fator, 0) {
+		return 0
+	}
+	return arredondar(new(big.Rat).Mul(big.NewRat(int64(d), 100), racional(fator)))
+}
+
+// DivFator divide por um fator (uma taxa de câmbio, 1 - taxa), arredondando aos centavos
+func (d Dinheiro) DivFator(fator float64) Dinheiro {
+	if fator == 0 || math.IsNaN(fator) || math.IsInf(fator, 0) {
+		return 0
+	}
+	return arredondar(new(big.Rat).Quo(big.NewRat(int64(d), 100), racional(fator)))
+}
+
+// Percentual retorna p% do valor, arredondado aos centavos
+func (d Dinheiro) Percentual(p float64) Dinheiro {
+	if math.IsNaN(p) || math.IsInf(p, 0) {
+		return 0
+	}
+	return arredondar(new(big.Rat).Mul(big.NewRat(int64(d), 100*100), racional(p)))
+}
+
+// Proporcao retorna a fração parte/total do valor, arredondada aos centavos
+func (d Dinheiro) Proporcao(parte, total Dinheiro) Dinheiro {
+	if total == 0 {
+		return 0
+	}
+	return arredondar(new(big.Rat).Mul(big.NewRat(int64(d), 100), big.NewRat(int64(parte), int64(total))))
+}
+
+// Div divide em n partes, arredondando aos centavos. Para que as partes somem o total, use Dividir.
+func (d Dinheiro) Div(n int) Dinheiro {
+	if n == 0 {
+		return 0
+	}
+	return arredondar(big.NewRat(int64(d), 100*int64(n)))
+}
+
+// Dividir reparte o valor em n parcelas que somam exatamente o total; os centavos que sobram vão para as
+// primeiras parcelas
+func (d Dinheiro) Dividir(n int) []Dinheiro {
+	if n < 1 {
+		return nil
+	}
+	partes := make([]Dinheiro, n)
+	base, resto := int64(d)/int64(n), int64(d)%int64(n)
+	for i := range partes {
+		partes[i] = Dinheiro(base)
+		if int64(i) < resto {
+			partes[i]++
+		} else if -int64(i) > resto {
+			partes[i]--
+		}
+	}
+	return partes
+}
+
+// Ratear reparte o valor proporcionalmente aos pesos, de modo que as partes somem exatamente o total: cada parte
+// é arredondada pela NBR 5891 e a diferença do arredondamento fica com a última parte de peso não nulo
+func (d Dinheiro) Ratear(pesos []Dinheiro) []Dinheiro {
+	partes := make([]Dinheiro, len(pesos))
+	var total Dinheiro
+	ultimo := -1
+	for i, p := range pesos {
+		total += p
+		if p != 0 {
+			ultimo = i
+		}
+	}
+	if total == 0 {
+		return partes
+	}
+	var distribuido Dinheiro
+	for i, p := range pesos {
+		if i == ultimo {
+			partes[i] = d - distribuido
+			break
+		}
+		partes[i] = d.Proporcao(p, total)
+		distribuido += partes[i]
+	}
+	return partes
+}
+
+// Abs retorna o valor sem sinal
+func (d Dinheiro) Abs() Dinheiro {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
+
+// String formata com ponto e duas casas ("-12.50"), como as colunas decimal
+func (d Dinheiro) String() string {
+	sinal := ""
+	c := int64(d)
+	if c < 0 {
+		sinal, c = "-", -c
+	}
+	return fmt.Sprintf("%s%d.%02d", sinal, c/100, c%100)
+}
+
+// BR formata no padrão brasileiro, sem o símbolo da moeda ("1.234,50")
+func (d Dinheiro) BR() string {
+	return utils.FormatFloatBR(d.Float64(), 2)
+}
+
+// MarshalJSON escreve um número em reais, sem zeros à direita (12.5), como o float64 escrevia
+func (d Dinheiro) MarshalJSON() ([]byte, error) {
+	s := strings.TrimRight(strings.TrimRight(d.String(), "0"), ".")
+	if s == "" || s == "-" {
+		s = "0"
+	}
+	return []byte(s), nil
+}
+
+// UnmarshalJSON aceita um número em reais ou um texto com o número ("12.50"); null mantém o valor
+func (d *Dinheiro) UnmarshalJSON(b []byte) error {
+	s := string(b)
+	if s == "null" {
+		return nil
+	}
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		s = unquoted
+	}
+	v, err := Parse(s)
+	if err != nil {
+		return err
+	}
+	*d = v
+	return nil
+}
+
+// Value grava o valor como texto decimal, exato na coluna decimal
+func (d Dinheiro) Value() (driver.Value, error) {
+	return d.String(), nil
+}
+
+// Scan lê o valor de colunas decimal (texto), de somas e de expressões numéricas
+func (d *Dinheiro) Scan(valor interface{}) error {
+	switch v := valor.(type) {
+	case nil:
+		*d = 0
+	case []byte:
+		return d.scanTexto(string(v))
+	case string:
+		return d.scanTexto(v)
+	case int64:
+		if v > int64(Maximo)/100 || v < -int64(Maximo)/100 {
+			return ErrForaDoLimite
+		}
+		*d = Dinheiro(v * 100)
+	case float64:
+		return d.scanFloat(v)
+	case float32:
+		return d.scanFloat(float64(v))
+	default:
+		return fmt.Errorf("tipo inválido para Dinheiro: %T", valor)
+	}
+	return nil
+}
+
+func (d *Dinheiro) scanFloat(f float64) error {
+	v, err := DeReais(f)
+	if err != nil {
+		return err
+	}
+	*d = v
+	return nil
+}
+
+func (d *Dinheiro) scanTexto(s string) error {
+	v, err := Parse(s)
+	if err != nil {
+		return err
+	}
+	*d = v
+	return nil
+}
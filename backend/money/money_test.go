@@ -0,0 +1,281 @@
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func soma(partes []Dinheiro) Dinheiro {
+	var total Dinheiro
+	for _, p := range partes {
+		total += p
+	}
+	return total
+}
+
+func iguais(a, b []Dinheiro) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Exatamente na metade, fica o centavo par (NBR 5891); fora dela, arredonda para o mais próximo
+func TestReaisArredondamento(t *testing.T) {
+	casos := []struct {
+		reais    float64
+		centavos int64
+	}{
+		{2.675, 268},
+		{2.665, 266},
+		{0.015, 2},
+		{0.025, 2},
+		{0.035, 4},
+		{-0.005, 0},
+		{-0.015, -2},
+		{-2.675, -268},
+		{1.005, 100},
+		{1.0051, 101},
+		{1.0049, 100},
+		{0.1, 10},
+		{1999.9, 199990},
+	}
+	for _, c := range casos {
+		if got := Reais(c.reais).Centavos(); got != c.centavos {
+			t.Errorf("Reais(%v) = %d centavos, esperado %d", c.reais, got, c.centavos)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	casos := []struct {
+		texto    string
+		centavos int64
+	}{
+		{"12.345", 1234},
+		{"12.355", 1236},
+		{" -3 ", -300},
+		{"1e3", 100000},
+		{"0.005", 0},
+		{"10000000000000", int64(Maximo)},
+		{"-10000000000000", -int64(Maximo)},
+	}
+	for _, c := range casos {
+		got, err := Parse(c.texto)
+		if err != nil || got.Centavos() != c.centavos {
+			t.Errorf("Parse(%q) = %d, %v; esperado %d", c.texto, got.Centavos(), err, c.centavos)
+		}
+	}
+
+	for _, texto := range []string{"", "abc", "12,50", "1/3", "1e999999999", "1e-999999999"} {
+		if _, err := Parse(texto); err == nil {
+			t.Errorf("Parse(%q) aceitou um valor inválido", texto)
+		}
+	}
+	for _, texto := range []string{"1e20", "1e30", "-1e20", "10000000000000.01"} {
+		if _, err := Parse(texto); !errors.Is(err, ErrForaDoLimite) {
+			t.Errorf("Parse(%q) = %v, esperado ErrForaDoLimite", texto, err)
+		}
+	}
+}
+
+func TestDeReais(t *testing.T) {
+	if d, err := DeReais(12.5); err != nil || d != 1250 {
+		t.Errorf("DeReais(12.5) = %v, %v", d, err)
+	}
+	for _, v := range []float64{1e20, -1e300} {
+		if _, err := DeReais(v); !errors.Is(err, ErrForaDoLimite) {
+			t.Errorf("DeReais(%v) = %v, esperado ErrForaDoLimite", v, err)
+		}
+	}
+	// Nas contas internas, o valor fica no limite em vez de dar a volta no int64
+	if d := Reais(1e20); d != Maximo {
+		t.Errorf("Reais(1e20) = %v, esperado %v", d, Maximo)
+	}
+}
+
+func TestPercentualMulDiv(t *testing.T) {
+	casos := []struct {
+		nome     string
+		got      Dinheiro
+		esperado Dinheiro
+	}{
+		{"12,5% de 100", Reais(100).Percentual(12.5), 1250},
+		{"50% de 0,01", Centavos(1).Percentual(50), 0},
+		{"50% de 0,03", Centavos(3).Percentual(50), 2},
+		{"50% de -0,03", Centavos(-3).Percentual(50), -2},
+		{"100 dólares a 5,4321", Reais(100).Mul(5.4321), 54321},
+		{"1 dólar a 5,4325", Reais(1).Mul(5.4325), 543},
+		{"1 dólar a 5,4355", Reais(1).Mul(5.4355), 544},
+		{"100 / 3", Reais(100).DivFator(3), 3333},
+		{"dividir por zero", Reais(100).DivFator(0), 0},
+		{"0,05 / 2", Centavos(5).Div(2), 2},
+		{"0,07 / 2", Centavos(7).Div(2), 4},
+		{"1/3 de 10", Reais(10).Proporcao(1, 3), 333},
+		{"proporção de total zero", Reais(10).Proporcao(1, 0), 0},
+	}
+	for _, c := range casos {
+		if c.got != c.esperado {
+			t.Errorf("%s = %v, esperado %v", c.nome, c.got, c.esperado)
+		}
+	}
+}
+
+func TestDividir(t *testing.T) {
+	casos := []struct {
+		valor    Dinheiro
+		n        int
+		esperado []Dinheiro
+	}{
+		{1000, 3, []Dinheiro{334, 333, 333}},
+		{-1000, 3, []Dinheiro{-334, -333, -333}},
+		{1, 3, []Dinheiro{1, 0, 0}},
+		{-2, 3, []Dinheiro{-1, -1, 0}},
+		{999, 1, []Dinheiro{999}},
+		{0, 2, []Dinheiro{0, 0}},
+	}
+	for _, c := range casos {
+		partes := c.valor.Dividir(c.n)
+		if !iguais(partes, c.esperado) {
+			t.Errorf("%v.Dividir(%d) = %v, esperado %v", c.valor, c.n, partes, c.esperado)
+		}
+		if soma(partes) != c.valor {
+			t.Errorf("%v.Dividir(%d) soma %v", c.valor, c.n, soma(partes))
+		}
+	}
+	if partes := Dinheiro(100).Dividir(0); partes != nil {
+		t.Errorf("Dividir(0) = %v, esperado nil", partes)
+	}
+}
+
+func TestRatear(t *testing.T) {
+	casos := []struct {
+		valor    Dinheiro
+		pesos    []Dinheiro
+		esperado []Dinheiro
+	}{
+		{1000, []Dinheiro{1, 1, 1}, []Dinheiro{333, 333, 334}},
+		{-1000, []Dinheiro{1, 1, 1}, []Dinheiro{-333, -333, -334}},
+		{1000, []Dinheiro{100, 200, 300}, []Dinheiro{167, 333, 500}},
+		{-1000, []Dinheiro{100, 200, 300}, []Dinheiro{-167, -333, -500}},
+		{1000, []Dinheiro{0, 5, 0}, []Dinheiro{0, 1000, 0}},
+		{1000, []Dinheiro{0, 0}, []Dinheiro{0, 0}},
+		{5, []Dinheiro{100, -50, 50}, []Dinheiro{5, -2, 2}},
+	}
+	for _, c := range casos {
+		partes := c.valor.Ratear(c.pesos)
+		if !iguais(partes, c.esperado) {
+			t.Errorf("%v.Ratear(%v) = %v, esperado %v", c.valor, c.pesos, partes, c.esperado)
+		}
+		if soma(c.pesos) != 0 && soma(partes) != c.valor {
+			t.Errorf("%v.Ratear(%v) soma %v", c.valor, c.pesos, soma(partes))
+		}
+	}
+}
+
+func TestFormatos(t *testing.T) {
+	casos := []struct {
+		valor Dinheiro
+		texto string
+		br    string
+	}{
+		{1050, "10.50", "10,50"},
+		{-1050, "-10.50", "-10,50"},
+		{5, "0.05", "0,05"},
+		{-5, "-0.05", "-0,05"},
+		{123456789, "1234567.89", "1.234.567,89"},
+	}
+	for _, c := range casos {
+		if c.valor.String() != c.texto || c.valor.BR() != c.br {
+			t.Errorf("%d centavos: String %q, BR %q; esperado %q e %q", c.valor, c.valor.String(), c.valor.BR(), c.texto, c.br)
+		}
+		if v, err := c.valor.Value(); err != nil || v != c.texto {
+			t.Errorf("%d centavos: Value %v, %v; esperado %q", c.valor, v, err, c.texto)
+		}
+	}
+}
+
+// O JSON continua o número em reais que o float64 escrevia
+func TestJSON(t *testing.T) {
+	casos := []struct {
+		entrada  string
+		centavos int64
+		saida    string
+	}{
+		{`10`, 1000, `10`},
+		{`12.5`, 1250, `12.5`},
+		{`"12.50"`, 1250, `12.5`},
+		{`0`, 0, `0`},
+		{`-0.5`, -50, `-0.5`},
+		{`1999.99`, 199999, `1999.99`},
+		{`2.675`, 268, `2.68`},
+	}
+	for _, c := range casos {
+		var d Dinheiro
+		if err := json.Unmarshal([]byte(c.entrada), &d); err != nil || d.Centavos() != c.centavos {
+			t.Errorf("Unmarshal(%s) = %d, %v; esperado %d", c.entrada, d.Centavos(), err, c.centavos)
+			continue
+		}
+		b, err := json.Marshal(d)
+		if err != nil || string(b) != c.saida {
+			t.Errorf("Marshal(%d) = %s, %v; esperado %s", c.centavos, b, err, c.saida)
+		}
+	}
+
+	// null mantém o valor; num ponteiro, continua nil
+	d := Dinheiro(700)
+	if err := json.Unmarshal([]byte(`null`), &d); err != nil || d != 700 {
+		t.Errorf("Unmarshal(null) = %v, %v; esperado 7.00", d, err)
+	}
+	var corpo struct {
+		Valor *Dinheiro `json:"valor"`
+	}
+	if err := json.Unmarshal([]byte(`{"valor": null}`), &corpo); err != nil || corpo.Valor != nil {
+		t.Errorf("Unmarshal ponteiro null = %v, %v", corpo.Valor, err)
+	}
+	if b, _ := json.Marshal(corpo); string(b) != `{"valor":null}` {
+		t.Errorf("Marshal ponteiro nil = %s", b)
+	}
+
+	for _, entrada := range []string{`1e300`, `1e20`, `"abc"`, `true`, `"1/3"`} {
+		var d Dinheiro
+		if err := json.Unmarshal([]byte(entrada), &d); err == nil {
+			t.Errorf("Unmarshal(%s) aceitou um valor inválido: %v", entrada, d)
+		}
+	}
+}
+
+func TestScan(t *testing.T) {
+	casos := []struct {
+		nome     string
+		valor    interface{}
+		centavos int64
+	}{
+		{"decimal como []byte", []byte("12.35"), 1235},
+		{"decimal como string", "-0.10", -10},
+		{"soma com mais casas", []byte("10.005"), 1000},
+		{"inteiro", int64(3), 300},
+		{"float64", float64(0.1), 10},
+		{"float32", float32(2.5), 250},
+		{"nulo", nil, 0},
+	}
+	for _, c := range casos {
+		d := Dinheiro(999)
+		if err := d.Scan(c.valor); err != nil || d.Centavos() != c.centavos {
+			t.Errorf("Scan %s = %d, %v; esperado %d", c.nome, d.Centavos(), err, c.centavos)
+		}
+	}
+
+	for _, valor := range []interface{}{true, []byte("abc"), float64(1e300), int64(1e17)} {
+		var d Dinheiro
+		if err := d.Scan(valor); err == nil {
+			t.Errorf("Scan(%v) aceitou um valor inválido: %v", valor, d)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+// Package notifications entrega avisos aos usuários. Cada aviso tem uma chave que identifica o evento:
+// notificar de novo o mesmo evento não duplica o aviso.
+package notifications
+
+import (
+	"cmdimport/backend/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Tipos de notificação
+const (
+	TipoOrcamentoDespesa = "orcamento_despesa"
+)
+
+// Aviso é o conteúdo de uma notificação
+type Aviso struct {
+	Tipo     string
+	Titulo   string
+	Mensagem string
+	Chave    string
+}
+
+// Notificar entrega o aviso aos usuários informados e retorna quantos ainda não o tinham recebido
+func Notificar(db *gorm.DB, usuarioIDs []int, aviso Aviso) (int, error) {
+	if len(usuarioIDs) == 0 {
+		return 0, nil
+	}
+	notificacoes := make([]models.Notificacao, len(usuarioIDs))
+	for i, id := range usuarioIDs {
+		notificacoes[i] = models.Notificacao{
+			UsuarioID: id,
+			Tipo:      aviso.Tipo,
+			Titulo:    aviso.Titulo,
+			Mensagem:  aviso.Mensagem,
+			Chave:     aviso.Chave,
+		}
+	}
+	result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&notificacoes)
+	return int(result.RowsAffected), result.Error
+}
+
+// NotificarAdmins entrega o aviso a todos os administradores
+func NotificarAdmins(db *gorm.DB, aviso Aviso) (int, error) {
+	var ids []int
+	if err := db.Model(&models.Usuario{}).Where("isAdmin = ?", true).Pluck("id", &ids).Error; err != nil {
+		return 0, err
+	}
+	return Notificar(db, ids, aviso)
+}
@@ -0,0 +1,243 @@
+// Package payments valida as linhas de pagamento de uma venda e converte
+// os campos antigos (formaPagamento, valorPix, valorCartao, valorDinheiro) em linhas.
+package payments
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"cmdimport/backend/models"
+	"cmdimport/backend/money"
+	"cmdimport/backend/tradein"
+)
+
+// Métodos de uma linha de pagamento
+const (
+	MetodoDinheiro  = "dinheiro"
+	MetodoPix       = "pix"
+	MetodoDebito    = "debito"
+	MetodoCredito   = "credito"
+	MetodoCrediario = "crediario"
+	MetodoBoleto    = "boleto"
+	MetodoTroca     = "troca" // Aparelho usado recebido como parte do pagamento
+	MetodoSinal     = "sinal" // Sinal pago na reserva do produto
+	MetodoOutro     = "outro"
+)
+
+var metodos = map[string]bool{
+	MetodoDinheiro:  true,
+	MetodoPix:       true,
+	MetodoDebito:    true,
+	MetodoCredito:   true,
+	MetodoCrediario: true,
+	MetodoBoleto:    true,
+	MetodoTroca:     true,
+	MetodoSinal:     true,
+	MetodoOutro:     true,
+}
+
+// Linha é uma forma de pagamento informada na venda
+type Linha struct {
+	Metodo        string            `json:"metodo"`
+	Valor         money.Dinheiro    `json:"valor"`
+	Parcelas      int               `json:"parcelas"`
+	ValorRecebido *money.Dinheiro   `json:"valorRecebido"` // Só para dinheiro: valor entregue, para calcular o troco
+	NSU           *string           `json:"nsu"`
+	Autorizacao   *string           `json:"autorizacao"`
+	Bandeira      *string           `json:"bandeira"`
+	Aparelho      *tradein.Aparelho `json:"aparelho"`   // Só para troca: aparelho recebido; o valor é o avaliado
+	Vencimento    *string           `json:"vencimento"` // Pix ou boleto a prazo: data do pagamento (YYYY-MM-DD)
+}
+
+// Validar confere as linhas contra o total da venda e monta os registros de PagamentoVenda
+// (sem vendaId). Os erros têm mensagens para o cliente.
+func Validar(linhas []Linha, total money.Dinheiro) ([]models.PagamentoVenda, error) {
+	if len(linhas) == 0 {
+		return nil, fmt.Errorf("informe ao menos uma forma de pagamento")
+	}
+
+	pagamentos := make([]models.PagamentoVenda, 0, len(linhas))
+	var soma money.Dinheiro
+	for i, l := range linhas {
+		n := i + 1
+		metodo := strings.ToLower(strings.TrimSpace(l.Metodo))
+		if !metodos[metodo] {
+			return nil, fmt.Errorf("pagamento %d: método inválido %q", n, l.Metodo)
+		}
+		if l.Valor <= 0 {
+			return nil, fmt.Errorf("pagamento %d: o valor deve ser maior que zero", n)
+		}
+
+		parcelas := l.Parcelas
+		if parcelas <= 0 {
+			parcelas = 1
+		}
+		if parcelas > 1 && metodo != MetodoCredito && metodo != MetodoCrediario {
+			return nil, fmt.Errorf("pagamento %d: só crédito e crediário podem ser parcelados", n)
+		}
+		if metodo == MetodoTroca {
+			if l.Aparelho == nil {
+				return nil, fmt.Errorf("pagamento %d: informe o aparelho recebido em troca", n)
+			}
+			if err := l.Aparelho.Validar(); err != nil {
+				return nil, fmt.Errorf("pagamento %d: %w", n, err)
+			}
+		} else if l.Aparelho != nil {
+			return nil, fmt.Errorf("pagamento %d: aparelho só se aplica a troca", n)
+		}
+
+		var vencimento *time.Time
+		if l.Vencimento != nil && strings.TrimSpace(*l.Vencimento) != "" {
+			if metodo != MetodoPix && metodo != MetodoBoleto {
+				return nil, fmt.Errorf("pagamento %d: vencimento só se aplica a pix ou boleto", n)
+			}
+			data, err := time.Parse("2006-01-02", strings.TrimSpace(*l.Vencimento))
+			if err != nil {
+				return nil, fmt.Errorf("pagamento %d: vencimento inválido, use o formato YYYY-MM-DD", n)
+			}
+			vencimento = &data
+		} else if metodo == MetodoBoleto {
+			return nil, fmt.Errorf("pagamento %d: informe o vencimento do boleto", n)
+		}
+
+		p := models.PagamentoVenda{
+			Metodo:      metodo,
+			Valor:       l.Valor,
+			Parcelas:    parcelas,
+			NSU:         texto(l.NSU),
+			Autorizacao: texto(l.Autorizacao),
+			Bandeira:    texto(l.Bandeira),
+			Vencimento:  vencimento,
+		}
+
+		if l.ValorRecebido != nil {
+			if metodo != MetodoDinheiro {
+				return nil, fmt.Errorf("pagamento %d: valor recebido só se aplica a dinheiro", n)
+			}
+			if *l.ValorRecebido < l.Valor {
+				return nil, fmt.Errorf("pagamento %d: valor recebido menor que o valor pago em dinheiro", n)
+			}
+			recebido := *l.ValorRecebido
+			p.ValorRecebido = &recebido
+			p.Troco = recebido - l.Valor
+		}
+
+		soma += l.Valor
+		pagamentos = append(pagamentos, p)
+	}
+
+	if soma != total {
+		return nil, fmt.Errorf("os pagamentos somam R$ %s, mas o total da venda é R$ %s", soma.BR(), total.BR())
+	}
+	return pagamentos, nil
+}
+
+// DaFormaLegada monta as linhas de uma venda enviada no formato antigo.
+// Com valorPix/valorCartao/valorDinheiro, cada valor informado vira uma linha; sem eles,
+// uma única linha da formaPagamento cobre o total.
+func DaFormaLegada(forma string, valorPix, valorCartao, valorDinheiro *money.Dinheiro, total money.Dinheiro) []Linha {
+	metodoCartao, parcelas := metodoDaForma(forma)
+	if metodoCartao != MetodoDebito && metodoCartao != MetodoCredito {
+		metodoCartao, parcelas = MetodoCredito, 1
+	}
+
+	linhas := make([]Linha, 0, 3)
+	if valorPix != nil && *valorPix > 0 {
+		linhas = append(linhas, Linha{Metodo: MetodoPix, Valor: *valorPix, Parcelas: 1})
+	}
+	if valorCartao != nil && *valorCartao > 0 {
+		linhas = append(linhas, Linha{Metodo: metodoCartao, Valor: *valorCartao, Parcelas: parcelas})
+	}
+	if valorDinheiro != nil && *valorDinheiro > 0 {
+		linhas = append(linhas, Linha{Metodo: MetodoDinheiro, Valor: *valorDinheiro, Parcelas: 1})
+	}
+	if len(linhas) > 0 {
+		return linhas
+	}
+
+	metodo, parcelas := metodoDaForma(forma)
+	return []Linha{{Metodo: metodo, Valor: total, Parcelas: parcelas}}
+}
+
+// FormaDasLinhas retorna a formaPagamento equivalente às linhas, usada para escolher o preço
+// do plano: "credito_3x" para uma linha de crédito em 3x, "misto" para várias linhas.
+func FormaDasLinhas(linhas []Linha) string {
+	if len(linhas) != 1 {
+		return "misto"
+	}
+	l := linhas[0]
+	metodo := strings.ToLower(strings.TrimSpace(l.Metodo))
+	if metodo != MetodoCredito {
+		return metodo
+	}
+	if l.Parcelas <= 1 {
+		return "credito_vista"
+	}
+	return "credito_" + strconv.Itoa(l.Parcelas) + "x"
+}
+
+// metodoDaForma converte a forma de pagamento (ou código de plano) no método e nas parcelas
+func metodoDaForma(forma string) (string, int) {
+	forma = strings.ToLower(strings.TrimSpace(forma))
+	switch forma {
+	case "dinheiro":
+		return MetodoDinheiro, 1
+	case "pix", "dinheiro_pix":
+		return MetodoPix, 1
+	case "debito":
+		return MetodoDebito, 1
+	case "credito", "credito_vista":
+		return MetodoCredito, 1
+	case "crediario":
+		return MetodoCrediario, 1
+	}
+	// credito_3x, credito_12x...
+	if strings.HasPrefix(forma, "credito_") && strings.HasSuffix(forma, "x") {
+		if n, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(forma, "credito_"), "x")); err == nil && n > 0 {
+			return MetodoCredito, n
+		}
+	}
+	return MetodoOutro, 1
+}
+
+// Resumo soma as linhas nos campos antigos da venda (pix, cartão e dinheiro), para quem ainda os lê
+func Resumo(pagamentos []models.PagamentoVenda) (valorPix, valorCartao, valorDinheiro *money.Dinheiro) {
+	var pix, cartao, dinheiro money.Dinheiro
+	for _, p := range pagamentos {
+		switch p.Metodo {
+		case MetodoPix:
+			pix += p.Valor
+		case MetodoDebito, MetodoCredito:
+			cartao += p.Valor
+		case MetodoDinheiro:
+			dinheiro += p.Valor
+		}
+	}
+	return valorOuNil(pix), valorOuNil(cartao), valorOuNil(dinheiro)
+}
+
+// Troco soma o troco das linhas em dinheiro
+func Troco(pagamentos []models.PagamentoVenda) money.Dinheiro {
+	var troco money.Dinheiro
+	for _, p := range pagamentos {
+		troco += p.Troco
+	}
+	return troco
+}
+
+func valorOuNil(v money.Dinheiro) *money.Dinheiro {
+	if v == 0 {
+		return nil
+	}
+	return &v
+}
+
+func texto(s *string) *string {
+	if s == nil || strings.TrimSpace(*s) == "" {
+		return nil
+	}
+	t := strings.TrimSpace(*s)
+	return &t
+}
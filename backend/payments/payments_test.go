@@ -0,0 +1,178 @@
+package payments
+
+import (
+	"strings"
+	"testing"
+
+	"cmdimport/backend/money"
+	"cmdimport/backend/tradein"
+)
+
+func dinheiro(reais float64) *money.Dinheiro {
+	d := money.Reais(reais)
+	return &d
+}
+
+func str(s string) *string {
+	return &s
+}
+
+func TestValidar(t *testing.T) {
+	linhas := []Linha{
+		{Metodo: " PIX ", Valor: money.Reais(100)},
+		{Metodo: "credito", Valor: money.Reais(250.50), Parcelas: 3, Bandeira: str(" visa ")},
+		{Metodo: "boleto", Valor: money.Reais(49.50), Vencimento: str("2026-11-10")},
+	}
+	pagamentos, err := Validar(linhas, money.Reais(400))
+	if err != nil {
+		t.Fatalf("Validar: %v", err)
+	}
+	if len(pagamentos) != 3 {
+		t.Fatalf("Validar montou %d pagamentos, esperado 3", len(pagamentos))
+	}
+	if pagamentos[0].Metodo != MetodoPix || pagamentos[0].Parcelas != 1 {
+		t.Errorf("pix = %s em %dx, esperado pix à vista", pagamentos[0].Metodo, pagamentos[0].Parcelas)
+	}
+	if pagamentos[1].Parcelas != 3 || pagamentos[1].Bandeira == nil || *pagamentos[1].Bandeira != "visa" {
+		t.Errorf("crédito = %dx bandeira %v, esperado 3x visa", pagamentos[1].Parcelas, pagamentos[1].Bandeira)
+	}
+	if v := pagamentos[2].Vencimento; v == nil || v.Format("2006-01-02") != "2026-11-10" {
+		t.Errorf("vencimento do boleto = %v", v)
+	}
+}
+
+func TestValidarErros(t *testing.T) {
+	aparelho := &tradein.Aparelho{Modelo: "iPhone 11", Condicao: "bom"}
+	casos := []struct {
+		nome   string
+		linhas []Linha
+		total  money.Dinheiro
+		erro   string
+	}{
+		{"sem linhas", nil, 100, "ao menos uma forma"},
+		{"método inválido", []Linha{{Metodo: "cheque", Valor: 100}}, 100, "método inválido"},
+		{"valor zero", []Linha{{Metodo: "pix", Valor: 0}}, 0, "maior que zero"},
+		{"débito parcelado", []Linha{{Metodo: "debito", Valor: 100, Parcelas: 2}}, 100, "só crédito e crediário"},
+		{"troca sem aparelho", []Linha{{Metodo: "troca", Valor: 100}}, 100, "aparelho recebido"},
+		{"troca com aparelho inválido", []Linha{{Metodo: "troca", Valor: 100, Aparelho: &tradein.Aparelho{Modelo: "X", Condicao: "novo"}}}, 100, "condição inválida"},
+		{"aparelho fora da troca", []Linha{{Metodo: "pix", Valor: 100, Aparelho: aparelho}}, 100, "só se aplica a troca"},
+		{"vencimento no cartão", []Linha{{Metodo: "credito", Valor: 100, Vencimento: str("2026-11-10")}}, 100, "só se aplica a pix ou boleto"},
+		{"vencimento inválido", []Linha{{Metodo: "pix", Valor: 100, Vencimento: str("10/11/2026")}}, 100, "formato YYYY-MM-DD"},
+		{"boleto sem vencimento", []Linha{{Metodo: "boleto", Valor: 100}}, 100, "vencimento do boleto"},
+		{"recebido fora do dinheiro", []Linha{{Metodo: "pix", Valor: 100, ValorRecebido: dinheiro(2)}}, 100, "só se aplica a dinheiro"},
+		{"recebido menor que o valor", []Linha{{Metodo: "dinheiro", Valor: 1000, ValorRecebido: dinheiro(9.99)}}, 1000, "menor que o valor pago"},
+		{"soma diferente do total", []Linha{{Metodo: "pix", Valor: 1000}, {Metodo: "dinheiro", Valor: 999}}, 2000, "somam R$ 19,99, mas o total da venda é R$ 20,00"},
+	}
+	for _, c := range casos {
+		_, err := Validar(c.linhas, c.total)
+		if err == nil || !strings.Contains(err.Error(), c.erro) {
+			t.Errorf("%s: erro %v, esperado contendo %q", c.nome, err, c.erro)
+		}
+	}
+}
+
+func TestValidarTroco(t *testing.T) {
+	linhas := []Linha{
+		{Metodo: "dinheiro", Valor: money.Reais(37.40), ValorRecebido: dinheiro(50)},
+		{Metodo: "dinheiro", Valor: money.Reais(10), ValorRecebido: dinheiro(10)},
+		{Metodo: "troca", Valor: money.Reais(500), Aparelho: &tradein.Aparelho{Modelo: " iPhone 11 ", Condicao: "BOM"}},
+		{Metodo: "pix", Valor: money.Reais(2.60)},
+	}
+	pagamentos, err := Validar(linhas, money.Reais(550))
+	if err != nil {
+		t.Fatalf("Validar: %v", err)
+	}
+	if pagamentos[0].Troco != money.Reais(12.60) || pagamentos[0].ValorRecebido == nil || *pagamentos[0].ValorRecebido != money.Reais(50) {
+		t.Errorf("troco da primeira linha = %v (recebido %v), esperado 12.60", pagamentos[0].Troco, pagamentos[0].ValorRecebido)
+	}
+	if pagamentos[1].Troco != 0 {
+		t.Errorf("troco com valor exato = %v, esperado 0", pagamentos[1].Troco)
+	}
+	if pagamentos[3].Troco != 0 || pagamentos[3].ValorRecebido != nil {
+		t.Errorf("pix com troco %v e recebido %v", pagamentos[3].Troco, pagamentos[3].ValorRecebido)
+	}
+	if troco := Troco(pagamentos); troco != money.Reais(12.60) {
+		t.Errorf("Troco = %v, esperado 12.60", troco)
+	}
+	// O troco não entra na soma contra o total da venda
+	if _, err := Validar(linhas, money.Reais(562.60)); err == nil {
+		t.Errorf("Validar aceitou o valor recebido no lugar do valor pago")
+	}
+}
+
+func TestDaFormaLegada(t *testing.T) {
+	total := money.Reais(300)
+	casos := []struct {
+		nome      string
+		forma     string
+		pix       *money.Dinheiro
+		cartao    *money.Dinheiro
+		dinheiro  *money.Dinheiro
+		esperadas []Linha
+	}{
+		{"forma única", "credito_3x", nil, nil, nil, []Linha{{Metodo: MetodoCredito, Valor: total, Parcelas: 3}}},
+		{"dinheiro e pix", "dinheiro_pix", nil, nil, nil, []Linha{{Metodo: MetodoPix, Valor: total, Parcelas: 1}}},
+		{"forma desconhecida", "permuta", nil, nil, nil, []Linha{{Metodo: MetodoOutro, Valor: total, Parcelas: 1}}},
+		{"valores separados", "debito", dinheiro(100), dinheiro(150), dinheiro(50), []Linha{
+			{Metodo: MetodoPix, Valor: money.Reais(100), Parcelas: 1},
+			{Metodo: MetodoDebito, Valor: money.Reais(150), Parcelas: 1},
+			{Metodo: MetodoDinheiro, Valor: money.Reais(50), Parcelas: 1},
+		}},
+		{"cartão de forma não cartão vira crédito", "misto", nil, dinheiro(300), dinheiro(0), []Linha{
+			{Metodo: MetodoCredito, Valor: total, Parcelas: 1},
+		}},
+	}
+	for _, c := range casos {
+		linhas := DaFormaLegada(c.forma, c.pix, c.cartao, c.dinheiro, total)
+		if len(linhas) != len(c.esperadas) {
+			t.Errorf("%s: %d linhas, esperado %d", c.nome, len(linhas), len(c.esperadas))
+			continue
+		}
+		for i, l := range linhas {
+			e := c.esperadas[i]
+			if l.Metodo != e.Metodo || l.Valor != e.Valor || l.Parcelas != e.Parcelas {
+				t.Errorf("%s: linha %d = %s %v %dx, esperado %s %v %dx", c.nome, i+1, l.Metodo, l.Valor, l.Parcelas, e.Metodo, e.Valor, e.Parcelas)
+			}
+		}
+	}
+}
+
+func TestFormaDasLinhas(t *testing.T) {
+	casos := []struct {
+		linhas []Linha
+		forma  string
+	}{
+		{[]Linha{{Metodo: "pix"}}, "pix"},
+		{[]Linha{{Metodo: " Credito ", Parcelas: 1}}, "credito_vista"},
+		{[]Linha{{Metodo: "credito", Parcelas: 0}}, "credito_vista"},
+		{[]Linha{{Metodo: "credito", Parcelas: 10}}, "credito_10x"},
+		{[]Linha{{Metodo: "pix"}, {Metodo: "dinheiro"}}, "misto"},
+	}
+	for _, c := range casos {
+		if forma := FormaDasLinhas(c.linhas); forma != c.forma {
+			t.Errorf("FormaDasLinhas(%v) = %q, esperado %q", c.linhas, forma, c.forma)
+		}
+	}
+}
+
+func TestResumo(t *testing.T) {
+	pagamentos, err := Validar([]Linha{
+		{Metodo: "pix", Valor: 1000},
+		{Metodo: "debito", Valor: 2000},
+		{Metodo: "credito", Valor: 3000, Parcelas: 2},
+		{Metodo: "troca", Valor: 4000, Aparelho: &tradein.Aparelho{Modelo: "Galaxy S20", Condicao: "regular"}},
+	}, 10000)
+	if err != nil {
+		t.Fatalf("Validar: %v", err)
+	}
+	pix, cartao, especie := Resumo(pagamentos)
+	if pix == nil || *pix != 1000 {
+		t.Errorf("pix = %v, esperado 10.00", pix)
+	}
+	if cartao == nil || *cartao != 5000 {
+		t.Errorf("cartão = %v, esperado 50.00", cartao)
+	}
+	if especie != nil {
+		t.Errorf("dinheiro = %v, esperado nil", *especie)
+	}
+}
@@ -0,0 +1,348 @@
+package pricing
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"cmdimport/backend/catalog"
+	"cmdimport/backend/models"
+	"cmdimport/backend/money"
+
+	"gorm.io/gorm"
+)
+
+// Taxas é a tabela de taxas da maquininha (%), por método e número de parcelas
+type Taxas map[string]map[int]float64
+
+// CarregarTaxas lê a tabela de taxas da maquininha
+func CarregarTaxas(db *gorm.DB) (Taxas, error) {
+	var linhas []models.TaxaAdquirente
+	if err := db.Find(&linhas).Error; err != nil {
+		return nil, err
+	}
+	taxas := make(Taxas)
+	for _, t := range linhas {
+		if taxas[t.Metodo] == nil {
+			taxas[t.Metodo] = make(map[int]float64)
+		}
+		taxas[t.Metodo][t.Parcelas] = t.Percentual
+	}
+	return taxas, nil
+}
+
+// Percentual retorna a taxa do método/parcelas; sem taxa cadastrada (ou em dinheiro/pix), vale 0
+func (t Taxas) Percentual(metodo string, parcelas int) float64 {
+	if metodo == MetodoDinheiroPix {
+		return 0
+	}
+	return t[metodo][parcelas]
+}
+
+// Arredondar sobe o preço para o próximo valor com a terminação da regra.
+// Com múltiplo 10 e terminação 9,90: 1.234,00 vira 1.239,90. Sem múltiplo, o preço fica como está.
+func Arredondar(preco, multiplo, terminacao money.Dinheiro) money.Dinheiro {
+	if multiplo <= 0 {
+		return preco
+	}
+	n := (preco - terminacao) / multiplo
+	if n*multiplo < preco-terminacao {
+		n++
+	}
+	return n*multiplo + terminacao
+}
+
+// Margem é o resultado de um preço depois da taxa da maquininha
+type Margem struct {
+	Plano   string         `json:"plano"`
+	Preco   money.Dinheiro `json:"preco"`
+	Taxa    float64        `json:"taxa"`    // % da maquininha
+	Liquido money.Dinheiro `json:"liquido"` // Preço menos a taxa
+	Lucro   money.Dinheiro `json:"lucro"`   // Líquido menos o custo
+	Margem  float64        `json:"margem"`  // % do lucro sobre o preço
+}
+
+// CustoFinal aplica à média dos lotes os custos adicionais da regra (frete, impostos, custo fixo)
+func CustoFinal(custoLotes money.Dinheiro, regra models.RegraPrecificacao) money.Dinheiro {
+	return custoLotes + custoLotes.Percentual(regra.CustoAdicional) + regra.CustoFixo
+}
+
+// Calcular deriva o preço de cada plano a partir do custo final: o preço à vista é o custo com o markup,
+// e cada plano no cartão é calculado para que, descontada a taxa, sobre o mesmo valor do à vista.
+func Calcular(custo money.Dinheiro, regra models.RegraPrecificacao, taxas Taxas, planos []models.PlanoPagamento) Valores {
+	base := custo + custo.Percentual(regra.Markup)
+	valores := Valores{ValorDinheiroPix: Arredondar(base, regra.Multiplo, regra.Terminacao)}
+	for _, p := range planos {
+		preco := base
+		if taxa := taxas.Percentual(p.Metodo, p.Parcelas); taxa > 0 && taxa < 100 {
+			preco = base.Vezes(100).DivFator(100 - taxa)
+		}
+		valores.DefinirPreco(p.Codigo, Arredondar(preco, regra.Multiplo, regra.Terminacao))
+	}
+	return valores
+}
+
+// Margens calcula o líquido e o lucro do preço de cada plano para o custo informado
+func Margens(valores Valores, custo money.Dinheiro, taxas Taxas, planos []models.PlanoPagamento) []Margem {
+	margens := make([]Margem, 0, len(planos))
+	for _, p := range planos {
+		preco := valores.Preco(p)
+		taxa := taxas.Percentual(p.Metodo, p.Parcelas)
+		liquido := preco - preco.Percentual(taxa)
+		m := Margem{
+			Plano:   p.Codigo,
+			Preco:   preco,
+			Taxa:    taxa,
+			Liquido: liquido,
+			Lucro:   liquido - custo,
+		}
+		if preco > 0 {
+			m.Margem = math.Round(float64(liquido-custo)/float64(preco)*10000) / 100
+		}
+		margens = append(margens, m)
+	}
+	return margens
+}
+
+// FiltroRegras limita os produtos recalculados pelas regras
+type FiltroRegras struct {
+	CategoriaID    *int
+	SKUID          *int
+	NomesProdutos  []string // Produtos sem SKU, pelo nome
+	IncluirManuais bool     // Também recalcula preços digitados pelo admin
+}
+
+// Simulacao é o preço calculado pelas regras para um produto em estoque, ao lado do preço atual
+type Simulacao struct {
+	SKUID          *int           `json:"skuId"`
+	NomeProduto    string         `json:"nomeProduto"`
+	CategoriaID    *int           `json:"categoriaId"`
+	Quantidade     int            `json:"quantidade"`
+	CustoLotes     money.Dinheiro `json:"custoLotes"` // Média ponderada do custo dos lotes em estoque
+	CustoFinal     money.Dinheiro `json:"custoFinal"`
+	RegraID        int            `json:"regraId"`
+	PrecificacaoID *int           `json:"precificacaoId"`
+	Manual         bool           `json:"manual"`
+	Ignorado       string         `json:"ignorado,omitempty"` // Motivo para não aplicar
+	Atual          *Valores       `json:"atual"`
+	MargensAtuais  []Margem       `json:"margensAtuais,omitempty"`
+	Novo           *Valores       `json:"novo"`
+	Margens        []Margem       `json:"margens,omitempty"`
+
+	chave string
+}
+
+// custoEstoque é o custo médio ponderado dos lotes em estoque de um SKU (ou nome, para lotes sem SKU)
+type custoEstoque struct {
+	SKUID       *int           `gorm:"column:sku_id"`
+	Codigo      *string        `gorm:"column:codigo"`
+	NomeProduto string         `gorm:"column:nome_produto"`
+	CategoriaID *int           `gorm:"column:categoria_id"`
+	Quantidade  int            `gorm:"column:quantidade"`
+	Custo       money.Dinheiro `gorm:"column:custo"`
+}
+
+func custosEmEstoque(db *gorm.DB, filtro FiltroRegras) ([]custoEstoque, error) {
+	query := `
+		SELECT
+			t.skuId as sku_id,
+			MAX(s.codigo) as codigo,
+			COALESCE(MAX(s.nome), MAX(t.nome)) as nome_produto,
+			COALESCE(MAX(s.categoriaId), MAX(t.categoriaId)) as categoria_id,
+			SUM(t.quantidade) as quantidade,
+			SUM(t.preco * t.quantidade) / SUM(t.quantidade) as custo
+		FROM (
+			SELECT skuId, nome, categoriaId, quantidade, preco FROM ProdutoComprado WHERE quantidade > 0 AND deletedAt IS NULL
+			UNION ALL
+			SELECT pc.skuId, pc.nome, pc.categoriaId, e.quantidade, pc.preco
+			FROM Estoque e
+			JOIN ProdutoComprado pc ON e.produtoCompradoId = pc.id
+			WHERE e.quantidade > 0 AND e.ativo = true AND e.deletedAt IS NULL
+		) as t
+		LEFT JOIN ProdutoSKU s ON s.id = t.skuId
+		GROUP BY t.skuId, CASE WHEN t.skuId IS NULL THEN t.nome END
+		ORDER BY nome_produto ASC
+	`
+	var custos []custoEstoque
+	if err := db.Raw(query).Scan(&custos).Error; err != nil {
+		return nil, err
+	}
+
+	filtrados := custos[:0]
+	for _, c := range custos {
+		if filtro.CategoriaID != nil && (c.CategoriaID == nil || *c.CategoriaID != *filtro.CategoriaID) {
+			continue
+		}
+		if filtro.SKUID != nil || len(filtro.NomesProdutos) > 0 {
+			doSKU := filtro.SKUID != nil && c.SKUID != nil && *c.SKUID == *filtro.SKUID
+			doNome := c.SKUID == nil && contem(filtro.NomesProdutos, c.NomeProduto)
+			if !doSKU && !doNome {
+				continue
+			}
+		}
+		filtrados = append(filtrados, c)
+	}
+	return filtrados, nil
+}
+
+func contem(lista []string, valor string) bool {
+	for _, v := range lista {
+		if v == valor {
+			return true
+		}
+	}
+	return false
+}
+
+// Simular calcula pelas regras os preços dos produtos em estoque, sem gravar nada.
+// Usa a regra da categoria do produto ou, sem ela, a regra padrão (sem categoria).
+func Simular(db *gorm.DB, filtro FiltroRegras) ([]Simulacao, error) {
+	custos, err := custosEmEstoque(db, filtro)
+	if err != nil {
+		return nil, err
+	}
+
+	var regras []models.RegraPrecificacao
+	if err := db.Where("ativo = ?", true).Find(&regras).Error; err != nil {
+		return nil, err
+	}
+	var padrao *models.RegraPrecificacao
+	porCategoria := make(map[int]models.RegraPrecificacao)
+	for i, r := range regras {
+		if r.CategoriaID == nil {
+			padrao = &regras[i]
+		} else {
+			porCategoria[*r.CategoriaID] = r
+		}
+	}
+
+	taxas, err := CarregarTaxas(db)
+	if err != nil {
+		return nil, err
+	}
+
+	planos, err := CarregarPlanos(db, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var precificacoes []models.Precificacao
+	if err := db.Find(&precificacoes).Error; err != nil {
+		return nil, err
+	}
+	porSKU := make(map[int]models.Precificacao)
+	porNome := make(map[string]models.Precificacao)
+	for _, p := range precificacoes {
+		if p.SKUID != nil {
+			porSKU[*p.SKUID] = p
+		} else {
+			porNome[p.NomeProduto] = p
+		}
+	}
+
+	simulacoes := make([]Simulacao, 0, len(custos))
+	for _, c := range custos {
+		s := Simulacao{
+			SKUID:       c.SKUID,
+			NomeProduto: c.NomeProduto,
+			CategoriaID: c.CategoriaID,
+			Quantidade:  c.Quantidade,
+			CustoLotes:  c.Custo,
+			chave:       c.NomeProduto,
+		}
+		if c.SKUID != nil && c.Codigo != nil {
+			s.chave = catalog.ChavePrecificacao(&models.ProdutoSKU{Codigo: *c.Codigo})
+		}
+
+		var prec models.Precificacao
+		var ok bool
+		if c.SKUID != nil {
+			prec, ok = porSKU[*c.SKUID]
+		} else {
+			prec, ok = porNome[c.NomeProduto]
+		}
+		if ok {
+			id := prec.ID
+			atual := ValoresDe(prec)
+			s.PrecificacaoID = &id
+			s.Manual = prec.Manual
+			s.Atual = &atual
+		}
+
+		regra := padrao
+		if c.CategoriaID != nil {
+			if r, ok := porCategoria[*c.CategoriaID]; ok {
+				regra = &r
+			}
+		}
+
+		switch {
+		case regra == nil:
+			s.Ignorado = "sem regra para a categoria"
+		case c.Custo <= 0:
+			s.Ignorado = "custo zerado"
+		case s.Manual && !filtro.IncluirManuais:
+			s.Ignorado = "preço manual"
+		}
+
+		if regra != nil {
+			s.RegraID = regra.ID
+			s.CustoFinal = CustoFinal(c.Custo, *regra)
+			if c.Custo > 0 {
+				novo := Calcular(s.CustoFinal, *regra, taxas, planos)
+				s.Novo = &novo
+				s.Margens = Margens(novo, s.CustoFinal, taxas, planos)
+			}
+			if s.Atual != nil {
+				s.MargensAtuais = Margens(*s.Atual, s.CustoFinal, taxas, planos)
+			}
+		}
+
+		simulacoes = append(simulacoes, s)
+	}
+	return simulacoes, nil
+}
+
+// AplicarRegras grava como nova versão de preço (origem "regra") cada simulação não ignorada.
+// As precificações aplicadas deixam de ser manuais. Retorna quantos produtos foram precificados.
+// Deve ser chamada dentro de uma transação.
+func AplicarRegras(tx *gorm.DB, simulacoes []Simulacao, vigenteDe time.Time, autor *models.Usuario) (int, error) {
+	aplicados := 0
+	for _, s := range simulacoes {
+		if s.Ignorado != "" || s.Novo == nil {
+			continue
+		}
+
+		var prec models.Precificacao
+		busca := tx.Where("nomeProduto = ? AND skuId IS NULL", s.NomeProduto)
+		if s.SKUID != nil {
+			busca = tx.Where("skuId = ?", *s.SKUID)
+		}
+		err := busca.First(&prec).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			agora := time.Now()
+			prec = models.Precificacao{
+				NomeProduto: s.chave,
+				SKUID:       s.SKUID,
+				CreatedAt:   agora,
+				UpdatedAt:   agora,
+			}
+			if err := tx.Create(&prec).Error; err != nil {
+				return 0, err
+			}
+		} else if err != nil {
+			return 0, err
+		}
+
+		if prec.Manual {
+			if err := tx.Model(&prec).UpdateColumn("manual", false).Error; err != nil {
+				return 0, err
+			}
+		}
+		if _, err := SalvarVersao(tx, &prec, *s.Novo, vigenteDe, autor, OrigemRegra); err != nil {
+			return 0, err
+		}
+		aplicados++
+	}
+	return aplicados, nil
+}
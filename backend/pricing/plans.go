@@ -0,0 +1,163 @@
+package pricing
+
+import (
+	"strings"
+
+	"cmdimport/backend/models"
+	"cmdimport/backend/money"
+
+	"gorm.io/gorm"
+)
+
+// Códigos dos planos que têm coluna própria em Precificacao
+const (
+	PlanoDinheiroPix  = "dinheiro_pix"
+	PlanoDebito       = "debito"
+	PlanoCreditoVista = "credito_vista"
+	PlanoCredito5x    = "credito_5x"
+	PlanoCredito10x   = "credito_10x"
+	PlanoCredito12x   = "credito_12x"
+)
+
+// Métodos de pagamento de um plano
+const (
+	MetodoDinheiroPix = "dinheiro_pix"
+	MetodoDebito      = "debito"
+	MetodoCredito     = "credito"
+)
+
+// PlanosPadrao são os planos usados enquanto nenhum plano foi cadastrado
+func PlanosPadrao() []models.PlanoPagamento {
+	return []models.PlanoPagamento{
+		{Codigo: PlanoDinheiroPix, Nome: "Dinheiro/Pix", Metodo: MetodoDinheiroPix, Parcelas: 1, Ativo: true, Ordem: 1},
+		{Codigo: PlanoDebito, Nome: "Débito", Metodo: MetodoDebito, Parcelas: 1, Ativo: true, Ordem: 2},
+		{Codigo: PlanoCreditoVista, Nome: "Crédito à vista", Metodo: MetodoCredito, Parcelas: 1, Ativo: true, Ordem: 3},
+		{Codigo: PlanoCredito5x, Nome: "Crédito 5x", Metodo: MetodoCredito, Parcelas: 5, Ativo: true, Ordem: 4},
+		{Codigo: PlanoCredito10x, Nome: "Crédito 10x", Metodo: MetodoCredito, Parcelas: 10, Ativo: true, Ordem: 5},
+		{Codigo: PlanoCredito12x, Nome: "Crédito 12x", Metodo: MetodoCredito, Parcelas: 12, Ativo: true, Ordem: 6},
+	}
+}
+
+// PlanoComColuna indica se o preço do plano fica em uma coluna própria de Precificacao
+func PlanoComColuna(codigo string) bool {
+	switch codigo {
+	case PlanoDinheiroPix, PlanoDebito, PlanoCreditoVista, PlanoCredito5x, PlanoCredito10x, PlanoCredito12x:
+		return true
+	}
+	return false
+}
+
+// CarregarPlanos lê os planos de pagamento em ordem de exibição.
+// Sem planos cadastrados, retorna PlanosPadrao.
+func CarregarPlanos(db *gorm.DB, somenteAtivos bool) ([]models.PlanoPagamento, error) {
+	var total int64
+	if err := db.Model(&models.PlanoPagamento{}).Count(&total).Error; err != nil {
+		return nil, err
+	}
+	if total == 0 {
+		return PlanosPadrao(), nil
+	}
+
+	query := db.Order("ordem ASC, id ASC")
+	if somenteAtivos {
+		query = query.Where("ativo = ?", true)
+	}
+	var planos []models.PlanoPagamento
+	if err := query.Find(&planos).Error; err != nil {
+		return nil, err
+	}
+	return planos, nil
+}
+
+// GarantirPlanosPadrao grava PlanosPadrao quando a tabela está vazia, para que o primeiro
+// plano cadastrado pelo admin não substitua os planos de sempre.
+func GarantirPlanosPadrao(tx *gorm.DB) error {
+	var total int64
+	if err := tx.Model(&models.PlanoPagamento{}).Count(&total).Error; err != nil {
+		return err
+	}
+	if total > 0 {
+		return nil
+	}
+	planos := PlanosPadrao()
+	return tx.Create(&planos).Error
+}
+
+// CodigoDaForma converte a forma de pagamento enviada na venda para o código do plano.
+// "pix" e "dinheiro" são formas antigas do plano dinheiro_pix.
+func CodigoDaForma(forma string) string {
+	forma = strings.ToLower(strings.TrimSpace(forma))
+	switch forma {
+	case "pix", "dinheiro":
+		return PlanoDinheiroPix
+	}
+	return forma
+}
+
+// BuscarPlano encontra o plano da forma de pagamento da venda
+func BuscarPlano(planos []models.PlanoPagamento, forma string) (models.PlanoPagamento, bool) {
+	codigo := CodigoDaForma(forma)
+	for _, p := range planos {
+		if p.Codigo == codigo {
+			return p, true
+		}
+	}
+	return models.PlanoPagamento{}, false
+}
+
+// PrecoDoPlano retorna o preço gravado para o plano (coluna própria ou precosPlanos), ou 0 se não houver
+func (v Valores) PrecoDoPlano(codigo string) money.Dinheiro {
+	switch codigo {
+	case PlanoDinheiroPix:
+		return v.ValorDinheiroPix
+	case PlanoDebito:
+		return v.ValorDebito
+	case PlanoCreditoVista:
+		return v.ValorCartaoVista
+	case PlanoCredito5x:
+		return v.ValorCredito5x
+	case PlanoCredito10x:
+		return v.ValorCredito10x
+	case PlanoCredito12x:
+		return v.ValorCredito12x
+	}
+	return v.PrecosPlanos[codigo]
+}
+
+// Preco retorna o preço do plano. Sem preço próprio, aplica o acréscimo do plano sobre o preço à vista.
+func (v Valores) Preco(plano models.PlanoPagamento) money.Dinheiro {
+	if preco := v.PrecoDoPlano(plano.Codigo); preco > 0 {
+		return preco
+	}
+	if plano.Codigo == PlanoDinheiroPix || v.ValorDinheiroPix == 0 {
+		return 0
+	}
+	return v.ValorDinheiroPix + v.ValorDinheiroPix.Percentual(plano.Acrescimo)
+}
+
+// DefinirPreco grava o preço do plano na coluna própria ou em precosPlanos
+func (v *Valores) DefinirPreco(codigo string, preco money.Dinheiro) {
+	switch codigo {
+	case PlanoDinheiroPix:
+		v.ValorDinheiroPix = preco
+	case PlanoDebito:
+		v.ValorDebito = preco
+	case PlanoCreditoVista:
+		v.ValorCartaoVista = preco
+	case PlanoCredito5x:
+		v.ValorCredito5x = preco
+	case PlanoCredito10x:
+		v.ValorCredito10x = preco
+	case PlanoCredito12x:
+		v.ValorCredito12x = preco
+	default:
+		if preco == 0 {
+			delete(v.PrecosPlanos, codigo)
+			return
+		}
+		if v.PrecosPlanos == nil {
+			v.PrecosPlanos = make(models.PrecosPlanos)
+		}
+		v.PrecosPlanos[codigo] = preco
+	}
+}
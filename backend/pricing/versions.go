@@ -0,0 +1,314 @@
+// Package pricing mantém o histórico de versões da precificação e resolve o preço vigente em uma data.
+package pricing
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"cmdimport/backend/models"
+	"cmdimport/backend/money"
+	"cmdimport/backend/timezone"
+
+	"gorm.io/gorm"
+)
+
+// Valores são os preços por forma de pagamento de uma precificação
+type Valores struct {
+	ValorDinheiroPix money.Dinheiro `json:"valorDinheiroPix"`
+	ValorDebito      money.Dinheiro `json:"valorDebito"`
+	ValorCartaoVista money.Dinheiro `json:"valorCartaoVista"`
+	ValorCredito5x   money.Dinheiro `json:"valorCredito5x"`
+	ValorCredito10x  money.Dinheiro `json:"valorCredito10x"`
+	ValorCredito12x  money.Dinheiro `json:"valorCredito12x"`
+	// Preços dos planos sem coluna própria, ex.: {"credito_3x": 1099.90}
+	PrecosPlanos models.PrecosPlanos `json:"precosPlanos,omitempty"`
+}
+
+// Origens de uma versão de preço
+const (
+	OrigemManual = "manual"
+	OrigemRegra  = "regra"
+)
+
+// ErrVersaoNaoEncontrada indica que a versão não existe
+var ErrVersaoNaoEncontrada = errors.New("versão de preço não encontrada")
+
+// ErrVersaoEmVigor indica que a versão já entrou em vigor e não pode ser cancelada
+var ErrVersaoEmVigor = errors.New("a versão já entrou em vigor")
+
+// ValoresDe retorna os preços atuais gravados na precificação
+func ValoresDe(p models.Precificacao) Valores {
+	return Valores{
+		ValorDinheiroPix: p.ValorDinheiroPix,
+		ValorDebito:      p.ValorDebito,
+		ValorCartaoVista: p.ValorCartaoVista,
+		ValorCredito5x:   p.ValorCredito5x,
+		ValorCredito10x:  p.ValorCredito10x,
+		ValorCredito12x:  p.ValorCredito12x,
+		PrecosPlanos:     copiarPrecos(p.PrecosPlanos),
+	}
+}
+
+// ValoresDaVersao retorna os preços de uma versão
+func ValoresDaVersao(v models.PrecificacaoVersao) Valores {
+	return Valores{
+		ValorDinheiroPix: v.ValorDinheiroPix,
+		ValorDebito:      v.ValorDebito,
+		ValorCartaoVista: v.ValorCartaoVista,
+		ValorCredito5x:   v.ValorCredito5x,
+		ValorCredito10x:  v.ValorCredito10x,
+		ValorCredito12x:  v.ValorCredito12x,
+		PrecosPlanos:     copiarPrecos(v.PrecosPlanos),
+	}
+}
+
+// vazio indica que nenhum preço foi informado
+func (v Valores) vazio() bool {
+	return v.ValorDinheiroPix == 0 && v.ValorDebito == 0 && v.ValorCartaoVista == 0 &&
+		v.ValorCredito5x == 0 && v.ValorCredito10x == 0 && v.ValorCredito12x == 0 && len(v.PrecosPlanos) == 0
+}
+
+func copiarPrecos(precos models.PrecosPlanos) models.PrecosPlanos {
+	if len(precos) == 0 {
+		return nil
+	}
+	copia := make(models.PrecosPlanos, len(precos))
+	for codigo, preco := range precos {
+		copia[codigo] = preco
+	}
+	return copia
+}
+
+func (v Valores) aplicarEm(p *models.Precificacao) {
+	p.ValorDinheiroPix = v.ValorDinheiroPix
+	p.ValorDebito = v.ValorDebito
+	p.ValorCartaoVista = v.ValorCartaoVista
+	p.ValorCredito5x = v.ValorCredito5x
+	p.ValorCredito10x = v.ValorCredito10x
+	p.ValorCredito12x = v.ValorCredito12x
+	p.PrecosPlanos = copiarPrecos(v.PrecosPlanos)
+}
+
+func (v Valores) aplicarNaVersao(versao *models.PrecificacaoVersao) {
+	versao.ValorDinheiroPix = v.ValorDinheiroPix
+	versao.ValorDebito = v.ValorDebito
+	versao.ValorCartaoVista = v.ValorCartaoVista
+	versao.ValorCredito5x = v.ValorCredito5x
+	versao.ValorCredito10x = v.ValorCredito10x
+	versao.ValorCredito12x = v.ValorCredito12x
+	versao.PrecosPlanos = copiarPrecos(v.PrecosPlanos)
+}
+
+// ParseVigencia aceita "2006-01-02T15:04:05Z07:00", "2006-01-02T15:04", "2006-01-02 15:04" e "2006-01-02"
+// (meia-noite), com data e hora sem fuso no fuso do negócio. somenteData indica que não foi informado horário.
+func ParseVigencia(s string) (t time.Time, somenteData bool, err error) {
+	s = strings.TrimSpace(s)
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, false, nil
+	}
+	for _, layout := range []string{"2006-01-02T15:04", "2006-01-02 15:04", "2006-01-02 15:04:05"} {
+		if t, err := time.ParseInLocation(layout, s, timezone.Local()); err == nil {
+			return t, false, nil
+		}
+	}
+	if t, err := timezone.ParseData(s); err == nil {
+		return t, true, nil
+	}
+	return time.Time{}, false, fmt.Errorf("data de vigência inválida: %q", s)
+}
+
+// SalvarVersao registra uma nova versão de preço a partir de vigenteDe.
+// Se vigenteDe já passou, a versão entra em vigor na hora e os preços da precificação são atualizados;
+// se está no futuro, fica agendada até AplicarAgendadas. Uma versão futura com o mesmo início é substituída.
+// origem é OrigemManual ou OrigemRegra. Deve ser chamada dentro de uma transação.
+func SalvarVersao(tx *gorm.DB, prec *models.Precificacao, valores Valores, vigenteDe time.Time, autor *models.Usuario, origem string) (*models.PrecificacaoVersao, error) {
+	// A coluna datetime não guarda frações de segundo
+	agora := time.Now().Truncate(time.Second)
+	vigenteDe = vigenteDe.Truncate(time.Second)
+	if vigenteDe.Before(agora) {
+		vigenteDe = agora
+	}
+
+	// Precificações anteriores ao histórico ganham uma versão inicial com os preços atuais
+	var total int64
+	if err := tx.Model(&models.PrecificacaoVersao{}).Where("precificacaoId = ?", prec.ID).Count(&total).Error; err != nil {
+		return nil, err
+	}
+	if total == 0 && !ValoresDe(*prec).vazio() && prec.UpdatedAt.Before(vigenteDe) {
+		inicial := models.PrecificacaoVersao{
+			PrecificacaoID: prec.ID,
+			VigenteDe:      prec.UpdatedAt,
+			Aplicada:       true,
+			Origem:         OrigemManual,
+		}
+		ValoresDe(*prec).aplicarNaVersao(&inicial)
+		if err := tx.Create(&inicial).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	var versao models.PrecificacaoVersao
+	err := tx.Where("precificacaoId = ? AND vigenteDe = ? AND aplicada = ?", prec.ID, vigenteDe, false).First(&versao).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	versao.PrecificacaoID = prec.ID
+	versao.VigenteDe = vigenteDe
+	versao.Origem = origem
+	valores.aplicarNaVersao(&versao)
+	if autor != nil {
+		versao.AutorID = &autor.ID
+		versao.AutorNome = &autor.Nome
+	}
+	if err := tx.Save(&versao).Error; err != nil {
+		return nil, err
+	}
+
+	if err := recalcularVigencias(tx, prec.ID); err != nil {
+		return nil, err
+	}
+
+	if !vigenteDe.After(agora) {
+		if err := aplicar(tx, prec.ID, agora); err != nil {
+			return nil, err
+		}
+		versao.Aplicada = true
+	}
+
+	if err := tx.First(&versao, versao.ID).Error; err != nil {
+		return nil, err
+	}
+	return &versao, nil
+}
+
+// CancelarVersao remove uma versão agendada que ainda não entrou em vigor
+func CancelarVersao(tx *gorm.DB, id int) (*models.PrecificacaoVersao, error) {
+	var versao models.PrecificacaoVersao
+	if err := tx.First(&versao, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrVersaoNaoEncontrada
+		}
+		return nil, err
+	}
+	if versao.Aplicada || !versao.VigenteDe.After(time.Now()) {
+		return nil, ErrVersaoEmVigor
+	}
+	if err := tx.Delete(&versao).Error; err != nil {
+		return nil, err
+	}
+	if err := recalcularVigencias(tx, versao.PrecificacaoID); err != nil {
+		return nil, err
+	}
+	return &versao, nil
+}
+
+// recalcularVigencias faz cada versão valer até o início da seguinte
+func recalcularVigencias(tx *gorm.DB, precificacaoID int) error {
+	var versoes []models.PrecificacaoVersao
+	if err := tx.Where("precificacaoId = ?", precificacaoID).Order("vigenteDe ASC, id ASC").Find(&versoes).Error; err != nil {
+		return err
+	}
+	for i := range versoes {
+		var ate *time.Time
+		if i+1 < len(versoes) {
+			ate = &versoes[i+1].VigenteDe
+		}
+		atual := versoes[i].VigenteAte
+		if (atual == nil && ate == nil) || (atual != nil && ate != nil && atual.Equal(*ate)) {
+			continue
+		}
+		if err := tx.Model(&versoes[i]).Update("vigenteAte", ate).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// VersaoVigente retorna a versão de preço válida no instante informado.
+// Retorna nil (sem erro) quando a precificação não tem histórico.
+func VersaoVigente(db *gorm.DB, precificacaoID int, em time.Time) (*models.PrecificacaoVersao, error) {
+	var versao models.PrecificacaoVersao
+	err := db.Where("precificacaoId = ? AND vigenteDe <= ?", precificacaoID, em).
+		Order("vigenteDe DESC, id DESC").
+		First(&versao).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &versao, nil
+}
+
+// ValoresVigentes resolve os preços válidos no instante informado para cada precificação
+// (chave: ID da precificação). Sem histórico, valem os preços gravados na precificação.
+func ValoresVigentes(db *gorm.DB, precificacoes []models.Precificacao, em time.Time) (map[int]Valores, error) {
+	resultado := make(map[int]Valores, len(precificacoes))
+	if len(precificacoes) == 0 {
+		return resultado, nil
+	}
+
+	ids := make([]int, 0, len(precificacoes))
+	for _, p := range precificacoes {
+		resultado[p.ID] = ValoresDe(p)
+		ids = append(ids, p.ID)
+	}
+
+	var versoes []models.PrecificacaoVersao
+	if err := db.Where("precificacaoId IN ? AND vigenteDe <= ?", ids, em).
+		Order("vigenteDe ASC, id ASC").
+		Find(&versoes).Error; err != nil {
+		return nil, err
+	}
+	// Ordenadas por início, a última de cada precificação é a vigente
+	for _, v := range versoes {
+		resultado[v.PrecificacaoID] = ValoresDaVersao(v)
+	}
+	return resultado, nil
+}
+
+// AplicarAgendadas copia para a precificação os preços das versões agendadas que já entraram em vigor.
+// Retorna quantas precificações foram atualizadas.
+func AplicarAgendadas(db *gorm.DB, agora time.Time) (int, error) {
+	var ids []int
+	if err := db.Model(&models.PrecificacaoVersao{}).
+		Where("aplicada = ? AND vigenteDe <= ?", false, agora).
+		Distinct("precificacaoId").
+		Pluck("precificacaoId", &ids).Error; err != nil {
+		return 0, err
+	}
+
+	for _, id := range ids {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			return aplicar(tx, id, agora)
+		})
+		if err != nil {
+			return 0, fmt.Errorf("erro ao aplicar preços da precificação %d: %w", id, err)
+		}
+	}
+	return len(ids), nil
+}
+
+// aplicar grava na precificação os preços da versão vigente e marca as versões já iniciadas como aplicadas
+func aplicar(tx *gorm.DB, precificacaoID int, agora time.Time) error {
+	versao, err := VersaoVigente(tx, precificacaoID, agora)
+	if err != nil || versao == nil {
+		return err
+	}
+
+	var prec models.Precificacao
+	if err := tx.First(&prec, precificacaoID).Error; err != nil {
+		return err
+	}
+	ValoresDaVersao(*versao).aplicarEm(&prec)
+	prec.UpdatedAt = agora
+	if err := tx.Save(&prec).Error; err != nil {
+		return err
+	}
+
+	return tx.Model(&models.PrecificacaoVersao{}).
+		Where("precificacaoId = ? AND vigenteDe <= ? AND aplicada = ?", precificacaoID, agora, false).
+		Update("aplicada", true).Error
+}
@@ -0,0 +1,251 @@
+// Package profitloss monta a DRE (demonstração do resultado) de um período: receita das vendas, devoluções,
+// custo dos produtos vendidos, margem bruta, despesas operacionais e resultado líquido.
+//
+// A receita e o custo de cada venda ficam no mês da venda; as devoluções, no mês em que aconteceram, com o custo
+// dos produtos devolvidos saindo do CMV. Assim, deletar um item de uma venda de meses atrás não muda a DRE daquele mês.
+package profitloss
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"time"
+
+	"cmdimport/backend/models"
+	"cmdimport/backend/money"
+	"cmdimport/backend/timezone"
+
+	"gorm.io/gorm"
+)
+
+// RegistrarDevolucao grava a devolução de unidades de um item de venda, antes de o item ser deletado ou
+// alterado. Devolvendo o item inteiro, estorna a receita líquida do item (com os descontos rateados);
+// devolvendo parte, estorna o preço unitário das unidades, já que os descontos rateados continuam no item.
+func RegistrarDevolucao(tx *gorm.DB, item models.HistoricoVenda, quantidade int) error {
+	if quantidade <= 0 || item.Quantidade <= 0 {
+		return nil
+	}
+	if quantidade > item.Quantidade {
+		quantidade = item.Quantidade
+	}
+
+	valor := item.PrecoUnitario.Vezes(quantidade)
+	if quantidade == item.Quantidade {
+		valor = item.PrecoUnitario.Vezes(item.Quantidade) - item.DescontoVenda - item.DescontoCupom
+	}
+
+	var custoUnitario money.Dinheiro
+	if err := tx.Table("Estoque e").
+		Select("COALESCE(pc.preco, 0)").
+		Joins("JOIN ProdutoComprado pc ON pc.id = e.produtoCompradoId").
+		Where("e.id = ?", item.EstoqueID).
+		Scan(&custoUnitario).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	return tx.Create(&models.DevolucaoVenda{
+		HistoricoVendaID: item.ID,
+		VendaID:          item.VendaID,
+		UsuarioID:        item.UsuarioID,
+		LojaID:           item.LojaID,
+		EstoqueID:        item.EstoqueID,
+		ProdutoNome:      item.ProdutoNome,
+		Quantidade:       quantidade,
+		Valor:            valor,
+		Custo:            custoUnitario.Vezes(quantidade),
+		DataVenda:        item.CreatedAt,
+	}).Error
+}
+
+// Agrupamentos da DRE
+const (
+	PorMes      = "mes"
+	PorLoja     = "loja"
+	PorVendedor = "vendedor"
+)
+
+// Linha é a DRE de um período ou de um grupo (mês, loja ou vendedor)
+type Linha struct {
+	Chave                string          `gorm:"column:chave" json:"chave"` // Mês (YYYY-MM), id da loja ou id do vendedor; vazio no total
+	Nome                 string          `json:"nome,omitempty"`
+	ReceitaBruta         money.Dinheiro  `gorm:"column:receitaBruta" json:"receitaBruta"` // Vendas já sem os descontos
+	Devolucoes           money.Dinheiro  `gorm:"column:devolucoes" json:"devolucoes"`
+	ReceitaLiquida       money.Dinheiro  `json:"receitaLiquida"`
+	CMV                  money.Dinheiro  `gorm:"column:cmv" json:"cmv"` // Custo dos produtos vendidos
+	LucroBruto           money.Dinheiro  `json:"lucroBruto"`
+	MargemBruta          float64         `json:"margemBruta"`                    // Lucro bruto sobre a receita líquida, em %
+	DespesasOperacionais *money.Dinheiro `json:"despesasOperacionais,omitempty"` // Só no total e por mês: as despesas não são por loja nem vendedor
+	ResultadoLiquido     *money.Dinheiro `json:"resultadoLiquido,omitempty"`
+}
+
+// DespesaCategoria é o total de despesas de uma categoria no período
+type DespesaCategoria struct {
+	CategoriaID int            `gorm:"column:categoriaId" json:"categoriaId"`
+	Categoria   string         `gorm:"column:categoria" json:"categoria"`
+	Valor       money.Dinheiro `gorm:"column:valor" json:"valor"`
+}
+
+// chave retorna a expressão SQL do grupo para uma tabela (hv ou d) e a coluna de data usada no período
+// (o mês é o do fuso do negócio). A loja é a gravada na venda, não a atual do vendedor.
+func chave(agrupar, alias, data string) string {
+	switch agrupar {
+	case PorMes:
+		return "DATE_FORMAT(" + timezone.SQL(alias+"."+data) + ", '%Y-%m')"
+	case PorLoja:
+		return "COALESCE(CAST(" + alias + ".lojaId AS CHAR), '')"
+	case PorVendedor:
+		return "CAST(" + alias + ".usuarioId AS CHAR)"
+	}
+	return "''"
+}
+
+// vendas soma receita bruta, devoluções e CMV do período [inicio, fim) por grupo, em uma única consulta:
+// itens vendidos no período, mais o que foi devolvido de vendas do período (a receita e o custo originais),
+// menos as devoluções que aconteceram no período.
+func vendas(db *gorm.DB, agrupar string, inicio, fim time.Time) ([]Linha, error) {
+	sql := "SELECT chave, SUM(receitaBruta) as receitaBruta, SUM(devolucoes) as devolucoes, SUM(cmv) as cmv FROM (" +
+		"SELECT " + chave(agrupar, "hv", "createdAt") + " as chave, " +
+		"SUM(hv.precoUnitario * hv.quantidade - hv.descontoVenda - hv.descontoCupom) as receitaBruta, 0 as devolucoes, " +
+		"SUM(COALESCE(pc.preco, 0) * hv.quantidade) as cmv " +
+		"FROM HistoricoVenda hv " +
+		"LEFT JOIN Estoque e ON e.id = hv.estoqueId " +
+		"LEFT JOIN ProdutoComprado pc ON pc.id = e.produtoCompradoId " +
+		"WHERE hv.createdAt >= ? AND hv.createdAt < ? GROUP BY chave " +
+		"UNION ALL " +
+		"SELECT " + chave(agrupar, "d", "dataVenda") + " as chave, SUM(d.valor), 0, SUM(d.custo) " +
+		"FROM DevolucaoVenda d " +
+		"WHERE d.dataVenda >= ? AND d.dataVenda < ? GROUP BY chave " +
+		"UNION ALL " +
+		"SELECT " + chave(agrupar, "d", "createdAt") + " as chave, 0, SUM(d.valor), -SUM(d.custo) " +
+		"FROM DevolucaoVenda d " +
+		"WHERE d.createdAt >= ? AND d.createdAt < ? GROUP BY chave" +
+		") x GROUP BY chave ORDER BY chave"
+
+	var linhas []Linha
+	if err := db.Raw(sql, inicio, fim, inicio, fim, inicio, fim).Scan(&linhas).Error; err != nil {
+		return nil, err
+	}
+	return linhas, nil
+}
+
+// Despesas soma as despesas do período [inicio, fim) por categoria, da maior para a menor. A despesa tem só o
+// dia, então o período vale pelos dias do calendário do negócio.
+func Despesas(db *gorm.DB, inicio, fim time.Time) ([]DespesaCategoria, error) {
+	var despesas []DespesaCategoria
+	if err := db.Table("Despesa d").
+		Select("d.categoriaId as categoriaId, COALESCE(cd.nome, '') as categoria, SUM(d.valor) as valor").
+		Joins("LEFT JOIN CategoriaDespesa cd ON cd.id = d.categoriaId").
+		Where("d.data >= ? AND d.data < ? AND d.deletedAt IS NULL", timezone.Data(inicio), timezone.Data(fim)).
+		Group("d.categoriaId, cd.nome").
+		Order("valor DESC").
+		Scan(&despesas).Error; err != nil {
+		return nil, err
+	}
+	return despesas, nil
+}
+
+// despesasPorMes soma as despesas do período [inicio, fim) por mês (YYYY-MM)
+func despesasPorMes(db *gorm.DB, inicio, fim time.Time) (map[string]money.Dinheiro, error) {
+	var linhas []struct {
+		Mes   string         `gorm:"column:mes"`
+		Valor money.Dinheiro `gorm:"column:valor"`
+	}
+	if err := db.Model(&models.Despesa{}).
+		Select("DATE_FORMAT(data, '%Y-%m') as mes, SUM(valor) as valor").
+		Where("data >= ? AND data < ?", timezone.Data(inicio), timezone.Data(fim)).
+		Group("mes").
+		Scan(&linhas).Error; err != nil {
+		return nil, err
+	}
+	porMes := make(map[string]money.Dinheiro, len(linhas))
+	for _, l := range linhas {
+		porMes[l.Mes] = l.Valor
+	}
+	return porMes, nil
+}
+
+// fechar calcula os subtotais da linha; despesas nil deixa o resultado em aberto
+func fechar(l *Linha, despesas *money.Dinheiro) {
+	l.ReceitaLiquida = l.ReceitaBruta - l.Devolucoes
+	l.LucroBruto = l.ReceitaLiquida - l.CMV
+	if l.ReceitaLiquida != 0 {
+		l.MargemBruta = math.Round(float64(l.LucroBruto)/float64(l.ReceitaLiquida)*10000) / 100
+	}
+	if despesas != nil {
+		d := *despesas
+		resultado := l.LucroBruto - d
+		l.DespesasOperacionais = &d
+		l.ResultadoLiquido = &resultado
+	}
+}
+
+// DRE é o relatório de um período
+type DRE struct {
+	Total    Linha              `json:"total"`
+	Despesas []DespesaCategoria `json:"despesas"` // Despesas operacionais por categoria
+}
+
+// Calcular monta a DRE do período [inicio, fim)
+func Calcular(db *gorm.DB, inicio, fim time.Time) (DRE, error) {
+	linhas, err := vendas(db, "", inicio, fim)
+	if err != nil {
+		return DRE{}, err
+	}
+	despesas, err := Despesas(db, inicio, fim)
+	if err != nil {
+		return DRE{}, err
+	}
+
+	var total Linha
+	if len(linhas) > 0 {
+		total = linhas[0]
+	}
+	total.Chave = ""
+	var totalDespesas money.Dinheiro
+	for _, d := range despesas {
+		totalDespesas += d.Valor
+	}
+	fechar(&total, &totalDespesas)
+	if despesas == nil {
+		despesas = []DespesaCategoria{}
+	}
+	return DRE{Total: total, Despesas: despesas}, nil
+}
+
+// Agrupar monta a DRE do período [inicio, fim) por mês, loja ou vendedor. Só a DRE por mês chega ao resultado
+// líquido; por loja e vendedor, vai até o lucro bruto. Os nomes de lojas e vendedores ficam com quem chama.
+func Agrupar(db *gorm.DB, agrupar string, inicio, fim time.Time) ([]Linha, error) {
+	linhas, err := vendas(db, agrupar, inicio, fim)
+	if err != nil {
+		return nil, err
+	}
+	if agrupar != PorMes {
+		for i := range linhas {
+			fechar(&linhas[i], nil)
+		}
+		return linhas, nil
+	}
+
+	// Meses só com despesas também aparecem
+	porMes, err := despesasPorMes(db, inicio, fim)
+	if err != nil {
+		return nil, err
+	}
+	meses := make([]Linha, 0, len(linhas)+len(porMes))
+	vistos := make(map[string]bool, len(linhas))
+	for _, l := range linhas {
+		vistos[l.Chave] = true
+		meses = append(meses, l)
+	}
+	for mes := range porMes {
+		if !vistos[mes] {
+			meses = append(meses, Linha{Chave: mes})
+		}
+	}
+	for i := range meses {
+		despesas := porMes[meses[i].Chave]
+		fechar(&meses[i], &despesas)
+	}
+	sort.Slice(meses, func(i, j int) bool { return meses[i].Chave < meses[j].Chave })
+	return meses, nil
+}
@@ -0,0 +1,60 @@
+// Package quotes controla a validade dos orçamentos e libera as reservas dos que expiraram.
+package quotes
+
+import (
+	"time"
+
+	"cmdimport/backend/models"
+	"cmdimport/backend/reservations"
+	"cmdimport/backend/timezone"
+
+	"gorm.io/gorm"
+)
+
+// Situações de um orçamento
+const (
+	StatusAberto     = "aberto"
+	StatusConvertido = "convertido"
+	StatusExpirado   = "expirado"
+	StatusCancelado  = "cancelado"
+)
+
+// ValidadePadrao é a validade do orçamento quando o vendedor não informa
+const ValidadePadrao = 7
+
+// ValidoAte retorna o fim do último dia de validade, contando a partir de agora, no fuso do negócio
+func ValidoAte(agora time.Time, dias int) time.Time {
+	if dias <= 0 {
+		dias = ValidadePadrao
+	}
+	return timezone.InicioDoDia(timezone.InicioDoDia(agora).AddDate(0, 0, dias+1)).Add(-time.Second)
+}
+
+// Expirar marca como expirados os orçamentos abertos vencidos e encerra suas reservas.
+// Retorna quantos orçamentos expiraram.
+func Expirar(db *gorm.DB, agora time.Time) (int, error) {
+	var ids []int
+	if err := db.Model(&models.Orcamento{}).
+		Where("status = ? AND validoAte <= ?", StatusAberto, agora).
+		Pluck("id", &ids).Error; err != nil {
+		return 0, err
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Orcamento{}).
+			Where("id IN ? AND status = ?", ids, StatusAberto).
+			Update("status", StatusExpirado).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.ReservaEstoque{}).
+			Where("orcamentoId IN ? AND status = ?", ids, reservations.StatusAtiva).
+			Updates(map[string]interface{}{"status": reservations.StatusExpirada, "encerradaEm": agora}).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(ids), nil
+}
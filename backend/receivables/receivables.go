@@ -0,0 +1,226 @@
+// Package receivables gera as parcelas a receber das vendas: repasses da adquirente no cartão (pelas regras
+// de liquidação e com a taxa da maquininha), parcelas do crediário e pix ou boleto combinados para depois.
+package receivables
+
+import (
+	"strings"
+	"time"
+
+	"cmdimport/backend/models"
+	"cmdimport/backend/money"
+	"cmdimport/backend/payments"
+	"cmdimport/backend/pricing"
+	"cmdimport/backend/timezone"
+
+	"gorm.io/gorm"
+)
+
+// Situações de um recebível
+const (
+	StatusPendente  = "pendente"
+	StatusLiquidado = "liquidado"
+	StatusCancelado = "cancelado" // A venda ou a linha de pagamento foi deletada ou alterada
+)
+
+// Prazos usados quando não há regra de liquidação cadastrada
+const (
+	PrazoDebitoPadrao  = 1  // D+1
+	PrazoCreditoPadrao = 30 // D+30 por parcela
+	IntervaloPadrao    = 30
+	IntervaloCrediario = 30 // Parcelas mensais do crediário, a primeira 30 dias após a venda
+)
+
+// dia retorna o dia do calendário de uma data de coluna date à 00:00 UTC, como a coluna guarda
+func dia(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// Regras são as regras de liquidação cadastradas
+type Regras []models.RegraLiquidacao
+
+// CarregarRegras lê as regras de liquidação
+func CarregarRegras(db *gorm.DB) (Regras, error) {
+	var regras []models.RegraLiquidacao
+	if err := db.Find(&regras).Error; err != nil {
+		return nil, err
+	}
+	return regras, nil
+}
+
+// Regra escolhe a regra do método e da bandeira; sem regra da bandeira, a do método sem bandeira;
+// sem nenhuma, o prazo padrão. A bandeira é comparada sem diferenciar maiúsculas.
+func (r Regras) Regra(metodo string, bandeira *string) models.RegraLiquidacao {
+	var geral *models.RegraLiquidacao
+	for i := range r {
+		if r[i].Metodo != metodo {
+			continue
+		}
+		if r[i].Bandeira == nil {
+			geral = &r[i]
+		} else if bandeira != nil && strings.EqualFold(*r[i].Bandeira, *bandeira) {
+			return r[i]
+		}
+	}
+	if geral != nil {
+		return *geral
+	}
+	prazo := PrazoCreditoPadrao
+	if metodo == payments.MetodoDebito {
+		prazo = PrazoDebitoPadrao
+	}
+	return models.RegraLiquidacao{Metodo: metodo, PrazoDias: prazo, IntervaloDias: IntervaloPadrao}
+}
+
+// GeraRecebiveis indica se o método de pagamento gera parcelas a receber. Dinheiro, troca e sinal
+// já foram recebidos; pix só quando combinado para depois.
+func GeraRecebiveis(p models.PagamentoVenda) bool {
+	switch p.Metodo {
+	case payments.MetodoDebito, payments.MetodoCredito, payments.MetodoCrediario, payments.MetodoBoleto:
+		return true
+	case payments.MetodoPix:
+		return p.Vencimento != nil
+	}
+	return false
+}
+
+// Gerar monta as parcelas a receber de uma linha de pagamento (sem gravar). A diferença de centavos
+// da divisão fica na primeira parcela.
+func Gerar(p models.PagamentoVenda, venda models.HistoricoVenda, regras Regras, taxas pricing.Taxas) []models.Recebivel {
+	if !GeraRecebiveis(p) {
+		return nil
+	}
+
+	parcelas := p.Parcelas
+	if parcelas < 1 {
+		parcelas = 1
+	}
+	dataVenda := timezone.Data(venda.CreatedAt)
+	percentual := 0.0
+	if p.Metodo == payments.MetodoDebito || p.Metodo == payments.MetodoCredito {
+		percentual = taxas.Percentual(p.Metodo, parcelas)
+	}
+
+	base := p.Valor / money.Dinheiro(parcelas)
+	recebiveis := make([]models.Recebivel, parcelas)
+	for i := range recebiveis {
+		bruto := base
+		if i == 0 {
+			bruto += p.Valor - base.Vezes(parcelas)
+		}
+
+		var vencimento time.Time
+		switch p.Metodo {
+		case payments.MetodoDebito, payments.MetodoCredito:
+			regra := regras.Regra(p.Metodo, p.Bandeira)
+			vencimento = dataVenda.AddDate(0, 0, regra.PrazoDias)
+			if !regra.Antecipado {
+				intervalo := regra.IntervaloDias
+				if intervalo <= 0 {
+					intervalo = IntervaloPadrao
+				}
+				vencimento = vencimento.AddDate(0, 0, i*intervalo)
+			}
+		case payments.MetodoCrediario:
+			vencimento = dataVenda.AddDate(0, 0, (i+1)*IntervaloCrediario)
+		default:
+			vencimento = dia(*p.Vencimento)
+		}
+
+		taxa := bruto.Percentual(percentual)
+		recebiveis[i] = models.Recebivel{
+			VendaID:          p.VendaID,
+			PagamentoVendaID: p.ID,
+			Metodo:           p.Metodo,
+			Bandeira:         p.Bandeira,
+			ClienteNome:      venda.ClienteNome,
+			TipoCliente:      venda.TipoCliente,
+			Parcela:          i + 1,
+			TotalParcelas:    parcelas,
+			ValorBruto:       bruto,
+			Taxa:             taxa,
+			ValorLiquido:     bruto - taxa,
+			Vencimento:       vencimento,
+			Status:           StatusPendente,
+		}
+	}
+	return recebiveis
+}
+
+// SincronizarVenda mantém os recebíveis da venda de acordo com as linhas de pagamento: gera os das linhas
+// novas, refaz os das linhas cujo valor mudou e cancela os das linhas deletadas. Parcelas já liquidadas
+// não são alteradas; com alguma liquidada, a linha alterada fica para o acerto manual.
+func SincronizarVenda(tx *gorm.DB, vendaID string) error {
+	var pagamentos []models.PagamentoVenda
+	if err := tx.Where("vendaId = ?", vendaID).Order("id ASC").Find(&pagamentos).Error; err != nil {
+		return err
+	}
+	var existentes []models.Recebivel
+	if err := tx.Where("vendaId = ? AND status != ?", vendaID, StatusCancelado).Find(&existentes).Error; err != nil {
+		return err
+	}
+	porPagamento := make(map[int][]models.Recebivel)
+	for _, r := range existentes {
+		porPagamento[r.PagamentoVendaID] = append(porPagamento[r.PagamentoVendaID], r)
+	}
+
+	cancelar := make([]int, 0)
+	ativos := make(map[int]bool, len(pagamentos))
+	for _, p := range pagamentos {
+		ativos[p.ID] = true
+	}
+	for pagamentoID, recebiveis := range porPagamento {
+		if ativos[pagamentoID] {
+			continue
+		}
+		for _, r := range recebiveis {
+			if r.Status == StatusPendente {
+				cancelar = append(cancelar, r.ID)
+			}
+		}
+	}
+
+	var venda models.HistoricoVenda
+	temVenda := tx.Where("vendaId = ?", vendaID).Order("id ASC").Limit(1).Find(&venda).RowsAffected > 0
+	var regras Regras
+	var taxas pricing.Taxas
+	novos := make([]models.Recebivel, 0)
+	for _, p := range pagamentos {
+		atuais := porPagamento[p.ID]
+		var soma money.Dinheiro
+		liquidado := false
+		for _, r := range atuais {
+			soma += r.ValorBruto
+			liquidado = liquidado || r.Status == StatusLiquidado
+		}
+		if len(atuais) > 0 && (soma == p.Valor || liquidado) {
+			continue
+		}
+		for _, r := range atuais {
+			cancelar = append(cancelar, r.ID)
+		}
+		if !temVenda || !GeraRecebiveis(p) {
+			continue
+		}
+		if regras == nil {
+			var err error
+			if regras, err = CarregarRegras(tx); err != nil {
+				return err
+			}
+			if taxas, err = pricing.CarregarTaxas(tx); err != nil {
+				return err
+			}
+		}
+		novos = append(novos, Gerar(p, venda, regras, taxas)...)
+	}
+
+	if len(cancelar) > 0 {
+		if err := tx.Model(&models.Recebivel{}).Where("id IN ?", cancelar).
+			Update("status", StatusCancelado).Error; err != nil {
+			return err
+		}
+	}
+	if len(novos) > 0 {
+		return tx.Create(&novos).Error
+	}
+	return nil
+}
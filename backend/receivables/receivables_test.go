@@ -0,0 +1,155 @@
+package receivables
+
+import (
+	"testing"
+	"time"
+
+	"cmdimport/backend/models"
+	"cmdimport/backend/money"
+	"cmdimport/backend/payments"
+	"cmdimport/backend/pricing"
+)
+
+func texto(s string) *string {
+	return &s
+}
+
+// Venda às 22:30 de 19/10 em São Paulo, já 20/10 em UTC: os prazos contam do dia local
+var venda = models.HistoricoVenda{
+	VendaID:     texto("V1"),
+	ClienteNome: "Maria",
+	CreatedAt:   time.Date(2026, 10, 20, 1, 30, 0, 0, time.UTC),
+}
+
+var taxas = pricing.Taxas{
+	payments.MetodoDebito:  {1: 1.5},
+	payments.MetodoCredito: {1: 3, 3: 4.99},
+}
+
+var regras = Regras{
+	{Metodo: payments.MetodoCredito, PrazoDias: 30, IntervaloDias: 30},
+	{Metodo: payments.MetodoCredito, Bandeira: texto("VISA"), PrazoDias: 2, Antecipado: true},
+	{Metodo: payments.MetodoCredito, Bandeira: texto("elo"), PrazoDias: 15},
+}
+
+func data(ano int, mes time.Month, dia int) time.Time {
+	return time.Date(ano, mes, dia, 0, 0, 0, 0, time.UTC)
+}
+
+type parcela struct {
+	bruto, taxa money.Dinheiro
+	vencimento  time.Time
+}
+
+func conferir(t *testing.T, nome string, p models.PagamentoVenda, recebiveis []models.Recebivel, esperadas []parcela) {
+	t.Helper()
+	if len(recebiveis) != len(esperadas) {
+		t.Errorf("%s: %d parcelas, esperado %d", nome, len(recebiveis), len(esperadas))
+		return
+	}
+	var soma money.Dinheiro
+	for i, e := range esperadas {
+		r := recebiveis[i]
+		soma += r.ValorBruto
+		if r.Parcela != i+1 || r.TotalParcelas != len(esperadas) || r.Status != StatusPendente ||
+			r.VendaID != p.VendaID || r.PagamentoVendaID != p.ID || r.Metodo != p.Metodo || r.ClienteNome != venda.ClienteNome {
+			t.Errorf("%s: parcela %d com dados errados: %+v", nome, i+1, r)
+		}
+		if r.ValorBruto != e.bruto || r.Taxa != e.taxa || r.ValorLiquido != e.bruto-e.taxa || !r.Vencimento.Equal(e.vencimento) {
+			t.Errorf("%s: parcela %d = %v - %v = %v em %s; esperado %v - %v em %s", nome, i+1,
+				r.ValorBruto, r.Taxa, r.ValorLiquido, r.Vencimento.Format("2006-01-02"), e.bruto, e.taxa, e.vencimento.Format("2006-01-02"))
+		}
+	}
+	if soma != p.Valor {
+		t.Errorf("%s: parcelas somam %v, esperado %v", nome, soma, p.Valor)
+	}
+}
+
+func TestGerarCartao(t *testing.T) {
+	casos := []struct {
+		nome      string
+		pagamento models.PagamentoVenda
+		esperadas []parcela
+	}{
+		{
+			"crédito 3x pela regra do método",
+			models.PagamentoVenda{ID: 1, VendaID: "V1", Metodo: payments.MetodoCredito, Valor: money.Reais(100), Parcelas: 3},
+			// O centavo da divisão fica na primeira parcela
+			[]parcela{
+				{3334, 166, data(2026, 11, 18)},
+				{3333, 166, data(2026, 12, 18)},
+				{3333, 166, data(2027, 1, 17)},
+			},
+		},
+		{
+			"crédito 3x antecipado pela regra da bandeira",
+			models.PagamentoVenda{ID: 2, VendaID: "V1", Metodo: payments.MetodoCredito, Valor: money.Reais(100), Parcelas: 3, Bandeira: texto("Visa")},
+			[]parcela{
+				{3334, 166, data(2026, 10, 21)},
+				{3333, 166, data(2026, 10, 21)},
+				{3333, 166, data(2026, 10, 21)},
+			},
+		},
+		{
+			"bandeira sem intervalo usa o padrão",
+			models.PagamentoVenda{ID: 3, VendaID: "V1", Metodo: payments.MetodoCredito, Valor: money.Reais(50), Parcelas: 2, Bandeira: texto("ELO")},
+			[]parcela{
+				{2500, 0, data(2026, 11, 3)},
+				{2500, 0, data(2026, 12, 3)},
+			},
+		},
+		{
+			"débito sem regra cadastrada",
+			models.PagamentoVenda{ID: 4, VendaID: "V1", Metodo: payments.MetodoDebito, Valor: money.Reais(199.90)},
+			[]parcela{{19990, 300, data(2026, 10, 20)}},
+		},
+	}
+	for _, c := range casos {
+		conferir(t, c.nome, c.pagamento, Gerar(c.pagamento, venda, regras, taxas), c.esperadas)
+	}
+
+	// Sem regra nenhuma para o método, o crédito cai em D+30 por parcela
+	p := models.PagamentoVenda{ID: 5, VendaID: "V1", Metodo: payments.MetodoCredito, Valor: money.Reais(30), Parcelas: 1}
+	conferir(t, "crédito sem regra", p, Gerar(p, venda, nil, taxas), []parcela{{3000, 90, data(2026, 11, 18)}})
+}
+
+func TestGerarCrediarioEPrazo(t *testing.T) {
+	p := models.PagamentoVenda{ID: 6, VendaID: "V1", Metodo: payments.MetodoCrediario, Valor: money.Reais(1000), Parcelas: 3}
+	conferir(t, "crediário 3x", p, Gerar(p, venda, regras, taxas), []parcela{
+		{33334, 0, data(2026, 11, 18)},
+		{33333, 0, data(2026, 12, 18)},
+		{33333, 0, data(2027, 1, 17)},
+	})
+
+	vencimento := time.Date(2026, 11, 5, 0, 0, 0, 0, time.UTC)
+	for _, metodo := range []string{payments.MetodoPix, payments.MetodoBoleto} {
+		p := models.PagamentoVenda{ID: 7, VendaID: "V1", Metodo: metodo, Valor: money.Reais(80), Vencimento: &vencimento}
+		conferir(t, metodo+" a prazo", p, Gerar(p, venda, regras, taxas), []parcela{{8000, 0, data(2026, 11, 5)}})
+	}
+}
+
+func TestGerarSemRecebiveis(t *testing.T) {
+	for _, p := range []models.PagamentoVenda{
+		{Metodo: payments.MetodoDinheiro, Valor: 1000},
+		{Metodo: payments.MetodoPix, Valor: 1000},
+		{Metodo: payments.MetodoTroca, Valor: 1000},
+		{Metodo: payments.MetodoSinal, Valor: 1000},
+		{Metodo: payments.MetodoOutro, Valor: 1000},
+	} {
+		if recebiveis := Gerar(p, venda, regras, taxas); recebiveis != nil {
+			t.Errorf("%s gerou %d recebíveis", p.Metodo, len(recebiveis))
+		}
+	}
+}
+
+func TestRegra(t *testing.T) {
+	if r := regras.Regra(payments.MetodoCredito, texto("visa")); r.PrazoDias != 2 {
+		t.Errorf("bandeira sem diferenciar maiúsculas: prazo %d", r.PrazoDias)
+	}
+	if r := regras.Regra(payments.MetodoCredito, texto("master")); r.PrazoDias != 30 {
+		t.Errorf("bandeira sem regra: prazo %d, esperado o do método", r.PrazoDias)
+	}
+	if r := regras.Regra(payments.MetodoDebito, nil); r.PrazoDias != PrazoDebitoPadrao {
+		t.Errorf("débito sem regra: prazo %d", r.PrazoDias)
+	}
+}
@@ -0,0 +1,115 @@
+// Package reservations controla as reservas de estoque: quantidades separadas para um
+// orçamento ou cliente que não podem ser vendidas por outra venda até expirarem.
+package reservations
+
+import (
+	"time"
+
+	"cmdimport/backend/models"
+
+	"gorm.io/gorm"
+)
+
+// Situações de uma reserva
+const (
+	StatusAtiva      = "ativa"
+	StatusConvertida = "convertida" // Virou venda
+	StatusLiberada   = "liberada"   // Cancelada antes de expirar
+	StatusExpirada   = "expirada"
+)
+
+// Destinos do sinal quando a reserva é encerrada
+const (
+	SinalAbatido   = "abatido"   // Usado como pagamento na venda
+	SinalDevolvido = "devolvido" // Devolvido ao cliente
+	SinalRetido    = "retido"    // Ficou com a loja: reserva expirada ou cancelada sem devolução
+)
+
+// HorasPadrao é a duração da reserva quando o vendedor não informa
+const HorasPadrao = 48
+
+// Ignorar indica reservas que não contam como indisponíveis: as do orçamento ou a reserva
+// que está sendo convertida em venda (0 = nenhuma)
+type Ignorar struct {
+	OrcamentoID int
+	ReservaID   int
+}
+
+// Reservado soma, por estoque, a quantidade em reservas ativas e não vencidas
+func Reservado(db *gorm.DB, estoqueIDs []int, ignorar Ignorar, agora time.Time) (map[int]int, error) {
+	reservado := make(map[int]int)
+	if len(estoqueIDs) == 0 {
+		return reservado, nil
+	}
+
+	var linhas []struct {
+		EstoqueID  int `gorm:"column:estoqueId"`
+		Quantidade int `gorm:"column:quantidade"`
+	}
+	query := db.Model(&models.ReservaEstoque{}).
+		Select("estoqueId, SUM(quantidade) as quantidade").
+		Where("estoqueId IN ? AND status = ? AND expiraEm > ?", estoqueIDs, StatusAtiva, agora)
+	if ignorar.OrcamentoID > 0 {
+		query = query.Where("(orcamentoId IS NULL OR orcamentoId != ?)", ignorar.OrcamentoID)
+	}
+	if ignorar.ReservaID > 0 {
+		query = query.Where("id != ?", ignorar.ReservaID)
+	}
+	if err := query.Group("estoqueId").Scan(&linhas).Error; err != nil {
+		return nil, err
+	}
+	for _, l := range linhas {
+		reservado[l.EstoqueID] = l.Quantidade
+	}
+	return reservado, nil
+}
+
+// Ativa indica se a reserva ainda segura o estoque
+func Ativa(r models.ReservaEstoque, agora time.Time) bool {
+	return r.Status == StatusAtiva && agora.Before(r.ExpiraEm)
+}
+
+// Encerrar muda a situação das reservas ativas do orçamento (convertida ou liberada)
+func Encerrar(tx *gorm.DB, orcamentoID int, status string) error {
+	return tx.Model(&models.ReservaEstoque{}).
+		Where("orcamentoId = ? AND status = ?", orcamentoID, StatusAtiva).
+		Updates(map[string]interface{}{"status": status, "encerradaEm": time.Now()}).Error
+}
+
+// Expirar marca como expiradas as reservas ativas vencidas, devolvendo as quantidades à venda.
+// O sinal do cliente que não voltou fica retido; a devolução, se combinada, é lançada depois.
+// Retorna quantas reservas expiraram.
+func Expirar(db *gorm.DB, agora time.Time) (int64, error) {
+	result := db.Model(&models.ReservaEstoque{}).
+		Where("status = ? AND expiraEm <= ?", StatusAtiva, agora).
+		Updates(map[string]interface{}{
+			"status":       StatusExpirada,
+			"encerradaEm":  agora,
+			"destinoSinal": gorm.Expr("CASE WHEN valorSinal > 0 THEN ? END", SinalRetido),
+		})
+	return result.RowsAffected, result.Error
+}
+
+// Reabrir devolve a reserva do cliente convertida na venda deletada: se ainda está no prazo, volta a ficar ativa
+// (e o sinal volta a ficar com ela); se venceu, fica expirada com o sinal retido, para ser devolvido se combinado.
+func Reabrir(tx *gorm.DB, vendaID string, agora time.Time) error {
+	convertidas := func() *gorm.DB {
+		return tx.Model(&models.ReservaEstoque{}).
+			Where("vendaId = ? AND status = ? AND orcamentoId IS NULL", vendaID, StatusConvertida)
+	}
+	if err := convertidas().Where("expiraEm > ?", agora).
+		Updates(map[string]interface{}{
+			"status":       StatusAtiva,
+			"vendaId":      nil,
+			"encerradaEm":  nil,
+			"destinoSinal": nil,
+		}).Error; err != nil {
+		return err
+	}
+	return convertidas().Where("expiraEm <= ?", agora).
+		Updates(map[string]interface{}{
+			"status":       StatusExpirada,
+			"encerradaEm":  agora,
+			"destinoSinal": gorm.Expr("CASE WHEN valorSinal > 0 THEN ? END", SinalRetido),
+		}).Error
+}
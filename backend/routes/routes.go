@@ -1,6 +1,8 @@
 package routes
 
 import (
+	"time"
+
 	"cmdimport/backend/config"
 	"cmdimport/backend/middleware"
 	"cmdimport/backend/handlers"
@@ -33,6 +35,21 @@ func SetupRoutes(router *gin.Engine, db *gorm.DB, cfg *config.Config) {
 	expenseHandler := handlers.NewExpenseHandler(db)
 	productCategoryHandler := handlers.NewProductCategoryHandler(db)
 	pricingHandler := handlers.NewPricingHandler(db)
+	exchangeRateHandler := handlers.NewExchangeRateHandler(db, cfg.ExchangeRateURL)
+	exportHandler := handlers.NewExportHandler(db)
+	catalogHandler := handlers.NewCatalogHandler(db)
+	paymentPlanHandler := handlers.NewPaymentPlanHandler(db)
+	discountHandler := handlers.NewDiscountHandler(db)
+	warrantyHandler := handlers.NewWarrantyHandler(db)
+	quoteHandler := handlers.NewQuoteHandler(db)
+	commissionHandler := handlers.NewCommissionHandler(db)
+	storeHandler := handlers.NewStoreHandler(db)
+	goalHandler := handlers.NewGoalHandler(db)
+	cashRegisterHandler := handlers.NewCashRegisterHandler(db)
+	receivableHandler := handlers.NewReceivableHandler(db)
+	profitLossHandler := handlers.NewProfitLossHandler(db)
+	notificationHandler := handlers.NewNotificationHandler(db)
+	trashHandler := handlers.NewTrashHandler(db, cfg.LixeiraRetencaoDias)
 
 	// Rotas públicas
 	api := router.Group("/api")
@@ -43,12 +60,23 @@ func SetupRoutes(router *gin.Engine, db *gorm.DB, cfg *config.Config) {
 			auth.POST("/login", authHandler.Login)
 			auth.POST("/register", authHandler.Register)
 		}
+
+		// Consulta de garantia pelo cliente (IMEI + telefone), com limite de consultas por IP
+		api.POST("/garantia/consulta", middleware.RateLimit(10, 10*time.Minute), warrantyHandler.ConsultaPublica)
 	}
 
 	// Rotas protegidas (requerem autenticação)
 	protected := api.Group("")
 	protected.Use(middleware.AuthMiddleware())
 	{
+		// Notificações do usuário logado
+		notificacoes := protected.Group("/notificacoes")
+		{
+			notificacoes.GET("", notificationHandler.Listar)
+			notificacoes.PUT("/lidas", notificationHandler.MarcarTodasLidas)
+			notificacoes.PUT("/:id/lida", notificationHandler.MarcarLida)
+		}
+
 		// Histórico de Distribuição Global
 		protected.GET("/admin/historico-distribuicao", productHandler.ListarHistoricoDistribuicaoGlobal)
 
@@ -57,6 +85,7 @@ func SetupRoutes(router *gin.Engine, db *gorm.DB, cfg *config.Config) {
 		{
 			produtos.GET("", productHandler.Listar)
 			produtos.POST("/cadastrar", productHandler.Cadastrar)
+			produtos.POST("/importar", productHandler.Importar)
 			produtos.GET("/:id", productHandler.BuscarPorID)
 			produtos.PUT("/:id", productHandler.Atualizar)
 			produtos.PUT("/:id/precificacao", productHandler.AtualizarPrecificacao)
@@ -70,6 +99,10 @@ func SetupRoutes(router *gin.Engine, db *gorm.DB, cfg *config.Config) {
 			estoque.GET("", stockHandler.Listar)
 			estoque.GET("/buscar-por-codigo-barras", stockHandler.BuscarPorCodigoBarras)
 			estoque.GET("/buscar-por-imei", stockHandler.BuscarPorIMEI)
+			estoque.GET("/reservas", stockHandler.ListarReservas)
+			estoque.POST("/reservas", stockHandler.CriarReserva)
+			estoque.PUT("/reservas/:id/liberar", stockHandler.LiberarReserva)
+			estoque.PUT("/reservas/:id/devolver-sinal", stockHandler.DevolverSinal)
 		}
 
 		// Admin - Estoque Usuários
@@ -97,6 +130,41 @@ func SetupRoutes(router *gin.Engine, db *gorm.DB, cfg *config.Config) {
 			vendas.POST("/cadastrar", saleHandler.Cadastrar)
 			vendas.GET("/historico", saleHandler.Historico)
 			vendas.GET("/venda/:id", saleHandler.BuscarPorID)
+			vendas.GET("/cupons/:codigo", discountHandler.ValidarCupom)
+			vendas.POST("/aprovacoes-desconto", discountHandler.SolicitarAprovacao)
+			vendas.GET("/aprovacoes-desconto", discountHandler.MinhasAprovacoes)
+		}
+
+		// Orçamentos
+		orcamentos := protected.Group("/orcamentos")
+		{
+			orcamentos.GET("", quoteHandler.Listar)
+			orcamentos.POST("", quoteHandler.Criar)
+			orcamentos.GET("/:id", quoteHandler.BuscarPorID)
+			orcamentos.PUT("/:id/cancelar", quoteHandler.Cancelar)
+			orcamentos.POST("/:id/converter", quoteHandler.Converter)
+		}
+
+		// Comissões
+		comissoes := protected.Group("/comissoes")
+		{
+			comissoes.GET("/extrato", commissionHandler.MeuExtrato)
+		}
+
+		// Caixa
+		caixa := protected.Group("/caixa")
+		{
+			caixa.POST("/abrir", cashRegisterHandler.Abrir)
+			caixa.GET("/atual", cashRegisterHandler.Atual)
+			caixa.POST("/:id/sangria", cashRegisterHandler.Sangria)
+			caixa.POST("/:id/suprimento", cashRegisterHandler.Suprimento)
+			caixa.POST("/:id/fechar", cashRegisterHandler.Fechar)
+		}
+
+		// Metas
+		metas := protected.Group("/metas")
+		{
+			metas.GET("/progresso", goalHandler.MeuProgresso)
 		}
 
 		// Admin - Histórico
@@ -104,6 +172,9 @@ func SetupRoutes(router *gin.Engine, db *gorm.DB, cfg *config.Config) {
 		{
 			adminHistorico.GET("", saleHandler.HistoricoAdmin)
 			adminHistorico.GET("/resumo-vendedores", saleHandler.ResumoVendedores)
+			adminHistorico.GET("/resumo-pagamentos", saleHandler.ResumoPagamentos)
+			adminHistorico.GET("/resumo-descontos", discountHandler.ResumoDescontos)
+			adminHistorico.GET("/trocas", saleHandler.ListarTrocas)
 		}
 
 		// Admin - Venda
@@ -118,10 +189,75 @@ func SetupRoutes(router *gin.Engine, db *gorm.DB, cfg *config.Config) {
 			adminVenda.PUT("/:id/transferir", saleHandler.TransferirVenda)
 		}
 
+		// Admin - Comissões
+		adminComissoes := protected.Group("/admin/comissoes")
+		{
+			adminComissoes.GET("/regras", commissionHandler.ListarRegras)
+			adminComissoes.POST("/regras", commissionHandler.CriarRegra)
+			adminComissoes.PUT("/regras/:id", commissionHandler.AtualizarRegra)
+			adminComissoes.DELETE("/regras/:id", commissionHandler.DeletarRegra)
+			adminComissoes.GET("/extrato", commissionHandler.Extrato)
+			adminComissoes.GET("/resumo", commissionHandler.Resumo)
+			adminComissoes.POST("/fechar", commissionHandler.Fechar)
+			adminComissoes.POST("/ajustes", commissionHandler.LancarAjuste)
+			adminComissoes.PUT("/:id/pagar", commissionHandler.Pagar)
+			adminComissoes.PUT("/:id/reabrir", commissionHandler.Reabrir)
+		}
+
+		// Admin - Caixas
+		adminCaixas := protected.Group("/admin/caixas")
+		{
+			adminCaixas.GET("", cashRegisterHandler.Listar)
+			adminCaixas.GET("/:id", cashRegisterHandler.Relatorio)
+			adminCaixas.PUT("/:id/aprovar", cashRegisterHandler.Aprovar)
+			adminCaixas.PUT("/:id/reabrir", cashRegisterHandler.Reabrir)
+		}
+
+		// Admin - Recebíveis
+		adminRecebiveis := protected.Group("/admin/recebiveis")
+		{
+			adminRecebiveis.GET("", receivableHandler.Listar)
+			adminRecebiveis.GET("/aging", receivableHandler.Aging)
+			adminRecebiveis.GET("/fluxo", receivableHandler.Fluxo)
+			adminRecebiveis.PUT("/:id/liquidar", receivableHandler.Liquidar)
+			adminRecebiveis.PUT("/:id/reabrir", receivableHandler.Reabrir)
+			adminRecebiveis.GET("/regras", receivableHandler.ListarRegras)
+			adminRecebiveis.POST("/regras", receivableHandler.CriarRegra)
+			adminRecebiveis.PUT("/regras/:id", receivableHandler.AtualizarRegra)
+			adminRecebiveis.DELETE("/regras/:id", receivableHandler.DeletarRegra)
+		}
+
+		// Admin - DRE
+		adminDRE := protected.Group("/admin/dre")
+		{
+			adminDRE.GET("", profitLossHandler.DRE)
+			adminDRE.GET("/devolucoes", profitLossHandler.ListarDevolucoes)
+		}
+
+		// Admin - Lojas
+		adminLojas := protected.Group("/admin/lojas")
+		{
+			adminLojas.GET("", storeHandler.ListarLojas)
+			adminLojas.POST("", storeHandler.CriarLoja)
+			adminLojas.PUT("/:id", storeHandler.AtualizarLoja)
+			adminLojas.DELETE("/:id", storeHandler.DeletarLoja)
+		}
+
+		// Admin - Metas
+		adminMetas := protected.Group("/admin/metas")
+		{
+			adminMetas.GET("", goalHandler.ListarMetas)
+			adminMetas.POST("", goalHandler.CriarMeta)
+			adminMetas.GET("/progresso", goalHandler.Progresso)
+			adminMetas.PUT("/:id", goalHandler.AtualizarMeta)
+			adminMetas.DELETE("/:id", goalHandler.DeletarMeta)
+		}
+
 		// Admin - Usuários
 		adminUsuarios := protected.Group("/admin/usuarios")
 		{
 			adminUsuarios.GET("", authHandler.ListarUsuarios)
+			adminUsuarios.PUT("/:id/loja", storeHandler.DefinirLoja)
 		}
 
 		// Admin - Atendentes
@@ -146,6 +282,37 @@ func SetupRoutes(router *gin.Engine, db *gorm.DB, cfg *config.Config) {
 			adminDespesas.POST("", expenseHandler.CriarDespesa)
 			adminDespesas.PUT("/:id", expenseHandler.AtualizarDespesa)
 			adminDespesas.DELETE("/:id", expenseHandler.DeletarDespesa)
+			adminDespesas.PUT("/:id/pagar", expenseHandler.PagarDespesa)
+			adminDespesas.GET("/proximas", expenseHandler.ProximasDespesas)
+			adminDespesas.GET("/:id/anexos", expenseHandler.ListarAnexos)
+			adminDespesas.POST("/:id/anexos", expenseHandler.EnviarAnexos)
+			adminDespesas.GET("/:id/anexos/:anexoId", expenseHandler.BaixarAnexo)
+			adminDespesas.DELETE("/:id/anexos/:anexoId", expenseHandler.DeletarAnexo)
+		}
+
+		// Admin - Despesas recorrentes
+		adminDespesasRecorrentes := protected.Group("/admin/despesas-recorrentes")
+		{
+			adminDespesasRecorrentes.GET("", expenseHandler.ListarDespesasRecorrentes)
+			adminDespesasRecorrentes.POST("", expenseHandler.CriarDespesaRecorrente)
+			adminDespesasRecorrentes.PUT("/:id", expenseHandler.AtualizarDespesaRecorrente)
+			adminDespesasRecorrentes.DELETE("/:id", expenseHandler.DeletarDespesaRecorrente)
+		}
+
+		// Admin - Orçamentos de despesas
+		adminOrcamentosDespesa := protected.Group("/admin/orcamentos-despesa")
+		{
+			adminOrcamentosDespesa.GET("", expenseHandler.ListarOrcamentosDespesa)
+			adminOrcamentosDespesa.POST("", expenseHandler.SalvarOrcamentoDespesa)
+			adminOrcamentosDespesa.DELETE("/:id", expenseHandler.DeletarOrcamentoDespesa)
+			adminOrcamentosDespesa.GET("/relatorio", expenseHandler.RelatorioOrcamentosDespesa)
+		}
+
+		// Admin - Lixeira
+		adminLixeira := protected.Group("/admin/lixeira")
+		{
+			adminLixeira.GET("", trashHandler.Listar)
+			adminLixeira.POST("/:tipo/:id/restaurar", trashHandler.Restaurar)
 		}
 
 		// Admin - Categorias de Produtos
@@ -163,6 +330,98 @@ func SetupRoutes(router *gin.Engine, db *gorm.DB, cfg *config.Config) {
 			adminPrecificacao.GET("", pricingHandler.ListarCombos)
 			adminPrecificacao.GET("/consultar", pricingHandler.Consultar)
 			adminPrecificacao.POST("", pricingHandler.Atualizar)
+			adminPrecificacao.GET("/agendadas", pricingHandler.Agendadas)
+			adminPrecificacao.GET("/regras", pricingHandler.ListarRegras)
+			adminPrecificacao.POST("/regras", pricingHandler.CriarRegra)
+			adminPrecificacao.PUT("/regras/:id", pricingHandler.AtualizarRegra)
+			adminPrecificacao.DELETE("/regras/:id", pricingHandler.DeletarRegra)
+			adminPrecificacao.GET("/taxas", pricingHandler.ListarTaxas)
+			adminPrecificacao.PUT("/taxas", pricingHandler.SalvarTaxas)
+			adminPrecificacao.GET("/simular", pricingHandler.Simular)
+			adminPrecificacao.POST("/aplicar-regras", pricingHandler.AplicarRegras)
+			adminPrecificacao.DELETE("/versoes/:id", pricingHandler.CancelarVersao)
+			adminPrecificacao.GET("/:id/historico", pricingHandler.Historico)
+			adminPrecificacao.GET("/:id/vigente", pricingHandler.Vigente)
+		}
+
+		// Planos de pagamento aceitos na venda
+		protected.GET("/planos-pagamento", paymentPlanHandler.Listar)
+
+		// Admin - Planos de Pagamento
+		adminPlanos := protected.Group("/admin/planos-pagamento")
+		{
+			adminPlanos.GET("", paymentPlanHandler.Listar)
+			adminPlanos.POST("", paymentPlanHandler.Criar)
+			adminPlanos.PUT("/:id", paymentPlanHandler.Atualizar)
+			adminPlanos.DELETE("/:id", paymentPlanHandler.Deletar)
+		}
+
+		// Garantias
+		garantias := protected.Group("/garantias")
+		{
+			garantias.GET("/imei/:imei", warrantyHandler.ConsultarIMEI)
+			garantias.POST("/chamados", warrantyHandler.AbrirChamado)
+		}
+
+		// Admin - Garantias
+		adminGarantias := protected.Group("/admin/garantias")
+		{
+			adminGarantias.GET("/termos", warrantyHandler.ListarTermos)
+			adminGarantias.POST("/termos", warrantyHandler.CriarTermo)
+			adminGarantias.PUT("/termos/:id", warrantyHandler.AtualizarTermo)
+			adminGarantias.DELETE("/termos/:id", warrantyHandler.DeletarTermo)
+			adminGarantias.GET("/chamados", warrantyHandler.ListarChamados)
+			adminGarantias.PUT("/chamados/:id", warrantyHandler.AtualizarChamado)
+		}
+
+		// Admin - Cupons
+		adminCupons := protected.Group("/admin/cupons")
+		{
+			adminCupons.GET("", discountHandler.ListarCupons)
+			adminCupons.POST("", discountHandler.CriarCupom)
+			adminCupons.PUT("/:id", discountHandler.AtualizarCupom)
+			adminCupons.DELETE("/:id", discountHandler.DeletarCupom)
+		}
+
+		// Admin - Limites e aprovações de desconto
+		protected.GET("/admin/limites-desconto", discountHandler.ListarLimites)
+		protected.PUT("/admin/limites-desconto", discountHandler.SalvarLimites)
+		adminAprovacoes := protected.Group("/admin/aprovacoes-desconto")
+		{
+			adminAprovacoes.GET("", discountHandler.ListarAprovacoes)
+			adminAprovacoes.PUT("/:id/aprovar", discountHandler.AprovarDesconto)
+			adminAprovacoes.PUT("/:id/recusar", discountHandler.RecusarDesconto)
+		}
+
+		// Admin - Cotações do Dólar
+		adminCotacoes := protected.Group("/admin/cotacoes")
+		{
+			adminCotacoes.GET("", exchangeRateHandler.Listar)
+			adminCotacoes.POST("", exchangeRateHandler.Salvar)
+			adminCotacoes.GET("/vigente", exchangeRateHandler.Vigente)
+			adminCotacoes.POST("/importar", exchangeRateHandler.Importar)
+			adminCotacoes.GET("/exposicao", exchangeRateHandler.Exposicao)
+			adminCotacoes.DELETE("/:id", exchangeRateHandler.Deletar)
+		}
+
+		// Admin - Catálogo de SKUs
+		adminCatalogo := protected.Group("/admin/catalogo")
+		{
+			adminCatalogo.GET("", catalogHandler.Listar)
+			adminCatalogo.POST("", catalogHandler.Criar)
+			adminCatalogo.GET("/sugestoes", catalogHandler.Sugestoes)
+			adminCatalogo.POST("/aplicar", catalogHandler.Aplicar)
+			adminCatalogo.PUT("/:id", catalogHandler.Atualizar)
+			adminCatalogo.DELETE("/:id", catalogHandler.Deletar)
+		}
+
+		// Admin - Exportação (CSV/XLSX)
+		adminExportar := protected.Group("/admin/exportar")
+		{
+			adminExportar.GET("/produtos", exportHandler.Produtos)
+			adminExportar.GET("/estoque", exportHandler.Estoque)
+			adminExportar.GET("/vendas", exportHandler.Vendas)
+			adminExportar.GET("/despesas", exportHandler.Despesas)
 		}
 	}
 
@@ -0,0 +1,126 @@
+// Package spreadsheet lê e escreve planilhas CSV e XLSX usadas em importações e exportações.
+package spreadsheet
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// Formatos suportados
+const (
+	FormatoCSV  = "csv"
+	FormatoXLSX = "xlsx"
+)
+
+// FormatoPorNome identifica o formato pela extensão do arquivo
+func FormatoPorNome(nomeArquivo string) (string, error) {
+	switch strings.ToLower(filepath.Ext(nomeArquivo)) {
+	case ".csv", ".txt":
+		return FormatoCSV, nil
+	case ".xlsx":
+		return FormatoXLSX, nil
+	default:
+		return "", fmt.Errorf("formato de arquivo não suportado: use CSV ou XLSX")
+	}
+}
+
+// Ler retorna todas as linhas da planilha (primeira aba no caso de XLSX).
+// Valores de células XLSX são lidos sem a formatação de número aplicada.
+func Ler(nomeArquivo string, r io.Reader) ([][]string, error) {
+	formato, err := FormatoPorNome(nomeArquivo)
+	if err != nil {
+		return nil, err
+	}
+
+	if formato == FormatoXLSX {
+		return lerXLSX(r)
+	}
+	return LerCSV(r)
+}
+
+// LerCSV lê um CSV separado por ";" ou ",", ignorando o BOM gravado pelo Excel
+func LerCSV(r io.Reader) ([][]string, error) {
+	conteudo, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler arquivo: %w", err)
+	}
+
+	texto := strings.TrimPrefix(string(conteudo), "\ufeff") // BOM do Excel
+	leitor := csv.NewReader(strings.NewReader(texto))
+	leitor.Comma = detectarSeparador(texto)
+	leitor.FieldsPerRecord = -1
+	leitor.TrimLeadingSpace = true
+
+	linhas, err := leitor.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("CSV inválido: %w", err)
+	}
+	return linhas, nil
+}
+
+func lerXLSX(r io.Reader) ([][]string, error) {
+	arquivo, err := excelize.OpenReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("XLSX inválido: %w", err)
+	}
+	defer arquivo.Close()
+
+	abas := arquivo.GetSheetList()
+	if len(abas) == 0 {
+		return nil, fmt.Errorf("planilha sem abas")
+	}
+
+	linhas, err := arquivo.GetRows(abas[0], excelize.Options{RawCellValue: true})
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler planilha: %w", err)
+	}
+	return linhas, nil
+}
+
+// detectarSeparador usa ";" (padrão do Excel em português) quando a primeira linha contém ponto e vírgula
+func detectarSeparador(texto string) rune {
+	primeiraLinha := texto
+	if idx := strings.IndexByte(texto, '\n'); idx >= 0 {
+		primeiraLinha = texto[:idx]
+	}
+	if strings.Contains(primeiraLinha, ";") {
+		return ';'
+	}
+	return ','
+}
+
+// NormalizarCabecalho deixa o nome da coluna em minúsculas, sem acentos, espaços, "_" ou "-",
+// para que "Código de Barras", "codigo_barras" e "codigoBarras" sejam equivalentes
+func NormalizarCabecalho(s string) string {
+	substituicoes := strings.NewReplacer(
+		"á", "a", "à", "a", "â", "a", "ã", "a",
+		"é", "e", "ê", "e",
+		"í", "i",
+		"ó", "o", "ô", "o", "õ", "o",
+		"ú", "u",
+		"ç", "c",
+		" ", "", "_", "", "-", "", ".", "", "(", "", ")", "", "$", "",
+	)
+	return substituicoes.Replace(strings.ToLower(strings.TrimSpace(s)))
+}
+
+// DataSerial converte o número de série de data do Excel (ex.: "45292") em time.Time.
+// Retorna false quando o valor não é numérico.
+func DataSerial(valor string) (time.Time, bool) {
+	serial, err := strconv.ParseFloat(strings.TrimSpace(valor), 64)
+	if err != nil || serial <= 0 {
+		return time.Time{}, false
+	}
+	data, err := excelize.ExcelDateToTime(serial, false)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return data, true
+}
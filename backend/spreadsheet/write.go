@@ -0,0 +1,269 @@
+package spreadsheet
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"cmdimport/backend/money"
+	"cmdimport/backend/timezone"
+	"cmdimport/backend/utils"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// Taxa é um número exibido com 4 casas decimais (ex.: taxa do dólar).
+// Valores float64 comuns são exibidos com 2 casas.
+type Taxa float64
+
+// Escritor grava uma planilha linha a linha, sem manter todas as linhas em memória
+type Escritor interface {
+	Cabecalho(colunas []string) error
+	Linha(valores []interface{}) error
+	// Fechar conclui o arquivo e grava o restante no destino
+	Fechar() error
+}
+
+// ContentType retorna o tipo MIME do formato
+func ContentType(formato string) string {
+	if formato == FormatoXLSX {
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// NovoEscritor cria um escritor CSV (separado por ";" com números no formato brasileiro)
+// ou XLSX (células numéricas com formato #.##0,00)
+func NovoEscritor(formato string, w io.Writer) (Escritor, error) {
+	switch formato {
+	case FormatoCSV:
+		return novoEscritorCSV(w)
+	case FormatoXLSX:
+		return novoEscritorXLSX(w)
+	default:
+		return nil, fmt.Errorf("formato não suportado: %s", formato)
+	}
+}
+
+type escritorCSV struct {
+	buf *bufio.Writer
+	csv *csv.Writer
+}
+
+func novoEscritorCSV(w io.Writer) (*escritorCSV, error) {
+	buf := bufio.NewWriter(w)
+	// BOM para o Excel reconhecer UTF-8
+	if _, err := buf.WriteString("\ufeff"); err != nil {
+		return nil, err
+	}
+	writer := csv.NewWriter(buf)
+	writer.Comma = ';'
+	return &escritorCSV{buf: buf, csv: writer}, nil
+}
+
+func (e *escritorCSV) Cabecalho(colunas []string) error {
+	return e.csv.Write(colunas)
+}
+
+func (e *escritorCSV) Linha(valores []interface{}) error {
+	registro := make([]string, len(valores))
+	for i, v := range valores {
+		registro[i] = formatarCSV(v)
+	}
+	return e.csv.Write(registro)
+}
+
+func (e *escritorCSV) Fechar() error {
+	e.csv.Flush()
+	if err := e.csv.Error(); err != nil {
+		return err
+	}
+	return e.buf.Flush()
+}
+
+func formatarCSV(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case *string:
+		if val == nil {
+			return ""
+		}
+		return *val
+	case int:
+		return strconv.Itoa(val)
+	case *int:
+		if val == nil {
+			return ""
+		}
+		return strconv.Itoa(*val)
+	case float64:
+		return utils.FormatFloatBR(val, 2)
+	case *float64:
+		if val == nil {
+			return ""
+		}
+		return utils.FormatFloatBR(*val, 2)
+	case money.Dinheiro:
+		return val.BR()
+	case *money.Dinheiro:
+		if val == nil {
+			return ""
+		}
+		return val.BR()
+	case Taxa:
+		return utils.FormatFloatBR(float64(val), 4)
+	case bool:
+		if val {
+			return "Sim"
+		}
+		return "Não"
+	case time.Time:
+		if val.IsZero() {
+			return ""
+		}
+		// Colunas date chegam à 00:00 UTC; os demais horários são mostrados no fuso do negócio
+		if h, m, s := val.UTC().Clock(); h != 0 || m != 0 || s != 0 {
+			val = val.In(timezone.Local())
+		}
+		if val.Hour() == 0 && val.Minute() == 0 && val.Second() == 0 {
+			return val.Format("02/01/2006")
+		}
+		return val.Format("02/01/2006 15:04")
+	default:
+		return fmt.Sprint(val)
+	}
+}
+
+type escritorXLSX struct {
+	destino     io.Writer
+	arquivo     *excelize.File
+	stream      *excelize.StreamWriter
+	linha       int
+	estiloMoeda int
+	estiloTaxa  int
+	estiloData  int
+}
+
+func novoEscritorXLSX(w io.Writer) (*escritorXLSX, error) {
+	arquivo := excelize.NewFile()
+	aba := arquivo.GetSheetName(0)
+
+	stream, err := arquivo.NewStreamWriter(aba)
+	if err != nil {
+		arquivo.Close()
+		return nil, err
+	}
+
+	formatoMoeda := "#,##0.00"
+	formatoTaxa := "#,##0.0000"
+	formatoData := "dd/mm/yyyy hh:mm"
+	estiloMoeda, err := arquivo.NewStyle(&excelize.Style{CustomNumFmt: &formatoMoeda})
+	if err != nil {
+		arquivo.Close()
+		return nil, err
+	}
+	estiloTaxa, err := arquivo.NewStyle(&excelize.Style{CustomNumFmt: &formatoTaxa})
+	if err != nil {
+		arquivo.Close()
+		return nil, err
+	}
+	estiloData, err := arquivo.NewStyle(&excelize.Style{CustomNumFmt: &formatoData})
+	if err != nil {
+		arquivo.Close()
+		return nil, err
+	}
+
+	return &escritorXLSX{
+		destino:     w,
+		arquivo:     arquivo,
+		stream:      stream,
+		linha:       1,
+		estiloMoeda: estiloMoeda,
+		estiloTaxa:  estiloTaxa,
+		estiloData:  estiloData,
+	}, nil
+}
+
+func (e *escritorXLSX) Cabecalho(colunas []string) error {
+	valores := make([]interface{}, len(colunas))
+	for i, coluna := range colunas {
+		valores[i] = coluna
+	}
+	return e.escrever(valores)
+}
+
+func (e *escritorXLSX) Linha(valores []interface{}) error {
+	celulas := make([]interface{}, len(valores))
+	for i, v := range valores {
+		celulas[i] = e.celula(v)
+	}
+	return e.escrever(celulas)
+}
+
+func (e *escritorXLSX) escrever(valores []interface{}) error {
+	endereco, err := excelize.CoordinatesToCellName(1, e.linha)
+	if err != nil {
+		return err
+	}
+	e.linha++
+	return e.stream.SetRow(endereco, valores)
+}
+
+func (e *escritorXLSX) celula(v interface{}) interface{} {
+	switch val := v.(type) {
+	case nil:
+		return nil
+	case *string:
+		if val == nil {
+			return nil
+		}
+		return *val
+	case *int:
+		if val == nil {
+			return nil
+		}
+		return *val
+	case float64:
+		return excelize.Cell{StyleID: e.estiloMoeda, Value: val}
+	case *float64:
+		if val == nil {
+			return nil
+		}
+		return excelize.Cell{StyleID: e.estiloMoeda, Value: *val}
+	case money.Dinheiro:
+		return excelize.Cell{StyleID: e.estiloMoeda, Value: val.Float64()}
+	case *money.Dinheiro:
+		if val == nil {
+			return nil
+		}
+		return excelize.Cell{StyleID: e.estiloMoeda, Value: val.Float64()}
+	case Taxa:
+		return excelize.Cell{StyleID: e.estiloTaxa, Value: float64(val)}
+	case bool:
+		if val {
+			return "Sim"
+		}
+		return "Não"
+	case time.Time:
+		if val.IsZero() {
+			return nil
+		}
+		return excelize.Cell{StyleID: e.estiloData, Value: val}
+	default:
+		return val
+	}
+}
+
+func (e *escritorXLSX) Fechar() error {
+	defer e.arquivo.Close()
+	if err := e.stream.Flush(); err != nil {
+		return err
+	}
+	return e.arquivo.Write(e.destino)
+}
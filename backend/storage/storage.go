@@ -0,0 +1,154 @@
+// Package storage grava os arquivos enviados (fotos de venda, comprovantes de despesa) em disco. O tipo é
+// identificado pelo conteúdo, não pelo nome ou cabeçalho enviados pelo cliente, e a extensão vem do tipo.
+package storage
+
+import (
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Pastas raiz: fotos de venda ficam em public/uploads (servidas como arquivos estáticos); comprovantes de despesa
+// ficam fora da pasta pública e só são baixados com autenticação
+var (
+	RaizPublica  = "public/uploads"
+	RaizArquivos = "arquivos"
+)
+
+// Erros de validação com mensagem para o cliente
+var (
+	ErrTipoNaoPermitido = errors.New("tipo de arquivo não permitido")
+	ErrArquivoGrande    = errors.New("arquivo muito grande")
+	ErrCaminhoInvalido  = errors.New("caminho de arquivo inválido")
+)
+
+// Tipos aceitos: tipo de conteúdo identificado → extensão gravada
+var (
+	Imagens = map[string]string{
+		"image/jpeg": ".jpg",
+		"image/png":  ".png",
+	}
+	Documentos = map[string]string{
+		"application/pdf": ".pdf",
+		"image/jpeg":      ".jpg",
+		"image/png":       ".png",
+	}
+)
+
+// Arquivo é um arquivo gravado
+type Arquivo struct {
+	Caminho      string // Relativo à raiz, com "/" (ex.: "despesas/3f2a....pdf")
+	NomeOriginal string
+	TipoConteudo string
+	Tamanho      int64
+}
+
+// Salvar grava o arquivo enviado em raiz/pasta com um nome único. Recusa tipos fora de tipos e arquivos
+// maiores que tamanhoMaximo; em caso de erro, nada fica gravado.
+func Salvar(raiz, pasta string, cabecalho *multipart.FileHeader, tipos map[string]string, tamanhoMaximo int64) (*Arquivo, error) {
+	if cabecalho.Size > tamanhoMaximo {
+		return nil, ErrArquivoGrande
+	}
+	origem, err := cabecalho.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer origem.Close()
+
+	inicio := make([]byte, 512)
+	n, err := io.ReadFull(origem, inicio)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	inicio = inicio[:n]
+	tipo := http.DetectContentType(inicio)
+	if i := strings.Index(tipo, ";"); i >= 0 {
+		tipo = tipo[:i]
+	}
+	extensao, ok := tipos[tipo]
+	if !ok {
+		return nil, ErrTipoNaoPermitido
+	}
+
+	dir := filepath.Join(raiz, pasta)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	temp, err := os.CreateTemp(dir, ".upload-*")
+	if err != nil {
+		return nil, err
+	}
+	gravado := false
+	defer func() {
+		if !gravado {
+			temp.Close()
+			os.Remove(temp.Name())
+		}
+	}()
+
+	if _, err := temp.Write(inicio); err != nil {
+		return nil, err
+	}
+	// O tamanho informado no formulário pode não ser o real: o limite vale para o que é lido
+	copiado, err := io.Copy(temp, io.LimitReader(origem, tamanhoMaximo-int64(n)+1))
+	if err != nil {
+		return nil, err
+	}
+	tamanho := int64(n) + copiado
+	if tamanho > tamanhoMaximo {
+		return nil, ErrArquivoGrande
+	}
+	if err := temp.Close(); err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(temp.Name(), 0644); err != nil {
+		return nil, err
+	}
+
+	nome := uuid.New().String() + extensao
+	if err := os.Rename(temp.Name(), filepath.Join(dir, nome)); err != nil {
+		return nil, err
+	}
+	gravado = true
+
+	return &Arquivo{
+		Caminho:      filepath.ToSlash(filepath.Join(pasta, nome)),
+		NomeOriginal: filepath.Base(cabecalho.Filename),
+		TipoConteudo: tipo,
+		Tamanho:      tamanho,
+	}, nil
+}
+
+// Caminho resolve o caminho relativo dentro da raiz, recusando caminhos que saiam dela
+func Caminho(raiz, relativo string) (string, error) {
+	base, err := filepath.Abs(raiz)
+	if err != nil {
+		return "", err
+	}
+	completo, err := filepath.Abs(filepath.Join(base, filepath.FromSlash(relativo)))
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasPrefix(completo, base+string(filepath.Separator)) {
+		return "", ErrCaminhoInvalido
+	}
+	return completo, nil
+}
+
+// Remover apaga o arquivo; um arquivo que já não existe não é erro
+func Remover(raiz, relativo string) error {
+	completo, err := Caminho(raiz, relativo)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(completo); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
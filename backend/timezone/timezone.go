@@ -0,0 +1,132 @@
+// Package timezone concentra o fuso horário do negócio (TIMEZONE, padrão America/Sao_Paulo).
+//
+// Os instantes (createdAt, dataCompra, liquidadoEm, ...) são gravados em UTC. As datas que chegam nos filtros
+// (YYYY-MM-DD) valem como dias do calendário do negócio: vão da 00:00 local até a 00:00 local do dia seguinte,
+// o que dá 23 ou 25 horas nos dias de mudança de horário de verão. Colunas date (data de uma despesa,
+// vencimento) guardam só o dia do calendário e são representadas à 00:00 UTC daquele dia.
+package timezone
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	_ "time/tzdata" // O fuso não depende do zoneinfo instalado no servidor
+)
+
+// Padrao é o fuso do negócio quando TIMEZONE não é informado
+const Padrao = "America/Sao_Paulo"
+
+var local = carregar(Padrao)
+
+func carregar(nome string) *time.Location {
+	loc, err := time.LoadLocation(nome)
+	if err != nil {
+		panic(err)
+	}
+	return loc
+}
+
+// Configurar define o fuso do negócio pelo nome IANA (ex.: America/Sao_Paulo)
+func Configurar(nome string) error {
+	if nome == "" || nome == "Local" {
+		return fmt.Errorf("fuso horário inválido: %q", nome)
+	}
+	loc, err := time.LoadLocation(nome)
+	if err != nil {
+		return fmt.Errorf("fuso horário inválido: %q: %w", nome, err)
+	}
+	local = loc
+	return nil
+}
+
+// Local retorna o fuso do negócio
+func Local() *time.Location {
+	return local
+}
+
+// Agora retorna o instante atual no fuso do negócio
+func Agora() time.Time {
+	return time.Now().In(local)
+}
+
+// meiaNoite retorna o primeiro instante do dia no fuso do negócio. Se a mudança para o horário de verão pula a
+// meia-noite (como no Brasil até 2019), o dia começa à 01:00.
+func meiaNoite(ano int, mes time.Month, dia int) time.Time {
+	t := time.Date(ano, mes, dia, 0, 0, 0, 0, local)
+	if d := time.Date(ano, mes, dia, 0, 0, 0, 0, time.UTC); t.Day() != d.Day() {
+		// Sem a meia-noite, o horário pode cair no fim do dia anterior
+		t = time.Date(d.Year(), d.Month(), d.Day(), 1, 0, 0, 0, local)
+	}
+	return t
+}
+
+// InicioDoDia retorna o início do dia de t no fuso do negócio
+func InicioDoDia(t time.Time) time.Time {
+	t = t.In(local)
+	return meiaNoite(t.Year(), t.Month(), t.Day())
+}
+
+// InicioDoMes retorna o início do primeiro dia do mês de t no fuso do negócio
+func InicioDoMes(t time.Time) time.Time {
+	t = t.In(local)
+	return meiaNoite(t.Year(), t.Month(), 1)
+}
+
+// InicioDaData retorna o início, no fuso do negócio, de um dia do calendário representado à 00:00 UTC (como os
+// lidos de colunas date ou de time.Parse("2006-01-02", ...))
+func InicioDaData(d time.Time) time.Time {
+	d = d.UTC()
+	return meiaNoite(d.Year(), d.Month(), d.Day())
+}
+
+// ParseData lê uma data YYYY-MM-DD e retorna o início desse dia no fuso do negócio
+func ParseData(s string) (time.Time, error) {
+	d, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return InicioDaData(d), nil
+}
+
+// ParseDiaSeguinte lê uma data YYYY-MM-DD e retorna o início do dia seguinte no fuso do negócio: o limite
+// exclusivo (< ?) de um filtro "até esta data, inclusive"
+func ParseDiaSeguinte(s string) (time.Time, error) {
+	d, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return meiaNoite(d.Year(), d.Month(), d.Day()+1), nil
+}
+
+// ParseMes lê um mês YYYY-MM e retorna o início do seu primeiro dia no fuso do negócio
+func ParseMes(s string) (time.Time, error) {
+	d, err := time.Parse("2006-01", s)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return meiaNoite(d.Year(), d.Month(), 1), nil
+}
+
+// Data retorna o dia do calendário de t no fuso do negócio, à 00:00 UTC: o valor gravado em colunas date
+func Data(t time.Time) time.Time {
+	t = t.In(local)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// Hoje retorna o dia atual do calendário do negócio, à 00:00 UTC
+func Hoje() time.Time {
+	return Data(time.Now())
+}
+
+// SQL converte uma coluna datetime gravada em UTC para o horário do negócio, para agrupar por dia ou mês no
+// banco (DATE(...), DATE_FORMAT(...)). Sem as tabelas de fuso do MySQL carregadas, CONVERT_TZ pelo nome
+// retorna NULL e vale o deslocamento atual do fuso.
+func SQL(coluna string) string {
+	_, offset := Agora().Zone()
+	sinal := '+'
+	if offset < 0 {
+		sinal, offset = '-', -offset
+	}
+	return fmt.Sprintf("COALESCE(CONVERT_TZ(%s, '+00:00', '%s'), CONVERT_TZ(%s, '+00:00', '%c%02d:%02d'))",
+		coluna, strings.ReplaceAll(local.String(), "'", ""), coluna, sinal, offset/3600, offset%3600/60)
+}
@@ -0,0 +1,167 @@
+package timezone
+
+import (
+	"testing"
+	"time"
+)
+
+// usarFuso troca o fuso do negócio durante o teste
+func usarFuso(t *testing.T, nome string) {
+	t.Helper()
+	anterior := local
+	if err := Configurar(nome); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { local = anterior })
+}
+
+func utc(s string) time.Time {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestConfigurar(t *testing.T) {
+	usarFuso(t, Padrao)
+	for _, nome := range []string{"", "Local", "America/Nao_Existe"} {
+		if err := Configurar(nome); err == nil {
+			t.Errorf("Configurar(%q) aceitou um fuso inválido", nome)
+		}
+	}
+	if Local().String() != Padrao {
+		t.Errorf("fuso mudou após erro: %s", Local())
+	}
+}
+
+// Os dias de um filtro vão da meia-noite local à meia-noite local seguinte, inclusive nas mudanças de horário
+func TestDiasDoFiltro(t *testing.T) {
+	casos := []struct {
+		nome     string
+		fuso     string
+		data     string
+		inicio   string
+		seguinte string
+		horas    float64
+	}{
+		{"dia comum em São Paulo", "America/Sao_Paulo", "2024-06-10",
+			"2024-06-10T03:00:00Z", "2024-06-11T03:00:00Z", 24},
+		// Início do horário de verão de 2018: o relógio pulou de 00:00 para 01:00, o dia não tem meia-noite
+		{"início do horário de verão em São Paulo", "America/Sao_Paulo", "2018-11-04",
+			"2018-11-04T03:00:00Z", "2018-11-05T02:00:00Z", 23},
+		{"véspera do horário de verão em São Paulo", "America/Sao_Paulo", "2018-11-03",
+			"2018-11-03T03:00:00Z", "2018-11-04T03:00:00Z", 24},
+		// Fim do horário de verão de 2019: de 00:00 o relógio voltou para 23:00 do dia 16, que teve 25 horas
+		{"fim do horário de verão em São Paulo", "America/Sao_Paulo", "2019-02-16",
+			"2019-02-16T02:00:00Z", "2019-02-17T03:00:00Z", 25},
+		{"início do horário de verão em Nova York", "America/New_York", "2024-03-10",
+			"2024-03-10T05:00:00Z", "2024-03-11T04:00:00Z", 23},
+		{"fim do horário de verão em Nova York", "America/New_York", "2024-11-03",
+			"2024-11-03T04:00:00Z", "2024-11-04T05:00:00Z", 25},
+		{"fuso a leste de UTC", "Asia/Tokyo", "2024-06-10",
+			"2024-06-09T15:00:00Z", "2024-06-10T15:00:00Z", 24},
+	}
+	for _, c := range casos {
+		t.Run(c.nome, func(t *testing.T) {
+			usarFuso(t, c.fuso)
+			inicio, err := ParseData(c.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			seguinte, err := ParseDiaSeguinte(c.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !inicio.Equal(utc(c.inicio)) {
+				t.Errorf("ParseData(%s) = %s, esperado %s", c.data, inicio.UTC(), c.inicio)
+			}
+			if !seguinte.Equal(utc(c.seguinte)) {
+				t.Errorf("ParseDiaSeguinte(%s) = %s, esperado %s", c.data, seguinte.UTC(), c.seguinte)
+			}
+			if h := seguinte.Sub(inicio).Hours(); h != c.horas {
+				t.Errorf("dia com %v horas, esperado %v", h, c.horas)
+			}
+			if got := inicio.In(Local()).Format("2006-01-02"); got != c.data {
+				t.Errorf("início cai no dia %s", got)
+			}
+			if !InicioDoDia(seguinte.Add(-time.Nanosecond)).Equal(inicio) {
+				t.Errorf("InicioDoDia do último instante do dia = %s", InicioDoDia(seguinte.Add(-time.Nanosecond)).UTC())
+			}
+		})
+	}
+}
+
+// Uma venda perto da meia-noite cai no dia do negócio, não no dia em UTC nem no do servidor
+func TestVendaPertoDaMeiaNoite(t *testing.T) {
+	usarFuso(t, "America/Sao_Paulo")
+	casos := []struct {
+		instante string
+		dia      string
+	}{
+		{"2024-06-11T02:59:59Z", "2024-06-10"}, // 23:59:59 em São Paulo
+		{"2024-06-11T03:00:00Z", "2024-06-11"}, // 00:00
+		{"2018-11-04T02:59:59Z", "2018-11-03"}, // 23:59:59, antes do salto
+		{"2018-11-04T03:00:00Z", "2018-11-04"}, // 01:00 do horário de verão
+		{"2019-02-17T02:30:00Z", "2019-02-16"}, // 23:30 da segunda passagem pelas 23h
+		{"2019-02-17T03:00:00Z", "2019-02-17"},
+	}
+	for _, c := range casos {
+		instante := utc(c.instante)
+		if got := Data(instante).Format("2006-01-02"); got != c.dia {
+			t.Errorf("Data(%s) = %s, esperado %s", c.instante, got, c.dia)
+		}
+		inicio, _ := ParseData(c.dia)
+		fim, _ := ParseDiaSeguinte(c.dia)
+		if instante.Before(inicio) || !instante.Before(fim) {
+			t.Errorf("%s fora do filtro do dia %s [%s, %s)", c.instante, c.dia, inicio.UTC(), fim.UTC())
+		}
+	}
+}
+
+func TestMeses(t *testing.T) {
+	usarFuso(t, "America/Sao_Paulo")
+	inicio, err := ParseMes("2018-11")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !inicio.Equal(utc("2018-11-01T03:00:00Z")) {
+		t.Errorf("ParseMes = %s", inicio.UTC())
+	}
+	// Em fevereiro de 2019 o horário de verão ainda valia no dia 1º
+	if got := InicioDoMes(utc("2019-02-20T12:00:00Z")); !got.Equal(utc("2019-02-01T02:00:00Z")) {
+		t.Errorf("InicioDoMes = %s", got.UTC())
+	}
+	// 31/01 às 22:00 em São Paulo já é fevereiro em UTC
+	if got := InicioDoMes(utc("2024-02-01T01:00:00Z")); !got.Equal(utc("2024-01-01T03:00:00Z")) {
+		t.Errorf("InicioDoMes perto da virada = %s", got.UTC())
+	}
+}
+
+// Datas de colunas date ficam à 00:00 UTC e não mudam de dia na conversão
+func TestDatasDoCalendario(t *testing.T) {
+	usarFuso(t, "America/Sao_Paulo")
+	d := utc("2018-11-04T00:00:00Z")
+	if got := InicioDaData(d); !got.Equal(utc("2018-11-04T03:00:00Z")) {
+		t.Errorf("InicioDaData = %s", got.UTC())
+	}
+	if got := Data(InicioDaData(d)); !got.Equal(d) {
+		t.Errorf("Data(InicioDaData(d)) = %s", got)
+	}
+	if got := Hoje(); got.Location() != time.UTC || got.Hour() != 0 {
+		t.Errorf("Hoje = %s", got)
+	}
+}
+
+func TestSQL(t *testing.T) {
+	usarFuso(t, "UTC")
+	esperado := "COALESCE(CONVERT_TZ(hv.createdAt, '+00:00', 'UTC'), CONVERT_TZ(hv.createdAt, '+00:00', '+00:00'))"
+	if got := SQL("hv.createdAt"); got != esperado {
+		t.Errorf("SQL = %s", got)
+	}
+	usarFuso(t, "Asia/Kolkata")
+	esperado = "COALESCE(CONVERT_TZ(data, '+00:00', 'Asia/Kolkata'), CONVERT_TZ(data, '+00:00', '+05:30'))"
+	if got := SQL("data"); got != esperado {
+		t.Errorf("SQL = %s", got)
+	}
+}
@@ -0,0 +1,199 @@
+// Package tradein recebe aparelhos usados como parte do pagamento de uma venda
+// e os coloca no estoque, na categoria de seminovos, com custo igual ao valor avaliado.
+package tradein
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"cmdimport/backend/exchange"
+	"cmdimport/backend/models"
+
+	"gorm.io/gorm"
+)
+
+// CategoriaSeminovos é a categoria de produto dos aparelhos recebidos em troca
+const CategoriaSeminovos = "Seminovos"
+
+// Condições de um aparelho recebido em troca
+const (
+	CondicaoExcelente = "excelente"
+	CondicaoBom       = "bom"
+	CondicaoRegular   = "regular"
+	CondicaoDefeito   = "defeito"
+)
+
+var condicoes = map[string]bool{
+	CondicaoExcelente: true,
+	CondicaoBom:       true,
+	CondicaoRegular:   true,
+	CondicaoDefeito:   true,
+}
+
+var (
+	// ErrIMEIDuplicado indica que já existe um produto com o IMEI do aparelho
+	ErrIMEIDuplicado = errors.New("já existe um produto com este IMEI")
+	// ErrAparelhoMovimentado indica que o aparelho da troca já foi distribuído ou vendido
+	ErrAparelhoMovimentado = errors.New("o aparelho recebido em troca já foi distribuído ou vendido")
+)
+
+// Aparelho é o aparelho informado na linha de pagamento "troca"
+type Aparelho struct {
+	Modelo      string  `json:"modelo"`
+	IMEI        *string `json:"imei"`
+	Cor         *string `json:"cor"`
+	Condicao    string  `json:"condicao"` // "excelente", "bom", "regular" ou "defeito"
+	Observacoes *string `json:"observacoes"`
+}
+
+// Validar confere modelo e condição e normaliza os campos
+func (a *Aparelho) Validar() error {
+	a.Modelo = strings.TrimSpace(a.Modelo)
+	a.Condicao = strings.ToLower(strings.TrimSpace(a.Condicao))
+	a.IMEI = texto(a.IMEI)
+	a.Cor = texto(a.Cor)
+	a.Observacoes = texto(a.Observacoes)
+	if a.Modelo == "" {
+		return fmt.Errorf("informe o modelo do aparelho")
+	}
+	if !condicoes[a.Condicao] {
+		return fmt.Errorf("condição inválida %q (use excelente, bom, regular ou defeito)", a.Condicao)
+	}
+	return nil
+}
+
+// GarantirCategoria retorna o ID da categoria de seminovos, criando-a se não existir
+func GarantirCategoria(tx *gorm.DB) (int, error) {
+	var categoria models.CategoriaProduto
+	err := tx.Unscoped().Where("nome = ?", CategoriaSeminovos).First(&categoria).Error
+	if err == nil {
+		// Categoria na lixeira volta a ser usada
+		if categoria.DeletedAt.Valid {
+			if err := tx.Unscoped().Model(&categoria).Update("deletedAt", nil).Error; err != nil {
+				return 0, err
+			}
+		}
+		return categoria.ID, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, err
+	}
+	descricao := "Aparelhos usados recebidos em troca"
+	categoria = models.CategoriaProduto{Nome: CategoriaSeminovos, Descricao: &descricao, Ativo: true}
+	if err := tx.Create(&categoria).Error; err != nil {
+		return 0, err
+	}
+	return categoria.ID, nil
+}
+
+// Receber cadastra o aparelho como produto no estoque central (quantidade 1, custo = valor avaliado)
+// e registra a troca ligada à venda e à linha de pagamento. O admin distribui o aparelho depois
+// de revisado, como qualquer outro produto.
+func Receber(tx *gorm.DB, aparelho Aparelho, pagamento models.PagamentoVenda, clienteNome string, vendedor models.Usuario) (*models.AparelhoTroca, error) {
+	if aparelho.IMEI != nil {
+		var count int64
+		if err := tx.Unscoped().Model(&models.ProdutoComprado{}).Where("imei = ?", *aparelho.IMEI).Count(&count).Error; err != nil {
+			return nil, err
+		}
+		if count > 0 {
+			return nil, ErrIMEIDuplicado
+		}
+	}
+
+	categoriaID, err := GarantirCategoria(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	// O custo é em reais; com cotação cadastrada ele também fica registrado em dólar
+	agora := time.Now()
+	taxa := 1.0
+	if cotacao, err := exchange.TaxaVigente(tx, agora); err == nil && cotacao.Taxa > 0 {
+		taxa = cotacao.Taxa
+	}
+
+	descricao := fmt.Sprintf("Recebido em troca na venda %s (condição: %s)", pagamento.VendaID, aparelho.Condicao)
+	if aparelho.Observacoes != nil {
+		descricao += ". " + *aparelho.Observacoes
+	}
+	fornecedor := "Troca - " + clienteNome
+
+	produto := models.ProdutoComprado{
+		Nome:             aparelho.Modelo,
+		Descricao:        &descricao,
+		Cor:              aparelho.Cor,
+		IMEI:             aparelho.IMEI,
+		CustoDolar:       pagamento.Valor.DivFator(taxa),
+		TaxaDolar:        taxa,
+		Preco:            pagamento.Valor,
+		Quantidade:       1,
+		QuantidadeBackup: 1,
+		Fornecedor:       &fornecedor,
+		DataCompra:       agora,
+		CategoriaID:      &categoriaID,
+	}
+	if err := tx.Create(&produto).Error; err != nil {
+		return nil, err
+	}
+
+	troca := models.AparelhoTroca{
+		VendaID:           pagamento.VendaID,
+		PagamentoVendaID:  pagamento.ID,
+		ProdutoCompradoID: produto.ID,
+		Modelo:            aparelho.Modelo,
+		IMEI:              aparelho.IMEI,
+		Cor:               aparelho.Cor,
+		Condicao:          aparelho.Condicao,
+		Observacoes:       aparelho.Observacoes,
+		ValorAvaliado:     pagamento.Valor,
+		ClienteNome:       clienteNome,
+		UsuarioID:         vendedor.ID,
+	}
+	if err := tx.Create(&troca).Error; err != nil {
+		return nil, err
+	}
+	return &troca, nil
+}
+
+// Desfazer remove os aparelhos recebidos na venda e os produtos criados para eles.
+// Falha com ErrAparelhoMovimentado se algum aparelho já saiu do estoque central.
+func Desfazer(tx *gorm.DB, vendaID string) error {
+	var trocas []models.AparelhoTroca
+	if err := tx.Where("vendaId = ?", vendaID).Find(&trocas).Error; err != nil {
+		return err
+	}
+
+	for _, t := range trocas {
+		var produto models.ProdutoComprado
+		err := tx.First(&produto, t.ProdutoCompradoID).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if err == nil {
+			var distribuicoes int64
+			if err := tx.Model(&models.Estoque{}).Where("produtoCompradoId = ?", produto.ID).Count(&distribuicoes).Error; err != nil {
+				return err
+			}
+			if produto.Quantidade < produto.QuantidadeBackup || distribuicoes > 0 {
+				return ErrAparelhoMovimentado
+			}
+			if err := tx.Unscoped().Delete(&produto).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Delete(&t).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func texto(s *string) *string {
+	if s == nil || strings.TrimSpace(*s) == "" {
+		return nil
+	}
+	t := strings.TrimSpace(*s)
+	return &t
+}
@@ -0,0 +1,295 @@
+// Package trash cuida da lixeira: categorias de despesas, despesas, categorias de produtos e produtos deletados
+// ficam marcados com deletedAt e podem ser restaurados até a exclusão definitiva, depois do prazo de retenção.
+// Uma categoria de despesas vai para a lixeira com as suas despesas, e um produto com o seu estoque distribuído:
+// os que foram juntos (mesmo deletedAt) voltam juntos.
+package trash
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"cmdimport/backend/models"
+	"cmdimport/backend/money"
+	"cmdimport/backend/storage"
+
+	"gorm.io/gorm"
+)
+
+// Tipos de item da lixeira
+const (
+	TipoCategoriaDespesa = "categoria-despesa"
+	TipoDespesa          = "despesa"
+	TipoCategoriaProduto = "categoria-produto"
+	TipoProduto          = "produto"
+)
+
+// Tipos lista os tipos na ordem da listagem
+var Tipos = []string{TipoCategoriaDespesa, TipoDespesa, TipoCategoriaProduto, TipoProduto}
+
+// Erros com mensagem para o cliente
+var (
+	ErrTipoInvalido       = errors.New("tipo de item inválido")
+	ErrNaoEncontrado      = errors.New("item não encontrado na lixeira")
+	ErrCategoriaNaLixeira = errors.New("a categoria do item está na lixeira")
+)
+
+// Item é um registro na lixeira. ExcluirEm é quando a exclusão definitiva acontece; nil quando o registro
+// ainda é referenciado (ex.: produto com vendas) e fica na lixeira até deixar de ser.
+type Item struct {
+	Tipo       string          `gorm:"-" json:"tipo"`
+	ID         int             `gorm:"column:id" json:"id"`
+	Nome       string          `gorm:"column:nome" json:"nome"`
+	Valor      *money.Dinheiro `gorm:"column:valor" json:"valor,omitempty"` // Despesa
+	IMEI       *string         `gorm:"column:imei" json:"imei,omitempty"`   // Produto
+	Itens      int64           `gorm:"column:itens" json:"itens"`           // Despesas ou estoques que foram junto
+	DeletadoEm time.Time       `gorm:"column:deletedAt" json:"deletadoEm"`
+	Vinculado  bool            `gorm:"column:vinculado" json:"-"`
+	ExcluirEm  *time.Time      `gorm:"-" json:"excluirEm"`
+}
+
+// Condições para a exclusão definitiva: nenhum registro que continua no sistema aponta para o item
+const (
+	categoriaDespesaSemVinculos = "NOT EXISTS (SELECT 1 FROM Despesa d WHERE d.categoriaId = CategoriaDespesa.id AND (d.deletedAt IS NULL OR d.deletedAt <> CategoriaDespesa.deletedAt)) " +
+		"AND NOT EXISTS (SELECT 1 FROM DespesaRecorrente r WHERE r.categoriaId = CategoriaDespesa.id)"
+
+	categoriaProdutoSemVinculos = "NOT EXISTS (SELECT 1 FROM ProdutoComprado p WHERE p.categoriaId = CategoriaProduto.id) " +
+		"AND NOT EXISTS (SELECT 1 FROM ProdutoSKU s WHERE s.categoriaId = CategoriaProduto.id) " +
+		"AND NOT EXISTS (SELECT 1 FROM RegraPrecificacao r WHERE r.categoriaId = CategoriaProduto.id) " +
+		"AND NOT EXISTS (SELECT 1 FROM RegraComissao r WHERE r.categoriaId = CategoriaProduto.id) " +
+		"AND NOT EXISTS (SELECT 1 FROM GarantiaTermo g WHERE g.categoriaId = CategoriaProduto.id) " +
+		"AND NOT EXISTS (SELECT 1 FROM Meta m WHERE m.categoriaId = CategoriaProduto.id)"
+
+	produtoSemVinculos = "NOT EXISTS (SELECT 1 FROM Estoque e JOIN HistoricoVenda hv ON hv.estoqueId = e.id WHERE e.produtoCompradoId = ProdutoComprado.id) " +
+		"AND NOT EXISTS (SELECT 1 FROM Estoque e JOIN DevolucaoVenda dv ON dv.estoqueId = e.id WHERE e.produtoCompradoId = ProdutoComprado.id) " +
+		"AND NOT EXISTS (SELECT 1 FROM Estoque e JOIN ReservaEstoque re ON re.estoqueId = e.id WHERE e.produtoCompradoId = ProdutoComprado.id) " +
+		"AND NOT EXISTS (SELECT 1 FROM Estoque e WHERE e.produtoCompradoId = ProdutoComprado.id AND (e.deletedAt IS NULL OR e.deletedAt <> ProdutoComprado.deletedAt)) " +
+		"AND NOT EXISTS (SELECT 1 FROM AparelhoTroca a WHERE a.produtoCompradoId = ProdutoComprado.id) " +
+		"AND NOT EXISTS (SELECT 1 FROM GarantiaChamado g WHERE g.produtoCompradoId = ProdutoComprado.id)"
+)
+
+// Listar lista os itens na lixeira do tipo informado (vazio: todos), dos deletados mais recentemente
+func Listar(db *gorm.DB, tipo string, retencao time.Duration) ([]Item, error) {
+	tipos := Tipos
+	if tipo != "" {
+		if !tipoValido(tipo) {
+			return nil, ErrTipoInvalido
+		}
+		tipos = []string{tipo}
+	}
+
+	itens := []Item{}
+	for _, t := range tipos {
+		var query *gorm.DB
+		switch t {
+		case TipoCategoriaDespesa:
+			query = db.Unscoped().Model(&models.CategoriaDespesa{}).
+				Select("id, nome, deletedAt, (SELECT COUNT(*) FROM Despesa d WHERE d.categoriaId = CategoriaDespesa.id AND d.deletedAt = CategoriaDespesa.deletedAt) as itens, NOT (" + categoriaDespesaSemVinculos + ") as vinculado")
+		case TipoDespesa:
+			query = db.Unscoped().Model(&models.Despesa{}).
+				Select("id, nome, valor, deletedAt, 0 as itens, false as vinculado").
+				// As despesas que foram junto com a categoria aparecem dentro dela
+				Where("NOT EXISTS (SELECT 1 FROM CategoriaDespesa cd WHERE cd.id = Despesa.categoriaId AND cd.deletedAt = Despesa.deletedAt)")
+		case TipoCategoriaProduto:
+			query = db.Unscoped().Model(&models.CategoriaProduto{}).
+				Select("id, nome, deletedAt, 0 as itens, NOT (" + categoriaProdutoSemVinculos + ") as vinculado")
+		case TipoProduto:
+			query = db.Unscoped().Model(&models.ProdutoComprado{}).
+				Select("id, nome, imei, deletedAt, (SELECT COUNT(*) FROM Estoque e WHERE e.produtoCompradoId = ProdutoComprado.id AND e.deletedAt = ProdutoComprado.deletedAt) as itens, NOT (" + produtoSemVinculos + ") as vinculado")
+		}
+
+		var encontrados []Item
+		if err := query.Where("deletedAt IS NOT NULL").Order("deletedAt DESC").Scan(&encontrados).Error; err != nil {
+			return nil, err
+		}
+		for i := range encontrados {
+			encontrados[i].Tipo = t
+			if !encontrados[i].Vinculado {
+				excluirEm := encontrados[i].DeletadoEm.Add(retencao)
+				encontrados[i].ExcluirEm = &excluirEm
+			}
+		}
+		itens = append(itens, encontrados...)
+	}
+	return itens, nil
+}
+
+func tipoValido(tipo string) bool {
+	for _, t := range Tipos {
+		if t == tipo {
+			return true
+		}
+	}
+	return false
+}
+
+// Restaurar tira o item da lixeira, com o que foi para a lixeira junto com ele. Uma despesa ou um produto
+// cuja categoria está na lixeira só volta depois da categoria (ErrCategoriaNaLixeira).
+func Restaurar(db *gorm.DB, tipo string, id int) error {
+	if !tipoValido(tipo) {
+		return ErrTipoInvalido
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		switch tipo {
+		case TipoCategoriaDespesa:
+			var categoria models.CategoriaDespesa
+			if err := buscarNaLixeira(tx, &categoria, id); err != nil {
+				return err
+			}
+			if err := tx.Unscoped().Model(&models.Despesa{}).
+				Where("categoriaId = ? AND deletedAt = ?", id, categoria.DeletedAt.Time).
+				Update("deletedAt", nil).Error; err != nil {
+				return err
+			}
+			return tx.Unscoped().Model(&categoria).Update("deletedAt", nil).Error
+
+		case TipoDespesa:
+			var despesa models.Despesa
+			if err := buscarNaLixeira(tx, &despesa, id); err != nil {
+				return err
+			}
+			if err := exigirCategoria(tx, &models.CategoriaDespesa{}, &despesa.CategoriaID); err != nil {
+				return err
+			}
+			return tx.Unscoped().Model(&despesa).Update("deletedAt", nil).Error
+
+		case TipoCategoriaProduto:
+			var categoria models.CategoriaProduto
+			if err := buscarNaLixeira(tx, &categoria, id); err != nil {
+				return err
+			}
+			return tx.Unscoped().Model(&categoria).Update("deletedAt", nil).Error
+
+		default:
+			var produto models.ProdutoComprado
+			if err := buscarNaLixeira(tx, &produto, id); err != nil {
+				return err
+			}
+			if err := exigirCategoria(tx, &models.CategoriaProduto{}, produto.CategoriaID); err != nil {
+				return err
+			}
+			if err := tx.Unscoped().Model(&models.Estoque{}).
+				Where("produtoCompradoId = ? AND deletedAt = ?", id, produto.DeletedAt.Time).
+				Update("deletedAt", nil).Error; err != nil {
+				return err
+			}
+			return tx.Unscoped().Model(&produto).Update("deletedAt", nil).Error
+		}
+	})
+}
+
+func buscarNaLixeira(tx *gorm.DB, destino interface{}, id int) error {
+	err := tx.Unscoped().Where("id = ? AND deletedAt IS NOT NULL", id).First(destino).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNaoEncontrado
+	}
+	return err
+}
+
+// exigirCategoria falha com ErrCategoriaNaLixeira se a categoria informada não está ativa
+func exigirCategoria(tx *gorm.DB, modelo interface{}, categoriaID *int) error {
+	if categoriaID == nil {
+		return nil
+	}
+	var count int64
+	if err := tx.Model(modelo).Where("id = ?", *categoriaID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrCategoriaNaLixeira
+	}
+	return nil
+}
+
+// Purgar exclui definitivamente o que está na lixeira desde antes de limite, com os anexos (e seus arquivos),
+// orçamentos, estoques e históricos de distribuição. Registros ainda referenciados ficam na lixeira.
+// Retorna quantos itens foram excluídos.
+func Purgar(db *gorm.DB, limite time.Time) (int, error) {
+	total := 0
+
+	// Despesas primeiro: a categoria só sai quando não tem mais despesas
+	var despesaIDs []int
+	if err := db.Unscoped().Model(&models.Despesa{}).
+		Where("deletedAt IS NOT NULL AND deletedAt < ?", limite).
+		Pluck("id", &despesaIDs).Error; err != nil {
+		return total, err
+	}
+	if len(despesaIDs) > 0 {
+		var anexos []models.AnexoDespesa
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Where("despesaId IN ?", despesaIDs).Find(&anexos).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("despesaId IN ?", despesaIDs).Delete(&models.AnexoDespesa{}).Error; err != nil {
+				return err
+			}
+			return tx.Unscoped().Delete(&models.Despesa{}, despesaIDs).Error
+		})
+		if err != nil {
+			return total, err
+		}
+		total += len(despesaIDs)
+		for _, a := range anexos {
+			if err := storage.Remover(storage.RaizArquivos, a.Caminho); err != nil {
+				log.Printf("Erro ao remover anexo %s: %v", a.Caminho, err)
+			}
+		}
+	}
+
+	var categoriaDespesaIDs []int
+	if err := db.Unscoped().Model(&models.CategoriaDespesa{}).
+		Where("deletedAt IS NOT NULL AND deletedAt < ? AND "+categoriaDespesaSemVinculos, limite).
+		Pluck("id", &categoriaDespesaIDs).Error; err != nil {
+		return total, err
+	}
+	if len(categoriaDespesaIDs) > 0 {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Where("categoriaId IN ?", categoriaDespesaIDs).Delete(&models.OrcamentoDespesa{}).Error; err != nil {
+				return err
+			}
+			return tx.Unscoped().Delete(&models.CategoriaDespesa{}, categoriaDespesaIDs).Error
+		})
+		if err != nil {
+			return total, err
+		}
+		total += len(categoriaDespesaIDs)
+	}
+
+	// Produtos antes das categorias de produtos, pelo mesmo motivo
+	var produtoIDs []int
+	if err := db.Unscoped().Model(&models.ProdutoComprado{}).
+		Where("deletedAt IS NOT NULL AND deletedAt < ? AND "+produtoSemVinculos, limite).
+		Pluck("id", &produtoIDs).Error; err != nil {
+		return total, err
+	}
+	if len(produtoIDs) > 0 {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Where("produtoCompradoId IN ?", produtoIDs).Delete(&models.HistoricoDistribuicao{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Unscoped().Where("produtoCompradoId IN ?", produtoIDs).Delete(&models.Estoque{}).Error; err != nil {
+				return err
+			}
+			return tx.Unscoped().Delete(&models.ProdutoComprado{}, produtoIDs).Error
+		})
+		if err != nil {
+			return total, err
+		}
+		total += len(produtoIDs)
+	}
+
+	var categoriaProdutoIDs []int
+	if err := db.Unscoped().Model(&models.CategoriaProduto{}).
+		Where("deletedAt IS NOT NULL AND deletedAt < ? AND "+categoriaProdutoSemVinculos, limite).
+		Pluck("id", &categoriaProdutoIDs).Error; err != nil {
+		return total, err
+	}
+	if len(categoriaProdutoIDs) > 0 {
+		if err := db.Unscoped().Delete(&models.CategoriaProduto{}, categoriaProdutoIDs).Error; err != nil {
+			return total, err
+		}
+		total += len(categoriaProdutoIDs)
+	}
+
+	return total, nil
+}
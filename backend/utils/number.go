@@ -3,6 +3,7 @@ package utils
 import (
 	"fmt"
 	"strconv"
+	"strings"
 )
 
 // ReplaceCommaWithDot substitui vírgula por ponto (formato brasileiro)
@@ -77,8 +78,45 @@ func ParseIntFlexible(v interface{}) (*int, error) {
 }
 
 // ParseFloatBR converte string brasileira (com vírgula) para float64
+// Quando há vírgula decimal, pontos são separadores de milhar: "1.234,56" -> 1234.56
 func ParseFloatBR(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	if strings.Contains(s, ",") {
+		s = strings.ReplaceAll(s, ".", "")
+	}
 	s = ReplaceCommaWithDot(s)
 	return strconv.ParseFloat(s, 64)
 }
 
+
+// FormatFloatBR formata um número no padrão brasileiro com separador de milhar
+// Exemplo: FormatFloatBR(1234.5, 2) -> "1.234,50"
+func FormatFloatBR(v float64, casas int) string {
+	s := strconv.FormatFloat(v, 'f', casas, 64)
+
+	sinal := ""
+	if strings.HasPrefix(s, "-") {
+		sinal = "-"
+		s = s[1:]
+	}
+
+	inteiro, decimal := s, ""
+	if idx := strings.IndexByte(s, '.'); idx >= 0 {
+		inteiro, decimal = s[:idx], s[idx+1:]
+	}
+
+	var b strings.Builder
+	for i, char := range inteiro {
+		if i > 0 && (len(inteiro)-i)%3 == 0 {
+			b.WriteByte('.')
+		}
+		b.WriteRune(char)
+	}
+
+	if decimal != "" {
+		b.WriteByte(',')
+		b.WriteString(decimal)
+	}
+
+	return sinal + b.String()
+}
@@ -0,0 +1,215 @@
+// Package warranty calcula o prazo de garantia das unidades vendidas a partir dos termos
+// por SKU e por categoria e controla a situação dos chamados de garantia.
+package warranty
+
+import (
+	"strings"
+	"time"
+
+	"cmdimport/backend/models"
+	"cmdimport/backend/timezone"
+
+	"gorm.io/gorm"
+)
+
+// DiasPadrao é o prazo usado quando nenhum termo se aplica (nem o padrão da loja)
+const DiasPadrao = 90
+
+// Origem do prazo aplicado a uma unidade
+const (
+	OrigemSKU       = "sku"
+	OrigemCategoria = "categoria"
+	OrigemPadrao    = "padrao"
+)
+
+// Situações de um chamado de garantia
+const (
+	StatusAberto   = "aberto"
+	StatusEmReparo = "em_reparo"
+	StatusTrocado  = "trocado"
+	StatusReparado = "reparado"
+	StatusRecusado = "recusado"
+)
+
+// transicoes lista para quais situações cada situação pode ir
+var transicoes = map[string][]string{
+	StatusAberto:   {StatusEmReparo, StatusTrocado, StatusReparado, StatusRecusado},
+	StatusEmReparo: {StatusTrocado, StatusReparado, StatusRecusado},
+}
+
+// StatusValido indica se a situação existe
+func StatusValido(status string) bool {
+	switch status {
+	case StatusAberto, StatusEmReparo, StatusTrocado, StatusReparado, StatusRecusado:
+		return true
+	}
+	return false
+}
+
+// Encerrado indica uma situação final (trocado, reparado ou recusado)
+func Encerrado(status string) bool {
+	_, ok := transicoes[status]
+	return StatusValido(status) && !ok
+}
+
+// PodeMudar indica se o chamado pode passar de uma situação para outra
+func PodeMudar(de, para string) bool {
+	for _, s := range transicoes[de] {
+		if s == para {
+			return true
+		}
+	}
+	return false
+}
+
+// Termos são os termos de garantia ativos, indexados para consulta
+type Termos struct {
+	porSKU       map[int]models.GarantiaTermo
+	porCategoria map[int]models.GarantiaTermo
+	padrao       *models.GarantiaTermo
+}
+
+// CarregarTermos lê os termos de garantia ativos
+func CarregarTermos(db *gorm.DB) (Termos, error) {
+	var lista []models.GarantiaTermo
+	if err := db.Where("ativo = ?", true).Find(&lista).Error; err != nil {
+		return Termos{}, err
+	}
+
+	t := Termos{porSKU: make(map[int]models.GarantiaTermo), porCategoria: make(map[int]models.GarantiaTermo)}
+	for i, termo := range lista {
+		switch {
+		case termo.SKUID != nil:
+			t.porSKU[*termo.SKUID] = termo
+		case termo.CategoriaID != nil:
+			t.porCategoria[*termo.CategoriaID] = termo
+		default:
+			t.padrao = &lista[i]
+		}
+	}
+	return t, nil
+}
+
+// Termo escolhe o prazo do produto: termo do SKU, depois o da categoria e por fim o padrão da loja
+func (t Termos) Termo(produto models.ProdutoComprado) (dias int, descricao *string, origem string) {
+	if produto.SKUID != nil {
+		if termo, ok := t.porSKU[*produto.SKUID]; ok {
+			return termo.Dias, termo.Descricao, OrigemSKU
+		}
+	}
+	if produto.CategoriaID != nil {
+		if termo, ok := t.porCategoria[*produto.CategoriaID]; ok {
+			return termo.Dias, termo.Descricao, OrigemCategoria
+		}
+	}
+	if t.padrao != nil {
+		return t.padrao.Dias, t.padrao.Descricao, OrigemPadrao
+	}
+	return DiasPadrao, nil, OrigemPadrao
+}
+
+// Situacao é o prazo de garantia de uma unidade vendida
+type Situacao struct {
+	Dias          int       `json:"dias"`
+	Origem        string    `json:"origem"`
+	Descricao     *string   `json:"descricao"`
+	Inicio        time.Time `json:"inicio"`
+	Fim           time.Time `json:"fim"` // Último dia coberto, até 23:59:59
+	Ativa         bool      `json:"ativa"`
+	DiasRestantes int       `json:"diasRestantes"`
+}
+
+// Calcular conta o prazo a partir do dia da venda, no fuso do negócio. A garantia vale até o fim do último dia.
+func (t Termos) Calcular(produto models.ProdutoComprado, dataVenda, agora time.Time) Situacao {
+	dias, descricao, origem := t.Termo(produto)
+
+	inicio := timezone.InicioDoDia(dataVenda)
+	fim := timezone.InicioDoDia(inicio.AddDate(0, 0, dias+1)).Add(-time.Second)
+
+	s := Situacao{Dias: dias, Origem: origem, Descricao: descricao, Inicio: inicio, Fim: fim}
+	if !agora.After(fim) {
+		s.Ativa = true
+		s.DiasRestantes = int(timezone.Data(fim).Sub(timezone.Data(agora)).Hours() / 24)
+	}
+	return s
+}
+
+// Unidade é uma unidade vendida: a linha da venda e o produto do estoque
+type Unidade struct {
+	Venda   models.HistoricoVenda
+	Produto models.ProdutoComprado
+}
+
+// incluirLixeira faz o Preload trazer também produto e estoque que estão na lixeira
+func incluirLixeira(db *gorm.DB) *gorm.DB {
+	return db.Unscoped()
+}
+
+// BuscarPorIMEI retorna as vendas do aparelho com o IMEI, da mais recente para a mais antiga
+// (um aparelho devolvido ou recebido em troca pode ter sido vendido mais de uma vez)
+func BuscarPorIMEI(db *gorm.DB, imei string) ([]Unidade, error) {
+	imei = strings.TrimSpace(imei)
+	if imei == "" {
+		return nil, nil
+	}
+
+	// Produtos na lixeira entram: a garantia de quem comprou continua valendo
+	var produtos []models.ProdutoComprado
+	if err := db.Unscoped().Where("imei = ?", imei).Find(&produtos).Error; err != nil {
+		return nil, err
+	}
+	if len(produtos) == 0 {
+		return nil, nil
+	}
+	porID := make(map[int]models.ProdutoComprado, len(produtos))
+	ids := make([]int, len(produtos))
+	for i, p := range produtos {
+		porID[p.ID] = p
+		ids[i] = p.ID
+	}
+
+	var vendas []models.HistoricoVenda
+	if err := db.Preload("Estoque", incluirLixeira).
+		Joins("JOIN Estoque e ON e.id = HistoricoVenda.estoqueId").
+		Where("e.produtoCompradoId IN ?", ids).
+		Order("HistoricoVenda.createdAt DESC").
+		Find(&vendas).Error; err != nil {
+		return nil, err
+	}
+
+	unidades := make([]Unidade, len(vendas))
+	for i, v := range vendas {
+		unidades[i] = Unidade{Venda: v, Produto: porID[v.Estoque.ProdutoCompradoID]}
+	}
+	return unidades, nil
+}
+
+// BuscarUnidade retorna a unidade vendida de uma linha de HistoricoVenda
+func BuscarUnidade(db *gorm.DB, historicoVendaID int) (*Unidade, error) {
+	var venda models.HistoricoVenda
+	if err := db.Preload("Estoque", incluirLixeira).Preload("Estoque.ProdutoComprado", incluirLixeira).
+		First(&venda, historicoVendaID).Error; err != nil {
+		return nil, err
+	}
+	return &Unidade{Venda: venda, Produto: venda.Estoque.ProdutoComprado}, nil
+}
+
+// MesmoTelefone compara dois telefones pelos últimos 8 dígitos, ignorando formatação,
+// DDI, DDD e o nono dígito
+func MesmoTelefone(a, b string) bool {
+	da, db := digitos(a), digitos(b)
+	if len(da) < 8 || len(db) < 8 {
+		return false
+	}
+	return da[len(da)-8:] == db[len(db)-8:]
+}
+
+func digitos(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}